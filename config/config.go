@@ -4,12 +4,14 @@ import (
 	"crypto/rand"
 	"encoding/hex"
 	"fmt"
-	"log"
+	"net/url"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 	"github.com/nodeloc-faka/database"
+	"github.com/nodeloc-faka/logging"
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -32,10 +34,38 @@ type Config struct {
 	AdminPath     string
 	AdminUsername string
 	AdminPassword string
+
+	// 安全配置
+	CookieSecure   bool     // Cookie 是否只通过 HTTPS 发送
+	CookieSameSite string   // Cookie SameSite 策略：lax / strict / none
+	TrustedOrigins []string // 允许发起写请求的来源（CSRF 校验），同域请求始终允许
+	TrustedProxies []string // 可信反向代理的 IP 或网段，只有来自这些地址的 X-Forwarded-For 才被采信；为空时使用连接地址作为客户端 IP
+
+	// 限流配置
+	RateLimitBackend string            // 限流存储后端：memory / mysql
+	RateLimits       map[string]string // 覆盖内置限流规则，如 order_create=10/1m
+
+	// 接口文档
+	OpenAPIValidateResponses bool // 按 OpenAPI 文档校验响应，不一致时记录日志（用于测试环境）
+
+	// 销售统计
+	AnalyticsLocation *time.Location // 按该时区划分统计日期
+
+	// 监控指标
+	MetricsToken string // 访问 /metrics 需携带的 Bearer 令牌，为空时主端口不开放 /metrics
+	MetricsAddr  string // 单独提供 /metrics 的监听地址（如 :9090），设置 MetricsToken 时同样校验令牌
+
+	// 日志
+	LogLevel  string            // 默认日志级别：debug / info / warn / error
+	LogFormat string            // 日志格式：text / json
+	LogLevels map[string]string // 按包覆盖日志级别，如 services=debug,access=warn
 }
 
 var AppConfig *Config
 
+// logger 配置加载日志，在日志配置生效前按默认格式输出
+var logger = logging.For("config")
+
 // LoadConfig 加载配置
 func LoadConfig() (*Config, error) {
 	// 尝试加载 .env 文件（如果存在）
@@ -60,10 +90,35 @@ func LoadConfig() (*Config, error) {
 		AdminPassword: getEnv("ADMIN_PASSWORD", ""),
 	}
 
+	// 回调地址为 HTTPS 时默认启用 Secure Cookie，并信任回调地址所在的站点
+	redirectOrigin := originOf(config.NodeLocRedirectURI)
+	config.CookieSecure = getEnv("COOKIE_SECURE", fmt.Sprint(strings.HasPrefix(redirectOrigin, "https://"))) == "true"
+	config.CookieSameSite = strings.ToLower(getEnv("COOKIE_SAMESITE", "lax"))
+	config.TrustedOrigins = getEnvList("CSRF_TRUSTED_ORIGINS", redirectOrigin)
+	config.TrustedProxies = getEnvList("TRUSTED_PROXIES", "")
+
+	config.RateLimitBackend = strings.ToLower(getEnv("RATE_LIMIT_BACKEND", "memory"))
+	config.RateLimits = getEnvMap("RATE_LIMITS")
+
+	config.OpenAPIValidateResponses = getEnv("OPENAPI_VALIDATE_RESPONSES", "false") == "true"
+
+	location, err := time.LoadLocation(getEnv("ANALYTICS_TIMEZONE", "Local"))
+	if err != nil {
+		return nil, fmt.Errorf("ANALYTICS_TIMEZONE 无效: %w", err)
+	}
+	config.AnalyticsLocation = location
+
+	config.MetricsToken = getEnv("METRICS_TOKEN", "")
+	config.MetricsAddr = getEnv("METRICS_ADDR", "")
+
+	config.LogLevel = strings.ToLower(getEnv("LOG_LEVEL", "info"))
+	config.LogFormat = strings.ToLower(getEnv("LOG_FORMAT", "text"))
+	config.LogLevels = getEnvMap("LOG_LEVELS")
+
 	// 如果没有设置SESSION_SECRET，则生成一个
 	if config.SessionSecret == "" {
 		config.SessionSecret = generateRandomString(32)
-		log.Println("生成随机 SESSION_SECRET")
+		logger.Warn("未设置 SESSION_SECRET，已生成随机密钥，重启后会话将失效")
 	}
 
 	AppConfig = config
@@ -114,6 +169,38 @@ func getEnv(key, defaultValue string) string {
 	return value
 }
 
+// getEnvList 获取逗号分隔的环境变量列表，如果不存在则使用默认值
+func getEnvList(key, defaultValue string) []string {
+	var list []string
+	for _, item := range strings.Split(getEnv(key, defaultValue), ",") {
+		if item = strings.TrimRight(strings.TrimSpace(item), "/"); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// getEnvMap 获取逗号分隔的 key=value 环境变量
+func getEnvMap(key string) map[string]string {
+	values := make(map[string]string)
+	for _, item := range strings.Split(getEnv(key, ""), ",") {
+		name, value, ok := strings.Cut(item, "=")
+		if name = strings.TrimSpace(name); ok && name != "" {
+			values[name] = strings.TrimSpace(value)
+		}
+	}
+	return values
+}
+
+// originOf 获取 URL 的来源（scheme://host），解析失败返回空字符串
+func originOf(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return ""
+	}
+	return u.Scheme + "://" + u.Host
+}
+
 // SaveToEnv 保存配置到 .env 文件
 func SaveToEnv(key, value string) error {
 	// 读取现有的 .env 文件
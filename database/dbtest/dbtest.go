@@ -0,0 +1,68 @@
+// Package dbtest 为测试提供临时 SQLite 数据库，只应在 _test.go 文件中引用
+package dbtest
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"github.com/nodeloc-faka/database"
+	"github.com/nodeloc-faka/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// Open 在测试临时目录中创建 SQLite 数据库，迁移全部表并设置为 database.DB
+// WAL 模式下读写可以并发，事务外的查询看不到未提交的写入，与 MySQL 的行为一致；
+// 事务以 IMMEDIATE 方式开始，并发事务依次执行，代替 SQLite 不支持的 SELECT ... FOR UPDATE 行锁
+// 测试结束时恢复原来的 database.DB；使用全局连接的测试不能并行执行
+func Open(t testing.TB) *gorm.DB {
+	t.Helper()
+
+	dsn := "file:" + filepath.Join(t.TempDir(), "test.db") + "?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_txlock=immediate"
+	db, err := gorm.Open(dialector{sqlite.Open(dsn).(*sqlite.Dialector)}, &gorm.Config{
+		Logger:                                   logger.Default.LogMode(logger.Silent),
+		DisableForeignKeyConstraintWhenMigrating: true,
+		TranslateError:                           true,
+	})
+	if err != nil {
+		t.Fatalf("打开测试数据库失败: %v", err)
+	}
+	if err := models.AutoMigrate(db); err != nil {
+		t.Fatalf("迁移测试数据库失败: %v", err)
+	}
+
+	previous := database.DB
+	database.DB = db
+	t.Cleanup(func() {
+		database.DB = previous
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return db
+}
+
+// dialector 测试用 SQLite 方言，迁移时跳过 SQLite 不支持的 MySQL 全文索引
+type dialector struct {
+	*sqlite.Dialector
+}
+
+func (d dialector) Migrator(db *gorm.DB) gorm.Migrator {
+	return migrator{Migrator: d.Dialector.Migrator(db), db: db}
+}
+
+type migrator struct {
+	gorm.Migrator
+	db *gorm.DB
+}
+
+func (m migrator) CreateIndex(value interface{}, name string) error {
+	stmt := &gorm.Statement{DB: m.db}
+	if err := stmt.Parse(value); err == nil {
+		if index := stmt.Schema.LookIndex(name); index != nil && index.Class == "FULLTEXT" {
+			return nil
+		}
+	}
+	return m.Migrator.CreateIndex(value, name)
+}
@@ -2,15 +2,18 @@ package database
 
 import (
 	"fmt"
-	"log"
 
+	"github.com/nodeloc-faka/logging"
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
-	"gorm.io/gorm/logger"
+	gormlogger "gorm.io/gorm/logger"
 )
 
 var DB *gorm.DB
 
+// logger 数据库连接日志，级别可通过 LOG_LEVELS=database=debug 单独设置
+var logger = logging.For("database")
+
 // Config 数据库配置
 type Config struct {
 	Host     string
@@ -31,9 +34,11 @@ func Connect(cfg *Config) (*gorm.DB, error) {
 	)
 
 	db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{
-		Logger: logger.Default.LogMode(logger.Silent), // 改为 Silent 避免 SQL 日志过多
+		Logger: gormlogger.Default.LogMode(gormlogger.Silent), // 改为 Silent 避免 SQL 日志过多
 		// 禁用外键约束（如果需要）
 		DisableForeignKeyConstraintWhenMigrating: true,
+		// 将唯一索引冲突转换为 gorm.ErrDuplicatedKey，供订单号冲突重试等场景判断
+		TranslateError: true,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect database: %w", err)
@@ -49,7 +54,7 @@ func Connect(cfg *Config) (*gorm.DB, error) {
 	sqlDB.SetMaxOpenConns(100)
 
 	DB = db
-	log.Println("Database connected successfully")
+	logger.Info("数据库连接成功", "host", cfg.Host, "port", cfg.Port, "database", cfg.DBName)
 	return db, nil
 }
 
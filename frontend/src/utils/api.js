@@ -6,9 +6,33 @@ const api = axios.create({
   withCredentials: true
 })
 
+// CSRF token: read from the csrf_token cookie, fetched once when missing
+const UNSAFE_METHODS = ['post', 'put', 'patch', 'delete']
+let csrfTokenRequest = null
+
+function readCsrfCookie() {
+  const match = document.cookie.match(/(?:^|;\s*)csrf_token=([^;]*)/)
+  return match ? decodeURIComponent(match[1]) : ''
+}
+
+async function getCsrfToken() {
+  const token = readCsrfCookie()
+  if (token) return token
+  if (!csrfTokenRequest) {
+    csrfTokenRequest = axios
+      .get(`${api.defaults.baseURL}/api/csrf-token`, { withCredentials: true })
+      .then(res => res.data.csrf_token)
+      .finally(() => { csrfTokenRequest = null })
+  }
+  return csrfTokenRequest
+}
+
 // Request interceptor
 api.interceptors.request.use(
-  config => {
+  async config => {
+    if (UNSAFE_METHODS.includes((config.method || 'get').toLowerCase())) {
+      config.headers['X-CSRF-Token'] = await getCsrfToken()
+    }
     return config
   },
   error => {
@@ -29,7 +53,10 @@ api.interceptors.response.use(
           window.location.href = '/login'
           break
         case 403:
-          // Forbidden
+          // Forbidden; drop a stale CSRF token so the next request fetches a fresh one
+          if (error.response.data && error.response.data.code === 'csrf_failed') {
+            document.cookie = 'csrf_token=; Max-Age=0; path=/'
+          }
           console.error('Access denied')
           break
         case 404:
@@ -46,4 +73,16 @@ api.interceptors.response.use(
   }
 )
 
+// Fetch every page of an admin list by following next_cursor (page_size is capped at 100 server-side)
+export async function fetchAllPages(url, key, params = {}) {
+  const items = []
+  let cursor = ''
+  do {
+    const response = await api.get(url, { params: { ...params, page_size: 100, cursor: cursor || undefined } })
+    items.push(...(response.data[key] || []))
+    cursor = response.data.next_cursor || ''
+  } while (cursor)
+  return items
+}
+
 export default api
@@ -17,6 +17,15 @@ export async function copyToClipboard(text) {
   }
 }
 
+/**
+ * Generate a random Idempotency-Key so a resubmitted checkout returns the original order
+ */
+export function newIdempotencyKey() {
+  if (window.crypto?.randomUUID) return window.crypto.randomUUID()
+  const bytes = window.crypto.getRandomValues(new Uint8Array(16))
+  return Array.from(bytes, b => b.toString(16).padStart(2, '0')).join('')
+}
+
 /**
  * Format price
  */
@@ -4,31 +4,60 @@ import (
 	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/nodeloc-faka/i18n"
+	"github.com/nodeloc-faka/middleware"
 	"github.com/nodeloc-faka/models"
 	"github.com/nodeloc-faka/services"
 )
 
 // AdminHandler 管理员 API 处理器
 type AdminHandler struct {
-	categoryService *services.CategoryService
-	productService  *services.ProductService
-	cardKeyService  *services.CardKeyService
-	orderService    *services.OrderService
-	userService     *services.UserService
-	settingService  *services.SettingService
+	categoryService       *services.CategoryService
+	tagService            *services.TagService
+	productService        *services.ProductService
+	cardKeyService        *services.CardKeyService
+	orderService          *services.OrderService
+	userService           *services.UserService
+	settingService        *services.SettingService
+	fulfillmentService    *services.FulfillmentService
+	variantService        *services.VariantService
+	pricingService        *services.PricingService
+	flashSaleService      *services.FlashSaleService
+	bulkService           *services.BulkService
+	adminService          *services.AdminService
+	auditService          *services.AuditService
+	twoFactorService      *services.TwoFactorService
+	abuseService          *services.AbuseService
+	translationService    *services.TranslationService
+	analyticsService      *services.AnalyticsService
+	reconciliationService *services.ReconciliationService
 }
 
 // NewAdminHandler 创建管理员处理器
 func NewAdminHandler() *AdminHandler {
 	return &AdminHandler{
-		categoryService: services.NewCategoryService(),
-		productService:  services.NewProductService(),
-		cardKeyService:  services.NewCardKeyService(),
-		orderService:    services.NewOrderService(),
-		userService:     services.NewUserService(),
-		settingService:  services.NewSettingService(),
+		categoryService:       services.NewCategoryService(),
+		tagService:            services.NewTagService(),
+		productService:        services.NewProductService(),
+		cardKeyService:        services.NewCardKeyService(),
+		orderService:          services.NewOrderService(),
+		userService:           services.NewUserService(),
+		settingService:        services.NewSettingService(),
+		fulfillmentService:    services.NewFulfillmentService(),
+		variantService:        services.NewVariantService(),
+		pricingService:        services.NewPricingService(),
+		flashSaleService:      services.NewFlashSaleService(),
+		bulkService:           services.NewBulkService(),
+		adminService:          services.NewAdminService(),
+		auditService:          services.NewAuditService(),
+		twoFactorService:      services.NewTwoFactorService(),
+		abuseService:          services.NewAbuseService(),
+		translationService:    services.NewTranslationService(),
+		analyticsService:      services.NewAnalyticsService(),
+		reconciliationService: services.NewReconciliationService(),
 	}
 }
 
@@ -36,11 +65,21 @@ func NewAdminHandler() *AdminHandler {
 // 商品分类管理
 // ============================================
 
-// GetCategories 获取所有分类
+// GetCategories 获取所有分类（tree=true 时返回分类树）
 func (h *AdminHandler) GetCategories(c *gin.Context) {
+	if c.Query("tree") == "true" {
+		tree, err := h.categoryService.GetTree(false)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"categories": tree})
+		return
+	}
+
 	categories, err := h.categoryService.GetAll()
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取分类失败"})
+		c.Error(err)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"categories": categories})
@@ -51,23 +90,28 @@ func (h *AdminHandler) GetCategory(c *gin.Context) {
 	id, _ := strconv.ParseUint(c.Param("id"), 10, 32)
 	category, err := h.categoryService.FindByID(uint(id))
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "分类不存在"})
+		c.Error(services.ErrCategoryNotFound)
 		return
 	}
+	category.Path = h.categoryService.Breadcrumb(category.ID)
+	category.Translations, _ = h.translationService.GetCategoryTranslations(category.ID)
 	c.JSON(http.StatusOK, gin.H{"category": category})
 }
 
 // CreateCategory 创建分类
 func (h *AdminHandler) CreateCategory(c *gin.Context) {
 	var req struct {
+		ParentID    uint   `json:"parent_id"` // 为 0 时创建顶级分类
 		Name        string `json:"name" binding:"required"`
 		Description string `json:"description"`
 		Icon        string `json:"icon"`
 		Sort        int    `json:"sort"`
 		IsActive    bool   `json:"is_active"`
+		// 其他语言的名称和描述，键为语言（如 en）
+		Translations map[string]services.LocalizedText `json:"translations"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "参数错误"})
+		c.Error(services.ErrInvalidRequest)
 		return
 	}
 
@@ -78,12 +122,23 @@ func (h *AdminHandler) CreateCategory(c *gin.Context) {
 		Sort:        req.Sort,
 		IsActive:    req.IsActive,
 	}
+	if req.ParentID > 0 {
+		category.ParentID = &req.ParentID
+	}
 
 	if err := h.categoryService.Create(category); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "创建分类失败"})
+		c.Error(err)
 		return
 	}
 
+	if len(req.Translations) > 0 {
+		if err := h.translationService.SetCategoryTranslations(category.ID, req.Translations); err != nil {
+			c.Error(err)
+			return
+		}
+		category.Translations, _ = h.translationService.GetCategoryTranslations(category.ID)
+	}
+
 	c.JSON(http.StatusOK, gin.H{"category": category})
 }
 
@@ -93,22 +148,32 @@ func (h *AdminHandler) UpdateCategory(c *gin.Context) {
 
 	category, err := h.categoryService.FindByID(uint(id))
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "分类不存在"})
+		c.Error(services.ErrCategoryNotFound)
 		return
 	}
 
 	var req struct {
+		ParentID    *uint  `json:"parent_id"` // 为 0 时移动到顶级
 		Name        string `json:"name"`
 		Description string `json:"description"`
 		Icon        string `json:"icon"`
 		Sort        *int   `json:"sort"`
 		IsActive    *bool  `json:"is_active"`
+		// 传入时整体替换其他语言的名称和描述
+		Translations *map[string]services.LocalizedText `json:"translations"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "参数错误"})
+		c.Error(services.ErrInvalidRequest)
 		return
 	}
 
+	if req.ParentID != nil {
+		if *req.ParentID == 0 {
+			category.ParentID = nil
+		} else {
+			category.ParentID = req.ParentID
+		}
+	}
 	if req.Name != "" {
 		category.Name = req.Name
 	}
@@ -126,21 +191,29 @@ func (h *AdminHandler) UpdateCategory(c *gin.Context) {
 	}
 
 	if err := h.categoryService.Update(category); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新分类失败"})
+		c.Error(err)
 		return
 	}
 
+	if req.Translations != nil {
+		if err := h.translationService.SetCategoryTranslations(category.ID, *req.Translations); err != nil {
+			c.Error(err)
+			return
+		}
+	}
+	category.Translations, _ = h.translationService.GetCategoryTranslations(category.ID)
+
 	c.JSON(http.StatusOK, gin.H{"category": category})
 }
 
-// DeleteCategory 删除分类
+// DeleteCategory 删除分类（lift_children=true 时将子分类上移一级）
 func (h *AdminHandler) DeleteCategory(c *gin.Context) {
 	id, _ := strconv.ParseUint(c.Param("id"), 10, 32)
-	if err := h.categoryService.Delete(uint(id)); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除分类失败"})
+	if err := h.categoryService.Delete(uint(id), c.Query("lift_children") == "true"); err != nil {
+		c.Error(err)
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"message": "删除成功"})
+	c.JSON(http.StatusOK, gin.H{"message": middleware.T(c, "message.deleted")})
 }
 
 // ============================================
@@ -149,21 +222,13 @@ func (h *AdminHandler) DeleteCategory(c *gin.Context) {
 
 // GetProducts 获取所有商品（分页）
 func (h *AdminHandler) GetProducts(c *gin.Context) {
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
-
-	products, total, err := h.productService.GetWithPagination(page, pageSize)
+	products, page, err := h.productService.GetWithPagination(parseListQuery(c, services.DefaultListPageSize))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取商品失败"})
+		c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"products": products,
-		"total":    total,
-		"page":     page,
-		"pageSize": pageSize,
-	})
+	c.JSON(http.StatusOK, listResponse("products", products, page))
 }
 
 // GetProduct 获取单个商品
@@ -171,47 +236,76 @@ func (h *AdminHandler) GetProduct(c *gin.Context) {
 	id, _ := strconv.ParseUint(c.Param("id"), 10, 32)
 	product, err := h.productService.FindByID(uint(id))
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "商品不存在"})
+		c.Error(services.ErrProductNotFound)
 		return
 	}
+	product.Translations, _ = h.translationService.GetProductTranslations(product.ID)
 	c.JSON(http.StatusOK, gin.H{"product": product})
 }
 
 // CreateProduct 创建商品
 func (h *AdminHandler) CreateProduct(c *gin.Context) {
 	var req struct {
-		CategoryID  uint    `json:"category_id" binding:"required"`
-		Name        string  `json:"name" binding:"required"`
-		Description string  `json:"description"`
-		Price       float64 `json:"price" binding:"required"`
-		OrigPrice   float64 `json:"orig_price"`
-		Image       string  `json:"image"`
-		Sort        int     `json:"sort"`
-		IsActive    bool    `json:"is_active"`
+		CategoryID   uint    `json:"category_id" binding:"required"`
+		Name         string  `json:"name" binding:"required"`
+		Description  string  `json:"description"`
+		Price        float64 `json:"price" binding:"required"`
+		OrigPrice    float64 `json:"orig_price"`
+		Image        string  `json:"image"`
+		Sort         int     `json:"sort"`
+		IsActive     bool    `json:"is_active"`
+		DeliveryType string  `json:"delivery_type"` // 发货方式：auto / manual，默认 auto
+		TagIDs       []uint  `json:"tag_ids"`
+		// 其他语言的名称和描述，键为语言（如 en）
+		Translations map[string]services.LocalizedText `json:"translations"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "参数错误"})
+		c.Error(services.ErrInvalidRequest)
+		return
+	}
+
+	if req.DeliveryType == "" {
+		req.DeliveryType = models.DeliveryTypeAuto
+	}
+	if !validDeliveryType(req.DeliveryType) {
+		c.Error(services.ErrInvalidDeliveryType)
 		return
 	}
 
 	product := &models.Product{
-		CategoryID:  req.CategoryID,
-		Name:        req.Name,
-		Description: req.Description,
-		Price:       req.Price,
-		OrigPrice:   req.OrigPrice,
-		Image:       req.Image,
-		Sort:        req.Sort,
-		IsActive:    req.IsActive,
-		StockCount:  0,
-		SalesCount:  0,
+		CategoryID:   req.CategoryID,
+		Name:         req.Name,
+		Description:  req.Description,
+		Price:        req.Price,
+		OrigPrice:    req.OrigPrice,
+		Image:        req.Image,
+		Sort:         req.Sort,
+		IsActive:     req.IsActive,
+		StockCount:   0,
+		SalesCount:   0,
+		DeliveryType: req.DeliveryType,
 	}
 
 	if err := h.productService.Create(product); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "创建商品失败"})
+		c.Error(err)
 		return
 	}
 
+	if len(req.TagIDs) > 0 {
+		if err := h.productService.SetTags(product, req.TagIDs); err != nil {
+			c.Error(err)
+			return
+		}
+	}
+
+	if len(req.Translations) > 0 {
+		if err := h.translationService.SetProductTranslations(product.ID, req.Translations); err != nil {
+			c.Error(err)
+			return
+		}
+		product.Translations, _ = h.translationService.GetProductTranslations(product.ID)
+	}
+
 	c.JSON(http.StatusOK, gin.H{"product": product})
 }
 
@@ -221,22 +315,26 @@ func (h *AdminHandler) UpdateProduct(c *gin.Context) {
 
 	product, err := h.productService.FindByID(uint(id))
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "商品不存在"})
+		c.Error(services.ErrProductNotFound)
 		return
 	}
 
 	var req struct {
-		CategoryID  *uint    `json:"category_id"`
-		Name        string   `json:"name"`
-		Description string   `json:"description"`
-		Price       *float64 `json:"price"`
-		OrigPrice   *float64 `json:"orig_price"`
-		Image       string   `json:"image"`
-		Sort        *int     `json:"sort"`
-		IsActive    *bool    `json:"is_active"`
+		CategoryID   *uint    `json:"category_id"`
+		Name         string   `json:"name"`
+		Description  string   `json:"description"`
+		Price        *float64 `json:"price"`
+		OrigPrice    *float64 `json:"orig_price"`
+		Image        string   `json:"image"`
+		Sort         *int     `json:"sort"`
+		IsActive     *bool    `json:"is_active"`
+		DeliveryType string   `json:"delivery_type"`
+		TagIDs       *[]uint  `json:"tag_ids"` // 传入时整体替换商品标签
+		// 传入时整体替换其他语言的名称和描述
+		Translations *map[string]services.LocalizedText `json:"translations"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "参数错误"})
+		c.Error(services.ErrInvalidRequest)
 		return
 	}
 
@@ -249,6 +347,14 @@ func (h *AdminHandler) UpdateProduct(c *gin.Context) {
 	if req.Description != "" {
 		product.Description = req.Description
 	}
+	// 商品价格取启用规格的最低价，只有一个规格时可以直接修改（同步到该规格）
+	// 编辑表单会原样提交当前价格，只有价格实际变化时才视为修改
+	priceChanged := (req.Price != nil && *req.Price != product.Price) ||
+		(req.OrigPrice != nil && *req.OrigPrice != product.OrigPrice)
+	if priceChanged && len(product.Variants) != 1 {
+		c.Error(services.ErrProductPriceLocked)
+		return
+	}
 	if req.Price != nil {
 		product.Price = *req.Price
 	}
@@ -264,12 +370,44 @@ func (h *AdminHandler) UpdateProduct(c *gin.Context) {
 	if req.IsActive != nil {
 		product.IsActive = *req.IsActive
 	}
+	if req.DeliveryType != "" {
+		if !validDeliveryType(req.DeliveryType) {
+			c.Error(services.ErrInvalidDeliveryType)
+			return
+		}
+		product.DeliveryType = req.DeliveryType
+	}
 
 	if err := h.productService.Update(product); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新商品失败"})
+		c.Error(err)
 		return
 	}
 
+	if priceChanged {
+		variant := product.Variants[0]
+		variant.Price = product.Price
+		variant.OrigPrice = product.OrigPrice
+		if err := h.variantService.Update(&variant); err != nil {
+			c.Error(err)
+			return
+		}
+	}
+
+	if req.TagIDs != nil {
+		if err := h.productService.SetTags(product, *req.TagIDs); err != nil {
+			c.Error(err)
+			return
+		}
+	}
+
+	if req.Translations != nil {
+		if err := h.translationService.SetProductTranslations(product.ID, *req.Translations); err != nil {
+			c.Error(err)
+			return
+		}
+	}
+	product.Translations, _ = h.translationService.GetProductTranslations(product.ID)
+
 	c.JSON(http.StatusOK, gin.H{"product": product})
 }
 
@@ -277,88 +415,129 @@ func (h *AdminHandler) UpdateProduct(c *gin.Context) {
 func (h *AdminHandler) DeleteProduct(c *gin.Context) {
 	id, _ := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err := h.productService.Delete(uint(id)); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除商品失败"})
+		c.Error(err)
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"message": "删除成功"})
+	c.JSON(http.StatusOK, gin.H{"message": middleware.T(c, "message.deleted")})
+}
+
+// validDeliveryType 检查发货方式是否有效
+func validDeliveryType(deliveryType string) bool {
+	return deliveryType == models.DeliveryTypeAuto || deliveryType == models.DeliveryTypeManual
 }
 
 // ============================================
 // 卡密管理
 // ============================================
 
-// GetCardKeys 获取商品的卡密列表
+// GetCardKeys 获取卡密列表（可按商品、规格、状态和卡号筛选）
 func (h *AdminHandler) GetCardKeys(c *gin.Context) {
-	productID, _ := strconv.ParseUint(c.Query("product_id"), 10, 32)
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "50"))
+	filter, ok := parseCardKeyFilter(c)
+	if !ok {
+		return
+	}
 
-	cardKeys, total, err := h.cardKeyService.GetWithPagination(uint(productID), page, pageSize)
+	cardKeys, page, err := h.cardKeyService.GetWithPagination(filter, parseListQuery(c, 50))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取卡密失败"})
+		c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"card_keys": cardKeys,
-		"total":     total,
-		"page":      page,
-		"pageSize":  pageSize,
-	})
+	c.JSON(http.StatusOK, listResponse("card_keys", cardKeys, page))
+}
+
+// parseCardKeyFilter 解析卡密筛选条件
+func parseCardKeyFilter(c *gin.Context) (services.CardKeyFilter, bool) {
+	filter := services.CardKeyFilter{CardNo: strings.TrimSpace(c.Query("card_no"))}
+	var ok bool
+	if filter.ProductID, ok = queryUint(c, "product_id"); !ok {
+		return filter, false
+	}
+	if filter.VariantID, ok = queryUint(c, "variant_id"); !ok {
+		return filter, false
+	}
+	if filter.Status, ok = queryInt(c, "status"); !ok {
+		return filter, false
+	}
+	return filter, true
 }
 
 // AddCardKeys 批量添加卡密
 func (h *AdminHandler) AddCardKeys(c *gin.Context) {
 	var req struct {
 		ProductID uint   `json:"product_id" binding:"required"`
+		VariantID uint   `json:"variant_id"`                    // 为空时导入到默认规格
 		CardsText string `json:"cards_text" binding:"required"` // 格式：每行一个卡密，支持 "卡号----密码" 或只有卡号
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "参数错误"})
+		c.Error(services.ErrInvalidRequest)
+		return
+	}
+
+	variant, err := h.variantService.Resolve(req.ProductID, req.VariantID)
+	if err != nil {
+		c.Error(services.ErrVariantNotFound)
 		return
 	}
 
-	count, err := h.cardKeyService.BatchCreate(req.ProductID, req.CardsText)
+	count, err := h.cardKeyService.BatchCreate(req.ProductID, variant.ID, req.CardsText)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "添加卡密失败"})
+		c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "添加成功", "count": count})
+	c.JSON(http.StatusOK, gin.H{"message": middleware.T(c, "message.cards_added"), "count": count})
 }
 
 // DeleteCardKey 删除卡密
 func (h *AdminHandler) DeleteCardKey(c *gin.Context) {
 	id, _ := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err := h.cardKeyService.Delete(uint(id)); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除卡密失败"})
+		c.Error(err)
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"message": "删除成功"})
+	c.JSON(http.StatusOK, gin.H{"message": middleware.T(c, "message.deleted")})
 }
 
 // ============================================
 // 订单管理
 // ============================================
 
-// GetOrders 获取所有订单
+// GetOrders 获取订单列表（可按状态、用户、商品、下单时间、支付方式、交易号和联系方式筛选）
 func (h *AdminHandler) GetOrders(c *gin.Context) {
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
-	status, _ := strconv.Atoi(c.DefaultQuery("status", "-1"))
+	filter := services.OrderFilter{
+		PayMethod:     c.Query("pay_method"),
+		TransactionID: strings.TrimSpace(c.Query("transaction_id")),
+		Contact:       strings.TrimSpace(c.Query("contact")),
+	}
+	var ok bool
+	if filter.Status, ok = queryInt(c, "status"); !ok {
+		return
+	}
+	if filter.Status != nil && *filter.Status < 0 {
+		// -1 表示全部
+		filter.Status = nil
+	}
+	if filter.UserID, ok = queryUint(c, "user_id"); !ok {
+		return
+	}
+	if filter.ProductID, ok = queryUint(c, "product_id"); !ok {
+		return
+	}
+	if filter.From, ok = queryTime(c, "from"); !ok {
+		return
+	}
+	if filter.To, ok = queryTime(c, "to"); !ok {
+		return
+	}
 
-	orders, total, err := h.orderService.GetWithPagination(page, pageSize, status)
+	orders, page, err := h.orderService.GetWithPagination(filter, parseListQuery(c, services.DefaultListPageSize))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取订单失败"})
+		c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"orders":   orders,
-		"total":    total,
-		"page":     page,
-		"pageSize": pageSize,
-	})
+	c.JSON(http.StatusOK, listResponse("orders", orders, page))
 }
 
 // GetOrder 获取单个订单
@@ -366,7 +545,7 @@ func (h *AdminHandler) GetOrder(c *gin.Context) {
 	orderNo := c.Param("orderNo")
 	order, err := h.orderService.FindByOrderNo(orderNo)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "订单不存在"})
+		c.Error(services.ErrOrderNotFound)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"order": order})
@@ -379,13 +558,13 @@ func (h *AdminHandler) UpdateOrderStatus(c *gin.Context) {
 		Status int `json:"status" binding:"required"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "参数错误"})
+		c.Error(services.ErrInvalidRequest)
 		return
 	}
 
 	order, err := h.orderService.FindByOrderNo(orderNo)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "订单不存在"})
+		c.Error(services.ErrOrderNotFound)
 		return
 	}
 
@@ -398,39 +577,49 @@ func (h *AdminHandler) UpdateOrderStatus(c *gin.Context) {
 	case models.OrderStatusCancelled:
 		err = h.orderService.Cancel(order.ID)
 	default:
-		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的状态"})
+		c.Error(services.ErrInvalidOrderStatus)
 		return
 	}
 
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新订单状态失败"})
+		c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "更新成功"})
+	c.JSON(http.StatusOK, gin.H{"message": middleware.T(c, "message.updated")})
 }
 
 // ============================================
 // 用户管理
 // ============================================
 
-// GetUsers 获取所有用户
+// GetUsers 获取用户列表（可按信任等级、封禁状态、是否后台人员和最后登录时间筛选）
 func (h *AdminHandler) GetUsers(c *gin.Context) {
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	var filter services.UserFilter
+	var ok bool
+	if filter.TrustLevel, ok = queryInt(c, "trust_level"); !ok {
+		return
+	}
+	if filter.IsBlocked, ok = queryBool(c, "is_blocked"); !ok {
+		return
+	}
+	if filter.IsAdmin, ok = queryBool(c, "is_admin"); !ok {
+		return
+	}
+	if filter.LastLoginFrom, ok = queryTime(c, "last_login_from"); !ok {
+		return
+	}
+	if filter.LastLoginTo, ok = queryTime(c, "last_login_to"); !ok {
+		return
+	}
 
-	users, total, err := h.userService.GetWithPagination(page, pageSize)
+	users, page, err := h.userService.GetWithPagination(filter, parseListQuery(c, services.DefaultListPageSize))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取用户失败"})
+		c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"users":    users,
-		"total":    total,
-		"page":     page,
-		"pageSize": pageSize,
-	})
+	c.JSON(http.StatusOK, listResponse("users", users, page))
 }
 
 // GetUser 获取单个用户
@@ -438,7 +627,7 @@ func (h *AdminHandler) GetUser(c *gin.Context) {
 	id, _ := strconv.ParseUint(c.Param("id"), 10, 32)
 	user, err := h.userService.FindByID(uint(id))
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "用户不存在"})
+		c.Error(services.ErrUserNotFound)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"user": user})
@@ -452,19 +641,36 @@ func (h *AdminHandler) UpdateUser(c *gin.Context) {
 		IsBlocked *bool `json:"is_blocked"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "参数错误"})
+		c.Error(services.ErrInvalidRequest)
+		return
+	}
+
+	target, err := h.userService.FindByID(uint(id))
+	if err != nil {
+		c.Error(services.ErrUserNotFound)
+		return
+	}
+
+	// 修改后台人员状态需要人员管理权限
+	if (req.IsAdmin != nil || (req.IsBlocked != nil && target.IsStaff())) &&
+		!can(c, services.PermGroupStaff, services.PermWrite) {
+		c.Error(services.ErrForbidden)
 		return
 	}
 
 	if req.IsAdmin != nil {
 		if err := h.userService.SetAdmin(uint(id), *req.IsAdmin); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "更新管理员状态失败"})
+			c.Error(err)
 			return
 		}
 	}
 
 	if req.IsBlocked != nil {
 		if *req.IsBlocked {
+			if h.userService.IsLastOwner(uint(id)) {
+				c.Error(services.ErrLastOwner)
+				return
+			}
 			h.userService.Block(uint(id))
 		} else {
 			h.userService.Unblock(uint(id))
@@ -489,10 +695,32 @@ func (h *AdminHandler) GetSettings(c *gin.Context) {
 		"nodeloc_client_id":     h.settingService.Get(services.SettingNodeLocClientID),
 		"nodeloc_client_secret": h.settingService.Get(services.SettingNodeLocClientSecret),
 		"nodeloc_redirect_uri":  h.settingService.Get(services.SettingNodeLocRedirectURI),
-		"payment_enabled":       h.settingService.Get(services.SettingPaymentEnabled) == "true",
-		"payment_id":            h.settingService.Get(services.SettingPaymentID),
-		"payment_secret":        h.settingService.Get(services.SettingPaymentSecret),
-		"payment_callback_uri":  h.settingService.Get(services.SettingPaymentCallback),
+		"fulfill_sla_minutes":   int(h.fulfillmentService.SLA().Minutes()),
+		"require_admin_2fa":     h.twoFactorService.Required(),
+		"max_pending_orders":    h.orderService.MaxPendingOrders(),
+		"default_locale":        h.settingService.DefaultLocale(),
+		"locales":               i18n.Locales,
+	}
+	// 网站文案的各语言版本，如 site_name_en
+	for _, key := range services.LocalizableSettings {
+		for _, locale := range i18n.Locales {
+			localizedKey := services.LocalizedSettingKey(key, locale)
+			settings[localizedKey] = h.settingService.Get(localizedKey)
+		}
+	}
+	if !can(c, services.PermGroupSettings, services.PermWrite) {
+		settings["nodeloc_client_secret"] = maskValue(settings["nodeloc_client_secret"].(string))
+	}
+
+	// 支付配置需要支付权限，只有写权限才能查看密钥
+	if can(c, services.PermGroupPayments, services.PermRead) {
+		settings["payment_enabled"] = h.settingService.Get(services.SettingPaymentEnabled) == "true"
+		settings["payment_id"] = h.settingService.Get(services.SettingPaymentID)
+		settings["payment_secret"] = h.settingService.Get(services.SettingPaymentSecret)
+		settings["payment_callback_uri"] = h.settingService.Get(services.SettingPaymentCallback)
+		if !can(c, services.PermGroupPayments, services.PermWrite) {
+			settings["payment_secret"] = maskValue(settings["payment_secret"].(string))
+		}
 	}
 	c.JSON(http.StatusOK, gin.H{"settings": settings})
 }
@@ -501,13 +729,21 @@ func (h *AdminHandler) GetSettings(c *gin.Context) {
 func (h *AdminHandler) UpdateSettings(c *gin.Context) {
 	var req map[string]interface{}
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "参数错误"})
+		c.Error(services.ErrInvalidRequest)
 		return
 	}
 
 	// 转换为 map[string]string
 	settings := make(map[string]string)
 	for key, value := range req {
+		if strings.HasPrefix(key, "payment_") && !can(c, services.PermGroupPayments, services.PermWrite) {
+			c.Error(services.ErrForbidden.WithDetails(map[string]interface{}{"permission": services.PermGroupPayments + ":" + services.PermWrite}))
+			return
+		}
+		if key == services.SettingDefaultLocale && !i18n.Supported(fmt.Sprint(value)) {
+			c.Error(services.ErrUnsupportedLocale.WithDetails(map[string]interface{}{"locale": value}))
+			return
+		}
 		switch v := value.(type) {
 		case string:
 			settings[key] = v
@@ -524,12 +760,18 @@ func (h *AdminHandler) UpdateSettings(c *gin.Context) {
 		}
 	}
 
+	// 开启强制两步验证前，操作人自己需已启用两步验证，否则保存后会立即无法访问后台
+	if settings[services.SettingRequireAdmin2FA] == "true" && !currentAdmin(c).TOTPEnabled {
+		c.Error(services.ErrTwoFactorSelfNotEnabled)
+		return
+	}
+
 	if err := h.settingService.SetMultiple(settings); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新设置失败"})
+		c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "更新成功"})
+	c.JSON(http.StatusOK, gin.H{"message": middleware.T(c, "message.updated")})
 }
 
 // ============================================
@@ -542,13 +784,47 @@ func (h *AdminHandler) GetDashboard(c *gin.Context) {
 	productCount := h.productService.Count()
 	orderCount := h.orderService.Count()
 	categoryCount := h.categoryService.Count()
+	pendingFulfillment := h.fulfillmentService.CountPending()
+
+	stats := gin.H{
+		"users":               userCount,
+		"products":            productCount,
+		"orders":              orderCount,
+		"categories":          categoryCount,
+		"pending_fulfillment": pendingFulfillment,
+	}
+	// 销售数据需要订单查看权限
+	if can(c, services.PermGroupOrders, services.PermRead) {
+		stats["total_sales"] = h.orderService.GetTotalSales()
+		stats["today_sales"] = h.orderService.GetTodaySales()
+		stats["orders_by_status"] = gin.H{
+			"pending":   h.orderService.CountByStatus(models.OrderStatusPending),
+			"paid":      h.orderService.CountByStatus(models.OrderStatusPaid),
+			"completed": h.orderService.CountByStatus(models.OrderStatusCompleted),
+			"cancelled": h.orderService.CountByStatus(models.OrderStatusCancelled),
+			"refunded":  h.orderService.CountByStatus(models.OrderStatusRefunded),
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"stats": stats})
+}
+
+// ============================================
+// 辅助函数
+// ============================================
+
+// maskValue 隐藏敏感配置（只显示前后几位）
+func maskValue(value string) string {
+	if value == "" {
+		return ""
+	}
+	if len(value) <= 8 {
+		return "****"
+	}
+	return value[:4] + "****" + value[len(value)-4:]
+}
 
-	c.JSON(http.StatusOK, gin.H{
-		"stats": gin.H{
-			"users":      userCount,
-			"products":   productCount,
-			"orders":     orderCount,
-			"categories": categoryCount,
-		},
-	})
+// currentAdmin 获取当前登录的管理员
+func currentAdmin(c *gin.Context) *models.User {
+	return c.MustGet("user").(*models.User)
 }
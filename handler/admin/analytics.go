@@ -0,0 +1,90 @@
+package admin
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nodeloc-faka/services"
+)
+
+// ============================================
+// 销售统计
+// ============================================
+
+// GetSalesReport 按天、周或月统计销售额、订单数、售出数量、手续费、商家实收、转化率和平均支付耗时
+func (h *AdminHandler) GetSalesReport(c *gin.Context) {
+	query, ok := parseAnalyticsQuery(c)
+	if !ok {
+		return
+	}
+
+	report, err := h.analyticsService.Sales(query)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// GetProductSales 按商品统计销售排行
+func (h *AdminHandler) GetProductSales(c *gin.Context) {
+	query, ok := parseAnalyticsQuery(c)
+	if !ok {
+		return
+	}
+
+	products, err := h.analyticsService.Products(query)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"products": products})
+}
+
+// GetCategorySales 按分类统计销售排行
+func (h *AdminHandler) GetCategorySales(c *gin.Context) {
+	query, ok := parseAnalyticsQuery(c)
+	if !ok {
+		return
+	}
+
+	categories, err := h.analyticsService.Categories(query)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"categories": categories})
+}
+
+// GetTopBuyers 按消费金额统计买家排行
+func (h *AdminHandler) GetTopBuyers(c *gin.Context) {
+	query, ok := parseAnalyticsQuery(c)
+	if !ok {
+		return
+	}
+
+	buyers, err := h.analyticsService.TopBuyers(query)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"buyers": buyers})
+}
+
+// parseAnalyticsQuery 解析统计条件，日期格式为 2006-01-02（from、to 均包含）
+func parseAnalyticsQuery(c *gin.Context) (services.AnalyticsQuery, bool) {
+	query := services.AnalyticsQuery{
+		From:     c.Query("from"),
+		To:       c.Query("to"),
+		Interval: c.Query("interval"),
+		Timezone: c.Query("tz"),
+	}
+	limit, ok := queryInt(c, "limit")
+	if !ok {
+		return query, false
+	}
+	if limit != nil {
+		query.Limit = *limit
+	}
+	return query, true
+}
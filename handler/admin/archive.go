@@ -0,0 +1,81 @@
+package admin
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nodeloc-faka/middleware"
+	"github.com/nodeloc-faka/services"
+)
+
+// ============================================
+// 归档管理（已删除的分类、商品、卡密）
+// ============================================
+
+// GetArchivedCategories 获取已归档的分类
+func (h *AdminHandler) GetArchivedCategories(c *gin.Context) {
+	categories, err := h.categoryService.GetArchived()
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"categories": categories})
+}
+
+// RestoreCategory 恢复已归档的分类
+func (h *AdminHandler) RestoreCategory(c *gin.Context) {
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err := h.categoryService.Restore(uint(id)); err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": middleware.T(c, "message.restored")})
+}
+
+// GetArchivedProducts 获取已归档的商品（分页）
+func (h *AdminHandler) GetArchivedProducts(c *gin.Context) {
+	products, page, err := h.productService.GetArchived(parseListQuery(c, services.DefaultListPageSize))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, listResponse("products", products, page))
+}
+
+// RestoreProduct 恢复已归档的商品
+func (h *AdminHandler) RestoreProduct(c *gin.Context) {
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err := h.productService.Restore(uint(id)); err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": middleware.T(c, "message.restored")})
+}
+
+// GetArchivedCardKeys 获取已归档的卡密（分页）
+func (h *AdminHandler) GetArchivedCardKeys(c *gin.Context) {
+	filter, ok := parseCardKeyFilter(c)
+	if !ok {
+		return
+	}
+
+	cardKeys, page, err := h.cardKeyService.GetArchived(filter, parseListQuery(c, services.DefaultListPageSize))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, listResponse("card_keys", cardKeys, page))
+}
+
+// RestoreCardKey 恢复已归档的卡密
+func (h *AdminHandler) RestoreCardKey(c *gin.Context) {
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err := h.cardKeyService.Restore(uint(id)); err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": middleware.T(c, "message.restored")})
+}
@@ -0,0 +1,89 @@
+package admin
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nodeloc-faka/services"
+)
+
+// ============================================
+// 批量操作
+// ============================================
+
+// BulkCardKeys 批量操作卡密（删除 / 锁定 / 解锁 / 移动）
+func (h *AdminHandler) BulkCardKeys(c *gin.Context) {
+	var req struct {
+		Action          string                  `json:"action" binding:"required"`
+		IDs             []uint                  `json:"ids"`
+		Filter          *services.CardKeyFilter `json:"filter"` // 未指定 ids 时按筛选条件选择卡密
+		TargetProductID uint                    `json:"target_product_id"`
+		TargetVariantID uint                    `json:"target_variant_id"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(services.ErrInvalidRequest)
+		return
+	}
+
+	result, err := h.bulkService.CardKeys(services.CardKeyBulkRequest{
+		Action:          req.Action,
+		IDs:             req.IDs,
+		Filter:          req.Filter,
+		TargetProductID: req.TargetProductID,
+		TargetVariantID: req.TargetVariantID,
+	})
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// BulkProducts 批量操作商品（上架 / 下架 / 修改分类 / 调价）
+func (h *AdminHandler) BulkProducts(c *gin.Context) {
+	var req struct {
+		Action     string   `json:"action" binding:"required"`
+		IDs        []uint   `json:"ids" binding:"required"`
+		CategoryID uint     `json:"category_id"`
+		Price      *float64 `json:"price"`
+		Percent    *float64 `json:"percent"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(services.ErrInvalidRequest)
+		return
+	}
+
+	result, err := h.bulkService.Products(services.ProductBulkRequest{
+		Action:     req.Action,
+		IDs:        req.IDs,
+		CategoryID: req.CategoryID,
+		Price:      req.Price,
+		Percent:    req.Percent,
+	})
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// BulkCancelOrders 批量取消待支付订单
+func (h *AdminHandler) BulkCancelOrders(c *gin.Context) {
+	var req struct {
+		IDs []uint `json:"ids" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(services.ErrInvalidRequest)
+		return
+	}
+
+	result, err := h.bulkService.CancelOrders(req.IDs)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
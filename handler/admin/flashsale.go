@@ -0,0 +1,131 @@
+package admin
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nodeloc-faka/middleware"
+	"github.com/nodeloc-faka/models"
+	"github.com/nodeloc-faka/services"
+)
+
+// ============================================
+// 限时抢购管理
+// ============================================
+
+// flashSaleRequest 创建/更新限时抢购请求
+type flashSaleRequest struct {
+	ProductID       uint      `json:"product_id" binding:"required"`
+	VariantID       uint      `json:"variant_id"` // 为 0 时对所有规格生效
+	Name            string    `json:"name" binding:"required"`
+	StartAt         time.Time `json:"start_at" binding:"required"`
+	EndAt           time.Time `json:"end_at" binding:"required"`
+	SalePrice       float64   `json:"sale_price" binding:"required"`
+	PerUserLimit    int       `json:"per_user_limit"`
+	TotalQuota      int       `json:"total_quota"`
+	ActivateOnStart *bool     `json:"activate_on_start"` // 默认 true
+	DeactivateOnEnd *bool     `json:"deactivate_on_end"` // 默认 true
+	IsActive        *bool     `json:"is_active"`         // 默认 true
+}
+
+// apply 将请求写入抢购
+func (r *flashSaleRequest) apply(sale *models.FlashSale) {
+	sale.ProductID = r.ProductID
+	sale.VariantID = r.VariantID
+	sale.Name = r.Name
+	sale.StartAt = r.StartAt
+	sale.EndAt = r.EndAt
+	sale.SalePrice = r.SalePrice
+	sale.PerUserLimit = r.PerUserLimit
+	sale.TotalQuota = r.TotalQuota
+	sale.ActivateOnStart = r.ActivateOnStart == nil || *r.ActivateOnStart
+	sale.DeactivateOnEnd = r.DeactivateOnEnd == nil || *r.DeactivateOnEnd
+	sale.IsActive = r.IsActive == nil || *r.IsActive
+}
+
+// validateFlashSaleTarget 校验抢购的商品和规格
+func (h *AdminHandler) validateFlashSaleTarget(c *gin.Context, req *flashSaleRequest) bool {
+	if _, err := h.productService.FindByID(req.ProductID); err != nil {
+		c.Error(services.ErrProductNotFound)
+		return false
+	}
+	if req.VariantID > 0 {
+		variant, err := h.variantService.FindByID(req.VariantID)
+		if err != nil || variant.ProductID != req.ProductID {
+			c.Error(services.ErrVariantNotFound)
+			return false
+		}
+	}
+	return true
+}
+
+// GetFlashSales 获取限时抢购列表
+func (h *AdminHandler) GetFlashSales(c *gin.Context) {
+	productID, _ := strconv.ParseUint(c.Query("product_id"), 10, 32)
+	sales, err := h.flashSaleService.GetAll(uint(productID))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"flash_sales": sales})
+}
+
+// CreateFlashSale 创建限时抢购
+func (h *AdminHandler) CreateFlashSale(c *gin.Context) {
+	var req flashSaleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(services.ErrInvalidRequest)
+		return
+	}
+	if !h.validateFlashSaleTarget(c, &req) {
+		return
+	}
+
+	sale := &models.FlashSale{}
+	req.apply(sale)
+	if err := h.flashSaleService.Create(sale); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"flash_sale": sale})
+}
+
+// UpdateFlashSale 更新限时抢购
+func (h *AdminHandler) UpdateFlashSale(c *gin.Context) {
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 32)
+	sale, err := h.flashSaleService.FindByID(uint(id))
+	if err != nil {
+		c.Error(services.ErrFlashSaleNotFound)
+		return
+	}
+
+	var req flashSaleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(services.ErrInvalidRequest)
+		return
+	}
+	if !h.validateFlashSaleTarget(c, &req) {
+		return
+	}
+
+	req.apply(sale)
+	if err := h.flashSaleService.Update(sale); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"flash_sale": sale})
+}
+
+// DeleteFlashSale 删除限时抢购
+func (h *AdminHandler) DeleteFlashSale(c *gin.Context) {
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err := h.flashSaleService.Delete(uint(id)); err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": middleware.T(c, "message.deleted")})
+}
@@ -0,0 +1,128 @@
+package admin
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nodeloc-faka/middleware"
+	"github.com/nodeloc-faka/models"
+	"github.com/nodeloc-faka/services"
+)
+
+// ============================================
+// 人工发货队列
+// ============================================
+
+// GetFulfillmentQueue 获取待人工发货的订单队列
+func (h *AdminHandler) GetFulfillmentQueue(c *gin.Context) {
+	productID, ok := queryUint(c, "product_id")
+	if !ok {
+		return
+	}
+
+	filter := services.FulfillmentQueueFilter{
+		State:     c.Query("state"),
+		ProductID: productID,
+		Keyword:   c.Query("keyword"),
+	}
+	if c.Query("mine") == "true" {
+		filter.ClaimedBy = currentAdmin(c).ID
+	}
+
+	items, page, err := h.fulfillmentService.GetQueue(filter, parseListQuery(c, services.DefaultListPageSize))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	response := listResponse("orders", items, page)
+	response["sla_minutes"] = int(h.fulfillmentService.SLA().Minutes())
+	c.JSON(http.StatusOK, response)
+}
+
+// ClaimFulfillment 认领待发货订单
+func (h *AdminHandler) ClaimFulfillment(c *gin.Context) {
+	order, err := h.orderService.FindByOrderNo(c.Param("orderNo"))
+	if err != nil {
+		c.Error(services.ErrOrderNotFound)
+		return
+	}
+
+	if err := h.fulfillmentService.Claim(order.ID, currentAdmin(c).ID); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": middleware.T(c, "message.claimed")})
+}
+
+// ReleaseFulfillment 放弃认领待发货订单
+func (h *AdminHandler) ReleaseFulfillment(c *gin.Context) {
+	order, err := h.orderService.FindByOrderNo(c.Param("orderNo"))
+	if err != nil {
+		c.Error(services.ErrOrderNotFound)
+		return
+	}
+
+	if err := h.fulfillmentService.Release(order.ID, currentAdmin(c).ID); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": middleware.T(c, "message.unclaimed")})
+}
+
+// DeliverFulfillment 填写发货内容并完成订单
+func (h *AdminHandler) DeliverFulfillment(c *gin.Context) {
+	var req struct {
+		Content string `json:"content" binding:"required"` // 格式同卡密导入：每行一条，支持 "卡号----密码"
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(services.ErrInvalidRequest)
+		return
+	}
+
+	order, err := h.orderService.FindByOrderNo(c.Param("orderNo"))
+	if err != nil {
+		c.Error(services.ErrOrderNotFound)
+		return
+	}
+
+	order, err = h.fulfillmentService.Deliver(order.ID, currentAdmin(c).ID, req.Content)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": middleware.T(c, "message.delivered"), "order": order})
+}
+
+// RejectFulfillment 拒绝发货：已收款的订单退款，未收款的订单取消
+func (h *AdminHandler) RejectFulfillment(c *gin.Context) {
+	var req struct {
+		Reason string `json:"reason"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(services.ErrInvalidRequest)
+		return
+	}
+
+	order, err := h.orderService.FindByOrderNo(c.Param("orderNo"))
+	if err != nil {
+		c.Error(services.ErrOrderNotFound)
+		return
+	}
+
+	order, err = h.fulfillmentService.Reject(order.ID, currentAdmin(c).ID, req.Reason)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	// 未收款的订单只取消，不退款
+	message := "message.rejected"
+	if order.Status == models.OrderStatusCancelled {
+		message = "message.rejected_cancelled"
+	}
+	c.JSON(http.StatusOK, gin.H{"message": middleware.T(c, message), "order": order})
+}
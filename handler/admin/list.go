@@ -0,0 +1,101 @@
+package admin
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nodeloc-faka/services"
+)
+
+// ============================================
+// 列表查询参数
+// ============================================
+
+// parseListQuery 解析列表的分页、排序和游标参数，defaultPageSize 为未传 page_size 时的每页条数
+func parseListQuery(c *gin.Context, defaultPageSize int) services.ListQuery {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", strconv.Itoa(defaultPageSize)))
+	return services.ListQuery{
+		Page:     page,
+		PageSize: pageSize,
+		Sort:     c.Query("sort"),
+		Cursor:   c.Query("cursor"),
+	}
+}
+
+// listResponse 列表响应，key 为列表字段名；游标分页时不返回 total
+func listResponse(key string, items interface{}, page services.ListPage) gin.H {
+	response := gin.H{
+		key:        items,
+		"page":     page.Page,
+		"pageSize": page.PageSize,
+	}
+	if page.Total != nil {
+		response["total"] = *page.Total
+	}
+	if page.NextCursor != "" {
+		response["next_cursor"] = page.NextCursor
+	}
+	return response
+}
+
+// queryUint 解析可选的非负整数参数，未传时为 0
+func queryUint(c *gin.Context, name string) (uint, bool) {
+	raw := c.Query(name)
+	if raw == "" {
+		return 0, true
+	}
+	value, err := strconv.ParseUint(raw, 10, 32)
+	if err != nil {
+		c.Error(services.ErrInvalidRequest.WithDetails(map[string]interface{}{"field": name}))
+		return 0, false
+	}
+	return uint(value), true
+}
+
+// queryInt 解析可选的整数参数，未传时为 nil
+func queryInt(c *gin.Context, name string) (*int, bool) {
+	raw := c.Query(name)
+	if raw == "" {
+		return nil, true
+	}
+	value, err := strconv.Atoi(raw)
+	if err != nil {
+		c.Error(services.ErrInvalidRequest.WithDetails(map[string]interface{}{"field": name}))
+		return nil, false
+	}
+	return &value, true
+}
+
+// queryBool 解析可选的布尔参数（true / false），未传时为 nil
+func queryBool(c *gin.Context, name string) (*bool, bool) {
+	raw := c.Query(name)
+	if raw == "" {
+		return nil, true
+	}
+	value, err := strconv.ParseBool(raw)
+	if err != nil {
+		c.Error(services.ErrInvalidRequest.WithDetails(map[string]interface{}{"field": name}))
+		return nil, false
+	}
+	return &value, true
+}
+
+// queryTime 解析可选的时间参数，支持 RFC3339 或 2006-01-02 格式，未传时为 nil
+// 仅有日期时按服务器时区的零点处理，作为结束时间（不含）时需传入次日
+func queryTime(c *gin.Context, name string) (*time.Time, bool) {
+	raw := c.Query(name)
+	if raw == "" {
+		return nil, true
+	}
+	t, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		t, err = time.ParseInLocation("2006-01-02", raw, time.Local)
+	}
+	if err != nil {
+		c.Error(services.ErrInvalidRequest.WithDetails(map[string]interface{}{"field": name}))
+		return nil, false
+	}
+	return &t, true
+}
@@ -0,0 +1,66 @@
+package admin
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nodeloc-faka/models"
+	"github.com/nodeloc-faka/services"
+)
+
+// ============================================
+// 阶梯价管理
+// ============================================
+
+// GetPriceTiers 获取商品的阶梯价
+func (h *AdminHandler) GetPriceTiers(c *gin.Context) {
+	productID, _ := strconv.ParseUint(c.Param("id"), 10, 32)
+	tiers, err := h.pricingService.GetTiers(uint(productID))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"price_tiers": tiers})
+}
+
+// UpdatePriceTiers 整体替换商品的阶梯价（传空数组即清除）
+func (h *AdminHandler) UpdatePriceTiers(c *gin.Context) {
+	productID, _ := strconv.ParseUint(c.Param("id"), 10, 32)
+	product, err := h.productService.FindByID(uint(productID))
+	if err != nil {
+		c.Error(services.ErrProductNotFound)
+		return
+	}
+
+	var req struct {
+		Tiers []struct {
+			VariantID   uint    `json:"variant_id"`
+			MinQuantity int     `json:"min_quantity"`
+			MaxQuantity int     `json:"max_quantity"`
+			UnitPrice   float64 `json:"unit_price"`
+		} `json:"tiers"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(services.ErrInvalidRequest)
+		return
+	}
+
+	tiers := make([]models.PriceTier, 0, len(req.Tiers))
+	for _, t := range req.Tiers {
+		tiers = append(tiers, models.PriceTier{
+			VariantID:   t.VariantID,
+			MinQuantity: t.MinQuantity,
+			MaxQuantity: t.MaxQuantity,
+			UnitPrice:   t.UnitPrice,
+		})
+	}
+
+	if err := h.pricingService.ReplaceTiers(product.ID, tiers); err != nil {
+		c.Error(err)
+		return
+	}
+
+	tiers, _ = h.pricingService.GetTiers(product.ID)
+	c.JSON(http.StatusOK, gin.H{"price_tiers": tiers})
+}
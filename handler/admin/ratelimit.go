@@ -0,0 +1,37 @@
+package admin
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nodeloc-faka/services"
+)
+
+// ============================================
+// 限流封禁
+// ============================================
+
+// GetRateLimitBlocks 分页查询自动封禁记录（?active=true 只看生效中的）
+func (h *AdminHandler) GetRateLimitBlocks(c *gin.Context) {
+	activeOnly := c.Query("active") == "true"
+
+	blocks, page, err := h.abuseService.GetWithPagination(activeOnly, parseListQuery(c, services.DefaultListPageSize))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, listResponse("blocks", blocks, page))
+}
+
+// DeleteRateLimitBlock 提前解除封禁
+func (h *AdminHandler) DeleteRateLimitBlock(c *gin.Context) {
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err := h.abuseService.Unblock(uint(id)); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
@@ -0,0 +1,101 @@
+package admin
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nodeloc-faka/services"
+)
+
+// ============================================
+// 支付对账
+// ============================================
+
+// GetReconciliations 获取对账任务列表
+func (h *AdminHandler) GetReconciliations(c *gin.Context) {
+	runs, page, err := h.reconciliationService.GetRuns(parseListQuery(c, services.DefaultListPageSize))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, listResponse("runs", runs, page))
+}
+
+// StartReconciliation 发起对账任务，任务在后台执行，通过 GET /reconciliations/:id 查看进度
+func (h *AdminHandler) StartReconciliation(c *gin.Context) {
+	var req struct {
+		From string `json:"from" binding:"required"`
+		To   string `json:"to" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(services.ErrInvalidRequest)
+		return
+	}
+
+	adminID := currentAdmin(c).ID
+	run, err := h.reconciliationService.Start(req.From, req.To, &adminID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"run": run})
+}
+
+// GetReconciliation 获取对账任务
+func (h *AdminHandler) GetReconciliation(c *gin.Context) {
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 32)
+	run, err := h.reconciliationService.FindRun(uint(id))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"run": run})
+}
+
+// GetReconciliationIssues 获取对账差异（可按任务、类型和是否已处理筛选）
+func (h *AdminHandler) GetReconciliationIssues(c *gin.Context) {
+	filter := services.ReconciliationIssueFilter{Type: c.Query("type")}
+	var ok bool
+	if filter.RunID, ok = queryUint(c, "run_id"); !ok {
+		return
+	}
+	if filter.Resolved, ok = queryBool(c, "resolved"); !ok {
+		return
+	}
+
+	issues, page, err := h.reconciliationService.GetIssues(filter, parseListQuery(c, services.DefaultListPageSize))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, listResponse("issues", issues, page))
+}
+
+// FixReconciliationIssue 按支付平台记录修正差异
+func (h *AdminHandler) FixReconciliationIssue(c *gin.Context) {
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 32)
+	issue, err := h.reconciliationService.Fix(uint(id), currentAdmin(c).ID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"issue": issue})
+}
+
+// IgnoreReconciliationIssue 标记差异无需处理
+func (h *AdminHandler) IgnoreReconciliationIssue(c *gin.Context) {
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 32)
+	issue, err := h.reconciliationService.Ignore(uint(id), currentAdmin(c).ID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"issue": issue})
+}
@@ -0,0 +1,158 @@
+package admin
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nodeloc-faka/middleware"
+	"github.com/nodeloc-faka/models"
+	"github.com/nodeloc-faka/services"
+)
+
+// ============================================
+// 后台人员与角色管理
+// ============================================
+
+// staffItem 后台人员列表条目
+type staffItem struct {
+	models.User
+	LocalUsername string     `json:"local_username"` // 本地登录用户名，为空表示未设置本地账号
+	LockedUntil   *time.Time `json:"locked_until"`   // 本地账号锁定截止时间
+}
+
+// roleItem 角色及其权限
+type roleItem struct {
+	Role        string            `json:"role"`
+	Permissions map[string]string `json:"permissions"`
+}
+
+// GetCurrentStaff 获取当前后台人员及其权限
+func (h *AdminHandler) GetCurrentStaff(c *gin.Context) {
+	admin := currentAdmin(c)
+	c.JSON(http.StatusOK, gin.H{
+		"user":        admin,
+		"role":        admin.Role,
+		"permissions": services.RolePermissions(admin.Role),
+	})
+}
+
+// GetRoles 获取所有角色及其权限
+func (h *AdminHandler) GetRoles(c *gin.Context) {
+	roles := []roleItem{}
+	for _, role := range []string{
+		models.RoleOwner,
+		models.RoleOperator,
+		models.RoleStockManager,
+		models.RoleSupport,
+		models.RoleViewer,
+	} {
+		roles = append(roles, roleItem{Role: role, Permissions: services.RolePermissions(role)})
+	}
+	c.JSON(http.StatusOK, gin.H{"roles": roles, "groups": services.PermGroups})
+}
+
+// GetStaff 获取所有后台人员
+func (h *AdminHandler) GetStaff(c *gin.Context) {
+	users, err := h.userService.GetStaff()
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	admins, err := h.adminService.GetAll()
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	credentials := make(map[uint]models.Admin, len(admins))
+	for _, admin := range admins {
+		if admin.UserID != nil {
+			credentials[*admin.UserID] = admin
+		}
+	}
+
+	staff := make([]staffItem, len(users))
+	for i, user := range users {
+		credential := credentials[user.ID]
+		staff[i] = staffItem{User: user, LocalUsername: credential.Username, LockedUntil: credential.LockedUntil}
+	}
+	c.JSON(http.StatusOK, gin.H{"staff": staff})
+}
+
+// UpdateStaffRole 设置用户的后台角色（role 为空表示撤销后台权限）
+func (h *AdminHandler) UpdateStaffRole(c *gin.Context) {
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 32)
+	var req struct {
+		Role string `json:"role"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(services.ErrInvalidRequest)
+		return
+	}
+
+	if err := h.userService.SetRole(uint(id), req.Role); err != nil {
+		c.Error(err)
+		return
+	}
+
+	user, _ := h.userService.FindByID(uint(id))
+	c.JSON(http.StatusOK, gin.H{"user": user})
+}
+
+// SetStaffCredential 设置后台人员的本地登录账号
+func (h *AdminHandler) SetStaffCredential(c *gin.Context) {
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 32)
+	var req struct {
+		Username string `json:"username" binding:"required,max=50"`
+		Password string `json:"password" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(services.ErrInvalidRequest)
+		return
+	}
+
+	admin, err := h.adminService.SetCredential(uint(id), req.Username, req.Password)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"admin": admin})
+}
+
+// DeleteStaffCredential 删除后台人员的本地登录账号
+func (h *AdminHandler) DeleteStaffCredential(c *gin.Context) {
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err := h.adminService.RemoveCredential(uint(id)); err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": middleware.T(c, "message.deleted")})
+}
+
+// UnlockStaff 解除后台人员本地账号的登录锁定
+func (h *AdminHandler) UnlockStaff(c *gin.Context) {
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err := h.adminService.Unlock(uint(id)); err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": middleware.T(c, "message.unlocked")})
+}
+
+// ResetStaffTwoFactor 重置后台人员的两步验证（丢失设备且恢复码用尽时使用）
+func (h *AdminHandler) ResetStaffTwoFactor(c *gin.Context) {
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err := h.twoFactorService.Reset(uint(id)); err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": middleware.T(c, "message.two_factor_reset")})
+}
+
+// can 检查当前后台人员是否拥有指定权限
+func can(c *gin.Context, group, level string) bool {
+	return services.HasPermission(currentAdmin(c).Role, group, level)
+}
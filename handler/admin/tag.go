@@ -0,0 +1,115 @@
+package admin
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nodeloc-faka/middleware"
+	"github.com/nodeloc-faka/models"
+	"github.com/nodeloc-faka/services"
+)
+
+// ============================================
+// 商品标签管理
+// ============================================
+
+// GetTags 获取所有标签
+func (h *AdminHandler) GetTags(c *gin.Context) {
+	tags, err := h.tagService.GetAll()
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"tags": tags})
+}
+
+// CreateTag 创建标签
+func (h *AdminHandler) CreateTag(c *gin.Context) {
+	var req struct {
+		Name string `json:"name" binding:"required,max=50"`
+		Sort int    `json:"sort"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(services.ErrInvalidRequest)
+		return
+	}
+
+	tag := &models.Tag{Name: req.Name, Sort: req.Sort}
+	if err := h.tagService.Create(tag); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"tag": tag})
+}
+
+// UpdateTag 更新标签
+func (h *AdminHandler) UpdateTag(c *gin.Context) {
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 32)
+
+	tag, err := h.tagService.FindByID(uint(id))
+	if err != nil {
+		c.Error(services.ErrTagNotFound)
+		return
+	}
+
+	var req struct {
+		Name string `json:"name" binding:"max=50"`
+		Sort *int   `json:"sort"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(services.ErrInvalidRequest)
+		return
+	}
+
+	if req.Name != "" {
+		tag.Name = req.Name
+	}
+	if req.Sort != nil {
+		tag.Sort = *req.Sort
+	}
+
+	if err := h.tagService.Update(tag); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"tag": tag})
+}
+
+// DeleteTag 删除标签
+func (h *AdminHandler) DeleteTag(c *gin.Context) {
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err := h.tagService.Delete(uint(id)); err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": middleware.T(c, "message.deleted")})
+}
+
+// SetProductTags 设置商品标签
+func (h *AdminHandler) SetProductTags(c *gin.Context) {
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 32)
+
+	product, err := h.productService.FindByID(uint(id))
+	if err != nil {
+		c.Error(services.ErrProductNotFound)
+		return
+	}
+
+	var req struct {
+		TagIDs []uint `json:"tag_ids"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(services.ErrInvalidRequest)
+		return
+	}
+
+	if err := h.productService.SetTags(product, req.TagIDs); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"tags": product.Tags})
+}
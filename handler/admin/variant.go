@@ -0,0 +1,119 @@
+package admin
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nodeloc-faka/middleware"
+	"github.com/nodeloc-faka/models"
+	"github.com/nodeloc-faka/services"
+)
+
+// ============================================
+// 商品规格管理
+// ============================================
+
+// GetVariants 获取商品的所有规格
+func (h *AdminHandler) GetVariants(c *gin.Context) {
+	productID, _ := strconv.ParseUint(c.Param("id"), 10, 32)
+	variants, err := h.variantService.GetByProduct(uint(productID))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"variants": variants})
+}
+
+// CreateVariant 为商品创建规格
+func (h *AdminHandler) CreateVariant(c *gin.Context) {
+	productID, _ := strconv.ParseUint(c.Param("id"), 10, 32)
+	if _, err := h.productService.FindByID(uint(productID)); err != nil {
+		c.Error(services.ErrProductNotFound)
+		return
+	}
+
+	var req struct {
+		Name      string  `json:"name" binding:"required"`
+		Price     float64 `json:"price" binding:"required"`
+		OrigPrice float64 `json:"orig_price"`
+		Sort      int     `json:"sort"`
+		IsActive  bool    `json:"is_active"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(services.ErrInvalidRequest)
+		return
+	}
+
+	variant := &models.ProductVariant{
+		ProductID: uint(productID),
+		Name:      req.Name,
+		Price:     req.Price,
+		OrigPrice: req.OrigPrice,
+		Sort:      req.Sort,
+		IsActive:  req.IsActive,
+	}
+
+	if err := h.variantService.Create(variant); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"variant": variant})
+}
+
+// UpdateVariant 更新规格
+func (h *AdminHandler) UpdateVariant(c *gin.Context) {
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 32)
+
+	variant, err := h.variantService.FindByID(uint(id))
+	if err != nil {
+		c.Error(services.ErrVariantNotFound)
+		return
+	}
+
+	var req struct {
+		Name      string   `json:"name"`
+		Price     *float64 `json:"price"`
+		OrigPrice *float64 `json:"orig_price"`
+		Sort      *int     `json:"sort"`
+		IsActive  *bool    `json:"is_active"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(services.ErrInvalidRequest)
+		return
+	}
+
+	if req.Name != "" {
+		variant.Name = req.Name
+	}
+	if req.Price != nil {
+		variant.Price = *req.Price
+	}
+	if req.OrigPrice != nil {
+		variant.OrigPrice = *req.OrigPrice
+	}
+	if req.Sort != nil {
+		variant.Sort = *req.Sort
+	}
+	if req.IsActive != nil {
+		variant.IsActive = *req.IsActive
+	}
+
+	if err := h.variantService.Update(variant); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"variant": variant})
+}
+
+// DeleteVariant 删除规格
+func (h *AdminHandler) DeleteVariant(c *gin.Context) {
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err := h.variantService.Delete(uint(id)); err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": middleware.T(c, "message.deleted")})
+}
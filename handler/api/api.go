@@ -1,41 +1,57 @@
 package api
 
 import (
-	"fmt"
-	"log"
 	"net/http"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"github.com/nodeloc-faka/i18n"
+	"github.com/nodeloc-faka/middleware"
 	"github.com/nodeloc-faka/models"
 	"github.com/nodeloc-faka/services"
 )
 
 // APIHandler API处理器
 type APIHandler struct {
-	settingService  *services.SettingService
-	categoryService *services.CategoryService
-	productService  *services.ProductService
-	orderService    *services.OrderService
-	userService     *services.UserService
-	paymentService  *services.PaymentService
+	settingService      *services.SettingService
+	categoryService     *services.CategoryService
+	tagService          *services.TagService
+	productService      *services.ProductService
+	orderService        *services.OrderService
+	userService         *services.UserService
+	notificationService *services.NotificationService
+	flashSaleService    *services.FlashSaleService
+	searchService       *services.SearchService
+	variantService      *services.VariantService
+	pricingService      *services.PricingService
+	apiTokenService     *services.APITokenService
+	checkoutService     *services.CheckoutService
+	translationService  *services.TranslationService
 }
 
 // NewAPIHandler 创建API处理器
 func NewAPIHandler() *APIHandler {
 	return &APIHandler{
-		settingService:  services.NewSettingService(),
-		categoryService: services.NewCategoryService(),
-		productService:  services.NewProductService(),
-		orderService:    services.NewOrderService(),
-		userService:     services.NewUserService(),
-		paymentService:  services.NewPaymentService(),
+		settingService:      services.NewSettingService(),
+		categoryService:     services.NewCategoryService(),
+		tagService:          services.NewTagService(),
+		productService:      services.NewProductService(),
+		orderService:        services.NewOrderService(),
+		userService:         services.NewUserService(),
+		notificationService: services.NewNotificationService(),
+		flashSaleService:    services.NewFlashSaleService(),
+		searchService:       services.NewSearchService(),
+		variantService:      services.NewVariantService(),
+		pricingService:      services.NewPricingService(),
+		apiTokenService:     services.NewAPITokenService(),
+		checkoutService:     services.NewCheckoutService(),
+		translationService:  services.NewTranslationService(),
 	}
 }
 
 // GetSettings 获取网站设置
 func (h *APIHandler) GetSettings(c *gin.Context) {
-	settings := h.settingService.GetSiteSettings()
+	settings := h.settingService.GetSiteSettings(middleware.RequestLocale(c))
 	c.JSON(http.StatusOK, settings)
 }
 
@@ -43,9 +59,14 @@ func (h *APIHandler) GetSettings(c *gin.Context) {
 func (h *APIHandler) GetCategoriesWithProducts(c *gin.Context) {
 	categories, err := h.categoryService.GetWithProducts()
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取分类失败"})
+		c.Error(err)
 		return
 	}
+	localized := make([]*models.Category, len(categories))
+	for i := range categories {
+		localized[i] = &categories[i]
+	}
+	h.translationService.LocalizeCategories(middleware.RequestLocale(c), localized...)
 	c.JSON(http.StatusOK, categories)
 }
 
@@ -54,65 +75,200 @@ func (h *APIHandler) GetCategory(c *gin.Context) {
 	id := c.Param("id")
 	category, err := h.categoryService.FindByID(ParseUint(id))
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "分类不存在"})
+		c.Error(services.ErrCategoryNotFound)
 		return
 	}
+
+	tree, err := h.categoryService.GetTree(true)
+	if err == nil {
+		category.Children = findCategoryChildren(tree, category.ID)
+	}
+	category.Path = h.categoryService.Breadcrumb(category.ID)
+	h.translationService.LocalizeCategories(middleware.RequestLocale(c), category)
 	c.JSON(http.StatusOK, category)
 }
 
+// findCategoryChildren 在分类树中查找指定分类的子分类
+func findCategoryChildren(nodes []models.Category, id uint) []models.Category {
+	for _, node := range nodes {
+		if node.ID == id {
+			return node.Children
+		}
+		if children := findCategoryChildren(node.Children, id); children != nil {
+			return children
+		}
+	}
+	return nil
+}
+
+// GetTags 获取有上架商品的标签
+func (h *APIHandler) GetTags(c *gin.Context) {
+	tags, err := h.tagService.GetActive()
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, tags)
+}
+
 // GetProducts 获取商品列表
 func (h *APIHandler) GetProducts(c *gin.Context) {
 	categoryID := c.Query("category_id")
+	tagID := c.Query("tag_id")
 
 	var products []models.Product
 	var err error
 
 	if categoryID != "" {
 		products, err = h.productService.GetByCategory(ParseUint(categoryID))
+	} else if tagID != "" {
+		products, err = h.productService.GetByTag(ParseUint(tagID))
 	} else {
 		products, err = h.productService.GetActive()
 	}
 
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取商品失败"})
+		c.Error(err)
 		return
 	}
+
+	// 填充限时抢购倒计时
+	decorated := make([]*models.Product, len(products))
+	for i := range products {
+		decorated[i] = &products[i]
+	}
+	h.flashSaleService.Decorate(decorated...)
+	h.translationService.LocalizeProducts(middleware.RequestLocale(c), decorated...)
+
 	c.JSON(http.StatusOK, products)
 }
 
+// SearchProducts 搜索商品（关键词、价格区间、有货筛选、排序，游标分页）
+func (h *APIHandler) SearchProducts(c *gin.Context) {
+	query := services.ProductSearchQuery{
+		Keyword:    c.Query("q"),
+		CategoryID: ParseUint(c.Query("category_id")),
+		TagID:      ParseUint(c.Query("tag_id")),
+		InStock:    c.Query("in_stock") == "true",
+		Sort:       c.Query("sort"),
+		Cursor:     c.Query("cursor"),
+	}
+	query.Limit, _ = strconv.Atoi(c.Query("limit"))
+
+	var ok bool
+	if query.MinPrice, ok = parsePrice(c.Query("min_price")); !ok {
+		c.Error(services.ErrInvalidPriceRange)
+		return
+	}
+	if query.MaxPrice, ok = parsePrice(c.Query("max_price")); !ok {
+		c.Error(services.ErrInvalidPriceRange)
+		return
+	}
+
+	result, err := h.searchService.SearchProducts(query)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	// 命中片段来自原文，替换为其他语言版本的商品不再返回片段
+	products := make([]*models.Product, len(result.Items))
+	originals := make([][2]string, len(result.Items))
+	for i := range result.Items {
+		products[i] = &result.Items[i].Product
+		originals[i] = [2]string{result.Items[i].Name, result.Items[i].Description}
+	}
+	h.translationService.LocalizeProducts(middleware.RequestLocale(c), products...)
+	for i := range result.Items {
+		if originals[i] != [2]string{result.Items[i].Name, result.Items[i].Description} {
+			result.Items[i].Highlight = nil
+		}
+	}
+	c.JSON(http.StatusOK, result)
+}
+
 // GetProduct 获取单个商品
 func (h *APIHandler) GetProduct(c *gin.Context) {
 	id := c.Param("id")
-	product, err := h.productService.FindByID(ParseUint(id))
-	if err != nil || !product.IsActive {
-		c.JSON(http.StatusNotFound, gin.H{"error": "商品不存在"})
+	product, err := h.productService.FindActiveByID(ParseUint(id))
+	if err != nil {
+		c.Error(services.ErrProductNotFound)
 		return
 	}
+	if product.Category != nil {
+		product.Category.Path = h.categoryService.Breadcrumb(product.CategoryID)
+	}
+	h.flashSaleService.Decorate(product)
+	h.translationService.LocalizeProducts(middleware.RequestLocale(c), product)
 	c.JSON(http.StatusOK, product)
 }
 
+// GetPriceQuote 获取商品报价（按数量阶梯价）
+func (h *APIHandler) GetPriceQuote(c *gin.Context) {
+	product, err := h.productService.FindActiveByID(ParseUint(c.Param("id")))
+	if err != nil {
+		c.Error(services.ErrProductNotFound)
+		return
+	}
+
+	quantity, _ := strconv.Atoi(c.DefaultQuery("quantity", "1"))
+	if quantity < 1 {
+		c.Error(services.ErrInvalidQuantity)
+		return
+	}
+
+	variant, err := h.variantService.Resolve(product.ID, ParseUint(c.Query("variant_id")))
+	if err != nil {
+		c.Error(services.ErrVariantNotFound)
+		return
+	}
+
+	c.JSON(http.StatusOK, h.pricingService.Quote(variant, quantity))
+}
+
 // GetUserInfo 获取用户信息
 func (h *APIHandler) GetUserInfo(c *gin.Context) {
 	user, exists := c.Get("user")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "未登录"})
+		c.Error(services.ErrUnauthorized)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"user": user})
 }
 
+// UpdateLocale 设置当前用户的语言偏好，locale 为空表示跟随浏览器
+func (h *APIHandler) UpdateLocale(c *gin.Context) {
+	u := c.MustGet("user").(*models.User)
+
+	var req struct {
+		Locale string `json:"locale"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(services.ErrInvalidRequest)
+		return
+	}
+
+	if err := h.userService.SetLocale(u.ID, req.Locale); err != nil {
+		c.Error(err)
+		return
+	}
+	// 会话中缓存的用户同步更新，后续请求立即生效
+	u.Locale = req.Locale
+	c.JSON(http.StatusOK, gin.H{"locale": req.Locale, "locales": i18n.Locales})
+}
+
 // GetOrders 获取用户订单列表
 func (h *APIHandler) GetOrders(c *gin.Context) {
 	user, exists := c.Get("user")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "未登录"})
+		c.Error(services.ErrUnauthorized)
 		return
 	}
 
 	u := user.(*models.User)
 	orders, err := h.orderService.GetByUser(u.ID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取订单失败"})
+		c.Error(err)
 		return
 	}
 	c.JSON(http.StatusOK, orders)
@@ -123,164 +279,121 @@ func (h *APIHandler) GetOrder(c *gin.Context) {
 	orderNo := c.Param("orderNo")
 	user, exists := c.Get("user")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "未登录"})
+		c.Error(services.ErrUnauthorized)
 		return
 	}
 
-	order, err := h.orderService.FindByOrderNo(orderNo)
+	// 只能查看自己的订单
+	order, err := h.orderService.FindOwned(orderNo, user.(*models.User).ID)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "订单不存在"})
-		return
-	}
-
-	// 检查是否是自己的订单
-	u := user.(*models.User)
-	if order.UserID != u.ID {
-		c.JSON(http.StatusForbidden, gin.H{"error": "无权访问"})
+		c.Error(services.ErrOrderNotFound)
 		return
 	}
 
 	c.JSON(http.StatusOK, order)
 }
 
-// RepayOrder 重新支付订单
-func (h *APIHandler) RepayOrder(c *gin.Context) {
-	userInterface, exists := c.Get("user")
-	if !exists || userInterface == nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "请先登录"})
-		return
-	}
-	u := userInterface.(*models.User)
+// GetNotifications 获取当前用户的通知
+func (h *APIHandler) GetNotifications(c *gin.Context) {
+	u := c.MustGet("user").(*models.User)
 
-	orderNo := c.Param("orderNo")
-	order, err := h.orderService.FindByOrderNo(orderNo)
+	notifications, err := h.notificationService.GetByUser(u.ID, 50)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "订单不存在"})
+		c.Error(err)
 		return
 	}
 
-	// 验证订单归属
-	if order.UserID != u.ID {
-		c.JSON(http.StatusForbidden, gin.H{"error": "无权访问此订单"})
-		return
-	}
+	c.JSON(http.StatusOK, gin.H{
+		"notifications": notifications,
+		"unread":        h.notificationService.CountUnread(u.ID),
+	})
+}
 
-	// 只有待支付订单才能重新支付
-	if order.Status != 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "订单状态不允许重新支付"})
-		return
+// ReadNotification 标记通知为已读（id 为 all 时全部标记）
+func (h *APIHandler) ReadNotification(c *gin.Context) {
+	u := c.MustGet("user").(*models.User)
+
+	var id uint
+	if c.Param("id") != "all" {
+		id = ParseUint(c.Param("id"))
+		if id == 0 {
+			c.Error(services.ErrInvalidRequest)
+			return
+		}
 	}
 
-	// 重新发起支付
-	product, err := h.productService.FindByID(order.ProductID)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取商品信息失败"})
+	if err := h.notificationService.MarkAsRead(u.ID, id); err != nil {
+		c.Error(err)
 		return
 	}
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
 
-	paymentReq := &services.CreatePaymentRequest{
-		Amount:      int(order.TotalAmount),
-		Description: fmt.Sprintf("购买商品: %s x%d", product.Name, order.Quantity),
-		OrderID:     order.OrderNo,
-	}
+// RepayOrder 重新支付订单
+func (h *APIHandler) RepayOrder(c *gin.Context) {
+	u := c.MustGet("user").(*models.User)
 
-	paymentResp, err := h.paymentService.CreatePayment(paymentReq)
+	result, err := h.checkoutService.Repay(c.Request.Context(), c.Param("orderNo"), u.ID)
 	if err != nil {
-		log.Printf("重新支付失败: %v", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "支付接口调用失败: " + err.Error()})
+		c.Error(err)
 		return
 	}
 
-	// 更新订单支付信息
-	order.TransactionID = paymentResp.TransactionID
-	order.PaymentURL = paymentResp.PaymentURL
-	h.orderService.Update(order)
-
 	c.JSON(http.StatusOK, gin.H{
 		"success":     true,
-		"payment_url": paymentResp.PaymentURL,
-		"order":       order,
+		"payment_url": result.PaymentURL,
+		"order":       result.Order,
 	})
 }
 
-// CreateOrder 创建订单
+// CreateOrder 创建订单（支持 Idempotency-Key，重复提交返回首次结果）
 func (h *APIHandler) CreateOrder(c *gin.Context) {
-	user, exists := c.Get("user")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "未登录"})
-		return
-	}
-
-	u := user.(*models.User)
+	u := c.MustGet("user").(*models.User)
 
 	// 检查用户是否被封禁
 	if u.IsBlocked {
-		c.JSON(http.StatusForbidden, gin.H{"error": "您的账号已被封禁"})
+		c.Error(services.ErrUserBlocked)
 		return
 	}
 
 	var req struct {
 		ProductID uint   `json:"product_id" binding:"required"`
+		VariantID uint   `json:"variant_id"` // 为空时使用默认规格
 		Quantity  int    `json:"quantity" binding:"required,min=1"`
 		Contact   string `json:"contact"`
 		Remark    string `json:"remark"`
+		PayMethod string `json:"pay_method"` // nodeloc（默认）或 balance
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "参数错误"})
-		return
-	}
-
-	// 验证商品
-	product, err := h.productService.FindByID(req.ProductID)
-	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "商品不存在"})
-		return
-	}
-
-	if !product.IsActive {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "商品已下架"})
+		c.Error(services.ErrInvalidRequest)
 		return
 	}
-
-	// 创建订单（使用 CreatePendingOrder 创建待支付订单）
-	order, err := h.orderService.CreatePendingOrder(u.ID, req.ProductID, req.Quantity, req.Contact, req.Remark)
-	if err != nil {
-		if err.Error() == "insufficient stock" {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "库存不足"})
-		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "创建订单失败"})
-		}
+	if req.PayMethod == services.PayMethodBalance && !middleware.HasTokenScope(c, services.ScopeBalanceSpend) {
+		c.Error(services.ErrAPITokenScopeMissing)
 		return
 	}
 
-	// 发起支付（NodeLoc Payment）
-	paymentReq := &services.CreatePaymentRequest{
-		Amount:      int(order.TotalAmount), // 积分 = 人民币（1:1）
-		Description: fmt.Sprintf("购买商品: %s x%d", product.Name, req.Quantity),
-		OrderID:     order.OrderNo,
-	}
-
-	paymentResp, err := h.paymentService.CreatePayment(paymentReq)
+	result, err := h.checkoutService.Checkout(c.Request.Context(), &services.CheckoutRequest{
+		UserID:    u.ID,
+		ProductID: req.ProductID,
+		VariantID: req.VariantID,
+		Quantity:  req.Quantity,
+		Contact:   req.Contact,
+		Remark:    req.Remark,
+		PayMethod: req.PayMethod,
+	})
 	if err != nil {
-		// 支付接口调用失败，记录日志并返回错误
-		fmt.Printf("支付接口调用失败: %v\n", err)
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": fmt.Sprintf("支付接口调用失败: %v", err),
-		})
+		c.Error(err)
 		return
 	}
 
-	// 更新订单的支付信息
-	order.TransactionID = paymentResp.TransactionID
-	order.PaymentURL = paymentResp.PaymentURL
-	h.orderService.Update(order)
-
 	c.JSON(http.StatusOK, gin.H{
 		"success":     true,
-		"order_no":    order.OrderNo,
-		"payment_url": paymentResp.PaymentURL, // 前端需要跳转到这个URL
-		"order":       order,
+		"order_no":    result.Order.OrderNo,
+		"order_token": result.Order.PublicToken,
+		"payment_url": result.PaymentURL, // 不为空时前端需要跳转到这个URL
+		"order":       result.Order,
 	})
 }
 
@@ -292,3 +405,15 @@ func ParseUint(s string) uint {
 	}
 	return uint(id)
 }
+
+// parsePrice 解析可选的价格参数，为空时返回 nil
+func parsePrice(s string) (*float64, bool) {
+	if s == "" {
+		return nil, true
+	}
+	price, err := strconv.ParseFloat(s, 64)
+	if err != nil || price < 0 {
+		return nil, false
+	}
+	return &price, true
+}
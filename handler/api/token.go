@@ -0,0 +1,70 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nodeloc-faka/models"
+	"github.com/nodeloc-faka/services"
+)
+
+// ============================================
+// 个人访问令牌
+// ============================================
+
+// GetAPITokens 获取当前用户的 API 令牌
+func (h *APIHandler) GetAPITokens(c *gin.Context) {
+	u := c.MustGet("user").(*models.User)
+
+	tokens, err := h.apiTokenService.GetByUser(u.ID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"tokens": tokens,
+		"scopes": services.APITokenScopes,
+	})
+}
+
+// CreateAPIToken 创建 API 令牌，明文只在本次响应中返回
+func (h *APIHandler) CreateAPIToken(c *gin.Context) {
+	u := c.MustGet("user").(*models.User)
+
+	var req struct {
+		Name          string   `json:"name"`
+		Scopes        []string `json:"scopes"`
+		ExpiresInDays int      `json:"expires_in_days"` // 0 表示永不过期
+		RateLimit     int      `json:"rate_limit"`      // 每分钟请求上限，0 使用默认值
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || req.ExpiresInDays < 0 {
+		c.Error(services.ErrInvalidRequest)
+		return
+	}
+
+	expiresIn := time.Duration(req.ExpiresInDays) * 24 * time.Hour
+	token, plaintext, err := h.apiTokenService.Create(u.ID, req.Name, req.Scopes, expiresIn, req.RateLimit)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"token":     token,
+		"plaintext": plaintext,
+	})
+}
+
+// RevokeAPIToken 撤销 API 令牌
+func (h *APIHandler) RevokeAPIToken(c *gin.Context) {
+	u := c.MustGet("user").(*models.User)
+
+	if err := h.apiTokenService.Revoke(u.ID, ParseUint(c.Param("id"))); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
@@ -6,6 +6,7 @@ import (
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/nodeloc-faka/middleware"
 	"github.com/nodeloc-faka/models"
 	"github.com/nodeloc-faka/oauth"
 	"github.com/nodeloc-faka/services"
@@ -13,17 +14,21 @@ import (
 
 // AuthHandler 认证处理器
 type AuthHandler struct {
-	oauthClient    *oauth.Client
-	userService    *services.UserService
-	settingService *services.SettingService
+	oauthClient      *oauth.Client
+	userService      *services.UserService
+	adminService     *services.AdminService
+	twoFactorService *services.TwoFactorService
+	settingService   *services.SettingService
 }
 
 // NewAuthHandler 创建认证处理器
 func NewAuthHandler(oauthClient *oauth.Client) *AuthHandler {
 	return &AuthHandler{
-		oauthClient:    oauthClient,
-		userService:    services.NewUserService(),
-		settingService: services.NewSettingService(),
+		oauthClient:      oauthClient,
+		userService:      services.NewUserService(),
+		adminService:     services.NewAdminService(),
+		twoFactorService: services.NewTwoFactorService(),
+		settingService:   services.NewSettingService(),
 	}
 }
 
@@ -112,9 +117,10 @@ func (h *AuthHandler) Callback(c *gin.Context) {
 		return
 	}
 
-	// 保存用户信息到 session
+	// 保存用户信息到 session（后台人员启用了两步验证时，进入后台前还需验证）
 	session["user"] = user
 	session["token"] = token
+	delete(session, middleware.SessionTwoFactorVerified)
 
 	// 获取重定向地址
 	redirect := "/"
@@ -127,12 +133,55 @@ func (h *AuthHandler) Callback(c *gin.Context) {
 	c.Redirect(http.StatusTemporaryRedirect, redirect)
 }
 
+// LocalLogin 后台人员本地账号登录（返回 JSON）
+func (h *AuthHandler) LocalLogin(c *gin.Context) {
+	var req struct {
+		Username string `json:"username" binding:"required"`
+		Password string `json:"password" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(services.ErrInvalidRequest)
+		return
+	}
+
+	user, err := h.adminService.Authenticate(req.Username, req.Password)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	// 密码验证通过后换发 session ID，防止登录前被植入的 session ID 继承登录状态
+	middleware.RegenerateSession(c)
+	session := c.MustGet("session").(map[string]interface{})
+	delete(session, "user")
+	delete(session, middleware.SessionTwoFactorVerified)
+
+	// 已启用两步验证时，密码验证通过后还需提交验证码才算登录成功
+	if user.TOTPEnabled {
+		session[middleware.SessionTwoFactorUserID] = user.ID
+		session[middleware.SessionTwoFactorAttempts] = 0
+		c.JSON(http.StatusOK, gin.H{"two_factor_required": true})
+		return
+	}
+
+	// 保存用户信息到 session
+	session["user"] = user
+
+	c.JSON(http.StatusOK, gin.H{
+		"user":                      user,
+		"two_factor_setup_required": h.twoFactorService.Required(),
+	})
+}
+
 // Logout 退出登录
 func (h *AuthHandler) Logout(c *gin.Context) {
 	// 清除 session
 	session := c.MustGet("session").(map[string]interface{})
 	delete(session, "user")
 	delete(session, "token")
+	delete(session, middleware.SessionTwoFactorUserID)
+	delete(session, middleware.SessionTwoFactorAttempts)
+	delete(session, middleware.SessionTwoFactorVerified)
 
 	c.Redirect(http.StatusTemporaryRedirect, "/")
 }
@@ -0,0 +1,55 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nodeloc-faka/oauth"
+	"github.com/nodeloc-faka/scheduler"
+	"github.com/nodeloc-faka/services"
+)
+
+// HealthHandler 存活与就绪检查处理器
+type HealthHandler struct {
+	healthService *services.HealthService
+}
+
+// NewHealthHandler 创建健康检查处理器，在内置检查项之外检查 OAuth 配置和定时任务心跳
+func NewHealthHandler(oauthClient *oauth.Client, sched *scheduler.Scheduler) *HealthHandler {
+	healthService := services.NewHealthService(UploadDir)
+	healthService.Register("oauth", func(context.Context) (string, string) {
+		if !oauthClient.IsConfigured() {
+			return services.HealthFail, "未配置 NodeLoc OAuth Client ID 或 Client Secret"
+		}
+		return services.HealthOK, ""
+	})
+	healthService.Register("scheduler", func(context.Context) (string, string) {
+		if overdue := sched.Overdue(); len(overdue) > 0 {
+			return services.HealthFail, "定时任务未按时执行: " + strings.Join(overdue, ", ")
+		}
+		return services.HealthOK, ""
+	})
+	return &HealthHandler{healthService: healthService}
+}
+
+// Live 存活检查：进程能处理请求即返回 200，不检查依赖
+func (h *HealthHandler) Live(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": services.HealthOK})
+}
+
+// Ready 就绪检查（供负载均衡和容器探针使用）：只返回总体状态，未就绪时返回 503
+func (h *HealthHandler) Ready(c *gin.Context) {
+	report := h.healthService.Check(c.Request.Context())
+	status := http.StatusOK
+	if !report.Ready() {
+		status = http.StatusServiceUnavailable
+	}
+	c.JSON(status, gin.H{"status": report.Status})
+}
+
+// Report 就绪检查详细报告（后台人员查看），始终返回 200
+func (h *HealthHandler) Report(c *gin.Context) {
+	c.JSON(http.StatusOK, h.healthService.Check(c.Request.Context()))
+}
@@ -0,0 +1,188 @@
+package handler_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nodeloc-faka/database/dbtest"
+	"github.com/nodeloc-faka/handler"
+	"github.com/nodeloc-faka/handler/api"
+	"github.com/nodeloc-faka/middleware"
+	"github.com/nodeloc-faka/models"
+	"gorm.io/gorm"
+)
+
+// testUserHeader 测试中以该请求头指定当前登录用户的 ID，代替会话认证
+const testUserHeader = "X-Test-User"
+
+// orderAccessFixture 两个用户和用户 A 的一笔待支付订单
+type orderAccessFixture struct {
+	router *gin.Engine
+	db     *gorm.DB
+	owner  models.User
+	other  models.User
+	order  models.Order
+}
+
+func newOrderAccessFixture(t *testing.T) *orderAccessFixture {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	db := dbtest.Open(t)
+
+	f := &orderAccessFixture{
+		db:    db,
+		owner: models.User{NodeLocID: 1001, Username: "alice"},
+		other: models.User{NodeLocID: 1002, Username: "bob"},
+	}
+	mustCreate(t, db, &f.owner)
+	mustCreate(t, db, &f.other)
+
+	expiredAt := time.Now().Add(30 * time.Minute)
+	f.order = models.Order{
+		OrderNo:     "20261019000000000001",
+		PublicToken: "owner-public-token",
+		UserID:      f.owner.ID,
+		Quantity:    1,
+		UnitPrice:   10,
+		TotalAmount: 10,
+		Status:      models.OrderStatusPending,
+		ExpiredAt:   &expiredAt,
+	}
+	mustCreate(t, db, &f.order)
+
+	apiHandler := api.NewAPIHandler()
+	paymentHandler := handler.NewPaymentHandler()
+
+	router := gin.New()
+	router.Use(middleware.ErrorHandler(), func(c *gin.Context) {
+		if id, err := strconv.ParseUint(c.GetHeader(testUserHeader), 10, 64); err == nil {
+			var user models.User
+			if err := db.First(&user, id).Error; err != nil {
+				t.Fatalf("加载测试用户失败: %v", err)
+			}
+			c.Set("user", &user)
+		}
+	})
+	router.GET("/api/orders/:orderNo", apiHandler.GetOrder)
+	router.POST("/api/orders/:orderNo/repay", apiHandler.RepayOrder)
+	router.GET("/api/order/:order_no/status", paymentHandler.QueryOrder)
+	router.POST("/api/order/:order_no/cancel", paymentHandler.CancelOrder)
+	f.router = router
+	return f
+}
+
+func mustCreate(t *testing.T, db *gorm.DB, value interface{}) {
+	t.Helper()
+	if err := db.Create(value).Error; err != nil {
+		t.Fatalf("写入测试数据失败: %v", err)
+	}
+}
+
+// do 以 user 的身份发起请求，user 为 nil 时不登录
+func (f *orderAccessFixture) do(method, path string, user *models.User) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	if user != nil {
+		req.Header.Set(testUserHeader, strconv.FormatUint(uint64(user.ID), 10))
+	}
+	recorder := httptest.NewRecorder()
+	f.router.ServeHTTP(recorder, req)
+	return recorder
+}
+
+func TestOrderEndpointsHideOtherUsersOrders(t *testing.T) {
+	f := newOrderAccessFixture(t)
+	orderNo := f.order.OrderNo
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+	}{
+		{"get order", http.MethodGet, "/api/orders/" + orderNo},
+		{"repay order", http.MethodPost, "/api/orders/" + orderNo + "/repay"},
+		{"poll status", http.MethodGet, "/api/order/" + orderNo + "/status"},
+		{"cancel order", http.MethodPost, "/api/order/" + orderNo + "/cancel"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := f.do(tt.method, tt.path, &f.other)
+			if recorder.Code != http.StatusNotFound {
+				t.Fatalf("其他用户访问订单返回 %d，期望 404: %s", recorder.Code, recorder.Body)
+			}
+			var body struct {
+				Code string `json:"code"`
+			}
+			if err := json.Unmarshal(recorder.Body.Bytes(), &body); err != nil || body.Code != "order_not_found" {
+				t.Fatalf("错误码为 %q，期望 order_not_found: %s", body.Code, recorder.Body)
+			}
+		})
+	}
+
+	// 其他用户的请求不能改变订单状态
+	var order models.Order
+	if err := f.db.First(&order, f.order.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if order.Status != models.OrderStatusPending {
+		t.Fatalf("订单状态变为 %d，期望保持待支付", order.Status)
+	}
+}
+
+func TestOrderEndpointsAllowOwner(t *testing.T) {
+	f := newOrderAccessFixture(t)
+	orderNo := f.order.OrderNo
+
+	if recorder := f.do(http.MethodGet, "/api/orders/"+orderNo, &f.owner); recorder.Code != http.StatusOK {
+		t.Fatalf("本人查看订单返回 %d: %s", recorder.Code, recorder.Body)
+	}
+	if recorder := f.do(http.MethodGet, "/api/order/"+orderNo+"/status", &f.owner); recorder.Code != http.StatusOK {
+		t.Fatalf("本人查询支付状态返回 %d: %s", recorder.Code, recorder.Body)
+	}
+	if recorder := f.do(http.MethodPost, "/api/order/"+orderNo+"/cancel", &f.owner); recorder.Code != http.StatusOK {
+		t.Fatalf("本人取消订单返回 %d: %s", recorder.Code, recorder.Body)
+	}
+}
+
+func TestPublicStatusPollingRequiresToken(t *testing.T) {
+	f := newOrderAccessFixture(t)
+	path := "/api/order/" + f.order.OrderNo + "/status"
+
+	tests := []struct {
+		name  string
+		query string
+		user  *models.User
+		want  int
+	}{
+		{"missing token", "", nil, http.StatusNotFound},
+		{"empty token", "?token=", nil, http.StatusNotFound},
+		{"wrong token", "?token=wrong-token", nil, http.StatusNotFound},
+		{"wrong token as other user", "?token=wrong-token", &f.other, http.StatusNotFound},
+		{"token of order", "?token=" + f.order.PublicToken, nil, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := f.do(http.MethodGet, path+tt.query, tt.user)
+			if recorder.Code != tt.want {
+				t.Fatalf("返回 %d，期望 %d: %s", recorder.Code, tt.want, recorder.Body)
+			}
+		})
+	}
+
+	// 凭证只对签发的订单有效
+	other := models.Order{
+		OrderNo:     "20261019000000000002",
+		PublicToken: "other-public-token",
+		UserID:      f.other.ID,
+		Quantity:    1,
+		Status:      models.OrderStatusPending,
+	}
+	mustCreate(t, f.db, &other)
+	if recorder := f.do(http.MethodGet, path+"?token="+other.PublicToken, nil); recorder.Code != http.StatusNotFound {
+		t.Fatalf("使用其他订单的凭证返回 %d，期望 404: %s", recorder.Code, recorder.Body)
+	}
+}
@@ -1,23 +1,24 @@
 package handler
 
 import (
-	"fmt"
 	"net/http"
 	"strconv"
-	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/nodeloc-faka/database"
+	"github.com/nodeloc-faka/logging"
+	"github.com/nodeloc-faka/middleware"
 	"github.com/nodeloc-faka/models"
 	"github.com/nodeloc-faka/services"
 )
 
+// logger 处理器日志
+var logger = logging.For("handler")
+
 // PaymentHandler 支付处理器
 type PaymentHandler struct {
 	paymentService  *services.PaymentService
 	orderService    *services.OrderService
-	productService  *services.ProductService
-	settingService  *services.SettingService
+	checkoutService *services.CheckoutService
 }
 
 // NewPaymentHandler 创建支付处理器
@@ -25,141 +26,64 @@ func NewPaymentHandler() *PaymentHandler {
 	return &PaymentHandler{
 		paymentService:  services.NewPaymentService(),
 		orderService:    services.NewOrderService(),
-		productService:  services.NewProductService(),
-		settingService:  services.NewSettingService(),
+		checkoutService: services.NewCheckoutService(),
 	}
 }
 
-// CreateOrder 创建订单并发起支付
+// CreateOrder 创建订单并发起支付（表单提交，与 JSON 下单接口共用下单流程）
 func (h *PaymentHandler) CreateOrder(c *gin.Context) {
 	// 检查用户是否登录
 	userInterface, exists := c.Get("user")
 	if !exists || userInterface == nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "请先登录"})
+		c.Error(services.ErrUnauthorized)
 		return
 	}
 	user := userInterface.(*models.User)
 
 	// 检查用户是否被封禁
 	if user.IsBlocked {
-		c.JSON(http.StatusForbidden, gin.H{"error": "您的账号已被封禁"})
+		c.Error(services.ErrUserBlocked)
 		return
 	}
 
 	// 获取请求参数
 	productID, _ := strconv.ParseUint(c.PostForm("product_id"), 10, 64)
-	quantity, _ := strconv.Atoi(c.PostForm("quantity"))
-	contact := c.PostForm("contact")
-	remark := c.PostForm("remark")
-
-	if quantity <= 0 {
-		quantity = 1
-	}
-
-	// 获取商品
-	product, err := h.productService.FindByID(uint(productID))
-	if err != nil || !product.IsActive {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "商品不存在或已下架"})
-		return
-	}
-
-	// 检查库存
-	if product.StockCount < quantity {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "库存不足"})
-		return
-	}
-
-	// 计算金额
-	totalAmount := product.Price * float64(quantity)
+	variantID, _ := strconv.ParseUint(c.PostForm("variant_id"), 10, 64)
+	quantity, _ := strconv.Atoi(c.DefaultPostForm("quantity", "1"))
+	payMethod := c.PostForm("pay_method")
 
-	// 创建订单
-	order := &models.Order{
-		UserID:      user.ID,
-		ProductID:   uint(productID),
-		Quantity:    quantity,
-		TotalAmount: totalAmount,
-		Status:      models.OrderStatusPending,
-		Contact:     contact,
-		Remark:      remark,
-	}
-
-	// 设置过期时间（30分钟）
-	expiredAt := time.Now().Add(30 * time.Minute)
-	order.ExpiredAt = &expiredAt
-
-	if err := h.orderService.Create(order); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "创建订单失败"})
-		return
-	}
-
-	// 检查是否配置了支付
-	if !h.paymentService.IsConfigured() {
-		// 未配置支付，直接完成订单（免费模式）
-		h.completeOrderFree(order)
-		c.JSON(http.StatusOK, gin.H{
-			"success":  true,
-			"order_no": order.OrderNo,
-			"message":  "订单创建成功",
-			"redirect": "/order/" + order.OrderNo,
-		})
+	if payMethod == services.PayMethodBalance && !middleware.HasTokenScope(c, services.ScopeBalanceSpend) {
+		c.Error(services.ErrAPITokenScopeMissing)
 		return
 	}
 
-	// 发起支付请求
-	payResp, err := h.paymentService.CreatePayment(&services.CreatePaymentRequest{
-		Amount:      int(totalAmount), // 假设1积分=1元
-		Description: fmt.Sprintf("购买 %s x%d", product.Name, quantity),
-		OrderID:     order.OrderNo,
+	result, err := h.checkoutService.Checkout(c.Request.Context(), &services.CheckoutRequest{
+		UserID:    user.ID,
+		ProductID: uint(productID),
+		VariantID: uint(variantID),
+		Quantity:  quantity,
+		Contact:   c.PostForm("contact"),
+		Remark:    c.PostForm("remark"),
+		PayMethod: payMethod,
 	})
-
 	if err != nil {
-		// 支付请求失败，记录错误但不影响订单
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":    "发起支付失败: " + err.Error(),
-			"order_no": order.OrderNo,
-		})
+		c.Error(err)
 		return
 	}
 
-	// 保存支付信息
-	order.TransactionID = payResp.TransactionID
-	order.PaymentURL = payResp.PaymentURL
-	database.GetDB().Save(order)
-
+	redirect := "/order/" + result.Order.OrderNo
+	if result.PaymentURL != "" {
+		redirect = result.PaymentURL
+	}
 	c.JSON(http.StatusOK, gin.H{
-		"success":      true,
-		"order_no":     order.OrderNo,
-		"payment_url":  payResp.PaymentURL,
-		"redirect":     payResp.PaymentURL,
+		"success":     true,
+		"order_no":    result.Order.OrderNo,
+		"order_token": result.Order.PublicToken,
+		"payment_url": result.PaymentURL,
+		"redirect":    redirect,
 	})
 }
 
-// completeOrderFree 免费模式完成订单
-func (h *PaymentHandler) completeOrderFree(order *models.Order) {
-	cardKeyService := services.NewCardKeyService()
-	
-	// 分配卡密
-	availableCards, _ := cardKeyService.GetAvailableByProduct(order.ProductID, order.Quantity)
-	if len(availableCards) >= order.Quantity {
-		cardIDs := make([]uint, order.Quantity)
-		for i := 0; i < order.Quantity; i++ {
-			cardIDs[i] = availableCards[i].ID
-		}
-		cardKeyService.MarkAsSold(cardIDs, order.ID)
-	}
-
-	// 更新订单状态
-	now := time.Now()
-	order.Status = models.OrderStatusCompleted
-	order.PayMethod = "free"
-	order.PaidAt = &now
-	database.GetDB().Save(order)
-
-	// 更新商品库存和销量
-	h.productService.UpdateStock(order.ProductID)
-	h.productService.IncrementSales(order.ProductID, order.Quantity)
-}
-
 // PaymentCallback 支付回调
 func (h *PaymentHandler) PaymentCallback(c *gin.Context) {
 	// 解析回调参数
@@ -175,10 +99,11 @@ func (h *PaymentHandler) PaymentCallback(c *gin.Context) {
 	callback.MerchantPoints, _ = strconv.Atoi(c.Query("merchant_points"))
 
 	// 处理回调
-	err := h.paymentService.ProcessPaymentCallback(callback)
+	ctx := logging.With(c.Request.Context(), logging.OrderNo(callback.ExternalReference), logging.TransactionID(callback.TransactionID))
+	err := h.paymentService.ProcessPaymentCallback(ctx, callback)
 	if err != nil {
 		// 记录错误日志
-		fmt.Printf("支付回调处理失败: %v\n", err)
+		logger.WarnContext(ctx, "支付回调处理失败", "error", err)
 		// 仍然重定向到订单页面，但显示错误
 		c.Redirect(http.StatusFound, "/order/"+callback.ExternalReference+"?error="+err.Error())
 		return
@@ -189,12 +114,13 @@ func (h *PaymentHandler) PaymentCallback(c *gin.Context) {
 }
 
 // QueryOrder 查询订单支付状态
+// 下单用户登录后可直接查询，未登录时需携带下单时返回的查询凭证 ?token=
 func (h *PaymentHandler) QueryOrder(c *gin.Context) {
 	orderNo := c.Param("order_no")
 
-	order, err := h.orderService.FindByOrderNo(orderNo)
+	order, err := h.findQueryableOrder(c, orderNo)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "订单不存在"})
+		c.Error(services.ErrOrderNotFound)
 		return
 	}
 
@@ -210,7 +136,8 @@ func (h *PaymentHandler) QueryOrder(c *gin.Context) {
 
 	// 如果有交易ID，查询支付状态
 	if order.TransactionID != "" {
-		queryResp, err := h.paymentService.QueryPayment(order.TransactionID)
+		ctx := logging.With(c.Request.Context(), logging.OrderNo(order.OrderNo), logging.TransactionID(order.TransactionID))
+		queryResp, err := h.paymentService.QueryPayment(ctx, order.TransactionID)
 		if err == nil && queryResp.Status == "completed" {
 			// 支付已完成，处理订单
 			callback := &services.PaymentCallback{
@@ -224,10 +151,14 @@ func (h *PaymentHandler) QueryOrder(c *gin.Context) {
 			if queryResp.PaidAt != nil {
 				callback.PaidAt = *queryResp.PaidAt
 			}
-			h.paymentService.ProcessPaymentCallback(callback)
-			
+			if err := h.paymentService.ProcessPaymentCallback(ctx, callback); err != nil {
+				logger.WarnContext(ctx, "处理已完成的支付失败", "error", err)
+			}
+
 			// 重新查询订单
-			order, _ = h.orderService.FindByOrderNo(orderNo)
+			if reloaded, err := h.orderService.FindByOrderNo(orderNo); err == nil {
+				order = reloaded
+			}
 		}
 	}
 
@@ -238,6 +169,17 @@ func (h *PaymentHandler) QueryOrder(c *gin.Context) {
 	})
 }
 
+// findQueryableOrder 查找当前请求有权查询的订单：本人订单或查询凭证匹配的订单
+func (h *PaymentHandler) findQueryableOrder(c *gin.Context, orderNo string) (*models.Order, error) {
+	if token := c.Query("token"); token != "" {
+		return h.orderService.FindByPublicToken(orderNo, token)
+	}
+	if userInterface, exists := c.Get("user"); exists && userInterface != nil {
+		return h.orderService.FindOwned(orderNo, userInterface.(*models.User).ID)
+	}
+	return nil, services.ErrOrderNotFound
+}
+
 // CancelOrder 取消订单
 func (h *PaymentHandler) CancelOrder(c *gin.Context) {
 	orderNo := c.Param("order_no")
@@ -245,26 +187,21 @@ func (h *PaymentHandler) CancelOrder(c *gin.Context) {
 	// 检查用户
 	userInterface, exists := c.Get("user")
 	if !exists || userInterface == nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "请先登录"})
+		c.Error(services.ErrUnauthorized)
 		return
 	}
 	user := userInterface.(*models.User)
 
-	order, err := h.orderService.FindByOrderNo(orderNo)
+	// 只能取消自己的订单
+	order, err := h.orderService.FindOwned(orderNo, user.ID)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "订单不存在"})
-		return
-	}
-
-	// 检查是否是自己的订单
-	if order.UserID != user.ID {
-		c.JSON(http.StatusForbidden, gin.H{"error": "无权操作此订单"})
+		c.Error(services.ErrOrderNotFound)
 		return
 	}
 
 	// 只能取消待支付的订单
 	if order.Status != models.OrderStatusPending {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "订单状态不允许取消"})
+		c.Error(services.ErrOrderNotPending)
 		return
 	}
 
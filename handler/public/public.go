@@ -31,7 +31,7 @@ func NewHandler() *Handler {
 
 // getSiteData 获取网站公共数据
 func (h *Handler) getSiteData(c *gin.Context) gin.H {
-	settings := h.settingService.GetSiteSettings()
+	settings := h.settingService.GetSiteSettings(h.settingService.DefaultLocale())
 	categories, _ := h.categoryService.GetActive()
 
 	// 获取当前用户
@@ -178,13 +178,6 @@ func (h *Handler) OrderDetail(c *gin.Context) {
 	data := h.getSiteData(c)
 
 	orderNo := c.Param("order_no")
-	order, err := h.orderService.FindByOrderNo(orderNo)
-	if err != nil {
-		c.HTML(http.StatusNotFound, "public/404", data)
-		return
-	}
-
-	// 检查是否是自己的订单
 	userInterface, exists := c.Get("user")
 	if !exists || userInterface == nil {
 		c.Redirect(http.StatusFound, "/login?redirect=/order/"+orderNo)
@@ -192,8 +185,10 @@ func (h *Handler) OrderDetail(c *gin.Context) {
 	}
 	user := userInterface.(*models.User)
 
-	if order.UserID != user.ID {
-		c.HTML(http.StatusForbidden, "public/403", data)
+	// 只能查看自己的订单
+	order, err := h.orderService.FindOwned(orderNo, user.ID)
+	if err != nil {
+		c.HTML(http.StatusNotFound, "public/404", data)
 		return
 	}
 
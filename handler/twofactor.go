@@ -0,0 +1,165 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nodeloc-faka/middleware"
+	"github.com/nodeloc-faka/services"
+)
+
+// MaxTwoFactorAttempts 单次登录允许的两步验证失败次数，超过后需重新登录
+// 本地账号的失败次数另外计入账号的登录锁定，不因更换 session 而清零
+const MaxTwoFactorAttempts = 5
+
+// twoFactorCodeRequest 验证码请求（TOTP 验证码或恢复码）
+type twoFactorCodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// VerifyTwoFactor 提交两步验证码
+// 用于本地账号密码验证后的第二步，以及 OAuth 登录的后台人员进入后台前的验证
+func (h *AuthHandler) VerifyTwoFactor(c *gin.Context) {
+	var req twoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(services.ErrInvalidRequest)
+		return
+	}
+
+	session := c.MustGet("session").(map[string]interface{})
+	pendingID, pending := session[middleware.SessionTwoFactorUserID].(uint)
+	userID := pendingID
+	if !pending {
+		user := GetCurrentUser(c)
+		if user == nil {
+			c.Error(services.ErrUnauthorized)
+			return
+		}
+		userID = user.ID
+	}
+
+	if err := h.adminService.VerifyTwoFactor(userID, req.Code); err != nil {
+		attempts, _ := session[middleware.SessionTwoFactorAttempts].(int)
+		attempts++
+		session[middleware.SessionTwoFactorAttempts] = attempts
+		if attempts >= MaxTwoFactorAttempts || errors.Is(err, services.ErrAccountLocked) {
+			// 失败次数过多，清除登录状态，需重新登录
+			delete(session, "user")
+			delete(session, "token")
+			delete(session, middleware.SessionTwoFactorUserID)
+			delete(session, middleware.SessionTwoFactorAttempts)
+			if errors.Is(err, services.ErrAccountLocked) {
+				c.Error(err)
+				return
+			}
+			c.Error(services.ErrTwoFactorAttemptsExceeded)
+			return
+		}
+		c.Error(err)
+		return
+	}
+
+	user, err := h.userService.FindByID(userID)
+	if err != nil {
+		c.Error(services.ErrInvalidCredentials)
+		return
+	}
+	if user.IsBlocked {
+		c.Error(services.ErrUserBlocked)
+		return
+	}
+
+	// 完成两步验证后再次换发 session ID
+	middleware.RegenerateSession(c)
+	session["user"] = user
+	session[middleware.SessionTwoFactorVerified] = true
+	delete(session, middleware.SessionTwoFactorUserID)
+	delete(session, middleware.SessionTwoFactorAttempts)
+
+	c.JSON(http.StatusOK, gin.H{"user": user})
+}
+
+// GetTwoFactorStatus 获取当前用户的两步验证状态
+func (h *AuthHandler) GetTwoFactorStatus(c *gin.Context) {
+	user, err := h.userService.FindByID(GetCurrentUser(c).ID)
+	if err != nil {
+		c.Error(services.ErrUserNotFound)
+		return
+	}
+
+	session := c.MustGet("session").(map[string]interface{})
+	verified, _ := session[middleware.SessionTwoFactorVerified].(bool)
+	c.JSON(http.StatusOK, gin.H{
+		"enabled":                  user.TOTPEnabled,
+		"verified":                 verified,
+		"required":                 user.IsStaff() && h.twoFactorService.Required(),
+		"recovery_codes_remaining": h.twoFactorService.RemainingRecoveryCodes(user.ID),
+	})
+}
+
+// SetupTwoFactor 生成两步验证密钥
+func (h *AuthHandler) SetupTwoFactor(c *gin.Context) {
+	setup, err := h.twoFactorService.Setup(GetCurrentUser(c).ID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"setup": setup})
+}
+
+// EnableTwoFactor 验证并启用两步验证，返回恢复码
+func (h *AuthHandler) EnableTwoFactor(c *gin.Context) {
+	var req twoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(services.ErrInvalidRequest)
+		return
+	}
+
+	codes, err := h.twoFactorService.Enable(GetCurrentUser(c).ID, req.Code)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	// 刚验证过验证码，本次会话视为已完成两步验证
+	session := c.MustGet("session").(map[string]interface{})
+	session[middleware.SessionTwoFactorVerified] = true
+
+	c.JSON(http.StatusOK, gin.H{"recovery_codes": codes})
+}
+
+// DisableTwoFactor 关闭两步验证
+func (h *AuthHandler) DisableTwoFactor(c *gin.Context) {
+	var req twoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(services.ErrInvalidRequest)
+		return
+	}
+
+	if err := h.twoFactorService.Disable(GetCurrentUser(c).ID, req.Code); err != nil {
+		c.Error(err)
+		return
+	}
+
+	session := c.MustGet("session").(map[string]interface{})
+	delete(session, middleware.SessionTwoFactorVerified)
+
+	c.JSON(http.StatusOK, gin.H{"message": middleware.T(c, "message.two_factor_disabled")})
+}
+
+// RegenerateRecoveryCodes 重新生成恢复码
+func (h *AuthHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	var req twoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(services.ErrInvalidRequest)
+		return
+	}
+
+	codes, err := h.twoFactorService.RegenerateRecoveryCodes(GetCurrentUser(c).ID, req.Code)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"recovery_codes": codes})
+}
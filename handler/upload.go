@@ -12,8 +12,12 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/nodeloc-faka/services"
 )
 
+// UploadDir 上传文件的保存目录，通过 /uploads 对外提供
+const UploadDir = "uploads"
+
 // UploadHandler 上传处理器
 type UploadHandler struct{}
 
@@ -27,7 +31,7 @@ func (h *UploadHandler) UploadImage(c *gin.Context) {
 	// 获取上传的文件
 	file, err := c.FormFile("file")
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "请选择文件"})
+		c.Error(ErrFileRequired)
 		return
 	}
 
@@ -42,20 +46,20 @@ func (h *UploadHandler) UploadImage(c *gin.Context) {
 	}
 	
 	if !allowedExts[ext] {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "只支持 JPG、PNG、GIF、WEBP 格式的图片"})
+		c.Error(ErrUnsupportedImageType)
 		return
 	}
 
 	// 验证文件大小（最大 5MB）
 	if file.Size > 5*1024*1024 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "图片大小不能超过 5MB"})
+		c.Error(ErrImageTooLarge)
 		return
 	}
 
 	// 打开文件
 	src, err := file.Open()
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "读取文件失败"})
+		c.Error(err)
 		return
 	}
 	defer src.Close()
@@ -63,7 +67,7 @@ func (h *UploadHandler) UploadImage(c *gin.Context) {
 	// 计算文件 MD5 作为文件名
 	hash := md5.New()
 	if _, err := io.Copy(hash, src); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "计算文件哈希失败"})
+		c.Error(err)
 		return
 	}
 	md5sum := hex.EncodeToString(hash.Sum(nil))
@@ -76,9 +80,9 @@ func (h *UploadHandler) UploadImage(c *gin.Context) {
 	filename := md5sum + ext
 	
 	// 创建保存目录
-	uploadDir := filepath.Join("uploads", "images", dateDir)
+	uploadDir := filepath.Join(UploadDir, "images", dateDir)
 	if err := os.MkdirAll(uploadDir, 0755); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "创建上传目录失败"})
+		c.Error(err)
 		return
 	}
 
@@ -86,13 +90,13 @@ func (h *UploadHandler) UploadImage(c *gin.Context) {
 	dst := filepath.Join(uploadDir, filename)
 	out, err := os.Create(dst)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "保存文件失败"})
+		c.Error(err)
 		return
 	}
 	defer out.Close()
 
 	if _, err := io.Copy(out, src); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "写入文件失败"})
+		c.Error(err)
 		return
 	}
 
@@ -105,3 +109,10 @@ func (h *UploadHandler) UploadImage(c *gin.Context) {
 		"size":    file.Size,
 	})
 }
+
+// 错误定义
+var (
+	ErrFileRequired         = &services.ServiceError{Code: "file_required", Message: "请选择文件"}
+	ErrUnsupportedImageType = &services.ServiceError{Code: "unsupported_image_type", Message: "只支持 JPG、PNG、GIF、WEBP 格式的图片"}
+	ErrImageTooLarge        = &services.ServiceError{Code: "image_too_large", Status: http.StatusRequestEntityTooLarge, Message: "图片大小不能超过 5MB"}
+)
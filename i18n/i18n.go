@@ -0,0 +1,88 @@
+// Package i18n 提供消息目录和语言协商，目前支持简体中文（默认）和英文
+package i18n
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// 支持的语言
+const (
+	LocaleZhCN = "zh-CN"
+	LocaleEn   = "en"
+
+	// DefaultLocale 默认语言，源代码中的提示文案即为该语言
+	DefaultLocale = LocaleZhCN
+)
+
+// Locales 所有支持的语言
+var Locales = []string{LocaleZhCN, LocaleEn}
+
+// catalogs 各语言的消息目录
+// 错误提示的默认语言文案写在错误定义中，默认语言目录只登记其余消息
+var catalogs = map[string]map[string]string{
+	LocaleZhCN: zhCNMessages,
+	LocaleEn:   enMessages,
+}
+
+// T 按语言查找消息，找不到时依次使用默认语言的消息和 fallback
+func T(locale, key, fallback string) string {
+	if message, ok := catalogs[locale][key]; ok {
+		return message
+	}
+	if message, ok := catalogs[DefaultLocale][key]; ok {
+		return message
+	}
+	return fallback
+}
+
+// Tf 按语言查找消息模板并格式化，各语言模板的参数顺序保持一致
+func Tf(locale, key string, args ...interface{}) string {
+	return fmt.Sprintf(T(locale, key, key), args...)
+}
+
+// Supported 检查是否为支持的语言
+func Supported(locale string) bool {
+	_, ok := catalogs[locale]
+	return ok
+}
+
+// Normalize 将语言标签规范为支持的语言，不支持时返回空字符串
+func Normalize(tag string) string {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	switch {
+	case tag == "zh" || strings.HasPrefix(tag, "zh-"):
+		return LocaleZhCN
+	case tag == "en" || strings.HasPrefix(tag, "en-"):
+		return LocaleEn
+	}
+	return ""
+}
+
+// Negotiate 根据 Accept-Language 选择语言，按 q 值从高到低匹配支持的语言，都不支持时返回空字符串
+func Negotiate(acceptLanguage string) string {
+	type candidate struct {
+		locale string
+		q      float64
+	}
+	var candidates []candidate
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(part, ";")
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+				q = parsed
+			}
+		}
+		if locale := Normalize(tag); locale != "" && q > 0 {
+			candidates = append(candidates, candidate{locale, q})
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].q > candidates[j].q })
+	if len(candidates) > 0 {
+		return candidates[0].locale
+	}
+	return ""
+}
@@ -0,0 +1,166 @@
+package i18n
+
+// enMessages 英文消息目录
+// 错误提示的键为 "error.<错误码>"
+var enMessages = map[string]string{
+	// 操作结果
+	"message.updated":             "Updated successfully",
+	"message.deleted":             "Deleted successfully",
+	"message.restored":            "Restored successfully",
+	"message.cards_added":         "Added successfully",
+	"message.claimed":             "Order claimed",
+	"message.unclaimed":           "Claim released",
+	"message.delivered":           "Order delivered",
+	"message.rejected":            "Order rejected and refunded",
+	"message.rejected_cancelled":  "Order rejected and cancelled",
+	"message.unlocked":            "Account unlocked",
+	"message.two_factor_reset":    "Two-factor authentication has been reset",
+	"message.two_factor_disabled": "Two-factor authentication disabled",
+
+	// 站内通知
+	"notification.order_delivered.title":   "Order delivered",
+	"notification.order_delivered.content": "Your order %s has been delivered by an administrator. Open the order details to view it.",
+	"notification.order_refunded.title":    "Order refunded",
+	"notification.order_refunded.content":  "Your order %s could not be delivered. %.2f credits have been refunded to your balance.",
+	"notification.order_refunded.reason":   " Reason: %s",
+	"notification.order_rejected.title":    "Order cancelled",
+	"notification.order_rejected.content":  "Your order %s could not be delivered and has been cancelled.",
+
+	// 通用错误
+	"error.invalid_request":        "Invalid request parameters",
+	"error.unauthorized":           "Not logged in",
+	"error.forbidden":              "You do not have permission to perform this action",
+	"error.not_found":              "Resource not found",
+	"error.rate_limited":           "Too many requests, please try again later",
+	"error.internal_error":         "Internal server error",
+	"error.csrf_failed":            "Request verification failed, please refresh the page and try again",
+	"error.file_required":          "Please choose a file",
+	"error.unsupported_image_type": "Only JPG, PNG, GIF and WEBP images are supported",
+	"error.image_too_large":        "Image size must not exceed 5MB",
+	"error.unsupported_locale":     "Unsupported language",
+	"error.invalid_sort":           "Unsupported sort field",
+	"error.invalid_cursor":         "Invalid pagination cursor",
+
+	// 登录与权限
+	"error.invalid_credentials":          "Incorrect username or password",
+	"error.user_blocked":                 "Your account has been blocked",
+	"error.not_staff":                    "This user is not a staff member",
+	"error.admin_required":               "Administrator access required",
+	"error.admin_username_required":      "Please enter a username",
+	"error.admin_password_too_short":     "Password must be at least 8 characters",
+	"error.admin_username_taken":         "Username is already taken",
+	"error.account_locked":               "Too many failed login attempts, the account is temporarily locked",
+	"error.already_bootstrapped":         "An owner already exists, no bootstrap needed",
+	"error.user_not_found":               "User not found",
+	"error.invalid_role":                 "Role does not exist",
+	"error.last_owner":                   "At least one owner must remain",
+	"error.two_factor_enabled":           "Two-factor authentication is already enabled",
+	"error.two_factor_not_setup":         "Please generate a two-factor secret first",
+	"error.two_factor_not_enabled":       "Two-factor authentication is not enabled",
+	"error.invalid_two_factor_code":      "Incorrect verification code",
+	"error.two_factor_required":          "Please complete two-factor verification first",
+	"error.two_factor_setup_required":    "Please enable two-factor authentication first",
+	"error.two_factor_attempts_exceeded": "Too many failed verification attempts, please log in again",
+	"error.two_factor_self_not_enabled":  "Enable two-factor authentication on your own account before requiring it for all staff",
+
+	// 限流与幂等
+	"error.abuse_blocked":           "Too many requests, access is temporarily restricted",
+	"error.block_not_active":        "Block record not found or no longer active",
+	"error.invalid_idempotency_key": "Invalid Idempotency-Key",
+	"error.idempotency_key_reused":  "Idempotency-Key was already used for a different request",
+	"error.idempotency_in_progress": "An identical request is still being processed, please retry later",
+
+	// API 令牌
+	"error.invalid_api_token":       "API token is invalid or expired",
+	"error.api_token_not_found":     "Token not found or already revoked",
+	"error.api_token_name_required": "Please enter a token name",
+	"error.invalid_api_token_scope": "Invalid token scope",
+	"error.api_token_rate_limit":    "Invalid token rate limit",
+	"error.too_many_api_tokens":     "Token limit reached, please revoke unused tokens first",
+	"error.api_token_scope_missing": "This API token is not allowed to access this endpoint",
+
+	// 商品、分类与标签
+	"error.product_not_found":       "Product not found",
+	"error.product_inactive":        "Product is no longer available",
+	"error.product_has_cards":       "Product still has unsold card keys and cannot be deleted",
+	"error.product_not_archived":    "Product not found or not archived",
+	"error.product_archived":        "Product is archived, please restore it first",
+	"error.invalid_delivery_type":   "Invalid delivery type",
+	"error.variant_not_found":       "Product variant not found",
+	"error.variant_has_cards":       "Variant still has unsold card keys and cannot be deleted",
+	"error.last_variant":            "A product must keep at least one variant",
+	"error.category_not_found":      "Category not found",
+	"error.category_has_products":   "Category still contains products and cannot be deleted",
+	"error.category_has_children":   "Category still has subcategories and cannot be deleted",
+	"error.category_cycle":          "A category cannot be moved under itself or its subcategories",
+	"error.category_not_archived":   "Category not found or not archived",
+	"error.category_archived":       "Category is archived, please restore it first",
+	"error.tag_not_found":           "Tag not found",
+	"error.tag_exists":              "Tag name already exists",
+	"error.invalid_search_cursor":   "Invalid pagination cursor",
+	"error.invalid_price_range":     "Invalid price range",
+	"error.invalid_price_tier":      "Invalid tiered price settings",
+	"error.overlapping_price_tiers": "Tiered price quantity ranges overlap",
+	"error.product_price_locked":    "This product takes its price from several variants; edit the variant prices instead",
+
+	// 卡密
+	"error.card_key_not_found":     "Card key not found",
+	"error.card_key_not_available": "Only available card keys can be locked",
+	"error.card_key_not_locked":    "Card key is not locked",
+	"error.card_key_already_sold":  "Card key has been sold and cannot be moved",
+	"error.card_key_sold":          "Card key has been sold and cannot be deleted",
+	"error.card_key_not_archived":  "Card key not found or not archived",
+
+	// 订单与支付
+	"error.order_not_found":         "Order not found",
+	"error.order_not_pending":       "Only unpaid orders can be cancelled",
+	"error.order_not_payable":       "Order cannot be paid in its current status",
+	"error.order_expired":           "Order has expired",
+	"error.order_no_exhausted":      "Failed to generate an order number, please retry",
+	"error.invalid_order_status":    "Invalid order status",
+	"error.insufficient_stock":      "Insufficient stock",
+	"error.insufficient_balance":    "Insufficient balance",
+	"error.amount_mismatch":         "Payment amount mismatch",
+	"error.too_many_pending_orders": "Too many unpaid orders, please pay or cancel them first",
+	"error.invalid_quantity":        "Invalid quantity",
+	"error.unsupported_pay_method":  "Unsupported payment method",
+	"error.payment_unavailable":     "Failed to start payment, please try again later",
+
+	// 销售统计
+	"error.invalid_analytics_range":    "Invalid date range (dates use YYYY-MM-DD, at most 366 days)",
+	"error.invalid_analytics_interval": "Invalid report interval",
+	"error.invalid_timezone":           "Invalid time zone",
+
+	// 支付对账
+	"error.payment_not_configured":           "NodeLoc Payment is not configured",
+	"error.invalid_reconciliation_range":     "Invalid reconciliation range (dates use YYYY-MM-DD, at most 31 days)",
+	"error.reconciliation_running":           "A reconciliation is already running",
+	"error.reconciliation_run_not_found":     "Reconciliation not found",
+	"error.reconciliation_issue_not_found":   "Reconciliation issue not found",
+	"error.reconciliation_issue_resolved":    "This issue has already been resolved",
+	"error.reconciliation_issue_changed":     "The order or transaction has changed, please run the reconciliation again",
+	"error.reconciliation_issue_not_fixable": "This issue cannot be fixed automatically; verify it manually and mark it as ignored",
+
+	// 限时抢购
+	"error.invalid_flash_sale":     "Invalid flash sale settings",
+	"error.flash_sale_not_found":   "Flash sale not found",
+	"error.flash_sale_not_running": "Flash sale has not started or has ended",
+	"error.flash_sale_user_limit":  "Per-user purchase limit exceeded",
+	"error.flash_sale_sold_out":    "Flash sale is sold out",
+	"error.flash_sale_has_orders":  "Flash sale already has orders and cannot be deleted, disable it instead",
+
+	// 发货队列
+	"error.order_not_in_queue":         "Order is not in the fulfillment queue",
+	"error.order_claimed_by_other":     "Order has been claimed by another administrator",
+	"error.order_not_claimed":          "You have not claimed this order",
+	"error.delivery_content_empty":     "Delivery content must not be empty",
+	"error.delivery_content_too_long":  "Each delivery item must not exceed 500 characters",
+	"error.delivery_quantity_mismatch": "The number of delivery items must match the ordered quantity",
+
+	// 批量操作与审计
+	"error.invalid_bulk_action":    "Unsupported bulk action",
+	"error.invalid_bulk_price":     "Invalid price adjustment",
+	"error.bulk_empty_selection":   "No items selected",
+	"error.bulk_too_many":          "A bulk action may include at most 1000 items",
+	"error.audit_export_too_large": "Export exceeds 50000 rows, please narrow the filters",
+}
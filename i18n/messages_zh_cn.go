@@ -0,0 +1,27 @@
+package i18n
+
+// zhCNMessages 简体中文消息目录
+var zhCNMessages = map[string]string{
+	// 操作结果
+	"message.updated":             "更新成功",
+	"message.deleted":             "删除成功",
+	"message.restored":            "恢复成功",
+	"message.cards_added":         "添加成功",
+	"message.claimed":             "认领成功",
+	"message.unclaimed":           "已放弃认领",
+	"message.delivered":           "发货成功",
+	"message.rejected":            "已拒绝并退款",
+	"message.rejected_cancelled":  "已拒绝并取消订单",
+	"message.unlocked":            "已解除锁定",
+	"message.two_factor_reset":    "已重置两步验证",
+	"message.two_factor_disabled": "已关闭两步验证",
+
+	// 站内通知
+	"notification.order_delivered.title":   "订单已发货",
+	"notification.order_delivered.content": "您的订单 %s 已由管理员发货，请前往订单详情查看。",
+	"notification.order_refunded.title":    "订单已退款",
+	"notification.order_refunded.content":  "您的订单 %s 无法发货，%.2f 积分已退回至账户余额。",
+	"notification.order_refunded.reason":   "原因：%s",
+	"notification.order_rejected.title":    "订单已取消",
+	"notification.order_rejected.content":  "您的订单 %s 无法发货，已取消。",
+}
@@ -0,0 +1,197 @@
+// Package logging 基于 log/slog 的结构化日志：支持文本或 JSON 输出、按包设置日志级别，
+// 并自动带上 context 中的请求 ID、用户 ID、订单号、交易ID 等字段
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Options 日志配置
+type Options struct {
+	Level  string            // 默认级别：debug / info / warn / error
+	Format string            // 输出格式：text / json
+	Levels map[string]string // 按包覆盖级别，如 services=debug、access=warn
+	Output io.Writer         // 为空时输出到标准错误
+}
+
+var (
+	mu           sync.RWMutex
+	base         slog.Handler = slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelDebug})
+	defaultLevel              = slog.LevelInfo
+	levels                    = map[string]slog.Level{}
+)
+
+// Setup 按配置创建日志输出并设为 slog 和标准库 log 的默认输出
+// 在 Setup 之前通过 For 创建的 logger 也会使用新的配置
+func Setup(opts Options) error {
+	level, err := ParseLevel(opts.Level)
+	if err != nil {
+		return err
+	}
+	packageLevels := make(map[string]slog.Level, len(opts.Levels))
+	for pkg, raw := range opts.Levels {
+		l, err := ParseLevel(raw)
+		if err != nil {
+			return fmt.Errorf("%s: %w", pkg, err)
+		}
+		packageLevels[pkg] = l
+	}
+
+	output := opts.Output
+	if output == nil {
+		output = os.Stderr
+	}
+	// 级别由 packageHandler 按包判断，底层输出不再过滤
+	handlerOptions := &slog.HandlerOptions{Level: slog.LevelDebug}
+	var handler slog.Handler
+	switch strings.ToLower(opts.Format) {
+	case "", "text":
+		handler = slog.NewTextHandler(output, handlerOptions)
+	case "json":
+		handler = slog.NewJSONHandler(output, handlerOptions)
+	default:
+		return fmt.Errorf("未知的日志格式: %s", opts.Format)
+	}
+
+	mu.Lock()
+	base = handler
+	defaultLevel = level
+	levels = packageLevels
+	mu.Unlock()
+
+	slog.SetDefault(For(""))
+	return nil
+}
+
+// ParseLevel 解析日志级别，为空时为 info
+func ParseLevel(raw string) (slog.Level, error) {
+	var level slog.Level
+	if raw == "" {
+		return slog.LevelInfo, nil
+	}
+	if err := level.UnmarshalText([]byte(raw)); err != nil {
+		return level, fmt.Errorf("未知的日志级别: %s", raw)
+	}
+	return level, nil
+}
+
+// For 创建指定包使用的 logger，日志带有 pkg 字段，级别可通过 Options.Levels 单独设置
+// pkg 为空时使用默认级别（main 包和标准库 log 的输出）
+func For(pkg string) *slog.Logger {
+	return slog.New(&packageHandler{pkg: pkg})
+}
+
+// packageHandler 按包判断级别，输出时附加 context 中的字段后交给当前的底层输出
+type packageHandler struct {
+	pkg string
+	ops []func(slog.Handler) slog.Handler // WithAttrs / WithGroup 的调用，输出时依次应用
+}
+
+// Enabled 按包的日志级别判断是否输出
+func (h *packageHandler) Enabled(_ context.Context, level slog.Level) bool {
+	mu.RLock()
+	defer mu.RUnlock()
+	min, ok := levels[h.pkg]
+	if !ok {
+		min = defaultLevel
+	}
+	return level >= min
+}
+
+// Handle 输出日志
+func (h *packageHandler) Handle(ctx context.Context, record slog.Record) error {
+	mu.RLock()
+	handler := base
+	mu.RUnlock()
+
+	var attrs []slog.Attr
+	if h.pkg != "" {
+		attrs = append(attrs, slog.String("pkg", h.pkg))
+	}
+	attrs = append(attrs, Attrs(ctx)...)
+	if len(attrs) > 0 {
+		handler = handler.WithAttrs(attrs)
+	}
+	for _, op := range h.ops {
+		handler = op(handler)
+	}
+	return handler.Handle(ctx, record)
+}
+
+// WithAttrs 返回附加了字段的 handler
+func (h *packageHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return h.with(func(handler slog.Handler) slog.Handler { return handler.WithAttrs(attrs) })
+}
+
+// WithGroup 返回使用字段分组的 handler
+func (h *packageHandler) WithGroup(name string) slog.Handler {
+	return h.with(func(handler slog.Handler) slog.Handler { return handler.WithGroup(name) })
+}
+
+// with 复制 handler 并追加一个调用
+func (h *packageHandler) with(op func(slog.Handler) slog.Handler) slog.Handler {
+	ops := make([]func(slog.Handler) slog.Handler, len(h.ops), len(h.ops)+1)
+	copy(ops, h.ops)
+	return &packageHandler{pkg: h.pkg, ops: append(ops, op)}
+}
+
+// contextKey context 中保存日志字段的键
+type contextKey struct{}
+
+// With 返回附加了日志字段的 context，之后通过该 context 记录的日志都会带上这些字段；同名字段以后加入的为准
+func With(ctx context.Context, attrs ...slog.Attr) context.Context {
+	existing := Attrs(ctx)
+	merged := make([]slog.Attr, 0, len(existing)+len(attrs))
+	for _, attr := range existing {
+		if !hasKey(attrs, attr.Key) {
+			merged = append(merged, attr)
+		}
+	}
+	merged = append(merged, attrs...)
+	return context.WithValue(ctx, contextKey{}, merged)
+}
+
+// Attrs context 中的日志字段
+func Attrs(ctx context.Context) []slog.Attr {
+	if ctx == nil {
+		return nil
+	}
+	attrs, _ := ctx.Value(contextKey{}).([]slog.Attr)
+	return attrs
+}
+
+// hasKey attrs 中是否有指定名称的字段
+func hasKey(attrs []slog.Attr, key string) bool {
+	for _, attr := range attrs {
+		if attr.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+// RequestID 请求 ID 字段
+func RequestID(id string) slog.Attr {
+	return slog.String("request_id", id)
+}
+
+// UserID 用户 ID 字段
+func UserID(id uint) slog.Attr {
+	return slog.Uint64("user_id", uint64(id))
+}
+
+// OrderNo 订单号字段
+func OrderNo(orderNo string) slog.Attr {
+	return slog.String("order_no", orderNo)
+}
+
+// TransactionID 支付交易ID字段
+func TransactionID(id string) slog.Attr {
+	return slog.String("transaction_id", id)
+}
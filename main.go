@@ -1,9 +1,14 @@
 package main
 
 import (
-	"log"
+	"database/sql"
+	"flag"
+	"fmt"
 	"net/http"
+	"net/http/httptest"
 	"os"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/nodeloc-faka/config"
@@ -11,42 +16,133 @@ import (
 	"github.com/nodeloc-faka/handler"
 	"github.com/nodeloc-faka/handler/admin"
 	"github.com/nodeloc-faka/handler/api"
+	"github.com/nodeloc-faka/logging"
+	"github.com/nodeloc-faka/metrics"
 	"github.com/nodeloc-faka/middleware"
 	"github.com/nodeloc-faka/models"
 	"github.com/nodeloc-faka/oauth"
+	"github.com/nodeloc-faka/openapi"
+	"github.com/nodeloc-faka/ratelimit"
+	"github.com/nodeloc-faka/scheduler"
 	"github.com/nodeloc-faka/services"
 )
 
+// logger 启动流程、命令行工具和定时任务的日志，级别可通过 LOG_LEVELS=main=debug 单独设置
+var logger = logging.For("main")
+
+// fatal 记录错误并退出
+func fatal(msg string, args ...any) {
+	logger.Error(msg, args...)
+	os.Exit(1)
+}
+
 func main() {
-	log.Println("==========================================")
-	log.Println("NodeLoc 社区发卡系统 API 启动中...")
-	log.Println("==========================================")
+	logger.Info("NodeLoc 社区发卡系统 API 启动中")
 
 	// 加载配置
 	cfg, err := config.LoadConfig()
 	if err != nil {
-		log.Fatalf("加载配置失败: %v", err)
+		fatal("加载配置失败", "error", err)
+	}
+
+	// 结构化日志：标准库 log 的输出也会按配置的格式输出
+	if err := logging.Setup(logging.Options{Level: cfg.LogLevel, Format: cfg.LogFormat, Levels: cfg.LogLevels}); err != nil {
+		fatal("日志配置无效", "error", err)
 	}
 
 	// 连接数据库
-	log.Println("正在连接数据库...")
-	db, err := database.Connect(cfg.Database)
-	if err != nil {
-		log.Fatalf("数据库连接失败: %v", err)
+	if _, err := database.Connect(cfg.Database); err != nil {
+		fatal("数据库连接失败", "error", err)
 	}
-	_ = db
-	log.Println("✓ 数据库连接成功")
 
 	// 数据库迁移
-	log.Println("正在执行数据库迁移...")
 	if err := models.AutoMigrate(database.GetDB()); err != nil {
-		log.Fatalf("数据库迁移失败: %v", err)
+		fatal("数据库迁移失败", "error", err)
+	}
+	if err := models.MigrateDefaultVariants(database.GetDB()); err != nil {
+		fatal("商品规格迁移失败", "error", err)
+	}
+	if err := models.MigrateOrderSnapshots(database.GetDB()); err != nil {
+		fatal("订单快照迁移失败", "error", err)
+	}
+	if err := models.MigrateAdminRoles(database.GetDB()); err != nil {
+		fatal("管理员角色迁移失败", "error", err)
+	}
+	if err := models.MigrateOrderTokens(database.GetDB()); err != nil {
+		fatal("订单查询凭证迁移失败", "error", err)
+	}
+	if err := models.RecordSchemaVersion(database.GetDB()); err != nil {
+		fatal("记录数据库结构版本失败", "error", err)
+	}
+	logger.Info("数据库迁移完成", "schema_version", models.SchemaVersion)
+
+	// 初始化管理员命令：faka bootstrap-admin [-username 用户名] [-password 密码]
+	if len(os.Args) > 1 && os.Args[1] == "bootstrap-admin" {
+		bootstrapAdmin(cfg, os.Args[2:])
+		return
 	}
-	log.Println("✓ 数据库迁移完成")
 
 	// 初始化系统（简化版 - 只初始化基础设置）
 	initSystemSimple()
 
+	// 启动定时任务
+	sched := scheduler.New()
+	flashSaleService := services.NewFlashSaleService()
+	orderService := services.NewOrderService()
+	sched.Every("flash_sale", 5*time.Second, flashSaleService.Tick)
+	sched.Every("cancel_expired_orders", time.Minute, func() {
+		if n, err := orderService.CancelExpiredOrders(); err != nil {
+			logger.Error("取消过期订单失败", "job", "cancel_expired_orders", "error", err)
+		} else if n > 0 {
+			logger.Info("已取消过期订单", "job", "cancel_expired_orders", "count", n)
+		}
+	})
+	analyticsService := services.NewAnalyticsService()
+	sched.Every("sales_daily_stats", 5*time.Minute, func() {
+		if _, err := analyticsService.Refresh(); err != nil {
+			logger.Error("更新每日销售汇总失败", "job", "sales_daily_stats", "error", err)
+		}
+	})
+	reconciliationService := services.NewReconciliationService()
+	sched.Every("payment_reconciliation", time.Hour, func() {
+		if run, err := reconciliationService.ReconcileYesterday(); err != nil {
+			logger.Error("支付对账失败", "job", "payment_reconciliation", "error", err)
+		} else if run != nil && run.IssueCount > 0 {
+			logger.Warn("支付对账发现差异", "job", "payment_reconciliation", "date", run.From, "issues", run.IssueCount)
+		}
+	})
+
+	// 限流存储：多实例部署时使用 MySQL 共享计数
+	rateLimitRules, err := ratelimit.LoadRules(cfg.RateLimits)
+	if err != nil {
+		fatal("加载限流规则失败", "error", err)
+	}
+	var rateLimitStore ratelimit.Store
+	switch cfg.RateLimitBackend {
+	case "mysql":
+		mysqlStore := ratelimit.NewMySQLStore(database.GetDB())
+		sched.Every("rate_limit_cleanup", 10*time.Minute, func() {
+			if _, err := mysqlStore.Cleanup(24 * time.Hour); err != nil {
+				logger.Error("清理限流记录失败", "job", "rate_limit_cleanup", "error", err)
+			}
+		})
+		rateLimitStore = mysqlStore
+	case "memory":
+		rateLimitStore = ratelimit.NewMemoryStore()
+	default:
+		fatal("未知的限流存储后端", "backend", cfg.RateLimitBackend)
+	}
+	rateLimiter := middleware.NewRateLimiter(rateLimitStore)
+	idempotencyService := services.NewIdempotencyService()
+	sched.Every("idempotency_cleanup", time.Hour, func() {
+		if _, err := idempotencyService.Cleanup(); err != nil {
+			logger.Error("清理幂等键失败", "job", "idempotency_cleanup", "error", err)
+		}
+	})
+
+	sched.Start()
+	logger.Info("定时任务已启动")
+
 	// 创建 OAuth 客户端
 	oauthClient := oauth.NewClient(
 		cfg.NodeLocURL,
@@ -57,90 +153,314 @@ func main() {
 
 	// 创建 session 存储
 	sessionStore := middleware.NewSessionStore()
+	registerMetrics(sessionStore)
 
+	// 加载 OpenAPI 文档，用于请求校验
+	spec, err := openapi.Load()
+	if err != nil {
+		fatal("加载 OpenAPI 文档失败", "error", err)
+	}
+
+	router, err := newRouter(cfg, spec, sessionStore, rateLimiter, rateLimitRules, oauthClient, sched)
+	if err != nil {
+		fatal("创建路由失败", "error", err)
+	}
+
+	if cfg.MetricsAddr != "" {
+		go func() {
+			mux := http.NewServeMux()
+			mux.Handle("/metrics", metrics.Handler(cfg.MetricsToken))
+			logger.Info("监控指标服务已启动", "addr", cfg.MetricsAddr)
+			if err := http.ListenAndServe(cfg.MetricsAddr, mux); err != nil {
+				fatal("监控指标服务启动失败", "addr", cfg.MetricsAddr, "error", err)
+			}
+		}()
+	}
+
+	// 检查路由与 OpenAPI 文档是否一致：faka openapi-check
+	if len(os.Args) > 1 && os.Args[1] == "openapi-check" {
+		if !checkOpenAPI(router, spec) {
+			os.Exit(1)
+		}
+		return
+	}
+	if undocumented, unregistered := spec.Diff(routeKeys(router)); len(undocumented)+len(unregistered) > 0 {
+		logger.Warn("路由与 OpenAPI 文档不一致", "undocumented", undocumented, "unregistered", unregistered)
+	}
+
+	// 启动服务器
+	addr := ":8080" // 固定使用 8080 端口（Docker 内部端口）
+	logger.Info("API 服务启动", "addr", "0.0.0.0"+addr)
+
+	// 使用 0.0.0.0 监听所有网络接口（Docker 需要）
+	if err := router.Run("0.0.0.0" + addr); err != nil {
+		fatal("服务器启动失败", "error", err)
+	}
+}
+
+// newRouter 创建 Gin 引擎，注册中间件和全部路由
+func newRouter(cfg *config.Config, spec *openapi.Spec, sessionStore *middleware.SessionStore, rateLimiter *middleware.RateLimiter,
+	rateLimitRules map[string]ratelimit.Rule, oauthClient *oauth.Client, sched *scheduler.Scheduler) (*gin.Engine, error) {
 	// 创建处理器
 	authHandler := handler.NewAuthHandler(oauthClient)
 	paymentHandler := handler.NewPaymentHandler()
 	apiHandler := api.NewAPIHandler()
 	adminHandler := admin.NewAdminHandler()
 	uploadHandler := handler.NewUploadHandler()
+	healthHandler := handler.NewHealthHandler(oauthClient, sched)
 
-	// 设置 Gin
-	router := gin.Default()
-	log.Println("✓ API 模式启动")
-
-	// 应用 Session 中间件
-	router.Use(middleware.SessionMiddleware(sessionStore))
+	// 限流规则
+	orderCreateLimit := rateLimiter.Limit(rateLimitRules["order_create"], middleware.RateLimitByUser)
+	orderQueryLimit := rateLimiter.Limit(rateLimitRules["order_query"], middleware.RateLimitByUser)
+	publicLimit := rateLimiter.Limit(rateLimitRules["public"], middleware.RateLimitByIP)
+	loginLimit := rateLimiter.Limit(rateLimitRules["login"], middleware.RateLimitByIP)
 
-	// ========================================
-	// API 路由 (JSON 响应)
-	// ========================================
+	// 设置 Gin
+	// 访问日志由 AccessLog 按日志配置输出，不使用 gin 自带的 Logger
+	router := gin.New()
+	router.Use(gin.Recovery())
 
-	// 公开 API
-	apiGroup := router.Group("/api")
-	{
-		apiGroup.GET("/settings", apiHandler.GetSettings)
-		apiGroup.GET("/categories/with-products", apiHandler.GetCategoriesWithProducts)
-		apiGroup.GET("/categories/:id", apiHandler.GetCategory)
-		apiGroup.GET("/products", apiHandler.GetProducts)
-		apiGroup.GET("/products/:id", apiHandler.GetProduct)
-		apiGroup.GET("/health", func(c *gin.Context) {
-			c.JSON(http.StatusOK, gin.H{"status": "ok"})
-		})
+	// 客户端 IP 用于限流和自动封禁，只采信可信代理转发的 X-Forwarded-For，避免伪造请求头封禁他人 IP
+	if err := router.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		return nil, fmt.Errorf("TRUSTED_PROXIES 配置无效: %w", err)
 	}
 
-	// 需要认证的 API
-	apiAuthGroup := apiGroup.Group("", middleware.AuthRequired())
-	{
-		apiAuthGroup.GET("/user/info", apiHandler.GetUserInfo)
-		apiAuthGroup.GET("/orders", apiHandler.GetOrders)
-		apiAuthGroup.GET("/orders/:orderNo", apiHandler.GetOrder)
-		apiAuthGroup.POST("/orders/:orderNo/repay", apiHandler.RepayOrder)
-		apiAuthGroup.POST("/orders/create", apiHandler.CreateOrder)
-	}
+	// 请求指标、访问日志、请求 ID、统一错误响应和 /api/v1 响应信封，需在其他中间件之前注册
+	router.Use(middleware.Metrics(), middleware.AccessLog(), middleware.RequestID(), middleware.ErrorHandler())
+
+	// 监控指标：在 Session 等中间件之前注册，抓取请求不会创建会话
+	// 主端口需携带 METRICS_TOKEN，未设置时不开放；METRICS_ADDR 可单独监听一个内网端口
+	metricsHandler := gin.WrapH(metrics.Handler(cfg.MetricsToken))
+	router.GET("/metrics", func(c *gin.Context) {
+		if cfg.MetricsToken == "" {
+			c.AbortWithStatus(http.StatusNotFound)
+			return
+		}
+		metricsHandler(c)
+	})
 
-	// 管理员 API
-	adminAPIGroup := apiGroup.Group("/admin", middleware.AdminRequired())
-	{
-		// 仪表板
-		adminAPIGroup.GET("/dashboard", adminHandler.GetDashboard)
+	// 存活与就绪检查：同样在 Session 等中间件之前注册，探针请求不会创建会话
+	router.GET("/health/live", healthHandler.Live)
+	router.GET("/health/ready", healthHandler.Ready)
 
-		// 文件上传
-		adminAPIGroup.POST("/upload/image", uploadHandler.UploadImage)
+	if cfg.OpenAPIValidateResponses {
+		router.Use(middleware.ValidateResponse(spec))
+		logger.Info("已启用 OpenAPI 响应校验")
+	}
+	router.Use(middleware.Envelope(openapi.VersionPrefix))
+
+	// 应用 Session 中间件、API 令牌认证、语言协商和 CSRF 防护
+	cookieOptions := middleware.NewCookieOptions(cfg.CookieSecure, cfg.CookieSameSite)
+	csrfOptions := middleware.CSRFOptions{
+		Cookie:         cookieOptions,
+		TrustedOrigins: cfg.TrustedOrigins,
+		// 支付回调由支付平台签名校验，不携带 CSRF token
+		ExemptPaths: []string{"/payment/callback"},
+	}
+	router.Use(middleware.SessionMiddleware(sessionStore, cookieOptions))
+	router.Use(middleware.BearerAuth(rateLimiter))
+	router.Use(middleware.Locale())
+	router.Use(middleware.CSRFProtect(csrfOptions))
+	router.Use(middleware.ValidateRequest(spec))
 
-		// 分类管理
-		adminAPIGroup.GET("/categories", adminHandler.GetCategories)
-		adminAPIGroup.GET("/categories/:id", adminHandler.GetCategory)
-		adminAPIGroup.POST("/categories", adminHandler.CreateCategory)
-		adminAPIGroup.PUT("/categories/:id", adminHandler.UpdateCategory)
-		adminAPIGroup.DELETE("/categories/:id", adminHandler.DeleteCategory)
+	// ========================================
+	// API 路由 (JSON 响应)
+	// ========================================
 
-		// 商品管理
-		adminAPIGroup.GET("/products", adminHandler.GetProducts)
-		adminAPIGroup.GET("/products/:id", adminHandler.GetProduct)
-		adminAPIGroup.POST("/products", adminHandler.CreateProduct)
-		adminAPIGroup.PUT("/products/:id", adminHandler.UpdateProduct)
-		adminAPIGroup.DELETE("/products/:id", adminHandler.DeleteProduct)
+	// 同一组路由注册在 /api/v1（信封格式响应）和 /api（旧格式，保留给现有前端和集成）下
+	orderReadAuth := middleware.AuthRequired(services.ScopeOrdersRead)
+	orderCreateAuth := middleware.AuthRequired(services.ScopeOrdersCreate)
+	idempotency := middleware.Idempotency()
+	registerAPI := func(apiGroup *gin.RouterGroup) {
+		// 公开 API（按 IP 限流）
+		publicGroup := apiGroup.Group("", publicLimit)
+		{
+			publicGroup.GET("/csrf-token", middleware.CSRFToken(csrfOptions))
+			publicGroup.GET("/settings", apiHandler.GetSettings)
+			publicGroup.GET("/categories/with-products", apiHandler.GetCategoriesWithProducts)
+			publicGroup.GET("/categories/:id", apiHandler.GetCategory)
+			publicGroup.GET("/products", apiHandler.GetProducts)
+			publicGroup.GET("/products/search", apiHandler.SearchProducts)
+			publicGroup.GET("/products/:id", apiHandler.GetProduct)
+			publicGroup.GET("/products/:id/quote", apiHandler.GetPriceQuote)
+			publicGroup.GET("/tags", apiHandler.GetTags)
+		}
+		apiGroup.GET("/health", healthHandler.Live)
+
+		// 需要认证的 API（仅会话登录）
+		apiAuthGroup := apiGroup.Group("", middleware.AuthRequired())
+		{
+			apiAuthGroup.GET("/user/info", apiHandler.GetUserInfo)
+			apiAuthGroup.PUT("/user/locale", apiHandler.UpdateLocale)
+			apiAuthGroup.GET("/notifications", apiHandler.GetNotifications)
+			apiAuthGroup.POST("/notifications/:id/read", apiHandler.ReadNotification)
+			apiAuthGroup.GET("/tokens", apiHandler.GetAPITokens)
+			apiAuthGroup.POST("/tokens", apiHandler.CreateAPIToken)
+			apiAuthGroup.DELETE("/tokens/:id", apiHandler.RevokeAPIToken)
+		}
+
+		// 订单 API（会话登录或具有相应权限范围的 API 令牌，下单和支付接口支持 Idempotency-Key）
+		apiGroup.GET("/orders", orderReadAuth, apiHandler.GetOrders)
+		apiGroup.GET("/orders/:orderNo", orderReadAuth, apiHandler.GetOrder)
+		apiGroup.POST("/orders/:orderNo/repay", orderCreateAuth, orderCreateLimit, idempotency, apiHandler.RepayOrder)
+		apiGroup.POST("/orders/create", orderCreateAuth, orderCreateLimit, idempotency, apiHandler.CreateOrder)
+
+		// 两步验证管理
+		twoFactorGroup := apiAuthGroup.Group("/auth/2fa")
+		{
+			twoFactorGroup.GET("", authHandler.GetTwoFactorStatus)
+			twoFactorGroup.POST("/setup", authHandler.SetupTwoFactor)
+			twoFactorGroup.POST("/enable", authHandler.EnableTwoFactor)
+			twoFactorGroup.POST("/disable", authHandler.DisableTwoFactor)
+			twoFactorGroup.POST("/recovery-codes", authHandler.RegenerateRecoveryCodes)
+		}
+
+		// 管理员 API（所有后台人员可访问，写操作记录审计日志）
+		adminAPIGroup := apiGroup.Group("/admin", middleware.AdminRequired(), middleware.AuditLog())
+		{
+			// 仪表板
+			adminAPIGroup.GET("/dashboard", adminHandler.GetDashboard)
+
+			// 就绪检查详细报告
+			adminAPIGroup.GET("/health", healthHandler.Report)
+
+			// 当前人员及角色
+			adminAPIGroup.GET("/me", adminHandler.GetCurrentStaff)
+			adminAPIGroup.GET("/roles", adminHandler.GetRoles)
+		}
+
+		// 商品目录
+		catalogGroup := adminAPIGroup.Group("", middleware.PermissionRequired(services.PermGroupCatalog))
+		{
+			// 文件上传
+			catalogGroup.POST("/upload/image", uploadHandler.UploadImage)
+
+			// 分类管理
+			catalogGroup.GET("/categories", adminHandler.GetCategories)
+			catalogGroup.GET("/categories/:id", adminHandler.GetCategory)
+			catalogGroup.POST("/categories", adminHandler.CreateCategory)
+			catalogGroup.PUT("/categories/:id", adminHandler.UpdateCategory)
+			catalogGroup.DELETE("/categories/:id", adminHandler.DeleteCategory)
+
+			// 商品管理
+			catalogGroup.GET("/products", adminHandler.GetProducts)
+			catalogGroup.GET("/products/:id", adminHandler.GetProduct)
+			catalogGroup.POST("/products", adminHandler.CreateProduct)
+			catalogGroup.PUT("/products/:id", adminHandler.UpdateProduct)
+			catalogGroup.DELETE("/products/:id", adminHandler.DeleteProduct)
+			catalogGroup.PUT("/products/:id/tags", adminHandler.SetProductTags)
+			catalogGroup.POST("/products/bulk", adminHandler.BulkProducts)
+
+			// 标签管理
+			catalogGroup.GET("/tags", adminHandler.GetTags)
+			catalogGroup.POST("/tags", adminHandler.CreateTag)
+			catalogGroup.PUT("/tags/:id", adminHandler.UpdateTag)
+			catalogGroup.DELETE("/tags/:id", adminHandler.DeleteTag)
+
+			// 商品规格管理
+			catalogGroup.GET("/products/:id/variants", adminHandler.GetVariants)
+			catalogGroup.POST("/products/:id/variants", adminHandler.CreateVariant)
+			catalogGroup.PUT("/variants/:id", adminHandler.UpdateVariant)
+			catalogGroup.DELETE("/variants/:id", adminHandler.DeleteVariant)
+
+			// 阶梯价管理
+			catalogGroup.GET("/products/:id/price-tiers", adminHandler.GetPriceTiers)
+			catalogGroup.PUT("/products/:id/price-tiers", adminHandler.UpdatePriceTiers)
+
+			// 限时抢购管理
+			catalogGroup.GET("/flash-sales", adminHandler.GetFlashSales)
+			catalogGroup.POST("/flash-sales", adminHandler.CreateFlashSale)
+			catalogGroup.PUT("/flash-sales/:id", adminHandler.UpdateFlashSale)
+			catalogGroup.DELETE("/flash-sales/:id", adminHandler.DeleteFlashSale)
+
+			// 归档管理
+			catalogGroup.GET("/archive/categories", adminHandler.GetArchivedCategories)
+			catalogGroup.POST("/archive/categories/:id/restore", adminHandler.RestoreCategory)
+			catalogGroup.GET("/archive/products", adminHandler.GetArchivedProducts)
+			catalogGroup.POST("/archive/products/:id/restore", adminHandler.RestoreProduct)
+		}
 
 		// 卡密管理
-		adminAPIGroup.GET("/card-keys", adminHandler.GetCardKeys)
-		adminAPIGroup.POST("/card-keys", adminHandler.AddCardKeys)
-		adminAPIGroup.DELETE("/card-keys/:id", adminHandler.DeleteCardKey)
+		cardKeyGroup := adminAPIGroup.Group("", middleware.PermissionRequired(services.PermGroupCardKeys))
+		{
+			cardKeyGroup.GET("/card-keys", adminHandler.GetCardKeys)
+			cardKeyGroup.POST("/card-keys", adminHandler.AddCardKeys)
+			cardKeyGroup.DELETE("/card-keys/:id", adminHandler.DeleteCardKey)
+			cardKeyGroup.POST("/card-keys/bulk", adminHandler.BulkCardKeys)
+			cardKeyGroup.GET("/archive/card-keys", adminHandler.GetArchivedCardKeys)
+			cardKeyGroup.POST("/archive/card-keys/:id/restore", adminHandler.RestoreCardKey)
+		}
 
 		// 订单管理
-		adminAPIGroup.GET("/orders", adminHandler.GetOrders)
-		adminAPIGroup.GET("/orders/:orderNo", adminHandler.GetOrder)
-		adminAPIGroup.PUT("/orders/:orderNo/status", adminHandler.UpdateOrderStatus)
+		orderGroup := adminAPIGroup.Group("", middleware.PermissionRequired(services.PermGroupOrders))
+		{
+			orderGroup.GET("/orders", adminHandler.GetOrders)
+			orderGroup.GET("/orders/:orderNo", adminHandler.GetOrder)
+			orderGroup.PUT("/orders/:orderNo/status", adminHandler.UpdateOrderStatus)
+			orderGroup.POST("/orders/bulk-cancel", adminHandler.BulkCancelOrders)
+
+			// 人工发货队列
+			orderGroup.GET("/fulfillment", adminHandler.GetFulfillmentQueue)
+			orderGroup.POST("/fulfillment/:orderNo/claim", adminHandler.ClaimFulfillment)
+			orderGroup.POST("/fulfillment/:orderNo/release", adminHandler.ReleaseFulfillment)
+			orderGroup.POST("/fulfillment/:orderNo/deliver", adminHandler.DeliverFulfillment)
+			orderGroup.POST("/fulfillment/:orderNo/reject", adminHandler.RejectFulfillment)
+
+			// 销售统计
+			orderGroup.GET("/analytics/sales", adminHandler.GetSalesReport)
+			orderGroup.GET("/analytics/products", adminHandler.GetProductSales)
+			orderGroup.GET("/analytics/categories", adminHandler.GetCategorySales)
+			orderGroup.GET("/analytics/buyers", adminHandler.GetTopBuyers)
+
+			// 支付对账
+			orderGroup.GET("/reconciliations", adminHandler.GetReconciliations)
+			orderGroup.POST("/reconciliations", adminHandler.StartReconciliation)
+			orderGroup.GET("/reconciliations/:id", adminHandler.GetReconciliation)
+			orderGroup.GET("/reconciliation-issues", adminHandler.GetReconciliationIssues)
+			orderGroup.POST("/reconciliation-issues/:id/fix", adminHandler.FixReconciliationIssue)
+			orderGroup.POST("/reconciliation-issues/:id/ignore", adminHandler.IgnoreReconciliationIssue)
+		}
 
 		// 用户管理
-		adminAPIGroup.GET("/users", adminHandler.GetUsers)
-		adminAPIGroup.GET("/users/:id", adminHandler.GetUser)
-		adminAPIGroup.PUT("/users/:id", adminHandler.UpdateUser)
-
-		// 系统设置
-		adminAPIGroup.GET("/settings", adminHandler.GetSettings)
-		adminAPIGroup.PUT("/settings", adminHandler.UpdateSettings)
+		userGroup := adminAPIGroup.Group("", middleware.PermissionRequired(services.PermGroupUsers))
+		{
+			userGroup.GET("/users", adminHandler.GetUsers)
+			userGroup.GET("/users/:id", adminHandler.GetUser)
+			userGroup.PUT("/users/:id", adminHandler.UpdateUser)
+			userGroup.GET("/rate-limit-blocks", adminHandler.GetRateLimitBlocks)
+			userGroup.DELETE("/rate-limit-blocks/:id", adminHandler.DeleteRateLimitBlock)
+		}
+
+		// 系统设置（支付配置在处理器中另行检查支付权限）
+		settingsGroup := adminAPIGroup.Group("", middleware.PermissionRequired(services.PermGroupSettings))
+		{
+			settingsGroup.GET("/settings", adminHandler.GetSettings)
+			settingsGroup.PUT("/settings", adminHandler.UpdateSettings)
+		}
+
+		// 后台人员管理
+		staffGroup := adminAPIGroup.Group("", middleware.PermissionRequired(services.PermGroupStaff))
+		{
+			staffGroup.GET("/staff", adminHandler.GetStaff)
+			staffGroup.PUT("/staff/:id/role", adminHandler.UpdateStaffRole)
+			staffGroup.PUT("/staff/:id/credential", adminHandler.SetStaffCredential)
+			staffGroup.DELETE("/staff/:id/credential", adminHandler.DeleteStaffCredential)
+			staffGroup.POST("/staff/:id/unlock", adminHandler.UnlockStaff)
+			staffGroup.DELETE("/staff/:id/2fa", adminHandler.ResetStaffTwoFactor)
+		}
+
+		// 审计日志
+		auditGroup := adminAPIGroup.Group("", middleware.PermissionRequired(services.PermGroupAudit))
+		{
+			auditGroup.GET("/audit-logs", adminHandler.GetAuditLogs)
+			auditGroup.GET("/audit-logs/export", adminHandler.ExportAuditLogs)
+		}
 	}
+	registerAPI(router.Group(openapi.VersionPrefix))
+	registerAPI(router.Group("/api"))
+
+	// OpenAPI 文档
+	router.GET("/api/openapi.json", middleware.OpenAPIDocument())
 
 	// ========================================
 	// OAuth 认证路由（后端处理）
@@ -149,38 +469,25 @@ func main() {
 	router.GET("/auth/callback", authHandler.Callback)
 	router.GET("/auth/logout", authHandler.Logout)
 
+	// 后台人员本地账号登录及两步验证（按 IP 限制尝试次数）
+	router.POST("/auth/local-login", loginLimit, authHandler.LocalLogin)
+	router.POST("/auth/2fa/verify", loginLimit, authHandler.VerifyTwoFactor)
+
 	// ========================================
 	// 支付回调路由（后端处理）
 	// ========================================
-	router.POST("/api/order/create", paymentHandler.CreateOrder)
+	router.POST("/api/order/create", middleware.TokenScope(services.ScopeOrdersCreate), orderCreateLimit, idempotency, paymentHandler.CreateOrder)
 	router.GET("/payment/callback", paymentHandler.PaymentCallback)
-	router.GET("/api/order/:order_no/status", paymentHandler.QueryOrder)
-	router.POST("/api/order/:order_no/cancel", paymentHandler.CancelOrder)
+	router.GET("/api/order/:order_no/status", middleware.TokenScope(services.ScopeOrdersRead), orderQueryLimit, paymentHandler.QueryOrder)
+	router.POST("/api/order/:order_no/cancel", middleware.TokenScope(services.ScopeOrdersCreate), paymentHandler.CancelOrder)
 
 	// 静态文件服务（上传的图片）
-	router.Static("/uploads", "./uploads")
-
-	// 健康检查
-	router.GET("/health", func(c *gin.Context) {
-		c.JSON(http.StatusOK, gin.H{"status": "ok"})
-	})
+	router.Static("/uploads", "./"+handler.UploadDir)
 
-	// 启动服务器
-	addr := ":8080" // 固定使用 8080 端口（Docker 内部端口）
-	log.Println("")
-	log.Println("╔════════════════════════════════════════════════════════════╗")
-	log.Println("║            NodeLoc 社区发卡系统 API v1.0                   ║")
-	log.Println("╠════════════════════════════════════════════════════════════╣")
-	log.Printf("║ API 服务: http://0.0.0.0%s                                ", addr)
-	log.Println("║ 前端: 由 Vue + Nginx 提供                                  ║")
-	log.Println("╚════════════════════════════════════════════════════════════╝")
-	log.Println("")
-	log.Printf("API 服务器启动在端口 8080，等待请求...")
+	// 存活检查（兼容旧的探针地址，新部署请使用 /health/live 和 /health/ready）
+	router.GET("/health", healthHandler.Live)
 
-	// 使用 0.0.0.0 监听所有网络接口（Docker 需要）
-	if err := router.Run("0.0.0.0" + addr); err != nil {
-		log.Fatalf("服务器启动失败: %v", err)
-	}
+	return router, nil
 }
 
 // initSystemSimple 简化的系统初始化（前后端分离版本）
@@ -195,7 +502,7 @@ func initSystemSimple() {
 		return
 	}
 
-	log.Println("首次运行，正在初始化系统...")
+	logger.Info("首次运行，正在初始化系统")
 
 	// 保存基础设置
 	err := settingService.SetMultiple(map[string]string{
@@ -205,14 +512,13 @@ func initSystemSimple() {
 		services.SettingInitialized:     "true",
 	})
 	if err != nil {
-		log.Fatalf("保存系统设置失败: %v", err)
+		fatal("保存系统设置失败", "error", err)
 	}
 
 	// 同步支付配置
 	syncPaymentConfig(settingService)
 
-	log.Println("✓ 系统初始化完成")
-	log.Println("提示: 首个登录的 NodeLoc 用户将自动成为管理员")
+	logger.Info("系统初始化完成，首个登录的 NodeLoc 用户将自动成为管理员")
 }
 
 // syncPaymentConfig 同步支付配置到数据库
@@ -223,22 +529,22 @@ func syncPaymentConfig(settingService *services.SettingService) {
 
 	if paymentID != "" {
 		settingService.Set(services.SettingPaymentID, paymentID)
-		log.Printf("✓ 同步 PAYMENT_ID: %s", paymentID)
+		logger.Info("已同步支付配置", "key", "PAYMENT_ID", "value", paymentID)
 	}
 	if paymentSecret != "" {
 		settingService.Set(services.SettingPaymentSecret, paymentSecret)
-		log.Printf("✓ 同步 PAYMENT_SECRET: %s", maskSecret(paymentSecret))
+		logger.Info("已同步支付配置", "key", "PAYMENT_SECRET", "value", maskSecret(paymentSecret))
 	}
 	if paymentCallback != "" {
 		settingService.Set(services.SettingPaymentCallback, paymentCallback)
-		log.Printf("✓ 同步 PAYMENT_CALLBACK: %s", paymentCallback)
+		logger.Info("已同步支付配置", "key", "PAYMENT_CALLBACK", "value", paymentCallback)
 	}
 
 	if paymentID != "" && paymentSecret != "" {
 		settingService.Set(services.SettingPaymentEnabled, "true")
-		log.Println("✓ 支付功能已启用")
+		logger.Info("支付功能已启用")
 	} else {
-		log.Println("⚠️  支付未配置，请在 .env 文件中设置 PAYMENT_ID 和 PAYMENT_SECRET")
+		logger.Warn("支付未配置，请在 .env 文件中设置 PAYMENT_ID 和 PAYMENT_SECRET")
 	}
 }
 
@@ -249,3 +555,146 @@ func maskSecret(secret string) string {
 	}
 	return secret[:4] + "****" + secret[len(secret)-4:]
 }
+
+// registerMetrics 注册抓取时读取的指标：会话数、商品可售库存和数据库连接池状态
+func registerMetrics(sessionStore *middleware.SessionStore) {
+	metrics.NewGaugeFunc("faka_sessions", "内存中保存的会话数", nil, func() []metrics.Sample {
+		return []metrics.Sample{{Value: float64(sessionStore.Count())}}
+	})
+
+	productService := services.NewProductService()
+	metrics.NewGaugeFunc("faka_product_stock_available", "商品可售卡密数量", []string{"product_id", "product"}, func() []metrics.Sample {
+		stocks, err := productService.AvailableStock()
+		if err != nil {
+			logger.Error("统计商品库存失败", "error", err)
+			return nil
+		}
+		samples := make([]metrics.Sample, len(stocks))
+		for i, stock := range stocks {
+			samples[i] = metrics.Sample{Labels: []string{strconv.FormatUint(uint64(stock.ProductID), 10), stock.Name}, Value: float64(stock.Available)}
+		}
+		return samples
+	})
+
+	dbStats := func() (sql.DBStats, bool) {
+		sqlDB, err := database.GetDB().DB()
+		if err != nil {
+			return sql.DBStats{}, false
+		}
+		return sqlDB.Stats(), true
+	}
+	metrics.NewGaugeFunc("faka_db_connections", "数据库连接数", []string{"state"}, func() []metrics.Sample {
+		stats, ok := dbStats()
+		if !ok {
+			return nil
+		}
+		return []metrics.Sample{
+			{Labels: []string{"open"}, Value: float64(stats.OpenConnections)},
+			{Labels: []string{"in_use"}, Value: float64(stats.InUse)},
+			{Labels: []string{"idle"}, Value: float64(stats.Idle)},
+		}
+	})
+	metrics.NewGaugeFunc("faka_db_max_open_connections", "数据库连接池上限", nil, func() []metrics.Sample {
+		stats, ok := dbStats()
+		if !ok {
+			return nil
+		}
+		return []metrics.Sample{{Value: float64(stats.MaxOpenConnections)}}
+	})
+	metrics.NewCounterFunc("faka_db_wait_total", "等待空闲连接的次数", nil, func() []metrics.Sample {
+		stats, ok := dbStats()
+		if !ok {
+			return nil
+		}
+		return []metrics.Sample{{Value: float64(stats.WaitCount)}}
+	})
+	metrics.NewCounterFunc("faka_db_wait_seconds_total", "等待空闲连接的总时长（秒）", nil, func() []metrics.Sample {
+		stats, ok := dbStats()
+		if !ok {
+			return nil
+		}
+		return []metrics.Sample{{Value: stats.WaitDuration.Seconds()}}
+	})
+}
+
+// routeKeys 已注册的路由，格式为 "METHOD 路由"
+func routeKeys(router *gin.Engine) []string {
+	routes := router.Routes()
+	keys := make([]string, len(routes))
+	for i, route := range routes {
+		keys[i] = route.Method + " " + route.Path
+	}
+	return keys
+}
+
+// openAPICheckPaths openapi-check 请求的公开接口，不需要登录和参数
+var openAPICheckPaths = []string{
+	"/api/v1/settings",
+	"/api/v1/categories/with-products",
+	"/api/v1/products",
+	"/api/v1/products/search",
+	"/api/v1/tags",
+	"/api/v1/health",
+	"/api/v1/csrf-token",
+	"/health",
+	"/health/live",
+}
+
+// checkOpenAPI 检查路由与 OpenAPI 文档是否一致，并请求不需要参数的公开接口，校验响应是否符合文档
+// 用于 CI 或部署前检查，需要可用的数据库
+func checkOpenAPI(router *gin.Engine, spec *openapi.Spec) bool {
+	ok := true
+	undocumented, unregistered := spec.Diff(routeKeys(router))
+	for _, route := range undocumented {
+		logger.Error("路由未写入 OpenAPI 文档", "route", route)
+		ok = false
+	}
+	for _, route := range unregistered {
+		logger.Error("OpenAPI 文档中的接口未注册", "route", route)
+		ok = false
+	}
+
+	for _, path := range openAPICheckPaths {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+		op, _ := spec.Lookup(http.MethodGet, path)
+		if err := spec.ValidateResponse(op, recorder.Code, recorder.Header().Get("Content-Type"), recorder.Body.Bytes()); err != nil {
+			logger.Error("响应与 OpenAPI 文档不一致", "method", http.MethodGet, "path", path, "status", recorder.Code, "error", err)
+			ok = false
+		}
+	}
+
+	if ok {
+		logger.Info("路由和响应与 OpenAPI 文档一致")
+	}
+	return ok
+}
+
+// bootstrapAdmin 创建第一个本地管理员（所有者），用于 NodeLoc OAuth 不可用时登录后台
+// 未指定用户名和密码时依次使用 ADMIN_USERNAME / ADMIN_PASSWORD 环境变量，仍为空则随机生成
+func bootstrapAdmin(cfg *config.Config, args []string) {
+	fs := flag.NewFlagSet("bootstrap-admin", flag.ExitOnError)
+	username := fs.String("username", cfg.AdminUsername, "管理员用户名")
+	password := fs.String("password", cfg.AdminPassword, "管理员密码（至少 8 位）")
+	fs.Parse(args)
+
+	generatedUsername, _, generatedPassword := config.GenerateAdminCredentials()
+	if *username == "" {
+		*username = generatedUsername
+	}
+	generated := *password == ""
+	if generated {
+		*password = generatedPassword
+	}
+
+	admin, err := services.NewAdminService().Bootstrap(*username, *password)
+	if err != nil {
+		fatal("初始化管理员失败", "error", err)
+	}
+
+	logger.Info("管理员创建成功", "username", admin.Username)
+	if generated {
+		// 随机生成的密码只输出到终端，不写入日志
+		fmt.Printf("用户名: %s\n密码: %s\n请妥善保存密码，登录后建议立即启用两步验证\n", admin.Username, *password)
+	}
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nodeloc-faka/config"
+	"github.com/nodeloc-faka/database/dbtest"
+	"github.com/nodeloc-faka/middleware"
+	"github.com/nodeloc-faka/models"
+	"github.com/nodeloc-faka/oauth"
+	"github.com/nodeloc-faka/openapi"
+	"github.com/nodeloc-faka/ratelimit"
+	"github.com/nodeloc-faka/scheduler"
+	"gorm.io/gorm"
+)
+
+// 测试会话：预先写入会话存储，请求时通过 session_id Cookie 使用
+const (
+	buyerSession = "test-buyer-session"
+	adminSession = "test-admin-session"
+	testCSRF     = "test-csrf-token"
+)
+
+// apiFixture 使用真实路由和测试数据库的 API 环境
+type apiFixture struct {
+	router  *gin.Engine
+	spec    *openapi.Spec
+	db      *gorm.DB
+	product models.Product
+	variant models.ProductVariant
+	order   models.Order
+}
+
+func newAPIFixture(t *testing.T) *apiFixture {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	f := &apiFixture{db: dbtest.Open(t)}
+
+	spec, err := openapi.Load()
+	if err != nil {
+		t.Fatalf("加载 OpenAPI 文档失败: %v", err)
+	}
+	f.spec = spec
+
+	buyer := models.User{NodeLocID: 2001, Username: "buyer", Balance: 100}
+	admin := models.User{NodeLocID: 2002, Username: "owner", IsAdmin: true, Role: models.RoleOwner}
+	category := models.Category{Name: "游戏", IsActive: true}
+	f.mustCreate(t, &buyer, &admin, &category)
+
+	f.product = models.Product{CategoryID: category.ID, Name: "测试商品", Price: 10, IsActive: true, DeliveryType: models.DeliveryTypeAuto, StockCount: 3}
+	f.mustCreate(t, &f.product)
+	f.variant = models.ProductVariant{ProductID: f.product.ID, Name: models.DefaultVariantName, Price: 10, IsActive: true, StockCount: 3}
+	f.mustCreate(t, &f.variant)
+	for i := 0; i < 3; i++ {
+		f.mustCreate(t, &models.CardKey{ProductID: f.product.ID, VariantID: f.variant.ID, CardNo: "CARD-" + strconv.Itoa(i)})
+	}
+
+	expiredAt := time.Now().Add(30 * time.Minute)
+	f.order = models.Order{
+		OrderNo:     "20261019000000000101",
+		PublicToken: "buyer-public-token",
+		UserID:      buyer.ID,
+		ProductID:   f.product.ID,
+		VariantID:   f.variant.ID,
+		ProductName: f.product.Name,
+		VariantName: f.variant.Name,
+		Quantity:    1,
+		UnitPrice:   10,
+		TotalAmount: 10,
+		Status:      models.OrderStatusPending,
+		PayMethod:   "nodeloc",
+		ExpiredAt:   &expiredAt,
+	}
+	f.mustCreate(t, &f.order)
+
+	sessionStore := middleware.NewSessionStore()
+	sessionStore.Set(buyerSession, map[string]interface{}{"user": &buyer, "csrf_token": testCSRF})
+	sessionStore.Set(adminSession, map[string]interface{}{"user": &admin, "csrf_token": testCSRF})
+
+	rules, err := ratelimit.LoadRules(nil)
+	if err != nil {
+		t.Fatalf("加载限流规则失败: %v", err)
+	}
+	cfg := &config.Config{CookieSameSite: "lax"}
+	oauthClient := oauth.NewClient("http://127.0.0.1:0", "", "", "")
+	router, err := newRouter(cfg, spec, sessionStore, middleware.NewRateLimiter(ratelimit.NewMemoryStore()), rules, oauthClient, scheduler.New())
+	if err != nil {
+		t.Fatalf("创建路由失败: %v", err)
+	}
+	f.router = router
+	return f
+}
+
+func (f *apiFixture) mustCreate(t *testing.T, values ...interface{}) {
+	t.Helper()
+	for _, value := range values {
+		if err := f.db.Create(value).Error; err != nil {
+			t.Fatalf("写入测试数据失败: %v", err)
+		}
+	}
+}
+
+// do 发起请求，session 为空时不登录；写请求携带 CSRF token
+func (f *apiFixture) do(method, path, session, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if session != "" {
+		req.AddCookie(&http.Cookie{Name: "session_id", Value: session})
+		req.AddCookie(&http.Cookie{Name: middleware.CSRFCookieName, Value: testCSRF})
+		req.Header.Set(middleware.CSRFHeaderName, testCSRF)
+	}
+	recorder := httptest.NewRecorder()
+	f.router.ServeHTTP(recorder, req)
+	return recorder
+}
+
+func TestRoutesMatchOpenAPI(t *testing.T) {
+	f := newAPIFixture(t)
+
+	undocumented, unregistered := f.spec.Diff(routeKeys(f.router))
+	for _, route := range undocumented {
+		t.Errorf("路由未写入 OpenAPI 文档: %s", route)
+	}
+	for _, route := range unregistered {
+		t.Errorf("OpenAPI 文档中的接口未注册: %s", route)
+	}
+}
+
+func TestResponsesMatchOpenAPI(t *testing.T) {
+	f := newAPIFixture(t)
+	orderNo := f.order.OrderNo
+	productID := strconv.FormatUint(uint64(f.product.ID), 10)
+
+	type request struct {
+		method  string
+		route   string // gin 路由写法，用于查找接口定义
+		path    string
+		session string
+		body    string
+		status  int
+	}
+	var requests []request
+	for _, path := range openAPICheckPaths {
+		requests = append(requests, request{method: http.MethodGet, route: path, path: path, status: http.StatusOK})
+	}
+	requests = append(requests,
+		// 公开接口
+		request{http.MethodGet, "/api/v1/products/:id", "/api/v1/products/" + productID, "", "", http.StatusOK},
+		request{http.MethodGet, "/api/v1/products/:id", "/api/v1/products/999999", "", "", http.StatusNotFound},
+
+		// 订单
+		request{http.MethodGet, "/api/v1/orders", "/api/v1/orders", buyerSession, "", http.StatusOK},
+		request{http.MethodGet, "/api/v1/orders", "/api/v1/orders", "", "", http.StatusUnauthorized},
+		request{http.MethodGet, "/api/v1/orders/:orderNo", "/api/v1/orders/" + orderNo, buyerSession, "", http.StatusOK},
+		request{http.MethodGet, "/api/v1/orders/:orderNo", "/api/v1/orders/20261019999999999999", buyerSession, "", http.StatusNotFound},
+		request{http.MethodGet, "/api/order/:order_no/status", "/api/order/" + orderNo + "/status?token=" + f.order.PublicToken, "", "", http.StatusOK},
+
+		// 下单（未配置支付时按免费模式直接完成）及请求校验失败
+		request{http.MethodPost, "/api/v1/orders/create", "/api/v1/orders/create", buyerSession,
+			`{"product_id":` + productID + `,"quantity":1,"contact":"buyer@example.com"}`, http.StatusOK},
+		request{http.MethodPost, "/api/v1/orders/create", "/api/v1/orders/create", buyerSession,
+			`{"product_id":` + productID + `,"quantity":0}`, http.StatusBadRequest},
+		request{http.MethodPost, "/api/v1/orders/create", "/api/v1/orders/create", buyerSession,
+			`{"product_id":999999,"quantity":1}`, http.StatusNotFound},
+
+		// 后台
+		request{http.MethodGet, "/api/v1/admin/dashboard", "/api/v1/admin/dashboard", adminSession, "", http.StatusOK},
+		request{http.MethodGet, "/api/v1/admin/orders", "/api/v1/admin/orders", adminSession, "", http.StatusOK},
+		request{http.MethodGet, "/api/v1/admin/orders/:orderNo", "/api/v1/admin/orders/" + orderNo, adminSession, "", http.StatusOK},
+		request{http.MethodGet, "/api/v1/admin/products", "/api/v1/admin/products", adminSession, "", http.StatusOK},
+		request{http.MethodGet, "/api/v1/admin/card-keys", "/api/v1/admin/card-keys", adminSession, "", http.StatusOK},
+		request{http.MethodGet, "/api/v1/admin/fulfillment", "/api/v1/admin/fulfillment", adminSession, "", http.StatusOK},
+		request{http.MethodGet, "/api/v1/admin/settings", "/api/v1/admin/settings", adminSession, "", http.StatusOK},
+		request{http.MethodGet, "/api/v1/admin/me", "/api/v1/admin/me", adminSession, "", http.StatusOK},
+		request{http.MethodPut, "/api/v1/admin/settings", "/api/v1/admin/settings", adminSession,
+			`{"require_admin_2fa":true}`, http.StatusConflict},
+		request{http.MethodGet, "/api/v1/admin/orders", "/api/v1/admin/orders", buyerSession, "", http.StatusForbidden},
+		request{http.MethodGet, "/api/v1/admin/orders", "/api/v1/admin/orders", "", "", http.StatusUnauthorized},
+	)
+
+	for _, r := range requests {
+		t.Run(r.method+" "+r.path, func(t *testing.T) {
+			op, documented := f.spec.Lookup(r.method, r.route)
+			if op == nil {
+				t.Fatalf("OpenAPI 文档中没有 %s %s", r.method, r.route)
+			}
+			recorder := f.do(r.method, r.path, r.session, r.body)
+			if recorder.Code != r.status {
+				t.Fatalf("返回 %d，期望 %d: %s", recorder.Code, r.status, recorder.Body)
+			}
+			if !documented {
+				// /api 下的旧路径响应不包裹信封，只检查是否为合法 JSON
+				if !json.Valid(recorder.Body.Bytes()) {
+					t.Fatalf("响应不是合法的 JSON: %s", recorder.Body)
+				}
+				return
+			}
+			if err := f.spec.ValidateResponse(op, recorder.Code, recorder.Header().Get("Content-Type"), recorder.Body.Bytes()); err != nil {
+				t.Fatalf("响应与文档不一致: %v\n%s", err, recorder.Body)
+			}
+		})
+	}
+}
@@ -0,0 +1,282 @@
+// Package metrics 以 Prometheus 文本格式导出运行指标（计数器、直方图和采集时读取的指标）
+package metrics
+
+import (
+	"bufio"
+	"crypto/subtle"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ContentType Prometheus 文本格式
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// DefaultBuckets 耗时直方图的默认分桶（秒）
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// collector 可导出的指标
+type collector interface {
+	write(w *bufio.Writer)
+}
+
+var (
+	registryMu sync.Mutex
+	registry   []collector
+)
+
+// register 注册指标，导出时按注册顺序输出
+func register(c collector) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry = append(registry, c)
+}
+
+// desc 指标的名称、说明、类型和标签名
+type desc struct {
+	name   string
+	help   string
+	kind   string // counter / gauge / histogram
+	labels []string
+}
+
+// writeHeader 输出 HELP 和 TYPE 行
+func (d desc) writeHeader(w *bufio.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n", d.name, strings.NewReplacer("\\", `\\`, "\n", `\n`).Replace(d.help))
+	fmt.Fprintf(w, "# TYPE %s %s\n", d.name, d.kind)
+}
+
+// Counter 带标签的计数器
+type Counter struct {
+	desc
+	mu     sync.Mutex
+	values map[string]*counterSeries
+}
+
+// counterSeries 计数器中一组标签值的计数
+type counterSeries struct {
+	labels []string
+	value  float64
+}
+
+// NewCounter 创建并注册计数器，labels 为标签名
+func NewCounter(name, help string, labels ...string) *Counter {
+	c := &Counter{
+		desc:   desc{name: name, help: help, kind: "counter", labels: labels},
+		values: make(map[string]*counterSeries),
+	}
+	register(c)
+	return c
+}
+
+// Inc 计数加一，values 为与标签名一一对应的标签值
+func (c *Counter) Inc(values ...string) {
+	c.Add(1, values...)
+}
+
+// Add 计数增加 delta
+func (c *Counter) Add(delta float64, values ...string) {
+	key := seriesKey(values)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	series, ok := c.values[key]
+	if !ok {
+		series = &counterSeries{labels: append([]string(nil), values...)}
+		c.values[key] = series
+	}
+	series.value += delta
+}
+
+// write 输出计数器
+func (c *Counter) write(w *bufio.Writer) {
+	c.writeHeader(w)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, key := range sortedKeys(c.values) {
+		series := c.values[key]
+		writeSample(w, c.name, c.labels, series.labels, "", "", series.value)
+	}
+}
+
+// Histogram 带标签的直方图
+type Histogram struct {
+	desc
+	buckets []float64
+	mu      sync.Mutex
+	values  map[string]*histogramSeries
+}
+
+// histogramSeries 直方图中一组标签值的分桶计数
+type histogramSeries struct {
+	labels []string
+	counts []uint64 // 各分桶的累计计数
+	sum    float64
+	count  uint64
+}
+
+// NewHistogram 创建并注册直方图，buckets 为升序的分桶上限
+func NewHistogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	h := &Histogram{
+		desc:    desc{name: name, help: help, kind: "histogram", labels: labels},
+		buckets: buckets,
+		values:  make(map[string]*histogramSeries),
+	}
+	register(h)
+	return h
+}
+
+// Observe 记录一次观测值
+func (h *Histogram) Observe(value float64, values ...string) {
+	key := seriesKey(values)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	series, ok := h.values[key]
+	if !ok {
+		series = &histogramSeries{labels: append([]string(nil), values...), counts: make([]uint64, len(h.buckets))}
+		h.values[key] = series
+	}
+	for i, bound := range h.buckets {
+		if value <= bound {
+			series.counts[i]++
+		}
+	}
+	series.sum += value
+	series.count++
+}
+
+// Since 记录从 start 到现在经过的秒数
+func (h *Histogram) Since(start time.Time, values ...string) {
+	h.Observe(time.Since(start).Seconds(), values...)
+}
+
+// write 输出直方图
+func (h *Histogram) write(w *bufio.Writer) {
+	h.writeHeader(w)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, key := range sortedKeys(h.values) {
+		series := h.values[key]
+		for i, bound := range h.buckets {
+			writeSample(w, h.name+"_bucket", h.labels, series.labels, "le", formatFloat(bound), float64(series.counts[i]))
+		}
+		writeSample(w, h.name+"_bucket", h.labels, series.labels, "le", "+Inf", float64(series.count))
+		writeSample(w, h.name+"_sum", h.labels, series.labels, "", "", series.sum)
+		writeSample(w, h.name+"_count", h.labels, series.labels, "", "", float64(series.count))
+	}
+}
+
+// Sample 采集时读取的一组标签值及其数值
+type Sample struct {
+	Labels []string
+	Value  float64
+}
+
+// funcCollector 导出时调用 collect 读取数值的指标
+type funcCollector struct {
+	desc
+	collect func() []Sample
+}
+
+// NewGaugeFunc 注册导出时读取数值的仪表盘指标，如库存、连接数
+func NewGaugeFunc(name, help string, labels []string, collect func() []Sample) {
+	register(&funcCollector{desc: desc{name: name, help: help, kind: "gauge", labels: labels}, collect: collect})
+}
+
+// NewCounterFunc 注册导出时读取数值的计数器，用于导出其他组件自行累计的计数
+func NewCounterFunc(name, help string, labels []string, collect func() []Sample) {
+	register(&funcCollector{desc: desc{name: name, help: help, kind: "counter", labels: labels}, collect: collect})
+}
+
+// write 输出采集到的数值
+func (f *funcCollector) write(w *bufio.Writer) {
+	f.writeHeader(w)
+	for _, sample := range f.collect() {
+		writeSample(w, f.name, f.labels, sample.Labels, "", "", sample.Value)
+	}
+}
+
+// Write 按 Prometheus 文本格式输出全部指标
+func Write(out io.Writer) error {
+	registryMu.Lock()
+	collectors := append([]collector(nil), registry...)
+	registryMu.Unlock()
+
+	w := bufio.NewWriter(out)
+	for _, c := range collectors {
+		c.write(w)
+	}
+	return w.Flush()
+}
+
+// Handler 导出指标的 HTTP 处理器，token 不为空时要求 Authorization: Bearer <token>
+func Handler(token string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if token != "" {
+			provided, _ := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="metrics"`)
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+		}
+		w.Header().Set("Content-Type", ContentType)
+		Write(w)
+	})
+}
+
+// writeSample 输出一行样本，extraName 不为空时追加一个标签（如直方图的 le）
+func writeSample(w *bufio.Writer, name string, labels, values []string, extraName, extraValue string, value float64) {
+	w.WriteString(name)
+	var pairs []string
+	for i, label := range labels {
+		v := ""
+		if i < len(values) {
+			v = values[i]
+		}
+		pairs = append(pairs, label+`="`+escapeLabel(v)+`"`)
+	}
+	if extraName != "" {
+		pairs = append(pairs, extraName+`="`+extraValue+`"`)
+	}
+	if len(pairs) > 0 {
+		w.WriteString("{" + strings.Join(pairs, ",") + "}")
+	}
+	w.WriteString(" " + formatFloat(value) + "\n")
+}
+
+// escapeLabel 转义标签值中的反斜杠、双引号和换行
+func escapeLabel(value string) string {
+	return strings.NewReplacer("\\", `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
+
+// formatFloat 按 Prometheus 格式输出数值
+func formatFloat(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+// seriesKey 标签值组合的唯一键
+func seriesKey(values []string) string {
+	return strings.Join(values, "\xff")
+}
+
+// sortedKeys 按键排序，保证输出顺序稳定
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,116 @@
+package middleware
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nodeloc-faka/models"
+	"github.com/nodeloc-faka/openapi"
+	"github.com/nodeloc-faka/services"
+)
+
+// adminPathPrefix 后台 API 路由前缀
+const adminPathPrefix = "/api/admin/"
+
+// AuditLog 后台审计中间件，记录所有写操作（包括被拒绝和失败的请求）
+// 需在 AdminRequired 之后使用
+func AuditLog() gin.HandlerFunc {
+	auditService := services.NewAuditService()
+
+	return func(c *gin.Context) {
+		method := c.Request.Method
+		if method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions {
+			c.Next()
+			return
+		}
+
+		action, targetType, targetID := resolveAuditTarget(c)
+		before := auditService.Snapshot(targetType, targetID)
+
+		// 读取 JSON 请求内容后放回，供处理器继续读取
+		var body []byte
+		if strings.HasPrefix(c.ContentType(), "application/json") && c.Request.Body != nil {
+			body, _ = io.ReadAll(c.Request.Body)
+			c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		}
+
+		c.Next()
+		// 先渲染错误响应，以便记录实际的状态码
+		renderPendingError(c)
+
+		after := auditService.Snapshot(targetType, targetID)
+		beforeValue, afterValue := auditService.Changes(before, after, body)
+
+		user := c.MustGet("user").(*models.User)
+		entry := &models.AuditLog{
+			ActorID:    user.ID,
+			ActorName:  user.Username,
+			ActorRole:  user.Role,
+			Action:     action,
+			Method:     method,
+			Path:       truncate(c.Request.URL.Path, 500),
+			TargetType: targetType,
+			TargetID:   targetID,
+			Before:     beforeValue,
+			After:      afterValue,
+			StatusCode: c.Writer.Status(),
+			IP:         c.ClientIP(),
+			UserAgent:  truncate(c.Request.UserAgent(), 500),
+		}
+		if err := auditService.Record(entry); err != nil {
+			logger.ErrorContext(c.Request.Context(), "写入审计日志失败", "error", err)
+		}
+	}
+}
+
+// resolveAuditTarget 根据路由解析操作名称和操作对象
+// 如 PUT /api/admin/products/:id -> products.update / products / 12
+// POST /api/admin/fulfillment/:orderNo/claim -> fulfillment.claim / fulfillment / 订单号
+func resolveAuditTarget(c *gin.Context) (action, targetType, targetID string) {
+	var segments []string
+	// /api/v1/admin 与 /api/admin 下的同一路由记录为相同的操作
+	path := strings.TrimPrefix(strings.Replace(c.FullPath(), openapi.VersionPrefix+"/", "/api/", 1), adminPathPrefix)
+	for _, segment := range strings.Split(path, "/") {
+		// 归档路由按被归档的对象记录
+		if segment == "" || segment == "archive" || strings.HasPrefix(segment, ":") {
+			continue
+		}
+		segments = append(segments, segment)
+	}
+	if len(c.Params) > 0 {
+		targetID = c.Params[0].Value
+	}
+	if len(segments) == 0 {
+		return strings.ToLower(c.Request.Method), "", targetID
+	}
+
+	targetType = segments[0]
+	action = strings.Join(segments, ".")
+	if len(segments) == 1 || c.Request.Method != http.MethodPost {
+		action += "." + auditVerb(c.Request.Method)
+	}
+	return action, targetType, targetID
+}
+
+// auditVerb 请求方法对应的操作
+func auditVerb(method string) string {
+	switch method {
+	case http.MethodPost:
+		return "create"
+	case http.MethodDelete:
+		return "delete"
+	default:
+		return "update"
+	}
+}
+
+// truncate 截断过长的字符串
+func truncate(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	return s[:max]
+}
@@ -0,0 +1,94 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nodeloc-faka/middleware"
+	"github.com/nodeloc-faka/models"
+)
+
+const testCSRFToken = "csrf-token"
+
+func TestCSRFProtect(t *testing.T) {
+	tests := []struct {
+		name     string
+		method   string
+		session  bool   // 是否携带 session Cookie
+		header   string // X-CSRF-Token 请求头
+		cookie   string // csrf_token Cookie
+		origin   string
+		apiToken bool // 是否已通过 API 令牌认证
+		path     string
+		want     int
+	}{
+		{"valid token", http.MethodPost, true, testCSRFToken, testCSRFToken, "http://shop.example", false, "/orders", http.StatusOK},
+		{"missing header", http.MethodPost, true, "", testCSRFToken, "http://shop.example", false, "/orders", http.StatusForbidden},
+		{"missing cookie", http.MethodPost, true, testCSRFToken, "", "http://shop.example", false, "/orders", http.StatusForbidden},
+		{"mismatched token", http.MethodPost, true, "other-token", "other-token", "http://shop.example", false, "/orders", http.StatusForbidden},
+		{"foreign origin", http.MethodPost, true, testCSRFToken, testCSRFToken, "http://evil.example", false, "/orders", http.StatusForbidden},
+		{"trusted origin", http.MethodPost, true, testCSRFToken, testCSRFToken, "https://admin.example", false, "/orders", http.StatusOK},
+		{"no origin from non-browser client", http.MethodPost, true, testCSRFToken, testCSRFToken, "", false, "/orders", http.StatusOK},
+		{"safe method", http.MethodGet, true, "", "", "http://evil.example", false, "/orders", http.StatusOK},
+		{"no session cookie", http.MethodPost, false, "", "", "http://evil.example", false, "/orders", http.StatusOK},
+		{"api token bypasses check", http.MethodPost, true, "", "", "http://evil.example", true, "/orders", http.StatusOK},
+		{"exempt payment callback", http.MethodPost, true, "", "", "http://evil.example", false, "/payment/callback", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			router := gin.New()
+			router.Use(middleware.ErrorHandler(), func(c *gin.Context) {
+				c.Set("session", map[string]interface{}{"csrf_token": testCSRFToken})
+				if tt.apiToken {
+					c.Set(middleware.APITokenContextKey, &models.APIToken{ID: 1})
+				}
+			}, middleware.CSRFProtect(middleware.CSRFOptions{
+				TrustedOrigins: []string{"https://admin.example"},
+				ExemptPaths:    []string{"/payment/callback"},
+			}))
+			router.Handle(tt.method, tt.path, func(c *gin.Context) { c.Status(http.StatusOK) })
+
+			req := httptest.NewRequest(tt.method, "http://shop.example"+tt.path, nil)
+			if tt.session {
+				req.AddCookie(&http.Cookie{Name: "session_id", Value: "session"})
+			}
+			if tt.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: middleware.CSRFCookieName, Value: tt.cookie})
+			}
+			if tt.header != "" {
+				req.Header.Set(middleware.CSRFHeaderName, tt.header)
+			}
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, req)
+			if recorder.Code != tt.want {
+				t.Fatalf("返回 %d %s，期望 %d", recorder.Code, recorder.Body, tt.want)
+			}
+		})
+	}
+}
+
+func TestCSRFProtectRejectsForeignReferer(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(middleware.ErrorHandler(), func(c *gin.Context) {
+		c.Set("session", map[string]interface{}{"csrf_token": testCSRFToken})
+	}, middleware.CSRFProtect(middleware.CSRFOptions{}))
+	router.POST("/orders", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	req := httptest.NewRequest(http.MethodPost, "http://shop.example/orders", nil)
+	req.AddCookie(&http.Cookie{Name: "session_id", Value: "session"})
+	req.AddCookie(&http.Cookie{Name: middleware.CSRFCookieName, Value: testCSRFToken})
+	req.Header.Set(middleware.CSRFHeaderName, testCSRFToken)
+	req.Header.Set("Referer", "http://evil.example/page")
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	if recorder.Code != http.StatusForbidden {
+		t.Fatalf("来自其他站点的 Referer 返回 %d，期望 403", recorder.Code)
+	}
+}
@@ -0,0 +1,155 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nodeloc-faka/services"
+)
+
+// envelopeResponse /api/v1 的响应信封
+type envelopeResponse struct {
+	Data      json.RawMessage `json:"data,omitempty"`
+	Error     *envelopeError  `json:"error,omitempty"`
+	RequestID string          `json:"request_id"`
+}
+
+// envelopeError 信封中的错误信息
+type envelopeError struct {
+	Code    string                 `json:"code"`
+	Message string                 `json:"message"`
+	Details map[string]interface{} `json:"details,omitempty"`
+}
+
+// envelopeWriter 暂存 JSON 响应，处理链结束后包装为信封再写出；
+// 非 JSON 响应（如 CSV 导出、重定向）在首次写入时直接透传
+type envelopeWriter struct {
+	gin.ResponseWriter
+	body        bytes.Buffer
+	status      int
+	wroteHeader bool
+	passthrough bool
+}
+
+func (w *envelopeWriter) WriteHeader(code int) {
+	if w.passthrough {
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+	if code > 0 && !w.wroteHeader {
+		w.status = code
+	}
+}
+
+func (w *envelopeWriter) WriteHeaderNow() {
+	if w.passthrough {
+		w.ResponseWriter.WriteHeaderNow()
+		return
+	}
+	w.wroteHeader = true
+}
+
+func (w *envelopeWriter) Write(data []byte) (int, error) {
+	if w.startPassthrough() {
+		return w.ResponseWriter.Write(data)
+	}
+	w.wroteHeader = true
+	return w.body.Write(data)
+}
+
+func (w *envelopeWriter) WriteString(s string) (int, error) {
+	if w.startPassthrough() {
+		return w.ResponseWriter.WriteString(s)
+	}
+	w.wroteHeader = true
+	return w.body.WriteString(s)
+}
+
+func (w *envelopeWriter) Status() int {
+	if w.passthrough {
+		return w.ResponseWriter.Status()
+	}
+	return w.status
+}
+
+func (w *envelopeWriter) Size() int {
+	if w.passthrough {
+		return w.ResponseWriter.Size()
+	}
+	return w.body.Len()
+}
+
+func (w *envelopeWriter) Written() bool {
+	if w.passthrough {
+		return w.ResponseWriter.Written()
+	}
+	return w.wroteHeader
+}
+
+func (w *envelopeWriter) Flush() {
+	if w.passthrough {
+		w.ResponseWriter.Flush()
+	}
+}
+
+// startPassthrough 首次写入非 JSON 内容时切换为透传
+func (w *envelopeWriter) startPassthrough() bool {
+	if !w.passthrough && !w.wroteHeader && !isJSON(w.Header().Get("Content-Type")) {
+		w.passthrough = true
+		w.ResponseWriter.WriteHeader(w.status)
+	}
+	return w.passthrough
+}
+
+// Envelope 将 prefix 下的 JSON 响应包装为统一的信封格式
+// 成功时为 {"data": <原响应>, "request_id": ...}，失败时为 {"error": {code, message, details}, "request_id": ...}；
+// 需注册在其他中间件之前，认证、CSRF 等中间件返回的错误也会被包装
+func Envelope(prefix string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !strings.HasPrefix(c.Request.URL.Path, prefix+"/") {
+			c.Next()
+			return
+		}
+
+		original := c.Writer
+		writer := &envelopeWriter{ResponseWriter: original, status: original.Status()}
+		c.Writer = writer
+		// 处理链 panic 时恢复原始 Writer，由 Recovery 中间件写出 500
+		defer func() { c.Writer = original }()
+		c.Next()
+		if c.FullPath() == "" && !writer.Written() && len(c.Errors) == 0 {
+			// 未匹配到路由
+			c.Error(services.ErrNotFound)
+		}
+		renderPendingError(c)
+		c.Writer = original
+
+		if writer.passthrough {
+			return
+		}
+		if !writer.wroteHeader {
+			// 处理函数没有写出响应内容（如 204），只保留状态码
+			original.WriteHeader(writer.status)
+			return
+		}
+
+		response := envelopeResponse{RequestID: RequestIDFromContext(c)}
+		var errorBody ErrorResponse
+		if writer.status >= http.StatusBadRequest && json.Unmarshal(writer.body.Bytes(), &errorBody) == nil && errorBody.Code != "" {
+			response.Error = &envelopeError{Code: errorBody.Code, Message: errorBody.Message, Details: errorBody.Details}
+		} else {
+			response.Data = json.RawMessage(writer.body.Bytes())
+		}
+		c.JSON(writer.status, response)
+	}
+}
+
+// isJSON 内容类型是否为 JSON
+func isJSON(contentType string) bool {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	return mediaType == "application/json"
+}
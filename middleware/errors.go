@@ -0,0 +1,96 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"regexp"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nodeloc-faka/i18n"
+	"github.com/nodeloc-faka/logging"
+	"github.com/nodeloc-faka/services"
+)
+
+// 请求 ID 相关名称
+const (
+	RequestIDHeader     = "X-Request-ID"
+	RequestIDContextKey = "request_id"
+)
+
+// requestIDPattern 接受客户端传入的请求 ID 的格式，避免把任意内容写入日志和响应头
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// ErrorResponse 错误响应
+type ErrorResponse struct {
+	Code      string                 `json:"code"`
+	Message   string                 `json:"message"`
+	Details   map[string]interface{} `json:"details,omitempty"`
+	RequestID string                 `json:"request_id"`
+}
+
+// RequestID 为每个请求分配请求 ID，优先使用客户端传入的 X-Request-ID，并在响应头中返回
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if !requestIDPattern.MatchString(id) {
+			id = newRequestID()
+		}
+		c.Set(RequestIDContextKey, id)
+		c.Header(RequestIDHeader, id)
+		withLogAttrs(c, logging.RequestID(id))
+		c.Next()
+	}
+}
+
+// ErrorHandler 统一渲染错误响应的中间件
+// 处理函数通过 c.Error(err) 登记错误并返回，由这里按错误码渲染为 {code, message, details, request_id}；
+// 非 ServiceError 的错误记录日志后按 internal_error 返回，不向客户端暴露内部信息
+func ErrorHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+		renderPendingError(c)
+	}
+}
+
+// renderPendingError 渲染请求中登记的最后一个错误；已经写出响应时不做处理
+func renderPendingError(c *gin.Context) {
+	if len(c.Errors) == 0 || c.Writer.Written() {
+		return
+	}
+	err := c.Errors.Last().Err
+
+	var serviceErr *services.ServiceError
+	if !errors.As(err, &serviceErr) {
+		logger.ErrorContext(c.Request.Context(), "请求处理失败", "method", c.Request.Method, "path", c.Request.URL.Path, "error", err)
+		serviceErr = services.ErrInternal
+	}
+
+	message := i18n.T(RequestLocale(c), "error."+serviceErr.Code, serviceErr.Message)
+	c.JSON(serviceErr.HTTPStatus(), ErrorResponse{
+		Code:      serviceErr.Code,
+		Message:   message,
+		Details:   serviceErr.Details,
+		RequestID: RequestIDFromContext(c),
+	})
+}
+
+// abortWithError 登记错误并中止请求，供中间件使用
+func abortWithError(c *gin.Context, err error) {
+	c.Error(err)
+	c.Abort()
+}
+
+// RequestIDFromContext 获取当前请求的请求 ID
+func RequestIDFromContext(c *gin.Context) string {
+	return c.GetString(RequestIDContextKey)
+}
+
+// newRequestID 生成请求 ID
+func newRequestID() string {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return "request_fallback"
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,123 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nodeloc-faka/models"
+	"github.com/nodeloc-faka/services"
+)
+
+// IdempotencyKeyHeader 幂等键请求头
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// idempotentResponseWriter 记录响应内容以便重放
+type idempotentResponseWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *idempotentResponseWriter) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *idempotentResponseWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// Idempotency 幂等中间件
+// 已登录用户的请求携带 Idempotency-Key 时，同一用户相同键的重复请求直接返回首次响应，不会重复执行；
+// 相同键用于不同的请求内容时返回 422，首次请求仍在处理中时返回 409；
+// 只保存成功响应和确定性的错误响应（见 replayableStatus），其余情况（包括处理中 panic）释放幂等键，
+// 客户端可以用相同的键重试
+func Idempotency() gin.HandlerFunc {
+	idempotencyService := services.NewIdempotencyService()
+
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		userInterface, exists := c.Get("user")
+		if key == "" || !exists || userInterface == nil {
+			c.Next()
+			return
+		}
+		user := userInterface.(*models.User)
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			abortWithError(c, services.ErrInvalidRequest)
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		hash := sha256.New()
+		hash.Write([]byte(c.Request.Method + " " + c.Request.URL.Path + "\n"))
+		hash.Write(body)
+		requestHash := hex.EncodeToString(hash.Sum(nil))
+
+		record, created, err := idempotencyService.Begin(user.ID, key, requestHash)
+		if err != nil {
+			abortWithError(c, err)
+			return
+		}
+
+		if !created {
+			switch {
+			case record.RequestHash != requestHash:
+				abortWithError(c, services.ErrIdempotencyKeyReused)
+			case record.StatusCode == 0:
+				abortWithError(c, services.ErrIdempotencyInProgress)
+			default:
+				c.Header("Idempotent-Replayed", "true")
+				c.Data(record.StatusCode, "application/json; charset=utf-8", []byte(record.Response))
+				c.Abort()
+			}
+			return
+		}
+
+		completed := false
+		defer func() {
+			if completed {
+				return
+			}
+			if err := idempotencyService.Release(record); err != nil {
+				logger.ErrorContext(c.Request.Context(), "释放幂等键失败", "error", err)
+			}
+		}()
+
+		writer := &idempotentResponseWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+		c.Next()
+		// 错误响应在处理链返回后才渲染，需要先写出才能保存
+		renderPendingError(c)
+
+		status := writer.Status()
+		if !replayableStatus(status) {
+			return
+		}
+		if err := idempotencyService.Complete(record, status, writer.body.String()); err != nil {
+			logger.ErrorContext(c.Request.Context(), "保存幂等响应失败", "error", err)
+			return
+		}
+		completed = true
+	}
+}
+
+// replayableStatus 响应是否保存下来供重复请求重放
+// 成功响应和请求本身有误的错误（参数校验失败、商品不存在等）重试结果不变，可以重放；
+// 冲突、限流、余额不足、认证失败和服务端错误在补货、支付或稍后重试时可能成功，不保存
+func replayableStatus(status int) bool {
+	switch {
+	case status >= http.StatusOK && status < http.StatusMultipleChoices:
+		return true
+	case status == http.StatusBadRequest, status == http.StatusNotFound, status == http.StatusUnprocessableEntity:
+		return true
+	default:
+		return false
+	}
+}
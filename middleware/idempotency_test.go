@@ -0,0 +1,135 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nodeloc-faka/database/dbtest"
+	"github.com/nodeloc-faka/middleware"
+	"github.com/nodeloc-faka/models"
+	"github.com/nodeloc-faka/services"
+)
+
+// idempotencyRouter 挂载幂等中间件的路由，handle 处理 POST /orders 并由测试控制返回结果
+type idempotencyRouter struct {
+	router *gin.Engine
+	calls  atomic.Int32
+	handle func(c *gin.Context)
+}
+
+func newIdempotencyRouter(t *testing.T) *idempotencyRouter {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	dbtest.Open(t)
+
+	r := &idempotencyRouter{}
+	r.router = gin.New()
+	r.router.Use(gin.Recovery(), middleware.ErrorHandler(), func(c *gin.Context) {
+		c.Set("user", &models.User{ID: 1})
+	}, middleware.Idempotency())
+	r.router.POST("/orders", func(c *gin.Context) {
+		r.calls.Add(1)
+		r.handle(c)
+	})
+	return r
+}
+
+func (r *idempotencyRouter) post(key, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(middleware.IdempotencyKeyHeader, key)
+	recorder := httptest.NewRecorder()
+	r.router.ServeHTTP(recorder, req)
+	return recorder
+}
+
+func TestIdempotencyReplaysResponse(t *testing.T) {
+	r := newIdempotencyRouter(t)
+	r.handle = func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"call": r.calls.Load()})
+	}
+
+	first := r.post("key-1", `{"quantity":1}`)
+	second := r.post("key-1", `{"quantity":1}`)
+	if r.calls.Load() != 1 {
+		t.Fatalf("处理函数执行了 %d 次，期望 1 次", r.calls.Load())
+	}
+	if second.Code != http.StatusOK || second.Body.String() != first.Body.String() {
+		t.Fatalf("重复请求返回 %d %s，期望重放 %s", second.Code, second.Body, first.Body)
+	}
+	if second.Header().Get("Idempotent-Replayed") != "true" {
+		t.Fatal("重放的响应缺少 Idempotent-Replayed 响应头")
+	}
+
+	if reused := r.post("key-1", `{"quantity":2}`); reused.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("相同的键用于不同请求返回 %d，期望 422", reused.Code)
+	}
+	if other := r.post("key-2", `{"quantity":1}`); other.Code != http.StatusOK || r.calls.Load() != 2 {
+		t.Fatalf("不同的键返回 %d，处理函数执行 %d 次", other.Code, r.calls.Load())
+	}
+}
+
+func TestIdempotencyRejectsRequestInProgress(t *testing.T) {
+	r := newIdempotencyRouter(t)
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	r.handle = func(c *gin.Context) {
+		close(entered)
+		<-release
+		c.JSON(http.StatusOK, gin.H{})
+	}
+
+	done := make(chan *httptest.ResponseRecorder)
+	go func() { done <- r.post("key-1", `{}`) }()
+	<-entered
+
+	if second := r.post("key-1", `{}`); second.Code != http.StatusConflict {
+		t.Fatalf("首次请求处理中时返回 %d，期望 409", second.Code)
+	}
+	close(release)
+	if first := <-done; first.Code != http.StatusOK {
+		t.Fatalf("首次请求返回 %d", first.Code)
+	}
+}
+
+func TestIdempotencyCachesOnlyDeterministicResponses(t *testing.T) {
+	tests := []struct {
+		name       string
+		first      func(c *gin.Context)
+		wantReplay bool
+	}{
+		{"validation error is replayed", func(c *gin.Context) { c.Error(services.ErrInvalidQuantity) }, true},
+		{"missing product is replayed", func(c *gin.Context) { c.Error(services.ErrProductNotFound) }, true},
+		{"insufficient stock is retried", func(c *gin.Context) { c.Error(services.ErrInsufficientStock) }, false},
+		{"flash sale sold out is retried", func(c *gin.Context) { c.Error(services.ErrFlashSaleSoldOut) }, false},
+		{"pending order limit is retried", func(c *gin.Context) { c.Error(services.ErrTooManyPendingOrders) }, false},
+		{"server error is retried", func(c *gin.Context) { c.Error(services.ErrInternal) }, false},
+		{"panic is retried", func(c *gin.Context) { panic("handler failed") }, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newIdempotencyRouter(t)
+			r.handle = func(c *gin.Context) {
+				if r.calls.Load() == 1 {
+					tt.first(c)
+					return
+				}
+				c.JSON(http.StatusOK, gin.H{})
+			}
+
+			first := r.post("key-1", `{}`)
+			second := r.post("key-1", `{}`)
+			replayed := second.Header().Get("Idempotent-Replayed") == "true"
+			if replayed != tt.wantReplay {
+				t.Fatalf("重复请求重放 = %v，期望 %v（首次 %d，重试 %d）", replayed, tt.wantReplay, first.Code, second.Code)
+			}
+			if !tt.wantReplay && (second.Code != http.StatusOK || r.calls.Load() != 2) {
+				t.Fatalf("重试返回 %d，处理函数执行 %d 次，期望重新执行并成功", second.Code, r.calls.Load())
+			}
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/nodeloc-faka/i18n"
+	"github.com/nodeloc-faka/models"
+	"github.com/nodeloc-faka/services"
+)
+
+// LocaleContextKey 当前请求使用的语言在 context 中的键
+const LocaleContextKey = "locale"
+
+// Locale 确定请求使用的语言，需在 SessionMiddleware / BearerAuth 之后使用
+// 优先级：用户设置的语言偏好 > Accept-Language > 站点默认语言
+func Locale() gin.HandlerFunc {
+	settingService := services.NewSettingService()
+
+	return func(c *gin.Context) {
+		locale := ""
+		if userInterface, exists := c.Get("user"); exists {
+			if user, ok := userInterface.(*models.User); ok && user != nil {
+				locale = user.Locale
+			}
+		}
+		if locale == "" {
+			locale = i18n.Negotiate(c.GetHeader("Accept-Language"))
+		}
+		if locale == "" {
+			locale = settingService.DefaultLocale()
+		}
+
+		c.Set(LocaleContextKey, locale)
+		c.Header("Content-Language", locale)
+		c.Next()
+	}
+}
+
+// RequestLocale 获取当前请求使用的语言；在 Locale 中间件之前出错的请求根据 Accept-Language 协商
+func RequestLocale(c *gin.Context) string {
+	if locale := c.GetString(LocaleContextKey); locale != "" {
+		return locale
+	}
+	if locale := i18n.Negotiate(c.GetHeader("Accept-Language")); locale != "" {
+		return locale
+	}
+	return i18n.DefaultLocale
+}
+
+// T 按当前请求的语言查找消息
+func T(c *gin.Context, key string, args ...interface{}) string {
+	if len(args) > 0 {
+		return i18n.Tf(RequestLocale(c), key, args...)
+	}
+	return i18n.T(RequestLocale(c), key, key)
+}
@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nodeloc-faka/logging"
+)
+
+var (
+	// logger 中间件日志
+	logger = logging.For("middleware")
+	// accessLogger 访问日志，可通过 LOG_LEVELS=access=warn 只保留出错的请求
+	accessLogger = logging.For("access")
+)
+
+// AccessLog 记录每个请求的方法、路由、状态码和耗时，5xx 记为 error，4xx 记为 warn
+// 处理链返回后才记录，日志中带有后续中间件附加的请求 ID 和用户 ID
+func AccessLog() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		status := c.Writer.Status()
+		level := slog.LevelInfo
+		switch {
+		case status >= 500:
+			level = slog.LevelError
+		case status >= 400:
+			level = slog.LevelWarn
+		}
+		accessLogger.LogAttrs(c.Request.Context(), level, "HTTP 请求",
+			slog.String("method", c.Request.Method),
+			slog.String("route", c.FullPath()),
+			slog.String("path", c.Request.URL.Path),
+			slog.Int("status", status),
+			slog.Duration("latency", time.Since(start)),
+			slog.String("client_ip", c.ClientIP()),
+		)
+	}
+}
+
+// withLogAttrs 为请求的 context 附加日志字段，之后的处理函数和服务通过 c.Request.Context() 记录的日志都会带上
+func withLogAttrs(c *gin.Context, attrs ...slog.Attr) {
+	c.Request = c.Request.WithContext(logging.With(c.Request.Context(), attrs...))
+}
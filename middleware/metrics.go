@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nodeloc-faka/metrics"
+)
+
+// HTTP 请求指标，按路由模板统计，避免订单号等路径参数造成标签数量膨胀
+var (
+	httpRequests = metrics.NewCounter("faka_http_requests_total", "HTTP 请求数", "method", "route", "status")
+	httpDuration = metrics.NewHistogram("faka_http_request_duration_seconds", "HTTP 请求耗时（秒）", metrics.DefaultBuckets, "method", "route")
+)
+
+// Metrics 记录每个请求的路由、状态码和耗时，需在其他中间件之前注册
+func Metrics() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		httpRequests.Inc(c.Request.Method, route, strconv.Itoa(c.Writer.Status()))
+		httpDuration.Since(start, c.Request.Method, route)
+	}
+}
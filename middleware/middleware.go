@@ -7,6 +7,7 @@ import (
 	"sync"
 
 	"github.com/gin-gonic/gin"
+	"github.com/nodeloc-faka/logging"
 	"github.com/nodeloc-faka/models"
 	"github.com/nodeloc-faka/services"
 )
@@ -44,26 +45,80 @@ func (s *SessionStore) Set(sessionID string, data map[string]interface{}) {
 	s.sessions[sessionID] = data
 }
 
+// Delete 删除 session
+func (s *SessionStore) Delete(sessionID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.sessions, sessionID)
+}
+
+// Count 当前保存的 session 数
+func (s *SessionStore) Count() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.sessions)
+}
+
+// 两步验证相关的 session 键
+const (
+	SessionTwoFactorUserID   = "two_factor_user_id"  // 密码已验证、等待两步验证的本地账号用户ID
+	SessionTwoFactorAttempts = "two_factor_attempts" // 两步验证失败次数
+	SessionTwoFactorVerified = "two_factor_verified" // 本次会话是否已完成两步验证
+)
+
+// CookieOptions Cookie 安全选项
+type CookieOptions struct {
+	Secure   bool          // 只通过 HTTPS 发送
+	SameSite http.SameSite // SameSite 策略
+}
+
+// NewCookieOptions 根据配置创建 Cookie 选项，sameSite 支持 lax / strict / none
+// SameSite=None 时浏览器要求必须同时设置 Secure
+func NewCookieOptions(secure bool, sameSite string) CookieOptions {
+	options := CookieOptions{Secure: secure, SameSite: http.SameSiteLaxMode}
+	switch sameSite {
+	case "strict":
+		options.SameSite = http.SameSiteStrictMode
+	case "none":
+		options.SameSite = http.SameSiteNoneMode
+		options.Secure = true
+	}
+	return options
+}
+
+// setCookie 按安全选项写入 Cookie
+func (o CookieOptions) setCookie(c *gin.Context, name, value string, maxAge int, httpOnly bool) {
+	c.SetSameSite(o.SameSite)
+	c.SetCookie(name, value, maxAge, "/", "", o.Secure, httpOnly)
+}
+
 // SessionMiddleware Session 中间件
-func SessionMiddleware(store *SessionStore) gin.HandlerFunc {
+func SessionMiddleware(store *SessionStore, cookie CookieOptions) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// 从 cookie 获取或生成 session ID
 		sessionID, err := c.Cookie("session_id")
 		if err != nil {
 			// 生成新的 session ID
 			sessionID = generateSessionID()
-			c.SetCookie("session_id", sessionID, 86400*7, "/", "", false, true)
+			cookie.setCookie(c, "session_id", sessionID, 86400*7, true)
 		}
 
 		// 获取 session 数据
 		session := store.Get(sessionID)
 		c.Set("session", session)
 		c.Set("session_id", sessionID)
+		c.Set(sessionRegenerateKey, func() {
+			store.Delete(sessionID)
+			sessionID = generateSessionID()
+			cookie.setCookie(c, "session_id", sessionID, 86400*7, true)
+			c.Set("session_id", sessionID)
+		})
 
 		// 如果 session 中有用户信息，设置到 context
 		if userInterface, exists := session["user"]; exists {
 			if user, ok := userInterface.(*models.User); ok {
 				c.Set("user", user)
+				withLogAttrs(c, logging.UserID(user.ID))
 			}
 		}
 
@@ -75,6 +130,17 @@ func SessionMiddleware(store *SessionStore) gin.HandlerFunc {
 	}
 }
 
+// sessionRegenerateKey 保存当前请求换发 session ID 的函数
+const sessionRegenerateKey = "session_regenerate"
+
+// RegenerateSession 为当前会话换发新的 session ID 并删除旧 ID，session 数据保留
+// 登录状态提升（密码验证通过、完成两步验证）时调用，防止会话固定攻击
+func RegenerateSession(c *gin.Context) {
+	if regenerate, ok := c.Get(sessionRegenerateKey); ok {
+		regenerate.(func())()
+	}
+}
+
 // generateSessionID 生成 session ID
 func generateSessionID() string {
 	b := make([]byte, 32)
@@ -136,12 +202,15 @@ func RequireUserMiddleware() gin.HandlerFunc {
 }
 
 // AuthRequired API 认证中间件（返回 JSON）
-func AuthRequired() gin.HandlerFunc {
+// scopes 为允许 API 令牌访问时令牌需要的权限范围，不传则只允许会话登录访问
+func AuthRequired(scopes ...string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		userInterface, exists := c.Get("user")
 		if !exists || userInterface == nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "未登录"})
-			c.Abort()
+			abortWithError(c, services.ErrUnauthorized)
+			return
+		}
+		if !checkTokenScope(c, scopes) {
 			return
 		}
 
@@ -151,20 +220,64 @@ func AuthRequired() gin.HandlerFunc {
 
 // AdminRequired API 管理员认证中间件（返回 JSON）
 func AdminRequired() gin.HandlerFunc {
+	userService := services.NewUserService()
+	twoFactorService := services.NewTwoFactorService()
+
 	return func(c *gin.Context) {
 		// 首先检查是否已登录
 		userInterface, exists := c.Get("user")
 		if !exists || userInterface == nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "未登录"})
-			c.Abort()
+			abortWithError(c, services.ErrUnauthorized)
 			return
 		}
 
-		// 检查是否是管理员
-		user, ok := userInterface.(*models.User)
-		if !ok || !user.IsAdmin {
-			c.JSON(http.StatusForbidden, gin.H{"error": "需要管理员权限"})
-			c.Abort()
+		sessionUser, ok := userInterface.(*models.User)
+		if !ok {
+			abortWithError(c, services.ErrUnauthorized)
+			return
+		}
+
+		// 后台只允许会话登录访问，API 令牌不能用于管理操作
+		if currentAPIToken(c) != nil {
+			abortWithError(c, services.ErrAPITokenScopeMissing)
+			return
+		}
+
+		// 从数据库重新加载用户，角色变更和封禁立即生效
+		user, err := userService.FindByID(sessionUser.ID)
+		if err != nil || user.IsBlocked || !user.IsStaff() {
+			abortWithError(c, services.ErrAdminRequired)
+			return
+		}
+		c.Set("user", user)
+
+		// 两步验证：已启用的人员需在本次会话中完成验证；系统要求时未启用的人员需先启用
+		session := c.MustGet("session").(map[string]interface{})
+		if verified, _ := session[SessionTwoFactorVerified].(bool); user.TOTPEnabled && !verified {
+			abortWithError(c, services.ErrTwoFactorRequired)
+			return
+		}
+		if !user.TOTPEnabled && twoFactorService.Required() {
+			abortWithError(c, services.ErrTwoFactorSetupRequired)
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// PermissionRequired 后台权限检查中间件
+// GET/HEAD 请求需要分组的读权限，其他请求需要写权限；需在 AdminRequired 之后使用
+func PermissionRequired(group string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		level := services.PermWrite
+		if c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead {
+			level = services.PermRead
+		}
+
+		user := c.MustGet("user").(*models.User)
+		if !services.HasPermission(user.Role, group, level) {
+			abortWithError(c, services.ErrForbidden.WithDetails(map[string]interface{}{"permission": group + ":" + level}))
 			return
 		}
 
@@ -0,0 +1,94 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nodeloc-faka/database/dbtest"
+	"github.com/nodeloc-faka/middleware"
+	"github.com/nodeloc-faka/models"
+	"github.com/nodeloc-faka/services"
+)
+
+func TestRegenerateSession(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	store := middleware.NewSessionStore()
+	router := gin.New()
+	router.Use(middleware.SessionMiddleware(store, middleware.NewCookieOptions(false, "lax")))
+	router.POST("/login", func(c *gin.Context) {
+		middleware.RegenerateSession(c)
+		c.MustGet("session").(map[string]interface{})["user"] = &models.User{ID: 1}
+	})
+
+	store.Set("planted-id", map[string]interface{}{"locale": "en"})
+	req := httptest.NewRequest(http.MethodPost, "/login", nil)
+	req.AddCookie(&http.Cookie{Name: "session_id", Value: "planted-id"})
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+
+	var issued string
+	for _, cookie := range recorder.Result().Cookies() {
+		if cookie.Name == "session_id" {
+			issued, _ = url.QueryUnescape(cookie.Value)
+		}
+	}
+	if issued == "" || issued == "planted-id" {
+		t.Fatalf("登录后 session ID 为 %q，期望换发新的 ID", issued)
+	}
+	if _, ok := store.Get("planted-id")["user"]; ok || store.Count() != 1 {
+		t.Fatalf("旧 session 仍然保存了登录状态（共 %d 个 session）", store.Count())
+	}
+	session := store.Get(issued)
+	if _, ok := session["user"]; !ok || session["locale"] != "en" {
+		t.Fatalf("新 session 数据为 %v，期望保留原数据并写入登录用户", session)
+	}
+}
+
+func TestAdminRequiredEnforcesTwoFactor(t *testing.T) {
+	tests := []struct {
+		name        string
+		totpEnabled bool
+		verified    bool
+		required    bool
+		want        int
+	}{
+		{"not enabled and not required", false, false, false, http.StatusOK},
+		{"enabled but not verified", true, false, false, http.StatusForbidden},
+		{"enabled and verified", true, true, true, http.StatusOK},
+		{"required but not enabled", false, false, true, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			db := dbtest.Open(t)
+			user := &models.User{NodeLocID: 1, Username: "staff", Role: models.RoleOperator}
+			if err := db.Create(user).Error; err != nil {
+				t.Fatal(err)
+			}
+			if err := db.Model(user).Update("totp_enabled", tt.totpEnabled).Error; err != nil {
+				t.Fatal(err)
+			}
+			if tt.required {
+				if err := db.Create(&models.Setting{Key: services.SettingRequireAdmin2FA, Value: "true"}).Error; err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			router := gin.New()
+			router.Use(middleware.ErrorHandler(), func(c *gin.Context) {
+				c.Set("session", map[string]interface{}{middleware.SessionTwoFactorVerified: tt.verified})
+				c.Set("user", user)
+			}, middleware.AdminRequired())
+			router.GET("/admin", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/admin", nil))
+			if recorder.Code != tt.want {
+				t.Fatalf("返回 %d %s，期望 %d", recorder.Code, recorder.Body, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package middleware
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nodeloc-faka/openapi"
+	"github.com/nodeloc-faka/services"
+)
+
+// contractResponseWriter 记录响应内容以便与文档比对
+type contractResponseWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *contractResponseWriter) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *contractResponseWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// OpenAPIDocument 返回 OpenAPI 文档
+func OpenAPIDocument() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Data(http.StatusOK, "application/json; charset=utf-8", openapi.Document())
+	}
+}
+
+// ValidateRequest 按 OpenAPI 文档校验请求的路径参数、查询参数和请求体
+// 文档中没有定义的路由不做校验；校验失败时返回 invalid_request，details 中给出字段和原因
+func ValidateRequest(spec *openapi.Spec) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		op, _ := spec.Lookup(c.Request.Method, c.FullPath())
+		if op == nil {
+			c.Next()
+			return
+		}
+
+		if err := spec.ValidateRequest(op, c.Request, c.Param); err != nil {
+			var validationErr *openapi.ValidationError
+			if !errors.As(err, &validationErr) {
+				abortWithError(c, services.ErrInvalidRequest)
+				return
+			}
+			details := map[string]interface{}{"in": validationErr.In, "reason": validationErr.Reason}
+			if validationErr.Field != "" {
+				details["field"] = validationErr.Field
+			}
+			abortWithError(c, services.ErrInvalidRequest.WithDetails(details))
+			return
+		}
+		c.Next()
+	}
+}
+
+// ValidateResponse 按 OpenAPI 文档校验响应内容，不一致时记录日志，不影响响应
+// 用于在测试环境发现处理函数与文档不一致的情况；需注册在 Envelope 之前，校验的是最终写出的内容
+func ValidateResponse(spec *openapi.Spec) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		op, documented := spec.Lookup(c.Request.Method, c.FullPath())
+		if op == nil || !documented {
+			c.Next()
+			return
+		}
+
+		writer := &contractResponseWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+		c.Next()
+		// 错误响应在处理链返回后才渲染，需要先写出才能校验
+		renderPendingError(c)
+
+		if err := spec.ValidateResponse(op, writer.Status(), writer.Header().Get("Content-Type"), writer.body.Bytes()); err != nil {
+			logger.WarnContext(c.Request.Context(), "响应与 OpenAPI 文档不一致", "method", c.Request.Method, "route", c.FullPath(), "error", err)
+		}
+	}
+}
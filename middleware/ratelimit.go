@@ -0,0 +1,107 @@
+package middleware
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nodeloc-faka/models"
+	"github.com/nodeloc-faka/ratelimit"
+	"github.com/nodeloc-faka/services"
+)
+
+// 自动封禁策略：同一对象在 AbuseWindow 内被限流拒绝 AbuseThreshold 次后，封禁 AbuseBlockDuration
+const (
+	AbuseThreshold     = 20
+	AbuseWindow        = 10 * time.Minute
+	AbuseBlockDuration = 30 * time.Minute
+)
+
+// RateLimitKeyFunc 生成限流对象的标识
+type RateLimitKeyFunc func(c *gin.Context) string
+
+// RateLimitByIP 按客户端 IP 限流
+func RateLimitByIP(c *gin.Context) string {
+	return "ip:" + c.ClientIP()
+}
+
+// RateLimitByUser 按 API 令牌或登录用户限流，未登录时按 IP
+func RateLimitByUser(c *gin.Context) string {
+	if token := currentAPIToken(c); token != nil {
+		return fmt.Sprintf("token:%d", token.ID)
+	}
+	if userInterface, exists := c.Get("user"); exists {
+		if user, ok := userInterface.(*models.User); ok && user != nil {
+			return fmt.Sprintf("user:%d", user.ID)
+		}
+	}
+	return RateLimitByIP(c)
+}
+
+// RateLimiter 限流器，共享同一个存储后端和自动封禁策略
+type RateLimiter struct {
+	store        ratelimit.Store
+	abuseService *services.AbuseService
+}
+
+// NewRateLimiter 创建限流器
+func NewRateLimiter(store ratelimit.Store) *RateLimiter {
+	return &RateLimiter{
+		store:        store,
+		abuseService: services.NewAbuseService(),
+	}
+}
+
+// Limit 按规则限流的中间件
+func (l *RateLimiter) Limit(rule ratelimit.Rule, keyFunc RateLimitKeyFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !l.allow(c, rule, keyFunc(c)) {
+			return
+		}
+		c.Next()
+	}
+}
+
+// allow 按规则为 key 取一个令牌，被拒绝时写入 429 响应并中止请求
+// 被临时封禁的对象直接拒绝；频繁触发限流的对象会被自动封禁；存储后端出错时放行
+func (l *RateLimiter) allow(c *gin.Context, rule ratelimit.Rule, key string) bool {
+	now := time.Now()
+
+	if block, blocked := l.abuseService.ActiveBlock(key); blocked {
+		abortRateLimited(c, block.BlockedUntil.Sub(now), services.ErrAbuseBlocked)
+		return false
+	}
+
+	result, err := l.store.Take(rule.Name+":"+key, rule, now)
+	if err != nil {
+		logger.ErrorContext(c.Request.Context(), "限流检查失败", "rule", rule.Name, "error", err)
+		return true
+	}
+	c.Header("X-RateLimit-Limit", strconv.Itoa(rule.Limit))
+	c.Header("X-RateLimit-Remaining", strconv.Itoa(result.Remaining))
+
+	if !result.Allowed {
+		// 记录一次违规，超过阈值时自动封禁
+		abuseRule := ratelimit.Rule{Name: "abuse", Limit: AbuseThreshold, Period: AbuseWindow}
+		violation, err := l.store.Take(abuseRule.Name+":"+key, abuseRule, now)
+		if err == nil && !violation.Allowed {
+			reason := fmt.Sprintf("%s 内触发限流 %d 次", AbuseWindow, AbuseThreshold)
+			if _, err := l.abuseService.Block(key, rule.Name, reason, AbuseBlockDuration); err != nil {
+				logger.ErrorContext(c.Request.Context(), "自动封禁失败", "key", key, "rule", rule.Name, "error", err)
+			} else {
+				logger.WarnContext(c.Request.Context(), "已自动封禁", "key", key, "rule", rule.Name, "duration", AbuseBlockDuration)
+			}
+		}
+		abortRateLimited(c, result.RetryAfter, services.ErrRateLimited)
+		return false
+	}
+	return true
+}
+
+// abortRateLimited 拒绝被限流的请求
+func abortRateLimited(c *gin.Context, retryAfter time.Duration, err *services.ServiceError) {
+	seconds := int(retryAfter.Seconds()) + 1
+	c.Header("Retry-After", strconv.Itoa(seconds))
+	abortWithError(c, err.WithDetails(map[string]interface{}{"retry_after": seconds}))
+}
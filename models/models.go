@@ -1,6 +1,10 @@
 package models
 
 import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
 	"time"
 
 	"gorm.io/gorm"
@@ -27,10 +31,14 @@ func (s *Setting) BeforeCreate(tx *gorm.DB) error {
 }
 
 // Admin 管理员
+// 本地账号需关联一个后台人员用户，登录后以该用户的身份和角色访问后台
 type Admin struct {
 	ID           uint       `gorm:"primaryKey" json:"id"`
+	UserID       *uint      `gorm:"uniqueIndex" json:"user_id"`
 	Username     string     `gorm:"uniqueIndex;size:50" json:"username"`
 	PasswordHash string     `gorm:"size:255" json:"-"`
+	FailedLogins int        `gorm:"default:0" json:"failed_logins"` // 连续登录失败次数
+	LockedUntil  *time.Time `json:"locked_until"`                   // 锁定截止时间
 	LastLoginAt  *time.Time `json:"last_login_at"`
 	CreatedAt    time.Time  `json:"created_at"`
 	UpdatedAt    time.Time  `json:"updated_at"`
@@ -46,25 +54,92 @@ type User struct {
 	AvatarURL   string     `gorm:"size:500" json:"avatar_url"`
 	TrustLevel  int        `json:"trust_level"`
 	Balance     float64    `gorm:"default:0" json:"balance"`
-	IsAdmin     bool       `gorm:"default:false;index" json:"is_admin"`
+	IsAdmin     bool       `gorm:"default:false;index" json:"is_admin"` // 是否为后台人员，与 Role 同步
+	Role        string     `gorm:"size:20;index" json:"role"`           // 后台角色，为空表示普通用户
 	IsBlocked   bool       `gorm:"default:false" json:"is_blocked"`
 	LastLoginAt *time.Time `json:"last_login_at"`
-	CreatedAt   time.Time  `json:"created_at"`
-	UpdatedAt   time.Time  `json:"updated_at"`
-	Orders      []Order    `gorm:"foreignKey:UserID" json:"orders,omitempty"`
+	Locale      string     `gorm:"size:10" json:"locale"` // 语言偏好，为空时跟随浏览器
+
+	// 两步验证（TOTP）
+	TOTPSecret   string `gorm:"size:64" json:"-"`
+	TOTPEnabled  bool   `gorm:"default:false" json:"totp_enabled"`
+	TOTPLastStep int64  `gorm:"default:0" json:"-"` // 最后一次使用的验证码周期，防止重放
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Orders    []Order   `gorm:"foreignKey:UserID" json:"orders,omitempty"`
+}
+
+// RecoveryCode 两步验证恢复码（只保存哈希，每个恢复码只能使用一次）
+type RecoveryCode struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	UserID    uint       `gorm:"index" json:"user_id"`
+	CodeHash  string     `gorm:"size:64;index" json:"-"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// Role 后台角色
+const (
+	RoleOwner        = "owner"         // 所有者
+	RoleOperator     = "operator"      // 运营
+	RoleStockManager = "stock_manager" // 库存管理
+	RoleSupport      = "support"       // 客服
+	RoleViewer       = "viewer"        // 只读
+)
+
+// IsStaff 是否为后台人员
+func (u *User) IsStaff() bool {
+	return u.Role != ""
 }
 
 // Category 商品分类
+// 通过 ParentID 组成无限层级的分类树，ParentID 为空表示顶级分类
 type Category struct {
+	ID          uint           `gorm:"primaryKey" json:"id"`
+	ParentID    *uint          `gorm:"index" json:"parent_id"`
+	Name        string         `gorm:"size:100" json:"name"`
+	Description string         `gorm:"type:text" json:"description"`
+	Icon        string         `gorm:"size:50" json:"icon"`
+	Sort        int            `gorm:"default:0" json:"sort"`
+	IsActive    bool           `gorm:"default:true" json:"is_active"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	DeletedAt   gorm.DeletedAt `gorm:"index" json:"deleted_at"` // 归档时间
+	Products    []Product      `gorm:"foreignKey:CategoryID" json:"products,omitempty"`
+
+	Translations []CategoryTranslation `gorm:"foreignKey:CategoryID" json:"translations,omitempty"`
+
+	// 层级数据（不入库，由服务层填充）
+	Children []Category      `gorm:"-" json:"children,omitempty"`
+	Path     []CategoryCrumb `gorm:"-" json:"path,omitempty"` // 从顶级分类到当前分类的面包屑
+}
+
+// CategoryCrumb 分类面包屑节点
+type CategoryCrumb struct {
+	ID   uint   `json:"id"`
+	Name string `json:"name"`
+}
+
+// CategoryTranslation 分类名称和描述的其他语言版本
+type CategoryTranslation struct {
 	ID          uint      `gorm:"primaryKey" json:"id"`
+	CategoryID  uint      `gorm:"uniqueIndex:idx_category_locale" json:"category_id"`
+	Locale      string    `gorm:"size:10;uniqueIndex:idx_category_locale" json:"locale"`
 	Name        string    `gorm:"size:100" json:"name"`
 	Description string    `gorm:"type:text" json:"description"`
-	Icon        string    `gorm:"size:50" json:"icon"`
-	Sort        int       `gorm:"default:0" json:"sort"`
-	IsActive    bool      `gorm:"default:true" json:"is_active"`
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
-	Products    []Product `gorm:"foreignKey:CategoryID" json:"products,omitempty"`
+}
+
+// Tag 商品标签
+type Tag struct {
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	Name         string    `gorm:"size:50;uniqueIndex" json:"name"`
+	Sort         int       `gorm:"default:0" json:"sort"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+	ProductCount int64     `gorm:"-" json:"product_count"`
 }
 
 // Product 商品
@@ -72,8 +147,8 @@ type Product struct {
 	ID          uint      `gorm:"primaryKey" json:"id"`
 	CategoryID  uint      `gorm:"index" json:"category_id"`
 	Category    *Category `gorm:"foreignKey:CategoryID" json:"category,omitempty"`
-	Name        string    `gorm:"size:200" json:"name"`
-	Description string    `gorm:"type:text" json:"description"`
+	Name        string    `gorm:"size:200;index:idx_products_fulltext,class:FULLTEXT,option:WITH PARSER ngram" json:"name"`
+	Description string    `gorm:"type:text;index:idx_products_fulltext,class:FULLTEXT,option:WITH PARSER ngram" json:"description"`
 	Price       float64   `json:"price"`
 	OrigPrice   float64   `json:"orig_price"`
 	Image       string    `gorm:"size:500" json:"image"`
@@ -84,21 +159,143 @@ type Product struct {
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
 	CardKeys    []CardKey `gorm:"foreignKey:ProductID" json:"card_keys,omitempty"`
+
+	// 发货方式：auto 自动发卡密，manual 管理员人工发货
+	DeliveryType string               `gorm:"size:20;default:auto" json:"delivery_type"`
+	DeletedAt    gorm.DeletedAt       `gorm:"index" json:"deleted_at"` // 归档时间
+	Variants     []ProductVariant     `gorm:"foreignKey:ProductID" json:"variants,omitempty"`
+	PriceTiers   []PriceTier          `gorm:"foreignKey:ProductID" json:"price_tiers,omitempty"`
+	Tags         []Tag                `gorm:"many2many:product_tags" json:"tags,omitempty"`
+	Translations []ProductTranslation `gorm:"foreignKey:ProductID" json:"translations,omitempty"`
+	FlashSale    *FlashSale           `gorm:"-" json:"flash_sale,omitempty"` // 进行中或即将开始的限时抢购
+}
+
+// IsManualDelivery 是否为人工发货商品
+func (p *Product) IsManualDelivery() bool {
+	return p.DeliveryType == DeliveryTypeManual
+}
+
+// ProductTranslation 商品名称和描述的其他语言版本
+type ProductTranslation struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	ProductID   uint      `gorm:"uniqueIndex:idx_product_locale" json:"product_id"`
+	Locale      string    `gorm:"size:10;uniqueIndex:idx_product_locale" json:"locale"`
+	Name        string    `gorm:"size:200" json:"name"`
+	Description string    `gorm:"type:text" json:"description"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// ProductVariant 商品规格（SKU），每个规格有独立的价格和卡密库存
+type ProductVariant struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	ProductID  uint      `gorm:"index" json:"product_id"`
+	Name       string    `gorm:"size:100" json:"name"`
+	Price      float64   `json:"price"`
+	OrigPrice  float64   `json:"orig_price"`
+	StockCount int       `gorm:"default:0" json:"stock_count"`
+	SalesCount int       `gorm:"default:0" json:"sales_count"`
+	Sort       int       `gorm:"default:0" json:"sort"`
+	IsActive   bool      `gorm:"default:true" json:"is_active"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// PriceTier 数量阶梯价（批发价）
+// VariantID 为 0 时对商品的所有规格生效；MaxQuantity 为 0 表示不设上限
+type PriceTier struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	ProductID   uint      `gorm:"index" json:"product_id"`
+	VariantID   uint      `gorm:"index;default:0" json:"variant_id"`
+	MinQuantity int       `json:"min_quantity"`
+	MaxQuantity int       `gorm:"default:0" json:"max_quantity"`
+	UnitPrice   float64   `json:"unit_price"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// Matches 数量是否落在该阶梯区间内
+func (t *PriceTier) Matches(quantity int) bool {
+	return quantity >= t.MinQuantity && (t.MaxQuantity == 0 || quantity <= t.MaxQuantity)
 }
 
+// Label 阶梯描述，用于订单留档
+func (t *PriceTier) Label() string {
+	if t.MaxQuantity == 0 {
+		return fmt.Sprintf("%d+ @ %.2f", t.MinQuantity, t.UnitPrice)
+	}
+	return fmt.Sprintf("%d-%d @ %.2f", t.MinQuantity, t.MaxQuantity, t.UnitPrice)
+}
+
+// FlashSale 限时抢购
+// VariantID 为 0 时对商品的所有规格生效；PerUserLimit、TotalQuota 为 0 表示不限
+type FlashSale struct {
+	ID              uint      `gorm:"primaryKey" json:"id"`
+	ProductID       uint      `gorm:"index" json:"product_id"`
+	VariantID       uint      `gorm:"default:0" json:"variant_id"`
+	Name            string    `gorm:"size:100" json:"name"`
+	StartAt         time.Time `gorm:"index" json:"start_at"`
+	EndAt           time.Time `gorm:"index" json:"end_at"`
+	SalePrice       float64   `json:"sale_price"`
+	PerUserLimit    int       `gorm:"default:0" json:"per_user_limit"`
+	TotalQuota      int       `gorm:"default:0" json:"total_quota"`
+	SoldCount       int       `gorm:"default:0" json:"sold_count"`
+	ActivateOnStart bool      `json:"activate_on_start"`                       // 开始时自动上架商品
+	DeactivateOnEnd bool      `json:"deactivate_on_end"`                       // 结束时自动下架商品
+	Status          string    `gorm:"size:20;default:scheduled" json:"status"` // 由调度器维护
+	IsActive        bool      `gorm:"default:true" json:"is_active"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+
+	// 倒计时数据（不入库，由服务层填充）
+	ServerTime     *time.Time `gorm:"-" json:"server_time,omitempty"`
+	StartsIn       int64      `gorm:"-" json:"starts_in_seconds"`
+	EndsIn         int64      `gorm:"-" json:"ends_in_seconds"`
+	RemainingQuota int        `gorm:"-" json:"remaining_quota"`
+}
+
+// FlashSaleStatus 限时抢购状态
+const (
+	FlashSaleStatusScheduled = "scheduled" // 未开始
+	FlashSaleStatusRunning   = "running"   // 进行中
+	FlashSaleStatusEnded     = "ended"     // 已结束
+)
+
+// IsRunningAt 指定时间是否处于抢购时间窗口内
+func (f *FlashSale) IsRunningAt(t time.Time) bool {
+	return f.IsActive && !t.Before(f.StartAt) && t.Before(f.EndAt)
+}
+
+// AppliesTo 是否适用于指定规格
+func (f *FlashSale) AppliesTo(variantID uint) bool {
+	return f.VariantID == 0 || f.VariantID == variantID
+}
+
+// DefaultVariantName 默认规格名称
+const DefaultVariantName = "默认"
+
+// DeliveryType 发货方式
+const (
+	DeliveryTypeAuto   = "auto"   // 自动发放卡密
+	DeliveryTypeManual = "manual" // 管理员人工发货
+)
+
 // CardKey 卡密
 type CardKey struct {
-	ID        uint       `gorm:"primaryKey" json:"id"`
-	ProductID uint       `gorm:"index" json:"product_id"`
-	Product   *Product   `gorm:"foreignKey:ProductID" json:"product,omitempty"`
-	CardNo    string     `gorm:"size:500" json:"card_no"`
-	CardPwd   string     `gorm:"size:500" json:"card_pwd"`
-	Status    int        `gorm:"default:0" json:"status"` // 0: 未售出, 1: 已售出, 2: 已锁定
-	OrderID   *uint      `gorm:"index" json:"order_id"`
-	Order     *Order     `gorm:"foreignKey:OrderID" json:"order,omitempty"`
-	SoldAt    *time.Time `json:"sold_at"`
-	CreatedAt time.Time  `json:"created_at"`
-	UpdatedAt time.Time  `json:"updated_at"`
+	ID        uint            `gorm:"primaryKey" json:"id"`
+	ProductID uint            `gorm:"index" json:"product_id"`
+	Product   *Product        `gorm:"foreignKey:ProductID" json:"product,omitempty"`
+	VariantID uint            `gorm:"index;default:0" json:"variant_id"`
+	Variant   *ProductVariant `gorm:"foreignKey:VariantID" json:"variant,omitempty"`
+	CardNo    string          `gorm:"size:500" json:"card_no"`
+	CardPwd   string          `gorm:"size:500" json:"card_pwd"`
+	Status    int             `gorm:"default:0" json:"status"` // 0: 未售出, 1: 已售出, 2: 已锁定
+	OrderID   *uint           `gorm:"index" json:"order_id"`
+	Order     *Order          `gorm:"foreignKey:OrderID" json:"order,omitempty"`
+	SoldAt    *time.Time      `json:"sold_at"`
+	CreatedAt time.Time       `json:"created_at"`
+	UpdatedAt time.Time       `json:"updated_at"`
+	DeletedAt gorm.DeletedAt  `gorm:"index" json:"deleted_at"` // 归档时间
 }
 
 // CardKeyStatus 卡密状态
@@ -112,11 +309,17 @@ const (
 type Order struct {
 	ID        uint      `gorm:"primaryKey" json:"id"`
 	OrderNo   string    `gorm:"uniqueIndex;size:50" json:"order_no"`
+	PublicToken string          `gorm:"size:64;index" json:"public_token"` // 免登录查询订单状态的凭证
 	UserID    uint      `gorm:"index" json:"user_id"`
 	User      *User     `gorm:"foreignKey:UserID" json:"user,omitempty"`
 	ProductID uint      `gorm:"index" json:"product_id"`
 	Product   *Product  `gorm:"foreignKey:ProductID" json:"product,omitempty"`
+	VariantID   uint            `gorm:"index;default:0" json:"variant_id"`
+	Variant     *ProductVariant `gorm:"foreignKey:VariantID" json:"variant,omitempty"`
 	Quantity  int       `json:"quantity"`
+	UnitPrice   float64         `json:"unit_price"`                   // 下单时的成交单价
+	ProductName string          `gorm:"size:200" json:"product_name"` // 下单时的商品名称快照
+	VariantName string          `gorm:"size:100" json:"variant_name"` // 下单时的规格名称快照
 	TotalAmount float64 `json:"total_amount"`
 	Status    int       `gorm:"default:0" json:"status"` // 0: 待支付, 1: 已支付, 2: 已完成, 3: 已取消
 	PayMethod string    `gorm:"size:50" json:"pay_method"`
@@ -124,6 +327,13 @@ type Order struct {
 	Contact   string    `gorm:"size:200" json:"contact"`
 	Remark    string    `gorm:"type:text" json:"remark"`
 	
+	// 阶梯价留档（下单时命中的阶梯）
+	PriceTierID    *uint  `json:"price_tier_id"`
+	PriceTierLabel string `gorm:"size:100" json:"price_tier_label"`
+
+	// 限时抢购订单
+	FlashSaleID *uint `gorm:"index" json:"flash_sale_id"`
+
 	// NodeLoc Payment 支付字段
 	TransactionID  string     `gorm:"size:100;index" json:"transaction_id"`  // 支付交易ID
 	PaymentURL     string     `gorm:"size:500" json:"payment_url"`           // 支付链接
@@ -131,8 +341,15 @@ type Order struct {
 	MerchantPoints int        `gorm:"default:0" json:"merchant_points"`      // 商家实收积分
 	ExpiredAt      *time.Time `json:"expired_at"`                            // 订单过期时间
 	
-	CreatedAt time.Time  `json:"created_at"`
-	UpdatedAt time.Time  `json:"updated_at"`
+	// 人工发货字段
+	ClaimedBy       *uint      `gorm:"index" json:"claimed_by"`       // 认领的管理员
+	ClaimedAt       *time.Time `json:"claimed_at"`                    // 认领时间
+	FulfillDeadline *time.Time `gorm:"index" json:"fulfill_deadline"` // 发货时限（SLA）
+	DeliveredAt     *time.Time `json:"delivered_at"`                  // 发货时间
+	RejectReason    string     `gorm:"size:500" json:"reject_reason"` // 拒绝原因
+
+	CreatedAt time.Time  `gorm:"index" json:"created_at"`
+	UpdatedAt time.Time  `gorm:"index" json:"updated_at"` // 销售统计据此找出有变动的订单
 	CardKeys  []CardKey  `gorm:"foreignKey:OrderID" json:"card_keys,omitempty"`
 }
 
@@ -142,17 +359,295 @@ const (
 	OrderStatusPaid      = 1 // 已支付
 	OrderStatusCompleted = 2 // 已完成
 	OrderStatusCancelled = 3 // 已取消
+	OrderStatusRefunded  = 4 // 已退款
+)
+
+// Notification 站内通知
+type Notification struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	UserID    uint       `gorm:"index" json:"user_id"`
+	Type      string     `gorm:"size:50" json:"type"`
+	Title     string     `gorm:"size:200" json:"title"`
+	Content   string     `gorm:"type:text" json:"content"`
+	OrderNo   string     `gorm:"size:50" json:"order_no"`
+	ReadAt    *time.Time `json:"read_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// NotificationType 通知类型
+const (
+	NotificationOrderDelivered = "order_delivered" // 订单已发货
+	NotificationOrderRefunded  = "order_refunded"  // 订单已退款
+	NotificationOrderRejected  = "order_rejected"  // 订单无法发货，已取消（未收款的订单）
 )
 
+// AuditLog 后台操作审计日志（只允许追加，不允许修改和删除）
+type AuditLog struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	ActorID    uint      `gorm:"index" json:"actor_id"`
+	ActorName  string    `gorm:"size:100" json:"actor_name"`
+	ActorRole  string    `gorm:"size:20" json:"actor_role"`
+	Action     string    `gorm:"size:100;index" json:"action"` // 操作，如 products.update
+	Method     string    `gorm:"size:10" json:"method"`
+	Path       string    `gorm:"size:500" json:"path"`
+	TargetType string    `gorm:"size:50;index:idx_audit_target" json:"target_type"`
+	TargetID   string    `gorm:"size:100;index:idx_audit_target" json:"target_id"`
+	Before     string    `gorm:"type:text" json:"before"` // 变更前的字段（JSON，敏感值已脱敏）
+	After      string    `gorm:"type:text" json:"after"`  // 变更后的字段或请求内容（JSON，敏感值已脱敏）
+	StatusCode int       `json:"status_code"`
+	IP         string    `gorm:"size:64" json:"ip"`
+	UserAgent  string    `gorm:"size:500" json:"user_agent"`
+	CreatedAt  time.Time `gorm:"index" json:"created_at"`
+}
+
+// ErrAuditLogImmutable 审计日志不可修改
+var ErrAuditLogImmutable = errors.New("审计日志不可修改或删除")
+
+// BeforeUpdate 禁止修改审计日志
+func (a *AuditLog) BeforeUpdate(tx *gorm.DB) error {
+	return ErrAuditLogImmutable
+}
+
+// BeforeDelete 禁止删除审计日志
+func (a *AuditLog) BeforeDelete(tx *gorm.DB) error {
+	return ErrAuditLogImmutable
+}
+
+// RateLimitBucket 限流令牌桶（多实例部署时使用 MySQL 存储）
+type RateLimitBucket struct {
+	Key       string    `gorm:"primaryKey;size:191" json:"key"`
+	Tokens    float64   `json:"tokens"`
+	UpdatedAt time.Time `gorm:"index" json:"updated_at"`
+}
+
+// RateLimitBlock 触发限流阈值后的临时封禁记录
+type RateLimitBlock struct {
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	Key          string    `gorm:"size:191;index:idx_rate_limit_block" json:"key"` // 如 user:12、ip:1.2.3.4
+	Rule         string    `gorm:"size:50" json:"rule"`                            // 触发的限流规则
+	Reason       string    `gorm:"size:200" json:"reason"`
+	BlockedUntil time.Time `gorm:"index:idx_rate_limit_block" json:"blocked_until"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// APIToken 个人访问令牌，供脚本调用 API 下单（只保存令牌哈希）
+type APIToken struct {
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	UserID     uint       `gorm:"index" json:"user_id"`
+	Name       string     `gorm:"size:100" json:"name"`
+	TokenHash  string     `gorm:"size:64;uniqueIndex" json:"-"`
+	Prefix     string     `gorm:"size:16" json:"prefix"`  // 令牌前几位，便于用户辨认
+	Scopes     string     `gorm:"size:200" json:"scopes"` // 逗号分隔的权限范围
+	RateLimit  int        `json:"rate_limit"`             // 每分钟请求上限
+	ExpiresAt  *time.Time `json:"expires_at"`             // 为空表示永不过期
+	LastUsedAt *time.Time `json:"last_used_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// IdempotencyKey 幂等键，记录请求摘要和首次响应，重复提交时直接返回首次响应
+type IdempotencyKey struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	UserID      uint      `gorm:"uniqueIndex:idx_idempotency_user_key" json:"user_id"`
+	Key         string    `gorm:"size:100;uniqueIndex:idx_idempotency_user_key" json:"key"`
+	RequestHash string    `gorm:"size:64" json:"request_hash"`
+	StatusCode  int       `json:"status_code"` // 0 表示请求仍在处理中
+	Response    string    `gorm:"type:mediumtext" json:"response"`
+	ExpiresAt   time.Time `gorm:"index" json:"expires_at"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// SalesDailyStat 每日销售汇总（按商品），由定时任务根据订单表生成，销售报表从该表读取
+// 订单按下单时间归入统计时区下的日期；已支付指状态为已支付或已完成
+type SalesDailyStat struct {
+	ID              uint      `gorm:"primaryKey" json:"id"`
+	Day             string    `gorm:"size:10;uniqueIndex:idx_sales_daily_stat" json:"day"` // 2006-01-02
+	ProductID       uint      `gorm:"uniqueIndex:idx_sales_daily_stat;index" json:"product_id"`
+	Orders          int64     `json:"orders"`           // 下单数
+	PaidOrders      int64     `json:"paid_orders"`      // 已支付订单数
+	CancelledOrders int64     `json:"cancelled_orders"` // 已取消订单数
+	UnitsSold       int64     `json:"units_sold"`       // 已支付订单的商品数量
+	Revenue         float64   `json:"revenue"`          // 已支付订单金额
+	PlatformFee     int64     `json:"platform_fee"`     // 平台手续费
+	MerchantNet     int64     `json:"merchant_net"`     // 商家实收积分
+	PaySeconds      int64     `json:"pay_seconds"`      // 从下单到支付的耗时合计（秒）
+	TimedOrders     int64     `json:"timed_orders"`     // 有支付时间的已支付订单数
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// ReconciliationRun 支付对账任务：逐笔查询一段时间内订单在 NodeLoc Payment 的交易记录并与本地状态核对
+type ReconciliationRun struct {
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	From       string     `gorm:"size:10" json:"from"` // 下单日期范围（含），2006-01-02
+	To         string     `gorm:"size:10" json:"to"`
+	Status     string     `gorm:"size:20;index" json:"status"` // running / completed / failed
+	Checked    int        `json:"checked"`                     // 已核对的订单数
+	IssueCount int        `json:"issue_count"`                 // 发现的差异数
+	Error      string     `gorm:"size:500" json:"error"`
+	StartedBy  *uint      `json:"started_by"` // 为空表示由定时任务发起
+	StartedAt  time.Time  `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at"`
+}
+
+// ReconciliationIssue 对账发现的差异
+type ReconciliationIssue struct {
+	ID                     uint       `gorm:"primaryKey" json:"id"`
+	RunID                  uint       `gorm:"index" json:"run_id"`
+	OrderID                uint       `gorm:"index" json:"order_id"`
+	OrderNo                string     `gorm:"size:50" json:"order_no"`
+	TransactionID          string     `gorm:"size:100" json:"transaction_id"`
+	Type                   string     `gorm:"size:30;index" json:"type"` // 差异类型，见 services.ReconciliationIssue*
+	LocalStatus            int        `json:"local_status"`
+	LocalAmount            float64    `json:"local_amount"`
+	UpstreamStatus         string     `gorm:"size:30" json:"upstream_status"`
+	UpstreamAmount         int        `json:"upstream_amount"`
+	UpstreamPlatformFee    int        `json:"upstream_platform_fee"`
+	UpstreamMerchantPoints int        `json:"upstream_merchant_points"`
+	Detail                 string     `gorm:"size:500" json:"detail"`
+	Resolution             string     `gorm:"size:20;index" json:"resolution"` // 为空表示未处理；fixed / ignored / cleared
+	ResolvedBy             *uint      `json:"resolved_by"`
+	ResolvedAt             *time.Time `json:"resolved_at"`
+	CreatedAt              time.Time  `json:"created_at"`
+}
+
+// SchemaVersion 当前代码要求的数据库结构版本，修改模型或新增数据迁移时递增
+const SchemaVersion = 1
+
+// SchemaMigration 已完成迁移的数据库结构版本
+type SchemaMigration struct {
+	Version   int       `gorm:"primaryKey;autoIncrement:false" json:"version"`
+	AppliedAt time.Time `json:"applied_at"`
+}
+
+// RecordSchemaVersion 在全部迁移完成后记录当前结构版本
+func RecordSchemaVersion(db *gorm.DB) error {
+	return db.Where(SchemaMigration{Version: SchemaVersion}).
+		Attrs(SchemaMigration{AppliedAt: time.Now()}).
+		FirstOrCreate(&SchemaMigration{}).Error
+}
+
+// AppliedSchemaVersion 数据库中已完成迁移的最高结构版本，尚未记录时为 0
+func AppliedSchemaVersion(db *gorm.DB) (int, error) {
+	var version int
+	err := db.Model(&SchemaMigration{}).Select("COALESCE(MAX(version), 0)").Scan(&version).Error
+	return version, err
+}
+
 // AutoMigrate 自动迁移数据库
 func AutoMigrate(db *gorm.DB) error {
 	return db.AutoMigrate(
 		&Setting{},
 		&Admin{},
 		&User{},
+		&RecoveryCode{},
 		&Category{},
+		&CategoryTranslation{},
+		&Tag{},
 		&Product{},
+		&ProductTranslation{},
+		&ProductVariant{},
+		&PriceTier{},
+		&FlashSale{},
 		&CardKey{},
 		&Order{},
+		&Notification{},
+		&AuditLog{},
+		&RateLimitBucket{},
+		&RateLimitBlock{},
+		&APIToken{},
+		&IdempotencyKey{},
+		&SalesDailyStat{},
+		&ReconciliationRun{},
+		&ReconciliationIssue{},
+		&SchemaMigration{},
 	)
 }
+
+// MigrateAdminRoles 将引入角色前的管理员设为所有者
+func MigrateAdminRoles(db *gorm.DB) error {
+	return db.Model(&User{}).
+		Where("is_admin = ? AND (role IS NULL OR role = '')", true).
+		Update("role", RoleOwner).Error
+}
+
+// NewOrderToken 生成订单查询凭证（32 字节随机数的十六进制）
+func NewOrderToken() (string, error) {
+	bytes := make([]byte, 32)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(bytes), nil
+}
+
+// MigrateOrderTokens 为历史订单生成查询凭证
+func MigrateOrderTokens(db *gorm.DB) error {
+	var orders []Order
+	return db.Select("id").
+		Where("public_token IS NULL OR public_token = ''").
+		FindInBatches(&orders, 500, func(tx *gorm.DB, batch int) error {
+			for _, order := range orders {
+				token, err := NewOrderToken()
+				if err != nil {
+					return err
+				}
+				if err := db.Model(&Order{}).Where("id = ?", order.ID).
+					UpdateColumn("public_token", token).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		}).Error
+}
+
+// MigrateOrderSnapshots 为历史订单补全商品名称、规格名称和成交单价快照
+func MigrateOrderSnapshots(db *gorm.DB) error {
+	statements := []string{
+		"UPDATE orders o JOIN products p ON p.id = o.product_id SET o.product_name = p.name WHERE o.product_name IS NULL OR o.product_name = ''",
+		"UPDATE orders o JOIN product_variants v ON v.id = o.variant_id SET o.variant_name = v.name WHERE o.variant_name IS NULL OR o.variant_name = ''",
+		"UPDATE orders SET unit_price = total_amount / quantity WHERE (unit_price IS NULL OR unit_price = 0) AND quantity > 0",
+	}
+	for _, statement := range statements {
+		if err := db.Exec(statement).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MigrateDefaultVariants 为尚无规格的商品创建默认规格，并将已有卡密和订单归入该规格
+func MigrateDefaultVariants(db *gorm.DB) error {
+	var products []Product
+	if err := db.Where("id NOT IN (?)", db.Model(&ProductVariant{}).Select("product_id")).
+		Find(&products).Error; err != nil {
+		return err
+	}
+
+	for _, product := range products {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			variant := &ProductVariant{
+				ProductID:  product.ID,
+				Name:       DefaultVariantName,
+				Price:      product.Price,
+				OrigPrice:  product.OrigPrice,
+				StockCount: product.StockCount,
+				SalesCount: product.SalesCount,
+				IsActive:   true,
+			}
+			if err := tx.Create(variant).Error; err != nil {
+				return err
+			}
+			if err := tx.Model(&CardKey{}).
+				Where("product_id = ? AND variant_id = 0", product.ID).
+				Update("variant_id", variant.ID).Error; err != nil {
+				return err
+			}
+			return tx.Model(&Order{}).
+				Where("product_id = ? AND variant_id = 0", product.ID).
+				Update("variant_id", variant.ID).Error
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
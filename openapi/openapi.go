@@ -0,0 +1,153 @@
+package openapi
+
+import (
+	_ "embed"
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// document OpenAPI 文档原文，修改接口时需同步更新 openapi.json
+//
+//go:embed openapi.json
+var document []byte
+
+// VersionPrefix 使用信封响应的版本化路径前缀
+const VersionPrefix = "/api/v1"
+
+// legacyPrefix 旧路径前缀，/api/xxx 与 /api/v1/xxx 的请求参数相同
+const legacyPrefix = "/api"
+
+// Spec 解析后的 OpenAPI 文档（只包含校验用到的部分）
+type Spec struct {
+	Paths      map[string]map[string]*Operation `json:"paths"`
+	Components struct {
+		Schemas   map[string]*Schema   `json:"schemas"`
+		Responses map[string]*Response `json:"responses"`
+	} `json:"components"`
+
+	// operations 按 "METHOD 路由" 索引，路由使用 gin 的写法，如 GET /api/v1/products/:id
+	operations map[string]*Operation
+}
+
+// Operation 接口定义
+type Operation struct {
+	OperationID string               `json:"operationId"`
+	Summary     string               `json:"summary"`
+	Parameters  []Parameter          `json:"parameters"`
+	RequestBody *RequestBody         `json:"requestBody"`
+	Responses   map[string]*Response `json:"responses"`
+}
+
+// Parameter 路径或查询参数
+type Parameter struct {
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required"`
+	Schema   *Schema `json:"schema"`
+}
+
+// RequestBody 请求体
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+// Response 响应定义
+type Response struct {
+	Ref         string               `json:"$ref"`
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content"`
+}
+
+// MediaType 某种内容类型的结构
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Document 返回 OpenAPI 文档原文
+func Document() []byte {
+	return document
+}
+
+// Load 解析内嵌的 OpenAPI 文档
+func Load() (*Spec, error) {
+	var spec Spec
+	if err := json.Unmarshal(document, &spec); err != nil {
+		return nil, err
+	}
+	spec.operations = make(map[string]*Operation)
+	for path, item := range spec.Paths {
+		for method, op := range item {
+			spec.operations[routeKey(strings.ToUpper(method), ginPath(path))] = op
+		}
+	}
+	return &spec, nil
+}
+
+// Lookup 查找路由对应的接口定义，route 为 gin 的路由写法（c.FullPath()）
+// /api 下的旧路径没有单独的定义，使用对应 /api/v1 路径的定义，此时 documented 为 false（响应格式不同）
+func (s *Spec) Lookup(method, route string) (op *Operation, documented bool) {
+	if op := s.operations[routeKey(method, route)]; op != nil {
+		return op, true
+	}
+	if strings.HasPrefix(route, legacyPrefix+"/") && !strings.HasPrefix(route, VersionPrefix+"/") {
+		return s.operations[routeKey(method, VersionPrefix+strings.TrimPrefix(route, legacyPrefix))], false
+	}
+	return nil, false
+}
+
+// Diff 对比已注册的路由和文档，routes 的元素为 "METHOD 路由"
+// undocumented 为文档中缺少的路由，unregistered 为文档中有但没有注册的接口
+func (s *Spec) Diff(routes []string) (undocumented, unregistered []string) {
+	registered := make(map[string]bool, len(routes))
+	for _, route := range routes {
+		registered[route] = true
+		method, path, _ := strings.Cut(route, " ")
+		if op, _ := s.Lookup(method, path); op == nil {
+			undocumented = append(undocumented, route)
+		}
+	}
+	for key := range s.operations {
+		if !registered[key] {
+			unregistered = append(unregistered, key)
+		}
+	}
+	sort.Strings(undocumented)
+	sort.Strings(unregistered)
+	return undocumented, unregistered
+}
+
+// response 获取状态码对应的响应定义，未单独定义时使用 default
+func (s *Spec) response(op *Operation, status int) *Response {
+	response, ok := op.Responses[strconv.Itoa(status)]
+	if !ok {
+		response = op.Responses["default"]
+	}
+	if response != nil && response.Ref != "" {
+		response = s.Components.Responses[strings.TrimPrefix(response.Ref, "#/components/responses/")]
+	}
+	return response
+}
+
+// routeKey 路由索引键
+func routeKey(method, route string) string {
+	return method + " " + route
+}
+
+// ginPath 将文档路径转为 gin 的路由写法：/products/{id} -> /products/:id，/uploads/{filepath} -> /uploads/*filepath
+func ginPath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			name := segment[1 : len(segment)-1]
+			if name == "filepath" {
+				segments[i] = "*" + name
+			} else {
+				segments[i] = ":" + name
+			}
+		}
+	}
+	return strings.Join(segments, "/")
+}
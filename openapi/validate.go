@@ -0,0 +1,303 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Schema JSON Schema（OpenAPI 3.0 的子集）
+// 支持 type、nullable、enum、minimum/maximum、minLength/maxLength、minItems/maxItems、
+// required、properties、items、additionalProperties、allOf 和 $ref；
+// 未列在 required 中的属性允许为 null，与 encoding/json 把 null 当作未传值的行为一致
+type Schema struct {
+	Ref                  string             `json:"$ref"`
+	Type                 string             `json:"type"`
+	Format               string             `json:"format"`
+	Nullable             bool               `json:"nullable"`
+	Enum                 []interface{}      `json:"enum"`
+	Minimum              *float64           `json:"minimum"`
+	Maximum              *float64           `json:"maximum"`
+	MinLength            *int               `json:"minLength"`
+	MaxLength            *int               `json:"maxLength"`
+	MinItems             *int               `json:"minItems"`
+	MaxItems             *int               `json:"maxItems"`
+	Required             []string           `json:"required"`
+	Properties           map[string]*Schema `json:"properties"`
+	Items                *Schema            `json:"items"`
+	AdditionalProperties *Schema            `json:"additionalProperties"`
+	AllOf                []*Schema          `json:"allOf"`
+}
+
+// ValidationError 校验失败的字段和原因
+type ValidationError struct {
+	In     string // path / query / body / response
+	Field  string // 字段路径，如 tiers[0].min_quantity，请求体整体不合法时为空
+	Reason string // 如 required、type、enum、minimum、max_length、invalid_json
+}
+
+func (e *ValidationError) Error() string {
+	if e.Field == "" {
+		return fmt.Sprintf("%s: %s", e.In, e.Reason)
+	}
+	return fmt.Sprintf("%s.%s: %s", e.In, e.Field, e.Reason)
+}
+
+// ValidateRequest 按接口定义校验请求的路径参数、查询参数和请求体
+// 查询参数为空字符串时视为未传；读取的请求体会放回 r.Body，处理函数仍可正常读取
+func (s *Spec) ValidateRequest(op *Operation, r *http.Request, pathParam func(name string) string) error {
+	query := r.URL.Query()
+	for _, param := range op.Parameters {
+		var raw string
+		switch param.In {
+		case "path":
+			raw = pathParam(param.Name)
+		case "query":
+			raw = query.Get(param.Name)
+		default:
+			continue
+		}
+		if err := s.validateString(param.Schema, raw, param.Required, param.In, param.Name); err != nil {
+			return err
+		}
+	}
+
+	if op.RequestBody == nil {
+		return nil
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return &ValidationError{In: "body", Reason: "invalid_body"}
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	if len(bytes.TrimSpace(body)) == 0 {
+		if op.RequestBody.Required {
+			return &ValidationError{In: "body", Reason: "required"}
+		}
+		return nil
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	content, ok := op.RequestBody.Content[mediaType]
+	if !ok || content.Schema == nil {
+		// 未定义的内容类型（如 multipart）交给处理函数校验
+		return nil
+	}
+	switch mediaType {
+	case "application/json":
+		value, err := decodeJSON(body)
+		if err != nil {
+			return &ValidationError{In: "body", Reason: "invalid_json"}
+		}
+		return s.validate(content.Schema, value, "body", "", false)
+	case "application/x-www-form-urlencoded":
+		form, err := url.ParseQuery(string(body))
+		if err != nil {
+			return &ValidationError{In: "body", Reason: "invalid_body"}
+		}
+		schema := s.resolve(content.Schema)
+		for name, property := range schema.Properties {
+			if err := s.validateString(property, form.Get(name), contains(schema.Required, name), "body", name); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// ValidateResponse 校验响应内容是否符合接口定义，只校验文档中有结构定义的 JSON 响应
+func (s *Spec) ValidateResponse(op *Operation, status int, contentType string, body []byte) error {
+	response := s.response(op, status)
+	if response == nil {
+		return &ValidationError{In: "response", Field: strconv.Itoa(status), Reason: "undocumented_status"}
+	}
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if mediaType != "application/json" {
+		return nil
+	}
+	content, ok := response.Content[mediaType]
+	if !ok || content.Schema == nil {
+		return nil
+	}
+	value, err := decodeJSON(body)
+	if err != nil {
+		return &ValidationError{In: "response", Reason: "invalid_json"}
+	}
+	return s.validate(content.Schema, value, "response", "", false)
+}
+
+// validateString 校验路径参数、查询参数和表单字段：按类型转换后再校验
+func (s *Spec) validateString(schema *Schema, raw string, required bool, in, field string) error {
+	if raw == "" {
+		if required {
+			return &ValidationError{In: in, Field: field, Reason: "required"}
+		}
+		return nil
+	}
+	if schema == nil {
+		return nil
+	}
+	schema = s.resolve(schema)
+
+	var value interface{} = raw
+	switch schema.Type {
+	case "integer", "number":
+		number, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return &ValidationError{In: in, Field: field, Reason: "type"}
+		}
+		value = number
+	case "boolean":
+		if raw != "true" && raw != "false" {
+			return &ValidationError{In: in, Field: field, Reason: "type"}
+		}
+		value = raw == "true"
+	}
+	return s.validate(schema, value, in, field, false)
+}
+
+// validate 校验 JSON 值，optional 表示该值是对象中未要求必填的属性
+func (s *Spec) validate(schema *Schema, value interface{}, in, field string, optional bool) error {
+	if schema == nil {
+		return nil
+	}
+	schema = s.resolve(schema)
+	fail := func(reason string) error {
+		return &ValidationError{In: in, Field: field, Reason: reason}
+	}
+
+	if value == nil {
+		if optional || schema.Nullable || (schema.Type == "" && len(schema.AllOf) == 0) {
+			return nil
+		}
+		return fail("type")
+	}
+	for _, part := range schema.AllOf {
+		if err := s.validate(part, value, in, field, optional); err != nil {
+			return err
+		}
+	}
+	if len(schema.Enum) > 0 && !containsValue(schema.Enum, value) {
+		return fail("enum")
+	}
+
+	switch schema.Type {
+	case "string":
+		str, ok := value.(string)
+		if !ok {
+			return fail("type")
+		}
+		length := len([]rune(str))
+		if schema.MinLength != nil && length < *schema.MinLength {
+			return fail("min_length")
+		}
+		if schema.MaxLength != nil && length > *schema.MaxLength {
+			return fail("max_length")
+		}
+	case "integer", "number":
+		number, ok := value.(float64)
+		if !ok || (schema.Type == "integer" && number != math.Trunc(number)) {
+			return fail("type")
+		}
+		if schema.Minimum != nil && number < *schema.Minimum {
+			return fail("minimum")
+		}
+		if schema.Maximum != nil && number > *schema.Maximum {
+			return fail("maximum")
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return fail("type")
+		}
+	case "array":
+		items, ok := value.([]interface{})
+		if !ok {
+			return fail("type")
+		}
+		if schema.MinItems != nil && len(items) < *schema.MinItems {
+			return fail("min_items")
+		}
+		if schema.MaxItems != nil && len(items) > *schema.MaxItems {
+			return fail("max_items")
+		}
+		for i, item := range items {
+			if err := s.validate(schema.Items, item, in, fmt.Sprintf("%s[%d]", field, i), false); err != nil {
+				return err
+			}
+		}
+	case "object":
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return fail("type")
+		}
+		for _, name := range schema.Required {
+			if _, ok := object[name]; !ok {
+				return &ValidationError{In: in, Field: joinField(field, name), Reason: "required"}
+			}
+		}
+		for name, item := range object {
+			property, ok := schema.Properties[name]
+			if !ok {
+				property = schema.AdditionalProperties
+			}
+			if err := s.validate(property, item, in, joinField(field, name), !contains(schema.Required, name)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// resolve 展开 $ref 引用
+func (s *Spec) resolve(schema *Schema) *Schema {
+	for schema.Ref != "" {
+		target, ok := s.Components.Schemas[strings.TrimPrefix(schema.Ref, "#/components/schemas/")]
+		if !ok {
+			return &Schema{}
+		}
+		schema = target
+	}
+	return schema
+}
+
+// decodeJSON 解析 JSON，数字统一为 float64
+func decodeJSON(data []byte) (interface{}, error) {
+	var value interface{}
+	err := json.Unmarshal(data, &value)
+	return value, err
+}
+
+// joinField 拼接字段路径
+func joinField(parent, name string) string {
+	if parent == "" {
+		return name
+	}
+	return parent + "." + name
+}
+
+// contains 字符串列表中是否包含指定值
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+// containsValue 枚举值中是否包含指定值
+func containsValue(list []interface{}, value interface{}) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
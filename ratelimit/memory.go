@@ -0,0 +1,49 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// MemoryStore 内存令牌桶存储，适用于单实例部署
+type MemoryStore struct {
+	buckets map[string]*bucket
+	mu      sync.Mutex
+	swept   time.Time
+}
+
+// NewMemoryStore 创建内存存储
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: make(map[string]*bucket)}
+}
+
+// sweepInterval 清理闲置令牌桶的间隔
+const sweepInterval = 10 * time.Minute
+
+// Take 从令牌桶中取一个令牌
+func (s *MemoryStore) Take(key string, rule Rule, now time.Time) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sweep(now)
+
+	b, exists := s.buckets[key]
+	if !exists {
+		b = &bucket{tokens: float64(rule.Limit), updatedAt: now}
+		s.buckets[key] = b
+	}
+	return b.take(rule, now), nil
+}
+
+// sweep 定期清理已补满的令牌桶（闲置超过一个周期），删除后重新创建的桶同样是满的
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Sub(s.swept) < sweepInterval {
+		return
+	}
+	s.swept = now
+	for key, b := range s.buckets {
+		if now.Sub(b.updatedAt) >= b.period {
+			delete(s.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package ratelimit
+
+import (
+	"time"
+
+	"github.com/nodeloc-faka/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// MySQLStore MySQL 令牌桶存储，多实例部署时共享限流状态
+type MySQLStore struct {
+	db *gorm.DB
+}
+
+// NewMySQLStore 创建 MySQL 存储
+func NewMySQLStore(db *gorm.DB) *MySQLStore {
+	return &MySQLStore{db: db}
+}
+
+// Take 从令牌桶中取一个令牌（行锁保证多实例并发安全）
+func (s *MySQLStore) Take(key string, rule Rule, now time.Time) (Result, error) {
+	var result Result
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		// 不存在时先插入满的令牌桶，已存在则忽略
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.RateLimitBucket{
+			Key:       key,
+			Tokens:    float64(rule.Limit),
+			UpdatedAt: now,
+		}).Error; err != nil {
+			return err
+		}
+
+		var row models.RateLimitBucket
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("`key` = ?", key).
+			First(&row).Error; err != nil {
+			return err
+		}
+
+		b := &bucket{tokens: row.Tokens, updatedAt: row.UpdatedAt}
+		result = b.take(rule, now)
+		return tx.Model(&models.RateLimitBucket{}).
+			Where("`key` = ?", key).
+			Updates(map[string]interface{}{"tokens": b.tokens, "updated_at": b.updatedAt}).Error
+	})
+	return result, err
+}
+
+// Cleanup 删除闲置超过 idle 的令牌桶（闲置足够久的桶已补满，删除不影响限流结果）
+func (s *MySQLStore) Cleanup(idle time.Duration) (int64, error) {
+	result := s.db.Where("updated_at < ?", time.Now().Add(-idle)).Delete(&models.RateLimitBucket{})
+	return result.RowsAffected, result.Error
+}
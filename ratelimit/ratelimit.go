@@ -0,0 +1,105 @@
+// Package ratelimit 提供基于令牌桶的限流，支持内存和 MySQL 两种存储后端
+package ratelimit
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Rule 限流规则：每 Period 最多 Limit 次，令牌按匀速补充，允许最多 Limit 次的突发
+type Rule struct {
+	Name   string
+	Limit  int
+	Period time.Duration
+}
+
+// String 规则的文本形式，如 10/1m0s
+func (r Rule) String() string {
+	return fmt.Sprintf("%d/%s", r.Limit, r.Period)
+}
+
+// Result 一次取令牌的结果
+type Result struct {
+	Allowed    bool
+	Remaining  int           // 剩余可用次数
+	RetryAfter time.Duration // 被拒绝时距离下一个令牌的时间
+}
+
+// Store 限流存储后端
+type Store interface {
+	// Take 从 key 对应的令牌桶中取一个令牌
+	Take(key string, rule Rule, now time.Time) (Result, error)
+}
+
+// bucket 令牌桶状态
+type bucket struct {
+	tokens    float64
+	updatedAt time.Time
+	period    time.Duration // 闲置超过一个周期后令牌必然补满
+}
+
+// take 按经过的时间补充令牌后尝试取出一个
+func (b *bucket) take(rule Rule, now time.Time) Result {
+	rate := float64(rule.Limit) / rule.Period.Seconds()
+	if elapsed := now.Sub(b.updatedAt).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(float64(rule.Limit), b.tokens+elapsed*rate)
+	}
+	b.updatedAt = now
+	b.period = rule.Period
+
+	if b.tokens < 1 {
+		wait := time.Duration((1 - b.tokens) / rate * float64(time.Second))
+		return Result{Allowed: false, RetryAfter: wait}
+	}
+	b.tokens--
+	return Result{Allowed: true, Remaining: int(b.tokens)}
+}
+
+// ParseRule 解析规则文本，格式为 次数/周期，如 10/1m、300/1h
+func ParseRule(name, spec string) (Rule, error) {
+	parts := strings.SplitN(strings.TrimSpace(spec), "/", 2)
+	if len(parts) != 2 {
+		return Rule{}, fmt.Errorf("限流规则格式错误: %s", spec)
+	}
+	limit, err := strconv.Atoi(parts[0])
+	if err != nil || limit <= 0 {
+		return Rule{}, fmt.Errorf("限流次数无效: %s", spec)
+	}
+	period, err := time.ParseDuration(parts[1])
+	if err != nil || period <= 0 {
+		return Rule{}, fmt.Errorf("限流周期无效: %s", spec)
+	}
+	return Rule{Name: name, Limit: limit, Period: period}, nil
+}
+
+// DefaultRules 内置规则的默认配置
+var DefaultRules = map[string]string{
+	"order_create": "10/1m",  // 按用户：创建订单、重新支付
+	"order_query":  "30/1m",  // 按用户：查询支付状态
+	"public":       "300/1m", // 按 IP：公开 API
+	"login":        "20/15m", // 按 IP：本地登录及两步验证
+}
+
+// LoadRules 在默认规则上应用覆盖配置，返回按名称索引的规则
+func LoadRules(overrides map[string]string) (map[string]Rule, error) {
+	rules := make(map[string]Rule, len(DefaultRules))
+	for name, spec := range DefaultRules {
+		if override, ok := overrides[name]; ok {
+			spec = override
+		}
+		rule, err := ParseRule(name, spec)
+		if err != nil {
+			return nil, err
+		}
+		rules[name] = rule
+	}
+	for name := range overrides {
+		if _, ok := DefaultRules[name]; !ok {
+			return nil, fmt.Errorf("未知的限流规则: %s", name)
+		}
+	}
+	return rules, nil
+}
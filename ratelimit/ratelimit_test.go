@@ -0,0 +1,91 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nodeloc-faka/database/dbtest"
+)
+
+// take 表示在起始时间之后 at 取一次令牌，期望结果为 allowed
+type take struct {
+	key     string
+	at      time.Duration
+	allowed bool
+}
+
+func TestStoreTokenBucket(t *testing.T) {
+	rule := Rule{Name: "test", Limit: 3, Period: time.Minute} // 每 20 秒补充一个令牌
+	tests := []struct {
+		name  string
+		takes []take
+	}{
+		{"burst up to limit", []take{
+			{"a", 0, true}, {"a", 0, true}, {"a", 0, true}, {"a", 0, false},
+		}},
+		{"refills at constant rate", []take{
+			{"a", 0, true}, {"a", 0, true}, {"a", 0, true},
+			{"a", 10 * time.Second, false},
+			{"a", 20 * time.Second, true},
+			{"a", 20 * time.Second, false},
+		}},
+		{"refill is capped at limit", []take{
+			{"a", 0, true},
+			{"a", time.Hour, true}, {"a", time.Hour, true}, {"a", time.Hour, true}, {"a", time.Hour, false},
+		}},
+		{"keys are independent", []take{
+			{"a", 0, true}, {"a", 0, true}, {"a", 0, true}, {"a", 0, false},
+			{"b", 0, true},
+		}},
+	}
+	stores := []struct {
+		name string
+		open func(t *testing.T) Store
+	}{
+		{"memory", func(t *testing.T) Store { return NewMemoryStore() }},
+		{"mysql", func(t *testing.T) Store { return NewMySQLStore(dbtest.Open(t)) }},
+	}
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, store := range stores {
+		for _, tt := range tests {
+			t.Run(store.name+"/"+tt.name, func(t *testing.T) {
+				s := store.open(t)
+				for i, step := range tt.takes {
+					result, err := s.Take(step.key, rule, start.Add(step.at))
+					if err != nil {
+						t.Fatal(err)
+					}
+					if result.Allowed != step.allowed {
+						t.Fatalf("第 %d 次取令牌 allowed = %v，期望 %v", i+1, result.Allowed, step.allowed)
+					}
+					if !result.Allowed && (result.RetryAfter <= 0 || result.RetryAfter > 20*time.Second) {
+						t.Fatalf("第 %d 次取令牌 RetryAfter = %v，期望在一个补充间隔内", i+1, result.RetryAfter)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestParseRule(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    Rule
+		wantErr bool
+	}{
+		{"10/1m", Rule{Name: "r", Limit: 10, Period: time.Minute}, false},
+		{" 300/1h ", Rule{Name: "r", Limit: 300, Period: time.Hour}, false},
+		{"10", Rule{}, true},
+		{"0/1m", Rule{}, true},
+		{"10/0s", Rule{}, true},
+		{"10/minute", Rule{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			got, err := ParseRule("r", tt.spec)
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Fatalf("ParseRule(%q) = %v, %v", tt.spec, got, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package scheduler
+
+import (
+	"sync"
+	"time"
+
+	"github.com/nodeloc-faka/logging"
+)
+
+// logger 定时任务日志
+var logger = logging.For("scheduler")
+
+// Scheduler 简单的定时任务调度器，每个任务在独立的 goroutine 中按固定间隔执行
+type Scheduler struct {
+	jobs    []*job
+	lastRun map[string]time.Time
+	started time.Time
+	mu      sync.RWMutex
+	stop    chan struct{}
+}
+
+// job 定时任务
+type job struct {
+	name     string
+	interval time.Duration
+	fn       func()
+}
+
+// New 创建调度器
+func New() *Scheduler {
+	return &Scheduler{
+		lastRun: make(map[string]time.Time),
+		stop:    make(chan struct{}),
+	}
+}
+
+// Every 注册按固定间隔执行的任务（需在 Start 之前调用）
+func (s *Scheduler) Every(name string, interval time.Duration, fn func()) {
+	s.jobs = append(s.jobs, &job{name: name, interval: interval, fn: fn})
+}
+
+// Start 启动所有任务，任务会在启动时立即执行一次
+func (s *Scheduler) Start() {
+	s.mu.Lock()
+	s.started = time.Now()
+	s.mu.Unlock()
+	for _, j := range s.jobs {
+		go s.loop(j)
+	}
+}
+
+// Stop 停止所有任务
+func (s *Scheduler) Stop() {
+	close(s.stop)
+}
+
+// LastRun 获取任务最近一次执行完成的时间
+func (s *Scheduler) LastRun(name string) time.Time {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.lastRun[name]
+}
+
+// Overdue 检查任务心跳，返回超过两个执行间隔（另加一分钟余量）仍未完成一次执行的任务名称
+// 执行异常的任务不会更新完成时间，同样视为逾期；调度器未启动时返回全部任务
+func (s *Scheduler) Overdue() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var overdue []string
+	for _, j := range s.jobs {
+		last, ok := s.lastRun[j.name]
+		if !ok {
+			last = s.started
+		}
+		if last.IsZero() || time.Since(last) > 2*j.interval+time.Minute {
+			overdue = append(overdue, j.name)
+		}
+	}
+	return overdue
+}
+
+// loop 任务循环
+func (s *Scheduler) loop(j *job) {
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	s.run(j)
+	for {
+		select {
+		case <-ticker.C:
+			s.run(j)
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+// run 执行一次任务，捕获 panic 避免影响其他任务
+func (s *Scheduler) run(j *job) {
+	defer func() {
+		if r := recover(); r != nil {
+			logger.Error("定时任务执行异常", "job", j.name, "panic", r)
+		}
+	}()
+
+	start := time.Now()
+	j.fn()
+	logger.Debug("定时任务执行完成", "job", j.name, "duration", time.Since(start))
+
+	s.mu.Lock()
+	s.lastRun[j.name] = time.Now()
+	s.mu.Unlock()
+}
@@ -0,0 +1,154 @@
+package services
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/nodeloc-faka/database"
+	"github.com/nodeloc-faka/models"
+)
+
+// AbuseBlockCacheTTL 封禁状态在进程内缓存的时间，多实例部署时其他实例的解封最多延迟这么久生效
+const AbuseBlockCacheTTL = 15 * time.Second
+
+// abuseBlockCacheLimit 缓存条目上限，超过时清理过期条目
+const abuseBlockCacheLimit = 10000
+
+// abuseBlockEntry 缓存的封禁状态，block 为 nil 表示未封禁
+type abuseBlockEntry struct {
+	block     *models.RateLimitBlock
+	expiresAt time.Time
+}
+
+// abuseBlockCache 封禁状态缓存，避免每个请求都查询数据库
+var abuseBlockCache = struct {
+	sync.RWMutex
+	entries map[string]abuseBlockEntry
+}{entries: make(map[string]abuseBlockEntry)}
+
+// AbuseService 滥用防护服务：管理触发限流阈值后的临时封禁
+type AbuseService struct{}
+
+// NewAbuseService 创建滥用防护服务
+func NewAbuseService() *AbuseService {
+	return &AbuseService{}
+}
+
+// ActiveBlock 获取 key 当前生效的封禁记录，结果缓存 AbuseBlockCacheTTL
+func (s *AbuseService) ActiveBlock(key string) (*models.RateLimitBlock, bool) {
+	now := time.Now()
+
+	abuseBlockCache.RLock()
+	entry, ok := abuseBlockCache.entries[key]
+	abuseBlockCache.RUnlock()
+	if !ok || now.After(entry.expiresAt) {
+		entry = abuseBlockEntry{expiresAt: now.Add(AbuseBlockCacheTTL)}
+		var block models.RateLimitBlock
+		if err := database.GetDB().
+			Where("`key` = ? AND blocked_until > ?", key, now).
+			Order("blocked_until desc").
+			First(&block).Error; err == nil {
+			entry.block = &block
+		}
+		cacheAbuseBlock(key, entry)
+	}
+
+	if entry.block == nil || !entry.block.BlockedUntil.After(now) {
+		return nil, false
+	}
+	return entry.block, true
+}
+
+// cacheAbuseBlock 写入封禁状态缓存
+func cacheAbuseBlock(key string, entry abuseBlockEntry) {
+	abuseBlockCache.Lock()
+	defer abuseBlockCache.Unlock()
+
+	if len(abuseBlockCache.entries) >= abuseBlockCacheLimit {
+		now := time.Now()
+		for k, e := range abuseBlockCache.entries {
+			if now.After(e.expiresAt) {
+				delete(abuseBlockCache.entries, k)
+			}
+		}
+		if len(abuseBlockCache.entries) >= abuseBlockCacheLimit {
+			abuseBlockCache.entries = make(map[string]abuseBlockEntry)
+		}
+	}
+	abuseBlockCache.entries[key] = entry
+}
+
+// forgetAbuseBlock 清除封禁记录 id 对应的缓存
+func forgetAbuseBlock(id uint) {
+	abuseBlockCache.Lock()
+	defer abuseBlockCache.Unlock()
+
+	for k, e := range abuseBlockCache.entries {
+		if e.block != nil && e.block.ID == id {
+			delete(abuseBlockCache.entries, k)
+		}
+	}
+}
+
+// Block 临时封禁 key
+func (s *AbuseService) Block(key, rule, reason string, duration time.Duration) (*models.RateLimitBlock, error) {
+	block := &models.RateLimitBlock{
+		Key:          key,
+		Rule:         rule,
+		Reason:       reason,
+		BlockedUntil: time.Now().Add(duration),
+	}
+	if err := database.GetDB().Create(block).Error; err != nil {
+		return nil, err
+	}
+	cacheAbuseBlock(key, abuseBlockEntry{block: block, expiresAt: time.Now().Add(AbuseBlockCacheTTL)})
+	return block, nil
+}
+
+// Unblock 提前解除封禁（保留记录）
+func (s *AbuseService) Unblock(id uint) error {
+	result := database.GetDB().Model(&models.RateLimitBlock{}).
+		Where("id = ? AND blocked_until > ?", id, time.Now()).
+		Update("blocked_until", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrBlockNotActive
+	}
+	forgetAbuseBlock(id)
+	return nil
+}
+
+// rateLimitBlockSorts 封禁记录列表允许的排序字段
+var rateLimitBlockSorts = listSorts{
+	table: "rate_limit_blocks",
+	columns: map[string]string{
+		"id":            "rate_limit_blocks.id",
+		"created_at":    "rate_limit_blocks.created_at",
+		"blocked_until": "rate_limit_blocks.blocked_until",
+	},
+	def: "-id",
+}
+
+// GetWithPagination 分页获取封禁记录（activeOnly 为 true 时只返回生效中的记录）
+func (s *AbuseService) GetWithPagination(activeOnly bool, query ListQuery) ([]models.RateLimitBlock, ListPage, error) {
+	var blocks []models.RateLimitBlock
+
+	db := database.GetDB().Model(&models.RateLimitBlock{})
+	if activeOnly {
+		db = db.Where("blocked_until > ?", time.Now())
+	}
+	page, err := paginate(db, query, rateLimitBlockSorts, &blocks)
+	if err != nil {
+		return nil, ListPage{}, err
+	}
+	return blocks, page, nil
+}
+
+// 错误定义
+var (
+	ErrBlockNotActive = &ServiceError{Code: "block_not_active", Status: http.StatusNotFound, Message: "封禁记录不存在或已失效"}
+	ErrAbuseBlocked   = &ServiceError{Code: "abuse_blocked", Status: http.StatusTooManyRequests, Message: "请求过于频繁，已被临时限制访问，请稍后再试"}
+)
@@ -2,11 +2,15 @@ package services
 
 import (
 	"errors"
+	"net/http"
+	"sync"
 	"time"
 
 	"github.com/nodeloc-faka/config"
 	"github.com/nodeloc-faka/database"
 	"github.com/nodeloc-faka/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // AdminService 管理员服务
@@ -54,25 +58,222 @@ func (s *AdminService) FindByID(id uint) (*models.Admin, error) {
 	return &admin, nil
 }
 
+// 登录失败锁定策略
+const (
+	MaxFailedLogins   = 5                // 连续失败次数达到该值后锁定账号
+	LoginLockDuration = 15 * time.Minute // 锁定时长
+)
+
+var (
+	dummyHashOnce sync.Once
+	dummyHash     string
+)
+
 // Verify 验证管理员登录
+// 连续失败 MaxFailedLogins 次后锁定 LoginLockDuration，锁定期间即使密码正确也拒绝登录
 func (s *AdminService) Verify(username, password string) (*models.Admin, error) {
 	admin, err := s.FindByUsername(username)
 	if err != nil {
-		return nil, errors.New("用户名或密码错误")
+		// 用户名不存在时同样执行一次哈希比较，避免通过响应时间探测用户名
+		dummyHashOnce.Do(func() { dummyHash, _ = config.HashPassword("dummy-password") })
+		config.CheckPassword(password, dummyHash)
+		return nil, ErrInvalidCredentials
+	}
+
+	if admin.LockedUntil != nil && admin.LockedUntil.After(time.Now()) {
+		return nil, ErrAccountLocked
 	}
 
 	if !config.CheckPassword(password, admin.PasswordHash) {
-		return nil, errors.New("用户名或密码错误")
+		if err := s.recordFailure(admin.ID); err != nil {
+			return nil, err
+		}
+		return nil, ErrInvalidCredentials
+	}
+
+	return admin, nil
+}
+
+// VerifyTwoFactor 校验本地账号登录的第二步验证码
+// 验证码错误与密码错误一起计入连续失败次数，锁定期间拒绝验证；
+// 没有本地账号的后台人员（仅 OAuth 登录）只校验验证码
+func (s *AdminService) VerifyTwoFactor(userID uint, code string) error {
+	admin, err := s.FindByUserID(userID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	if admin == nil {
+		return NewTwoFactorService().Verify(userID, code)
+	}
+
+	if admin.LockedUntil != nil && admin.LockedUntil.After(time.Now()) {
+		return ErrAccountLocked
+	}
+	if err := NewTwoFactorService().Verify(userID, code); err != nil {
+		if errors.Is(err, ErrInvalidTwoFactorCode) {
+			if err := s.recordFailure(admin.ID); err != nil {
+				return err
+			}
+		}
+		return err
+	}
+	return s.recordSuccess(admin.ID)
+}
+
+// recordSuccess 登录成功，清除失败记录并更新最后登录时间
+func (s *AdminService) recordSuccess(id uint) error {
+	return database.GetDB().Model(&models.Admin{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"failed_logins": 0,
+		"locked_until":  nil,
+		"last_login_at": time.Now(),
+	}).Error
+}
+
+// recordFailure 记录一次登录失败，达到上限时锁定账号
+func (s *AdminService) recordFailure(id uint) error {
+	return database.GetDB().Transaction(func(tx *gorm.DB) error {
+		var admin models.Admin
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&admin, id).Error; err != nil {
+			return err
+		}
+		updates := map[string]interface{}{"failed_logins": admin.FailedLogins + 1}
+		if admin.FailedLogins+1 >= MaxFailedLogins {
+			updates["failed_logins"] = 0
+			updates["locked_until"] = time.Now().Add(LoginLockDuration)
+		}
+		return tx.Model(&admin).Updates(updates).Error
+	})
+}
+
+// Unlock 解除后台人员本地账号的登录锁定
+func (s *AdminService) Unlock(userID uint) error {
+	return database.GetDB().Model(&models.Admin{}).
+		Where("user_id = ?", userID).
+		Updates(map[string]interface{}{"failed_logins": 0, "locked_until": nil}).Error
+}
+
+// Bootstrap 创建第一个管理员：一个本地所有者账号及其登录凭证
+// 已存在所有者时拒绝执行，避免被用来提权
+func (s *AdminService) Bootstrap(username, password string) (*models.Admin, error) {
+	if username == "" {
+		return nil, ErrAdminUsernameRequired
+	}
+	if len(password) < MinAdminPasswordLength {
+		return nil, ErrAdminPasswordTooShort
+	}
+	hash, err := config.HashPassword(password)
+	if err != nil {
+		return nil, err
+	}
+
+	admin := &models.Admin{Username: username, PasswordHash: hash}
+	err = database.GetDB().Transaction(func(tx *gorm.DB) error {
+		var owners int64
+		tx.Model(&models.User{}).Where("role = ?", models.RoleOwner).Count(&owners)
+		if owners > 0 {
+			return ErrAlreadyBootstrapped
+		}
+
+		var taken int64
+		tx.Model(&models.Admin{}).Where("username = ?", username).Count(&taken)
+		if taken > 0 {
+			return ErrAdminUsernameTaken
+		}
+
+		user, err := NewUserService().createLocal(tx, username, models.RoleOwner)
+		if err != nil {
+			return err
+		}
+		admin.UserID = &user.ID
+		return tx.Create(admin).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return admin, nil
+}
+
+// Authenticate 本地账号登录，返回关联的后台人员用户
+func (s *AdminService) Authenticate(username, password string) (*models.User, error) {
+	admin, err := s.Verify(username, password)
+	if err != nil {
+		return nil, err
+	}
+	if admin.UserID == nil {
+		return nil, ErrInvalidCredentials
+	}
+	user, err := NewUserService().FindByID(*admin.UserID)
+	if err != nil || !user.IsStaff() {
+		return nil, ErrInvalidCredentials
+	}
+	if user.IsBlocked {
+		return nil, ErrUserBlocked
+	}
+	// 启用两步验证的账号在验证码通过后才算登录成功，此前保留失败次数，
+	// 避免反复提交正确密码来清零验证码的失败次数
+	if !user.TOTPEnabled {
+		if err := s.recordSuccess(admin.ID); err != nil {
+			return nil, err
+		}
+	}
+	return user, nil
+}
+
+// FindByUserID 根据关联用户查找本地账号
+func (s *AdminService) FindByUserID(userID uint) (*models.Admin, error) {
+	var admin models.Admin
+	if err := database.GetDB().Where("user_id = ?", userID).First(&admin).Error; err != nil {
+		return nil, err
+	}
+	return &admin, nil
+}
+
+// SetCredential 为后台人员设置本地登录账号（已存在则更新用户名和密码）
+func (s *AdminService) SetCredential(userID uint, username, password string) (*models.Admin, error) {
+	user, err := NewUserService().FindByID(userID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+	if !user.IsStaff() {
+		return nil, ErrNotStaff
+	}
+	if username == "" {
+		return nil, ErrAdminUsernameRequired
+	}
+	if len(password) < MinAdminPasswordLength {
+		return nil, ErrAdminPasswordTooShort
+	}
+
+	var count int64
+	database.GetDB().Model(&models.Admin{}).
+		Where("username = ? AND (user_id IS NULL OR user_id <> ?)", username, userID).
+		Count(&count)
+	if count > 0 {
+		return nil, ErrAdminUsernameTaken
 	}
 
-	// 更新最后登录时间
-	now := time.Now()
-	admin.LastLoginAt = &now
-	database.GetDB().Save(admin)
+	hash, err := config.HashPassword(password)
+	if err != nil {
+		return nil, err
+	}
 
+	admin, err := s.FindByUserID(userID)
+	if err != nil {
+		admin = &models.Admin{UserID: &user.ID}
+	}
+	admin.Username = username
+	admin.PasswordHash = hash
+	if err := database.GetDB().Save(admin).Error; err != nil {
+		return nil, err
+	}
 	return admin, nil
 }
 
+// RemoveCredential 删除后台人员的本地登录账号
+func (s *AdminService) RemoveCredential(userID uint) error {
+	return database.GetDB().Where("user_id = ?", userID).Delete(&models.Admin{}).Error
+}
+
 // UpdatePassword 更新密码
 func (s *AdminService) UpdatePassword(id uint, newPassword string) error {
 	hash, err := config.HashPassword(newPassword)
@@ -103,3 +304,18 @@ func (s *AdminService) Count() int64 {
 	database.GetDB().Model(&models.Admin{}).Count(&count)
 	return count
 }
+
+// MinAdminPasswordLength 本地账号密码最小长度
+const MinAdminPasswordLength = 8
+
+// 错误定义
+var (
+	ErrInvalidCredentials    = &ServiceError{Code: "invalid_credentials", Status: http.StatusUnauthorized, Message: "用户名或密码错误"}
+	ErrUserBlocked           = &ServiceError{Code: "user_blocked", Status: http.StatusForbidden, Message: "账号已被封禁"}
+	ErrNotStaff              = &ServiceError{Code: "not_staff", Status: http.StatusForbidden, Message: "该用户不是后台人员"}
+	ErrAdminUsernameRequired = &ServiceError{Code: "admin_username_required", Message: "请输入用户名"}
+	ErrAdminPasswordTooShort = &ServiceError{Code: "admin_password_too_short", Message: "密码至少 8 位"}
+	ErrAdminUsernameTaken    = &ServiceError{Code: "admin_username_taken", Status: http.StatusConflict, Message: "用户名已被使用"}
+	ErrAccountLocked         = &ServiceError{Code: "account_locked", Status: http.StatusLocked, Message: "登录失败次数过多，账号已临时锁定，请稍后再试"}
+	ErrAlreadyBootstrapped   = &ServiceError{Code: "already_bootstrapped", Status: http.StatusConflict, Message: "已存在所有者，无需初始化管理员"}
+)
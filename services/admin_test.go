@@ -0,0 +1,161 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/nodeloc-faka/models"
+	"github.com/nodeloc-faka/totp"
+)
+
+const testAdminPassword = "correct-password"
+
+// staff 创建带本地登录账号的后台人员，totpEnabled 时同时启用两步验证
+func (s *testStore) staff(totpEnabled bool) (*models.User, string) {
+	s.t.Helper()
+	user := s.user(0)
+	updates := map[string]interface{}{"role": models.RoleOperator}
+	var secret string
+	if totpEnabled {
+		var err error
+		if secret, err = totp.GenerateSecret(); err != nil {
+			s.t.Fatal(err)
+		}
+		updates["totp_secret"] = secret
+		updates["totp_enabled"] = true
+	}
+	if err := s.db.Model(user).Updates(updates).Error; err != nil {
+		s.t.Fatal(err)
+	}
+	if _, err := NewAdminService().SetCredential(user.ID, user.Username, testAdminPassword); err != nil {
+		s.t.Fatal(err)
+	}
+	return user, secret
+}
+
+// totpCode 生成 offset 个周期后的验证码
+func totpCode(t *testing.T, secret string, offset int64) string {
+	t.Helper()
+	code, err := totp.Code(secret, totp.Step(time.Now())+offset)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return code
+}
+
+func TestAuthenticateLocksAccount(t *testing.T) {
+	tests := []struct {
+		name     string
+		failures int
+		want     error
+	}{
+		{"no failures", 0, nil},
+		{"below limit", MaxFailedLogins - 1, nil},
+		{"limit reached", MaxFailedLogins, ErrAccountLocked},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newTestStore(t)
+			user, _ := store.staff(false)
+			admins := NewAdminService()
+
+			for i := 0; i < tt.failures; i++ {
+				if _, err := admins.Authenticate(user.Username, "wrong-password"); !errors.Is(err, ErrInvalidCredentials) {
+					t.Fatalf("第 %d 次错误密码返回 %v", i+1, err)
+				}
+			}
+			if _, err := admins.Authenticate(user.Username, testAdminPassword); !errors.Is(err, tt.want) {
+				t.Fatalf("正确密码返回 %v，期望 %v", err, tt.want)
+			}
+
+			admin, err := admins.FindByUserID(user.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.want == nil && (admin.FailedLogins != 0 || admin.LastLoginAt == nil) {
+				t.Fatalf("登录成功后失败次数为 %d，最后登录时间 %v", admin.FailedLogins, admin.LastLoginAt)
+			}
+
+			// 解锁后可以再次登录
+			if err := admins.Unlock(user.ID); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := admins.Authenticate(user.Username, testAdminPassword); err != nil {
+				t.Fatalf("解锁后登录返回 %v", err)
+			}
+		})
+	}
+}
+
+func TestAdminVerifyTwoFactor(t *testing.T) {
+	tests := []struct {
+		name string
+		// codes 依次提交的验证码，最后一个的结果与 want 比较
+		codes        func(secret string) []string
+		want         error
+		wantFailures int
+	}{
+		{"valid code", func(secret string) []string {
+			return []string{totpCode(t, secret, 0)}
+		}, nil, 0},
+		{"valid code clears earlier failures", func(secret string) []string {
+			return []string{totpCode(t, secret, 10), totpCode(t, secret, 0)}
+		}, nil, 0},
+		{"wrong code counts as failed login", func(secret string) []string {
+			return []string{totpCode(t, secret, 10)}
+		}, ErrInvalidTwoFactorCode, 1},
+		{"replayed code is rejected", func(secret string) []string {
+			code := totpCode(t, secret, 0)
+			return []string{code, code}
+		}, ErrInvalidTwoFactorCode, 1},
+		{"too many wrong codes lock the account", func(secret string) []string {
+			var codes []string
+			for i := 0; i < MaxFailedLogins; i++ {
+				codes = append(codes, totpCode(t, secret, 10))
+			}
+			return append(codes, totpCode(t, secret, 0))
+		}, ErrAccountLocked, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newTestStore(t)
+			user, secret := store.staff(true)
+			admins := NewAdminService()
+
+			var err error
+			for _, code := range tt.codes(secret) {
+				err = admins.VerifyTwoFactor(user.ID, code)
+			}
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("VerifyTwoFactor = %v，期望 %v", err, tt.want)
+			}
+			admin, err := admins.FindByUserID(user.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if admin.FailedLogins != tt.wantFailures {
+				t.Fatalf("失败次数为 %d，期望 %d", admin.FailedLogins, tt.wantFailures)
+			}
+		})
+	}
+}
+
+func TestPasswordDoesNotResetTwoFactorFailures(t *testing.T) {
+	store := newTestStore(t)
+	user, secret := store.staff(true)
+	admins := NewAdminService()
+
+	// 每次重新提交正确密码都不会清零验证码的失败次数
+	for i := 0; i < MaxFailedLogins; i++ {
+		if _, err := admins.Authenticate(user.Username, testAdminPassword); err != nil {
+			t.Fatalf("第 %d 次密码登录返回 %v", i+1, err)
+		}
+		if err := admins.VerifyTwoFactor(user.ID, totpCode(t, secret, 10)); !errors.Is(err, ErrInvalidTwoFactorCode) {
+			t.Fatalf("第 %d 次错误验证码返回 %v", i+1, err)
+		}
+	}
+	if _, err := admins.Authenticate(user.Username, testAdminPassword); !errors.Is(err, ErrAccountLocked) {
+		t.Fatalf("验证码失败 %d 次后密码登录返回 %v，期望账号锁定", MaxFailedLogins, err)
+	}
+}
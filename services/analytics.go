@@ -0,0 +1,579 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/nodeloc-faka/config"
+	"github.com/nodeloc-faka/database"
+	"github.com/nodeloc-faka/models"
+	"gorm.io/gorm"
+)
+
+// AnalyticsService 销售统计服务
+// 时间序列、商品和分类统计读取每日销售汇总（SalesDailyStat），汇总由定时任务调用 Refresh 维护；
+// 查询时区与统计时区不同时日期边界不一致，无法使用汇总，直接从订单表统计
+type AnalyticsService struct {
+	settingService *SettingService
+	location       *time.Location
+}
+
+// NewAnalyticsService 创建销售统计服务
+func NewAnalyticsService() *AnalyticsService {
+	return &AnalyticsService{
+		settingService: NewSettingService(),
+		location:       analyticsLocation(),
+	}
+}
+
+// 统计周期
+const (
+	AnalyticsIntervalDay   = "day"
+	AnalyticsIntervalWeek  = "week" // 周一开始
+	AnalyticsIntervalMonth = "month"
+)
+
+// 统计范围和排行条数限制
+const (
+	DefaultAnalyticsDays  = 30
+	MaxAnalyticsDays      = 366
+	DefaultAnalyticsLimit = 10
+	MaxAnalyticsLimit     = 100
+)
+
+// analyticsRefreshOverlap 查找有变动的订单时向前多查的时间，容忍应用与数据库之间的时钟偏差
+const analyticsRefreshOverlap = 5 * time.Minute
+
+// dayLayout 统计日期格式
+const dayLayout = "2006-01-02"
+
+// AnalyticsQuery 统计条件
+type AnalyticsQuery struct {
+	From     string // 开始日期（含），为空时为结束日期前 29 天
+	To       string // 结束日期（含），为空时为今天
+	Interval string // 时间序列的周期，为空时按天
+	Timezone string // 划分日期的时区（IANA 名称），为空时使用统计时区
+	Limit    int    // 排行条数
+}
+
+// SalesTotals 销售指标
+// 订单按下单时间归入统计周期；已支付指状态为已支付或已完成，退款订单不计入销售额
+type SalesTotals struct {
+	Orders          int64   `json:"orders"`                  // 下单数
+	PaidOrders      int64   `json:"paid_orders"`             // 已支付订单数
+	CancelledOrders int64   `json:"cancelled_orders"`        // 已取消订单数
+	UnitsSold       int64   `json:"units_sold"`              // 售出数量
+	Revenue         float64 `json:"revenue"`                 // 销售额
+	PlatformFee     int64   `json:"platform_fee"`            // 平台手续费
+	MerchantNet     int64   `json:"merchant_net"`            // 商家实收积分
+	ConversionRate  float64 `json:"conversion_rate"`         // 支付转化率：已支付订单数 / 下单数
+	AvgTimeToPay    float64 `json:"avg_time_to_pay_seconds"` // 平均支付耗时（秒）
+	PaySeconds      int64   `json:"-"`
+	TimedOrders     int64   `json:"-"`
+}
+
+// SalesBucket 时间序列中的一个周期
+type SalesBucket struct {
+	Period string `json:"period"` // 周期的起始日期，第一个周期从统计开始日期算起
+	SalesTotals
+}
+
+// SalesReport 销售时间序列
+type SalesReport struct {
+	From        string        `json:"from"`
+	To          string        `json:"to"`
+	Interval    string        `json:"interval"`
+	Timezone    string        `json:"timezone"`
+	Aggregated  bool          `json:"aggregated"`             // 是否读取每日汇总（最多滞后一个更新周期）
+	RefreshedAt *time.Time    `json:"refreshed_at,omitempty"` // 每日汇总最近一次更新的时间
+	Totals      SalesTotals   `json:"totals"`
+	Buckets     []SalesBucket `json:"buckets"`
+}
+
+// ProductSales 商品销售统计
+type ProductSales struct {
+	ProductID   uint   `json:"product_id"`
+	ProductName string `json:"product_name"`
+	SalesTotals
+}
+
+// CategorySales 分类销售统计（按商品当前所属分类）
+type CategorySales struct {
+	CategoryID   uint   `json:"category_id"`
+	CategoryName string `json:"category_name"`
+	SalesTotals
+}
+
+// BuyerSales 买家消费统计
+type BuyerSales struct {
+	UserID   uint   `json:"user_id"`
+	Username string `json:"username"`
+	Name     string `json:"name"`
+	SalesTotals
+}
+
+// dailySales 每日汇总按日期合计的结果
+type dailySales struct {
+	Day string
+	SalesTotals
+}
+
+// analyticsRange 解析后的统计范围
+type analyticsRange struct {
+	location   *time.Location
+	start      time.Time // 开始日期零点
+	end        time.Time // 结束日期次日零点
+	aggregated bool      // 是否可以读取每日汇总
+}
+
+// analyticsWindow 时间序列中一个周期的起止时间
+type analyticsWindow struct {
+	start time.Time
+	end   time.Time
+}
+
+// Sales 按周期统计销售指标
+func (s *AnalyticsService) Sales(query AnalyticsQuery) (*SalesReport, error) {
+	interval := query.Interval
+	if interval == "" {
+		interval = AnalyticsIntervalDay
+	}
+	if interval != AnalyticsIntervalDay && interval != AnalyticsIntervalWeek && interval != AnalyticsIntervalMonth {
+		return nil, ErrInvalidAnalyticsInterval
+	}
+	r, err := s.resolve(query)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &SalesReport{
+		From:       r.firstDay(),
+		To:         r.lastDay(),
+		Interval:   interval,
+		Timezone:   r.location.String(),
+		Aggregated: r.aggregated,
+		Buckets:    []SalesBucket{},
+	}
+
+	var daily map[string]SalesTotals
+	if r.aggregated {
+		report.RefreshedAt = s.RefreshedAt()
+		var rows []dailySales
+		if err := database.GetDB().Model(&models.SalesDailyStat{}).
+			Select("day, "+statTotalsColumns("")).
+			Where("day >= ? AND day <= ?", r.firstDay(), r.lastDay()).
+			Group("day").
+			Scan(&rows).Error; err != nil {
+			return nil, err
+		}
+		daily = make(map[string]SalesTotals, len(rows))
+		for _, row := range rows {
+			daily[row.Day] = row.SalesTotals
+		}
+	}
+
+	for _, window := range r.windows(interval) {
+		bucket := SalesBucket{Period: window.start.Format(dayLayout)}
+		if r.aggregated {
+			for day := window.start; day.Before(window.end); day = day.AddDate(0, 0, 1) {
+				bucket.add(daily[day.Format(dayLayout)])
+			}
+		} else if err := database.GetDB().Model(&models.Order{}).
+			Select(orderTotalsColumns("")).
+			Where("created_at >= ? AND created_at < ?", window.start, window.end).
+			Scan(&bucket.SalesTotals).Error; err != nil {
+			return nil, err
+		}
+		report.Totals.add(bucket.SalesTotals)
+		bucket.finish()
+		report.Buckets = append(report.Buckets, bucket)
+	}
+	report.Totals.finish()
+	return report, nil
+}
+
+// Products 按商品统计，按销售额降序
+func (s *AnalyticsService) Products(query AnalyticsQuery) ([]ProductSales, error) {
+	r, err := s.resolve(query)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []ProductSales
+	if err := s.grouped(r, r.aggregated, "s.product_id").
+		Limit(analyticsLimit(query.Limit)).
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	ids := make([]uint, len(rows))
+	for i, row := range rows {
+		ids[i] = row.ProductID
+	}
+	var products []models.Product
+	if err := database.GetDB().Unscoped().Select("id, name").Where("id IN ?", ids).Find(&products).Error; err != nil {
+		return nil, err
+	}
+	names := make(map[uint]string, len(products))
+	for _, product := range products {
+		names[product.ID] = product.Name
+	}
+
+	for i := range rows {
+		rows[i].ProductName = names[rows[i].ProductID]
+		rows[i].finish()
+	}
+	return rows, nil
+}
+
+// Categories 按商品当前所属分类统计，按销售额降序
+func (s *AnalyticsService) Categories(query AnalyticsQuery) ([]CategorySales, error) {
+	r, err := s.resolve(query)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []CategorySales
+	if err := s.grouped(r, r.aggregated, "p.category_id").
+		Joins("JOIN products AS p ON p.id = s.product_id").
+		Limit(analyticsLimit(query.Limit)).
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	ids := make([]uint, len(rows))
+	for i, row := range rows {
+		ids[i] = row.CategoryID
+	}
+	var categories []models.Category
+	if err := database.GetDB().Unscoped().Select("id, name").Where("id IN ?", ids).Find(&categories).Error; err != nil {
+		return nil, err
+	}
+	names := make(map[uint]string, len(categories))
+	for _, category := range categories {
+		names[category.ID] = category.Name
+	}
+
+	for i := range rows {
+		rows[i].CategoryName = names[rows[i].CategoryID]
+		rows[i].finish()
+	}
+	return rows, nil
+}
+
+// TopBuyers 按消费金额统计买家排行
+// 每日汇总不区分买家，始终从订单表统计
+func (s *AnalyticsService) TopBuyers(query AnalyticsQuery) ([]BuyerSales, error) {
+	r, err := s.resolve(query)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []BuyerSales
+	if err := s.grouped(r, false, "s.user_id").
+		Having("paid_orders > 0").
+		Limit(analyticsLimit(query.Limit)).
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	ids := make([]uint, len(rows))
+	for i, row := range rows {
+		ids[i] = row.UserID
+	}
+	var users []models.User
+	if err := database.GetDB().Select("id, username, name").Where("id IN ?", ids).Find(&users).Error; err != nil {
+		return nil, err
+	}
+	byID := make(map[uint]models.User, len(users))
+	for _, user := range users {
+		byID[user.ID] = user
+	}
+
+	for i := range rows {
+		rows[i].Username = byID[rows[i].UserID].Username
+		rows[i].Name = byID[rows[i].UserID].Name
+		rows[i].finish()
+	}
+	return rows, nil
+}
+
+// Refresh 更新每日销售汇总：重新统计上次更新以来有变动的订单所在的日期，返回更新的天数
+// 首次运行或统计时区变化时重建全部汇总
+func (s *AnalyticsService) Refresh() (int, error) {
+	startedAt := time.Now()
+	days := make(map[string]bool)
+
+	refreshedAt := s.RefreshedAt()
+	if refreshedAt == nil || s.settingService.Get(SettingAnalyticsTimezone) != s.location.String() {
+		if err := database.GetDB().Where("1 = 1").Delete(&models.SalesDailyStat{}).Error; err != nil {
+			return 0, err
+		}
+		var first models.Order
+		err := database.GetDB().Select("created_at").Order("created_at asc").Take(&first).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return 0, err
+		}
+		if err == nil {
+			for day := startOfDay(first.CreatedAt, s.location); day.Before(startedAt); day = day.AddDate(0, 0, 1) {
+				days[day.Format(dayLayout)] = true
+			}
+		}
+	} else {
+		var createdAts []time.Time
+		if err := database.GetDB().Model(&models.Order{}).
+			Where("updated_at >= ?", refreshedAt.Add(-analyticsRefreshOverlap)).
+			Pluck("created_at", &createdAts).Error; err != nil {
+			return 0, err
+		}
+		for _, createdAt := range createdAts {
+			days[createdAt.In(s.location).Format(dayLayout)] = true
+		}
+	}
+
+	sorted := make([]string, 0, len(days))
+	for day := range days {
+		sorted = append(sorted, day)
+	}
+	sort.Strings(sorted)
+	for i, day := range sorted {
+		if err := s.refreshDay(day); err != nil {
+			return i, err
+		}
+	}
+
+	if err := s.settingService.Set(SettingAnalyticsTimezone, s.location.String()); err != nil {
+		return len(sorted), err
+	}
+	return len(sorted), s.settingService.Set(SettingAnalyticsRefreshedAt, startedAt.Format(time.RFC3339Nano))
+}
+
+// RefreshedAt 每日汇总最近一次更新的时间，从未更新时返回 nil
+func (s *AnalyticsService) RefreshedAt() *time.Time {
+	refreshedAt, err := time.Parse(time.RFC3339Nano, s.settingService.Get(SettingAnalyticsRefreshedAt))
+	if err != nil {
+		return nil
+	}
+	return &refreshedAt
+}
+
+// refreshDay 重新统计一天的汇总
+func (s *AnalyticsService) refreshDay(day string) error {
+	start, err := time.ParseInLocation(dayLayout, day, s.location)
+	if err != nil {
+		return err
+	}
+
+	var rows []ProductSales
+	if err := database.GetDB().Model(&models.Order{}).
+		Select("product_id, "+orderTotalsColumns("")).
+		Where("created_at >= ? AND created_at < ?", start, start.AddDate(0, 0, 1)).
+		Group("product_id").
+		Scan(&rows).Error; err != nil {
+		return err
+	}
+
+	stats := make([]models.SalesDailyStat, len(rows))
+	for i, row := range rows {
+		stats[i] = models.SalesDailyStat{
+			Day:             day,
+			ProductID:       row.ProductID,
+			Orders:          row.Orders,
+			PaidOrders:      row.PaidOrders,
+			CancelledOrders: row.CancelledOrders,
+			UnitsSold:       row.UnitsSold,
+			Revenue:         row.Revenue,
+			PlatformFee:     row.PlatformFee,
+			MerchantNet:     row.MerchantNet,
+			PaySeconds:      row.PaySeconds,
+			TimedOrders:     row.TimedOrders,
+		}
+	}
+
+	return database.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("day = ?", day).Delete(&models.SalesDailyStat{}).Error; err != nil {
+			return err
+		}
+		if len(stats) == 0 {
+			return nil
+		}
+		return tx.Create(&stats).Error
+	})
+}
+
+// resolve 解析统计范围
+func (s *AnalyticsService) resolve(query AnalyticsQuery) (analyticsRange, error) {
+	r := analyticsRange{location: s.location, aggregated: true}
+	if query.Timezone != "" && query.Timezone != s.location.String() {
+		location, err := time.LoadLocation(query.Timezone)
+		if err != nil {
+			return r, ErrInvalidTimezone
+		}
+		r.location = location
+		r.aggregated = false
+	}
+
+	to := startOfDay(time.Now(), r.location)
+	if query.To != "" {
+		t, err := time.ParseInLocation(dayLayout, query.To, r.location)
+		if err != nil {
+			return r, ErrInvalidAnalyticsRange.WithDetails(map[string]interface{}{"field": "to"})
+		}
+		to = t
+	}
+	from := to.AddDate(0, 0, 1-DefaultAnalyticsDays)
+	if query.From != "" {
+		t, err := time.ParseInLocation(dayLayout, query.From, r.location)
+		if err != nil {
+			return r, ErrInvalidAnalyticsRange.WithDetails(map[string]interface{}{"field": "from"})
+		}
+		from = t
+	}
+	if from.After(to) || from.AddDate(0, 0, MaxAnalyticsDays).Before(to.AddDate(0, 0, 1)) {
+		return r, ErrInvalidAnalyticsRange
+	}
+
+	r.start = from
+	r.end = to.AddDate(0, 0, 1)
+	return r, nil
+}
+
+// grouped 按指定列分组统计，按销售额降序；aggregated 为 true 时读取每日汇总，否则读取订单表
+// 两种数据源都使用别名 s，分类统计需调用方关联商品表（别名 p）
+func (s *AnalyticsService) grouped(r analyticsRange, aggregated bool, column string) *gorm.DB {
+	var db *gorm.DB
+	if aggregated {
+		db = database.GetDB().Table("sales_daily_stats AS s").
+			Select(column+", "+statTotalsColumns("s.")).
+			Where("s.day >= ? AND s.day <= ?", r.firstDay(), r.lastDay())
+	} else {
+		db = database.GetDB().Table("orders AS s").
+			Select(column+", "+orderTotalsColumns("s.")).
+			Where("s.created_at >= ? AND s.created_at < ?", r.start, r.end)
+	}
+	return db.Group(column).Order("revenue DESC").Order(column)
+}
+
+// firstDay 开始日期
+func (r analyticsRange) firstDay() string {
+	return r.start.Format(dayLayout)
+}
+
+// lastDay 结束日期（含）
+func (r analyticsRange) lastDay() string {
+	return r.end.AddDate(0, 0, -1).Format(dayLayout)
+}
+
+// windows 按周期划分统计范围，首尾周期截取到范围内
+func (r analyticsRange) windows(interval string) []analyticsWindow {
+	var windows []analyticsWindow
+	for start := r.start; start.Before(r.end); {
+		end := nextPeriod(start, interval)
+		if end.After(r.end) {
+			end = r.end
+		}
+		windows = append(windows, analyticsWindow{start: start, end: end})
+		start = end
+	}
+	return windows
+}
+
+// nextPeriod 下一个周期的起始时间
+func nextPeriod(t time.Time, interval string) time.Time {
+	switch interval {
+	case AnalyticsIntervalWeek:
+		return t.AddDate(0, 0, 7-(int(t.Weekday())+6)%7)
+	case AnalyticsIntervalMonth:
+		return time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+	}
+	return t.AddDate(0, 0, 1)
+}
+
+// add 累加指标
+func (t *SalesTotals) add(other SalesTotals) {
+	t.Orders += other.Orders
+	t.PaidOrders += other.PaidOrders
+	t.CancelledOrders += other.CancelledOrders
+	t.UnitsSold += other.UnitsSold
+	t.Revenue += other.Revenue
+	t.PlatformFee += other.PlatformFee
+	t.MerchantNet += other.MerchantNet
+	t.PaySeconds += other.PaySeconds
+	t.TimedOrders += other.TimedOrders
+}
+
+// finish 计算转化率和平均支付耗时，销售额保留两位小数
+func (t *SalesTotals) finish() {
+	t.Revenue = math.Round(t.Revenue*100) / 100
+	if t.Orders > 0 {
+		t.ConversionRate = math.Round(float64(t.PaidOrders)/float64(t.Orders)*10000) / 10000
+	}
+	if t.TimedOrders > 0 {
+		t.AvgTimeToPay = math.Round(float64(t.PaySeconds) / float64(t.TimedOrders))
+	}
+}
+
+// orderTotalsColumns 从订单表统计指标的查询列，prefix 为订单表别名（如 "s."）
+func orderTotalsColumns(prefix string) string {
+	paid := fmt.Sprintf("%sstatus IN (%d, %d)", prefix, models.OrderStatusPaid, models.OrderStatusCompleted)
+	timed := paid + " AND " + prefix + "paid_at IS NOT NULL"
+	sum := func(condition, value, alias string) string {
+		return "COALESCE(SUM(CASE WHEN " + condition + " THEN " + value + " ELSE 0 END), 0) AS " + alias
+	}
+	return strings.Join([]string{
+		"COUNT(*) AS orders",
+		sum(paid, "1", "paid_orders"),
+		sum(fmt.Sprintf("%sstatus = %d", prefix, models.OrderStatusCancelled), "1", "cancelled_orders"),
+		sum(paid, prefix+"quantity", "units_sold"),
+		sum(paid, prefix+"total_amount", "revenue"),
+		sum(paid, prefix+"platform_fee", "platform_fee"),
+		sum(paid, prefix+"merchant_points", "merchant_net"),
+		sum(timed, "TIMESTAMPDIFF(SECOND, "+prefix+"created_at, "+prefix+"paid_at)", "pay_seconds"),
+		sum(timed, "1", "timed_orders"),
+	}, ", ")
+}
+
+// statTotalsColumns 从每日汇总统计指标的查询列，prefix 为汇总表别名
+func statTotalsColumns(prefix string) string {
+	columns := []string{"orders", "paid_orders", "cancelled_orders", "units_sold", "revenue", "platform_fee", "merchant_net", "pay_seconds", "timed_orders"}
+	for i, column := range columns {
+		columns[i] = "COALESCE(SUM(" + prefix + column + "), 0) AS " + column
+	}
+	return strings.Join(columns, ", ")
+}
+
+// analyticsLimit 修正排行条数
+func analyticsLimit(limit int) int {
+	if limit <= 0 {
+		return DefaultAnalyticsLimit
+	}
+	if limit > MaxAnalyticsLimit {
+		return MaxAnalyticsLimit
+	}
+	return limit
+}
+
+// analyticsLocation 统计时区（ANALYTICS_TIMEZONE），未加载配置时使用服务器时区
+func analyticsLocation() *time.Location {
+	if cfg := config.GetConfig(); cfg != nil && cfg.AnalyticsLocation != nil {
+		return cfg.AnalyticsLocation
+	}
+	return time.Local
+}
+
+// startOfDay 指定时区下当天的零点
+func startOfDay(t time.Time, location *time.Location) time.Time {
+	year, month, day := t.In(location).Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, location)
+}
+
+// 错误定义
+var (
+	ErrInvalidAnalyticsRange    = &ServiceError{Code: "invalid_analytics_range", Message: "统计日期范围无效（日期格式为 2006-01-02，最长 366 天）"}
+	ErrInvalidAnalyticsInterval = &ServiceError{Code: "invalid_analytics_interval", Message: "统计周期无效"}
+	ErrInvalidTimezone          = &ServiceError{Code: "invalid_timezone", Message: "时区无效"}
+)
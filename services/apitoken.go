@@ -0,0 +1,197 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/nodeloc-faka/database"
+	"github.com/nodeloc-faka/models"
+)
+
+// API 令牌权限范围
+const (
+	ScopeOrdersRead   = "orders:read"   // 查看自己的订单
+	ScopeOrdersCreate = "orders:create" // 创建、重新支付、取消订单
+	ScopeBalanceSpend = "balance:spend" // 使用余额支付订单
+)
+
+// APITokenScopes 所有可授予的权限范围
+var APITokenScopes = []string{ScopeOrdersRead, ScopeOrdersCreate, ScopeBalanceSpend}
+
+// API 令牌限制
+const (
+	APITokenPrefix           = "nlf_" // 令牌明文前缀，便于识别泄露的令牌
+	MaxAPITokensPerUser      = 10
+	DefaultAPITokenRateLimit = 60  // 默认每分钟请求上限
+	MaxAPITokenRateLimit     = 600 // 用户可设置的每分钟请求上限
+	apiTokenTouchInterval    = time.Minute
+)
+
+// APITokenService 个人访问令牌服务
+type APITokenService struct{}
+
+// NewAPITokenService 创建个人访问令牌服务
+func NewAPITokenService() *APITokenService {
+	return &APITokenService{}
+}
+
+// Create 创建令牌，返回的明文只在创建时出现一次
+// expiresIn 为 0 表示永不过期，rateLimit 为 0 时使用默认值
+func (s *APITokenService) Create(userID uint, name string, scopes []string, expiresIn time.Duration, rateLimit int) (*models.APIToken, string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, "", ErrAPITokenNameRequired
+	}
+	normalized, err := normalizeScopes(scopes)
+	if err != nil {
+		return nil, "", err
+	}
+	if rateLimit == 0 {
+		rateLimit = DefaultAPITokenRateLimit
+	}
+	if rateLimit < 0 || rateLimit > MaxAPITokenRateLimit {
+		return nil, "", ErrAPITokenRateLimit
+	}
+
+	var count int64
+	database.GetDB().Model(&models.APIToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Count(&count)
+	if count >= MaxAPITokensPerUser {
+		return nil, "", ErrTooManyAPITokens
+	}
+
+	bytes := make([]byte, 32)
+	if _, err := rand.Read(bytes); err != nil {
+		return nil, "", err
+	}
+	plaintext := APITokenPrefix + hex.EncodeToString(bytes)
+
+	token := &models.APIToken{
+		UserID:    userID,
+		Name:      name,
+		TokenHash: hashAPIToken(plaintext),
+		Prefix:    plaintext[:len(APITokenPrefix)+8],
+		Scopes:    strings.Join(normalized, ","),
+		RateLimit: rateLimit,
+	}
+	if expiresIn > 0 {
+		expiresAt := time.Now().Add(expiresIn)
+		token.ExpiresAt = &expiresAt
+	}
+	if err := database.GetDB().Create(token).Error; err != nil {
+		return nil, "", err
+	}
+	return token, plaintext, nil
+}
+
+// GetByUser 获取用户的所有令牌（包括已撤销和已过期的）
+func (s *APITokenService) GetByUser(userID uint) ([]models.APIToken, error) {
+	var tokens []models.APIToken
+	err := database.GetDB().Where("user_id = ?", userID).Order("id desc").Find(&tokens).Error
+	return tokens, err
+}
+
+// Revoke 撤销用户的令牌
+func (s *APITokenService) Revoke(userID, id uint) error {
+	result := database.GetDB().Model(&models.APIToken{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", id, userID).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrAPITokenNotFound
+	}
+	return nil
+}
+
+// Authenticate 校验令牌明文，返回令牌和所属用户
+func (s *APITokenService) Authenticate(plaintext string) (*models.APIToken, *models.User, error) {
+	if !strings.HasPrefix(plaintext, APITokenPrefix) {
+		return nil, nil, ErrInvalidAPIToken
+	}
+
+	var token models.APIToken
+	if err := database.GetDB().Where("token_hash = ?", hashAPIToken(plaintext)).First(&token).Error; err != nil {
+		return nil, nil, ErrInvalidAPIToken
+	}
+	now := time.Now()
+	if token.RevokedAt != nil || (token.ExpiresAt != nil && now.After(*token.ExpiresAt)) {
+		return nil, nil, ErrInvalidAPIToken
+	}
+
+	user, err := NewUserService().FindByID(token.UserID)
+	if err != nil {
+		return nil, nil, ErrInvalidAPIToken
+	}
+	if user.IsBlocked {
+		return nil, nil, ErrUserBlocked
+	}
+
+	// 降低写入频率，最多每分钟记录一次使用时间
+	if token.LastUsedAt == nil || now.Sub(*token.LastUsedAt) >= apiTokenTouchInterval {
+		database.GetDB().Model(&token).UpdateColumn("last_used_at", now)
+		token.LastUsedAt = &now
+	}
+	return &token, user, nil
+}
+
+// HasScope 检查令牌是否拥有指定权限范围
+func (s *APITokenService) HasScope(token *models.APIToken, scope string) bool {
+	for _, granted := range strings.Split(token.Scopes, ",") {
+		if granted == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeScopes 校验并去重权限范围
+func normalizeScopes(scopes []string) ([]string, error) {
+	seen := make(map[string]bool)
+	var normalized []string
+	for _, scope := range scopes {
+		scope = strings.TrimSpace(scope)
+		if seen[scope] {
+			continue
+		}
+		valid := false
+		for _, known := range APITokenScopes {
+			if scope == known {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return nil, ErrInvalidAPITokenScope
+		}
+		seen[scope] = true
+		normalized = append(normalized, scope)
+	}
+	if len(normalized) == 0 {
+		return nil, ErrInvalidAPITokenScope
+	}
+	return normalized, nil
+}
+
+// hashAPIToken 计算令牌哈希（令牌本身是高熵随机数，无需加盐）
+func hashAPIToken(plaintext string) string {
+	sum := sha256.Sum256([]byte(plaintext))
+	return hex.EncodeToString(sum[:])
+}
+
+// 错误定义
+var (
+	ErrInvalidAPIToken      = &ServiceError{Code: "invalid_api_token", Status: http.StatusUnauthorized, Message: "API 令牌无效或已过期"}
+	ErrAPITokenNotFound     = &ServiceError{Code: "api_token_not_found", Status: http.StatusNotFound, Message: "令牌不存在或已撤销"}
+	ErrAPITokenNameRequired = &ServiceError{Code: "api_token_name_required", Message: "请填写令牌名称"}
+	ErrInvalidAPITokenScope = &ServiceError{Code: "invalid_api_token_scope", Message: "无效的令牌权限范围"}
+	ErrAPITokenRateLimit    = &ServiceError{Code: "api_token_rate_limit", Message: "令牌请求频率上限无效"}
+	ErrTooManyAPITokens     = &ServiceError{Code: "too_many_api_tokens", Message: "令牌数量已达上限，请先撤销不再使用的令牌"}
+	ErrAPITokenScopeMissing = &ServiceError{Code: "api_token_scope_missing", Status: http.StatusForbidden, Message: "API 令牌无权访问此接口"}
+)
@@ -0,0 +1,303 @@
+package services
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/nodeloc-faka/database"
+	"github.com/nodeloc-faka/models"
+	"gorm.io/gorm"
+)
+
+// AuditService 审计日志服务
+// 只提供写入和查询，审计日志在应用层不可修改或删除
+type AuditService struct{}
+
+// NewAuditService 创建审计日志服务
+func NewAuditService() *AuditService {
+	return &AuditService{}
+}
+
+// MaxAuditExportRows 单次导出的最大条数
+const MaxAuditExportRows = 50000
+
+// auditRedacted 脱敏后的占位值
+const auditRedacted = "[REDACTED]"
+
+// auditSensitiveKeys 字段名包含这些关键字时脱敏
+var auditSensitiveKeys = []string{"password", "secret", "token", "card_no", "card_pwd", "cards_text", "content"}
+
+// auditIgnoredKeys 比较差异时忽略的字段
+var auditIgnoredKeys = map[string]bool{"updated_at": true}
+
+// auditLoaders 各审计目标类型的快照加载方式，targetID 为空时返回 nil 表示无需快照
+var auditLoaders = map[string]func(db *gorm.DB, id string) (interface{}, error){
+	"categories": func(db *gorm.DB, id string) (interface{}, error) {
+		var category models.Category
+		return &category, db.Unscoped().First(&category, "id = ?", id).Error
+	},
+	"products": func(db *gorm.DB, id string) (interface{}, error) {
+		var product models.Product
+		return &product, db.Unscoped().
+			Preload("Variants").
+			Preload("PriceTiers").
+			Preload("Tags").
+			First(&product, "id = ?", id).Error
+	},
+	"variants": func(db *gorm.DB, id string) (interface{}, error) {
+		var variant models.ProductVariant
+		return &variant, db.First(&variant, "id = ?", id).Error
+	},
+	"tags": func(db *gorm.DB, id string) (interface{}, error) {
+		var tag models.Tag
+		return &tag, db.First(&tag, "id = ?", id).Error
+	},
+	"flash-sales": func(db *gorm.DB, id string) (interface{}, error) {
+		var sale models.FlashSale
+		return &sale, db.First(&sale, "id = ?", id).Error
+	},
+	"card-keys": func(db *gorm.DB, id string) (interface{}, error) {
+		var cardKey models.CardKey
+		return &cardKey, db.Unscoped().First(&cardKey, "id = ?", id).Error
+	},
+	"rate-limit-blocks": func(db *gorm.DB, id string) (interface{}, error) {
+		var block models.RateLimitBlock
+		return &block, db.First(&block, "id = ?", id).Error
+	},
+	"reconciliation-issues": func(db *gorm.DB, id string) (interface{}, error) {
+		var issue models.ReconciliationIssue
+		return &issue, db.First(&issue, "id = ?", id).Error
+	},
+	"orders":      loadAuditOrder,
+	"fulfillment": loadAuditOrder,
+	"users":       loadAuditUser,
+	"staff":       loadAuditUser,
+}
+
+// loadAuditOrder 加载订单快照
+func loadAuditOrder(db *gorm.DB, orderNo string) (interface{}, error) {
+	var order models.Order
+	return &order, db.Where("order_no = ?", orderNo).First(&order).Error
+}
+
+// loadAuditUser 加载用户快照
+func loadAuditUser(db *gorm.DB, id string) (interface{}, error) {
+	var user models.User
+	return &user, db.First(&user, "id = ?", id).Error
+}
+
+// Record 写入审计日志
+func (s *AuditService) Record(entry *models.AuditLog) error {
+	return database.GetDB().Create(entry).Error
+}
+
+// Snapshot 获取审计目标的当前状态，目标不存在或不支持时返回 nil
+// 快照未脱敏，只能传给 Changes 使用
+func (s *AuditService) Snapshot(targetType, targetID string) map[string]interface{} {
+	if targetType == "settings" {
+		var settings []models.Setting
+		if err := database.GetDB().Find(&settings).Error; err != nil {
+			return nil
+		}
+		snapshot := make(map[string]interface{}, len(settings))
+		for _, setting := range settings {
+			snapshot[setting.Key] = setting.Value
+		}
+		return snapshot
+	}
+
+	loader, ok := auditLoaders[targetType]
+	if !ok || targetID == "" {
+		return nil
+	}
+	value, err := loader(database.GetDB(), targetID)
+	if err != nil {
+		return nil
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil
+	}
+	var snapshot map[string]interface{}
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil
+	}
+	return snapshot
+}
+
+// Changes 生成审计日志的变更前后内容
+// 前后快照都存在时只保留有差异的字段；无法比较或没有差异时记录请求内容
+// 先比较再脱敏，敏感字段发生变化时仍会记录（值为占位符）
+func (s *AuditService) Changes(before, after map[string]interface{}, body []byte) (string, string) {
+	if before != nil && after != nil {
+		changedBefore := make(map[string]interface{})
+		changedAfter := make(map[string]interface{})
+		for key := range mergeAuditKeys(before, after) {
+			if auditIgnoredKeys[key] || reflect.DeepEqual(before[key], after[key]) {
+				continue
+			}
+			changedBefore[key] = before[key]
+			changedAfter[key] = after[key]
+		}
+		if len(changedAfter) > 0 {
+			return encodeAuditValue(redactAuditMap(changedBefore)), encodeAuditValue(redactAuditMap(changedAfter))
+		}
+		return "", encodeAuditValue(auditPayload(body))
+	}
+	if after == nil && before != nil {
+		return encodeAuditValue(redactAuditMap(before)), ""
+	}
+	return "", encodeAuditValue(auditPayload(body))
+}
+
+// AuditLogFilter 审计日志筛选条件
+type AuditLogFilter struct {
+	ActorID    uint
+	Action     string
+	TargetType string
+	TargetID   string
+	From       *time.Time
+	To         *time.Time
+}
+
+// apply 应用筛选条件
+func (f AuditLogFilter) apply(db *gorm.DB) *gorm.DB {
+	if f.ActorID > 0 {
+		db = db.Where("actor_id = ?", f.ActorID)
+	}
+	if f.Action != "" {
+		db = db.Where("action = ?", f.Action)
+	}
+	if f.TargetType != "" {
+		db = db.Where("target_type = ?", f.TargetType)
+	}
+	if f.TargetID != "" {
+		db = db.Where("target_id = ?", f.TargetID)
+	}
+	if f.From != nil {
+		db = db.Where("created_at >= ?", *f.From)
+	}
+	if f.To != nil {
+		db = db.Where("created_at < ?", *f.To)
+	}
+	return db
+}
+
+// auditLogSorts 审计日志列表允许的排序字段
+var auditLogSorts = listSorts{
+	table: "audit_logs",
+	columns: map[string]string{
+		"id":         "audit_logs.id",
+		"created_at": "audit_logs.created_at",
+	},
+	def: "-id",
+}
+
+// GetWithPagination 分页查询审计日志
+func (s *AuditService) GetWithPagination(filter AuditLogFilter, query ListQuery) ([]models.AuditLog, ListPage, error) {
+	var logs []models.AuditLog
+	page, err := paginate(filter.apply(database.GetDB().Model(&models.AuditLog{})), query, auditLogSorts, &logs)
+	if err != nil {
+		return nil, ListPage{}, err
+	}
+	return logs, page, nil
+}
+
+// Export 按时间顺序逐批读取审计日志，用于导出
+func (s *AuditService) Export(filter AuditLogFilter, fn func(logs []models.AuditLog) error) error {
+	var total int64
+	filter.apply(database.GetDB().Model(&models.AuditLog{})).Count(&total)
+	if total > MaxAuditExportRows {
+		return ErrAuditExportTooLarge
+	}
+
+	var batch []models.AuditLog
+	return filter.apply(database.GetDB().Model(&models.AuditLog{})).
+		FindInBatches(&batch, 500, func(tx *gorm.DB, _ int) error {
+			return fn(batch)
+		}).Error
+}
+
+// auditPayload 解析请求内容（已脱敏）
+func auditPayload(body []byte) map[string]interface{} {
+	if len(body) == 0 {
+		return nil
+	}
+	var payload interface{}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil
+	}
+	if m, ok := payload.(map[string]interface{}); ok {
+		return redactAuditMap(m)
+	}
+	return map[string]interface{}{"body": redactAuditValue(payload)}
+}
+
+// redactAuditMap 脱敏敏感字段
+func redactAuditMap(m map[string]interface{}) map[string]interface{} {
+	for key, value := range m {
+		if isSensitiveAuditKey(key) {
+			if value != nil && value != "" {
+				m[key] = auditRedacted
+			}
+			continue
+		}
+		m[key] = redactAuditValue(value)
+	}
+	return m
+}
+
+// redactAuditValue 递归脱敏嵌套结构
+func redactAuditValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return redactAuditMap(v)
+	case []interface{}:
+		for i := range v {
+			v[i] = redactAuditValue(v[i])
+		}
+	}
+	return value
+}
+
+// isSensitiveAuditKey 检查字段是否需要脱敏
+func isSensitiveAuditKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, sensitive := range auditSensitiveKeys {
+		if strings.Contains(key, sensitive) {
+			return true
+		}
+	}
+	return false
+}
+
+// mergeAuditKeys 合并两个快照的字段名
+func mergeAuditKeys(a, b map[string]interface{}) map[string]bool {
+	keys := make(map[string]bool, len(a)+len(b))
+	for key := range a {
+		keys[key] = true
+	}
+	for key := range b {
+		keys[key] = true
+	}
+	return keys
+}
+
+// encodeAuditValue 编码审计内容
+func encodeAuditValue(value map[string]interface{}) string {
+	if len(value) == 0 {
+		return ""
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+// 错误定义
+var (
+	ErrAuditExportTooLarge = &ServiceError{Code: "audit_export_too_large", Message: "导出条数超过 50000 条，请缩小筛选范围"}
+)
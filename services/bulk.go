@@ -0,0 +1,309 @@
+package services
+
+import (
+	"errors"
+	"math"
+	"net/http"
+
+	"github.com/nodeloc-faka/database"
+	"github.com/nodeloc-faka/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// BulkService 批量操作服务
+// 每次批量操作在一个事务中执行，每个条目使用独立的保存点，单条失败不影响其他条目
+type BulkService struct {
+	productService *ProductService
+	orderService   *OrderService
+}
+
+// NewBulkService 创建批量操作服务
+func NewBulkService() *BulkService {
+	return &BulkService{
+		productService: NewProductService(),
+		orderService:   NewOrderService(),
+	}
+}
+
+// MaxBulkItems 单次批量操作的最大条目数
+const MaxBulkItems = 1000
+
+// 卡密批量操作
+const (
+	BulkCardKeyDelete = "delete" // 删除（归档）
+	BulkCardKeyLock   = "lock"   // 锁定，锁定的卡密不参与销售
+	BulkCardKeyUnlock = "unlock" // 解锁
+	BulkCardKeyMove   = "move"   // 移动到其他商品/规格
+)
+
+// 商品批量操作
+const (
+	BulkProductActivate   = "activate"   // 上架
+	BulkProductDeactivate = "deactivate" // 下架
+	BulkProductCategorize = "categorize" // 修改分类
+	BulkProductReprice    = "reprice"    // 调价
+)
+
+// BulkItemResult 单个条目的处理结果
+type BulkItemResult struct {
+	ID      uint   `json:"id"`
+	Success bool   `json:"success"`
+	Error   string `json:"error,omitempty"`
+}
+
+// BulkResult 批量操作结果
+type BulkResult struct {
+	Total     int              `json:"total"`
+	Succeeded int              `json:"succeeded"`
+	Failed    int              `json:"failed"`
+	Items     []BulkItemResult `json:"items"`
+}
+
+// add 记录单个条目的结果
+func (r *BulkResult) add(id uint, err error) {
+	r.Total++
+	item := BulkItemResult{ID: id, Success: err == nil}
+	if err != nil {
+		r.Failed++
+		item.Error = "操作失败"
+		var serviceErr *ServiceError
+		if errors.As(err, &serviceErr) {
+			item.Error = serviceErr.Message
+		}
+	} else {
+		r.Succeeded++
+	}
+	r.Items = append(r.Items, item)
+}
+
+// CardKeyBulkRequest 卡密批量操作请求
+type CardKeyBulkRequest struct {
+	Action          string
+	IDs             []uint
+	Filter          *CardKeyFilter // 未指定ID列表时使用，必须指定商品
+	TargetProductID uint           // move 操作的目标商品
+	TargetVariantID uint           // move 操作的目标规格，为 0 时使用目标商品的默认规格
+}
+
+// CardKeys 批量操作卡密
+func (s *BulkService) CardKeys(req CardKeyBulkRequest) (*BulkResult, error) {
+	var target *models.ProductVariant
+	switch req.Action {
+	case BulkCardKeyDelete, BulkCardKeyLock, BulkCardKeyUnlock:
+	case BulkCardKeyMove:
+		variant, err := NewVariantService().Resolve(req.TargetProductID, req.TargetVariantID)
+		if err != nil {
+			return nil, err
+		}
+		target = variant
+	default:
+		return nil, ErrInvalidBulkAction
+	}
+
+	ids, err := s.selectCardKeys(req)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &BulkResult{Items: []BulkItemResult{}}
+	affected := make(map[uint]bool)
+	err = database.GetDB().Transaction(func(tx *gorm.DB) error {
+		for _, id := range ids {
+			err := tx.Transaction(func(itx *gorm.DB) error {
+				var card models.CardKey
+				if err := itx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&card, id).Error; err != nil {
+					return ErrCardKeyNotFound
+				}
+				affected[card.ProductID] = true
+
+				switch req.Action {
+				case BulkCardKeyDelete:
+					if card.Status == models.CardKeyStatusSold {
+						return ErrCardKeySold
+					}
+					return itx.Delete(&card).Error
+				case BulkCardKeyLock:
+					if card.Status != models.CardKeyStatusAvailable {
+						return ErrCardKeyNotAvailable
+					}
+					return itx.Model(&card).Update("status", models.CardKeyStatusLocked).Error
+				case BulkCardKeyUnlock:
+					if card.Status != models.CardKeyStatusLocked {
+						return ErrCardKeyNotLocked
+					}
+					return itx.Model(&card).Update("status", models.CardKeyStatusAvailable).Error
+				default:
+					if card.Status == models.CardKeyStatusSold {
+						return ErrCardKeyAlreadySold
+					}
+					affected[target.ProductID] = true
+					return itx.Model(&card).Updates(map[string]interface{}{
+						"product_id": target.ProductID,
+						"variant_id": target.ID,
+					}).Error
+				}
+			})
+			result.add(id, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for productID := range affected {
+		s.productService.UpdateStock(productID)
+	}
+	return result, nil
+}
+
+// selectCardKeys 根据ID列表或筛选条件确定要操作的卡密
+func (s *BulkService) selectCardKeys(req CardKeyBulkRequest) ([]uint, error) {
+	ids := uniqueIDs(req.IDs)
+	if len(ids) == 0 && req.Filter != nil && req.Filter.ProductID > 0 {
+		db := req.Filter.apply(database.GetDB().Model(&models.CardKey{}))
+		if err := db.Order("id asc").Limit(MaxBulkItems+1).Pluck("id", &ids).Error; err != nil {
+			return nil, err
+		}
+	}
+	return checkBulkSize(ids)
+}
+
+// ProductBulkRequest 商品批量操作请求
+type ProductBulkRequest struct {
+	Action     string
+	IDs        []uint
+	CategoryID uint     // categorize 操作的目标分类
+	Price      *float64 // reprice 操作：将所有规格设为该价格
+	Percent    *float64 // reprice 操作：所有规格按百分比调价，如 -10 表示降价 10%
+}
+
+// Products 批量操作商品
+func (s *BulkService) Products(req ProductBulkRequest) (*BulkResult, error) {
+	switch req.Action {
+	case BulkProductActivate, BulkProductDeactivate:
+	case BulkProductCategorize:
+		if _, err := NewCategoryService().FindByID(req.CategoryID); err != nil {
+			return nil, ErrCategoryNotFound
+		}
+	case BulkProductReprice:
+		if (req.Price == nil) == (req.Percent == nil) ||
+			(req.Price != nil && *req.Price <= 0) ||
+			(req.Percent != nil && *req.Percent <= -100) {
+			return nil, ErrInvalidBulkPrice
+		}
+	default:
+		return nil, ErrInvalidBulkAction
+	}
+
+	ids, err := checkBulkSize(uniqueIDs(req.IDs))
+	if err != nil {
+		return nil, err
+	}
+
+	result := &BulkResult{Items: []BulkItemResult{}}
+	err = database.GetDB().Transaction(func(tx *gorm.DB) error {
+		for _, id := range ids {
+			err := tx.Transaction(func(itx *gorm.DB) error {
+				var product models.Product
+				if err := itx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&product, id).Error; err != nil {
+					return ErrProductNotFound
+				}
+
+				switch req.Action {
+				case BulkProductActivate, BulkProductDeactivate:
+					return itx.Model(&product).Update("is_active", req.Action == BulkProductActivate).Error
+				case BulkProductCategorize:
+					return itx.Model(&product).Update("category_id", req.CategoryID).Error
+				default:
+					return s.reprice(itx, &product, req)
+				}
+			})
+			result.add(id, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// reprice 调整商品所有规格的价格并同步商品展示价格
+func (s *BulkService) reprice(tx *gorm.DB, product *models.Product, req ProductBulkRequest) error {
+	var variants []models.ProductVariant
+	if err := tx.Where("product_id = ?", product.ID).Find(&variants).Error; err != nil {
+		return err
+	}
+
+	for _, variant := range variants {
+		var price float64
+		if req.Price != nil {
+			price = *req.Price
+		} else {
+			price = math.Round(variant.Price*(100+*req.Percent)) / 100
+		}
+		if price <= 0 {
+			return ErrInvalidBulkPrice
+		}
+		if err := tx.Model(&variant).Update("price", price).Error; err != nil {
+			return err
+		}
+	}
+	return s.productService.syncPrice(tx, product.ID)
+}
+
+// CancelOrders 批量取消待支付订单
+func (s *BulkService) CancelOrders(ids []uint) (*BulkResult, error) {
+	ids, err := checkBulkSize(uniqueIDs(ids))
+	if err != nil {
+		return nil, err
+	}
+
+	result := &BulkResult{Items: []BulkItemResult{}}
+	err = database.GetDB().Transaction(func(tx *gorm.DB) error {
+		for _, id := range ids {
+			err := tx.Transaction(func(itx *gorm.DB) error {
+				var order models.Order
+				if err := itx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&order, id).Error; err != nil {
+					return ErrOrderNotFound
+				}
+				if order.Status != models.OrderStatusPending {
+					return ErrOrderNotPending
+				}
+				return s.orderService.cancel(itx, id)
+			})
+			result.add(id, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// checkBulkSize 校验批量操作条目数
+func checkBulkSize(ids []uint) ([]uint, error) {
+	if len(ids) == 0 {
+		return nil, ErrBulkEmptySelection
+	}
+	if len(ids) > MaxBulkItems {
+		return nil, ErrBulkTooMany
+	}
+	return ids, nil
+}
+
+// 错误定义
+var (
+	ErrInvalidBulkAction   = &ServiceError{Code: "invalid_bulk_action", Message: "不支持的批量操作"}
+	ErrInvalidBulkPrice    = &ServiceError{Code: "invalid_bulk_price", Message: "调价参数无效"}
+	ErrBulkEmptySelection  = &ServiceError{Code: "bulk_empty_selection", Message: "未选择任何条目"}
+	ErrBulkTooMany         = &ServiceError{Code: "bulk_too_many", Message: "单次批量操作最多 1000 条"}
+	ErrCardKeyNotFound     = &ServiceError{Code: "card_key_not_found", Status: http.StatusNotFound, Message: "卡密不存在"}
+	ErrCardKeyNotAvailable = &ServiceError{Code: "card_key_not_available", Status: http.StatusConflict, Message: "只能锁定可售状态的卡密"}
+	ErrCardKeyNotLocked    = &ServiceError{Code: "card_key_not_locked", Status: http.StatusConflict, Message: "卡密未锁定"}
+	ErrCardKeyAlreadySold  = &ServiceError{Code: "card_key_already_sold", Status: http.StatusConflict, Message: "卡密已售出，无法移动"}
+	ErrOrderNotPending     = &ServiceError{Code: "order_not_pending", Status: http.StatusConflict, Message: "只能取消待支付的订单"}
+)
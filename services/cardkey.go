@@ -1,11 +1,13 @@
 package services
 
 import (
+	"net/http"
 	"strings"
 	"time"
 
 	"github.com/nodeloc-faka/database"
 	"github.com/nodeloc-faka/models"
+	"gorm.io/gorm"
 )
 
 // CardKeyService 卡密服务
@@ -26,8 +28,8 @@ func (s *CardKeyService) Create(cardKey *models.CardKey) error {
 	return err
 }
 
-// BatchCreate 批量创建卡密
-func (s *CardKeyService) BatchCreate(productID uint, cardsText string) (int, error) {
+// BatchCreate 批量创建卡密（导入到指定规格）
+func (s *CardKeyService) BatchCreate(productID, variantID uint, cardsText string) (int, error) {
 	lines := strings.Split(cardsText, "\n")
 	count := 0
 
@@ -37,22 +39,10 @@ func (s *CardKeyService) BatchCreate(productID uint, cardsText string) (int, err
 			continue
 		}
 
-		// 支持两种格式：
-		// 1. 卡号----密码
-		// 2. 只有卡号
-		var cardNo, cardPwd string
-		if strings.Contains(line, "----") {
-			parts := strings.SplitN(line, "----", 2)
-			cardNo = strings.TrimSpace(parts[0])
-			if len(parts) > 1 {
-				cardPwd = strings.TrimSpace(parts[1])
-			}
-		} else {
-			cardNo = line
-		}
-
+		cardNo, cardPwd := parseCardLine(line)
 		cardKey := &models.CardKey{
 			ProductID: productID,
+			VariantID: variantID,
 			CardNo:    cardNo,
 			CardPwd:   cardPwd,
 			Status:    models.CardKeyStatusAvailable,
@@ -70,6 +60,22 @@ func (s *CardKeyService) BatchCreate(productID uint, cardsText string) (int, err
 	return count, nil
 }
 
+// parseCardLine 解析单行卡密
+// 支持两种格式：
+// 1. 卡号----密码
+// 2. 只有卡号
+func parseCardLine(line string) (cardNo, cardPwd string) {
+	if strings.Contains(line, "----") {
+		parts := strings.SplitN(line, "----", 2)
+		cardNo = strings.TrimSpace(parts[0])
+		if len(parts) > 1 {
+			cardPwd = strings.TrimSpace(parts[1])
+		}
+		return
+	}
+	return line, ""
+}
+
 // Update 更新卡密
 func (s *CardKeyService) Update(cardKey *models.CardKey) error {
 	return database.GetDB().Save(cardKey).Error
@@ -94,6 +100,47 @@ func (s *CardKeyService) Delete(id uint) error {
 	return err
 }
 
+// archivedCardKeySorts 已归档卡密列表允许的排序字段
+var archivedCardKeySorts = listSorts{
+	table: "card_keys",
+	columns: map[string]string{
+		"id":         "card_keys.id",
+		"deleted_at": "card_keys.deleted_at",
+		"created_at": "card_keys.created_at",
+	},
+	def: "-deleted_at",
+}
+
+// GetArchived 分页获取已归档的卡密
+func (s *CardKeyService) GetArchived(filter CardKeyFilter, query ListQuery) ([]models.CardKey, ListPage, error) {
+	var cardKeys []models.CardKey
+	db := filter.apply(database.GetDB().Unscoped().Model(&models.CardKey{}).Where("card_keys.deleted_at IS NOT NULL"))
+	page, err := paginate(db, query, archivedCardKeySorts, &cardKeys, preloadCardKeyProduct)
+	if err != nil {
+		return nil, ListPage{}, err
+	}
+	return cardKeys, page, nil
+}
+
+// Restore 恢复已归档的卡密
+func (s *CardKeyService) Restore(id uint) error {
+	var cardKey models.CardKey
+	if err := database.GetDB().Unscoped().Where("deleted_at IS NOT NULL").First(&cardKey, id).Error; err != nil {
+		return ErrCardKeyNotArchived
+	}
+
+	var count int64
+	database.GetDB().Model(&models.Product{}).Where("id = ?", cardKey.ProductID).Count(&count)
+	if count == 0 {
+		return ErrProductArchived
+	}
+
+	if err := database.GetDB().Unscoped().Model(&cardKey).Update("deleted_at", nil).Error; err != nil {
+		return err
+	}
+	return NewProductService().UpdateStock(cardKey.ProductID)
+}
+
 // BatchDelete 批量删除未售出的卡密
 func (s *CardKeyService) BatchDelete(ids []uint) (int, error) {
 	result := database.GetDB().
@@ -105,7 +152,7 @@ func (s *CardKeyService) BatchDelete(ids []uint) (int, error) {
 // FindByID 根据ID查找卡密
 func (s *CardKeyService) FindByID(id uint) (*models.CardKey, error) {
 	var cardKey models.CardKey
-	if err := database.GetDB().Preload("Product").First(&cardKey, id).Error; err != nil {
+	if err := database.GetDB().Preload("Product", includeArchived).First(&cardKey, id).Error; err != nil {
 		return nil, err
 	}
 	return &cardKey, nil
@@ -123,11 +170,14 @@ func (s *CardKeyService) GetByProduct(productID uint) ([]models.CardKey, error)
 	return cardKeys, nil
 }
 
-// GetAvailableByProduct 获取商品的可售卡密
-func (s *CardKeyService) GetAvailableByProduct(productID uint, limit int) ([]models.CardKey, error) {
+// GetAvailableByProduct 获取商品的可售卡密（variantID 为 0 时不限规格）
+func (s *CardKeyService) GetAvailableByProduct(productID, variantID uint, limit int) ([]models.CardKey, error) {
 	var cardKeys []models.CardKey
-	if err := database.GetDB().
-		Where("product_id = ? AND status = ?", productID, models.CardKeyStatusAvailable).
+	db := database.GetDB().Where("product_id = ? AND status = ?", productID, models.CardKeyStatusAvailable)
+	if variantID > 0 {
+		db = db.Where("variant_id = ?", variantID)
+	}
+	if err := db.
 		Order("id asc").
 		Limit(limit).
 		Find(&cardKeys).Error; err != nil {
@@ -148,27 +198,56 @@ func (s *CardKeyService) MarkAsSold(ids []uint, orderID uint) error {
 		}).Error
 }
 
-// GetWithPagination 分页获取卡密
-func (s *CardKeyService) GetWithPagination(productID uint, page, pageSize int) ([]models.CardKey, int64, error) {
-	var cardKeys []models.CardKey
-	var total int64
+// CardKeyFilter 卡密筛选条件（用于后台列表和批量操作）
+type CardKeyFilter struct {
+	ProductID uint   `json:"product_id"`
+	VariantID uint   `json:"variant_id"`
+	Status    *int   `json:"status"`
+	CardNo    string `json:"card_no"` // 卡号，模糊匹配
+}
 
-	db := database.GetDB().Model(&models.CardKey{})
-	if productID > 0 {
-		db = db.Where("product_id = ?", productID)
+// apply 应用筛选条件
+func (f CardKeyFilter) apply(db *gorm.DB) *gorm.DB {
+	if f.ProductID > 0 {
+		db = db.Where("card_keys.product_id = ?", f.ProductID)
 	}
-	db.Count(&total)
-
-	offset := (page - 1) * pageSize
-	if err := db.Preload("Product").
-		Order("status asc, id desc").
-		Offset(offset).
-		Limit(pageSize).
-		Find(&cardKeys).Error; err != nil {
-		return nil, 0, err
+	if f.VariantID > 0 {
+		db = db.Where("card_keys.variant_id = ?", f.VariantID)
+	}
+	if f.Status != nil {
+		db = db.Where("card_keys.status = ?", *f.Status)
 	}
+	if f.CardNo != "" {
+		db = db.Where("card_keys.card_no LIKE ?", "%"+escapeLike(f.CardNo)+"%")
+	}
+	return db
+}
+
+// cardKeySorts 卡密列表允许的排序字段
+var cardKeySorts = listSorts{
+	table: "card_keys",
+	columns: map[string]string{
+		"id":         "card_keys.id",
+		"status":     "card_keys.status",
+		"created_at": "card_keys.created_at",
+		"sold_at":    "COALESCE(card_keys.sold_at, '1970-01-01')",
+	},
+	def: "status,-id",
+}
+
+// preloadCardKeyProduct 预加载卡密所属商品（含已归档）和规格
+func preloadCardKeyProduct(db *gorm.DB) *gorm.DB {
+	return db.Preload("Product", includeArchived).Preload("Variant")
+}
 
-	return cardKeys, total, nil
+// GetWithPagination 分页获取卡密
+func (s *CardKeyService) GetWithPagination(filter CardKeyFilter, query ListQuery) ([]models.CardKey, ListPage, error) {
+	var cardKeys []models.CardKey
+	page, err := paginate(filter.apply(database.GetDB().Model(&models.CardKey{})), query, cardKeySorts, &cardKeys, preloadCardKeyProduct)
+	if err != nil {
+		return nil, ListPage{}, err
+	}
+	return cardKeys, page, nil
 }
 
 // CountByProduct 获取商品的卡密数量
@@ -182,6 +261,17 @@ func (s *CardKeyService) CountByProduct(productID uint, status int) int64 {
 	return count
 }
 
+// CountByVariant 获取规格的卡密数量
+func (s *CardKeyService) CountByVariant(variantID uint, status int) int64 {
+	var count int64
+	db := database.GetDB().Model(&models.CardKey{}).Where("variant_id = ?", variantID)
+	if status >= 0 {
+		db = db.Where("status = ?", status)
+	}
+	db.Count(&count)
+	return count
+}
+
 // CountAll 获取所有卡密数量
 func (s *CardKeyService) CountAll() int64 {
 	var count int64
@@ -197,4 +287,7 @@ func (s *CardKeyService) CountAvailable() int64 {
 }
 
 // 错误定义
-var ErrCardKeySold = &ServiceError{Message: "该卡密已售出，无法删除"}
+var (
+	ErrCardKeySold        = &ServiceError{Code: "card_key_sold", Status: http.StatusConflict, Message: "该卡密已售出，无法删除"}
+	ErrCardKeyNotArchived = &ServiceError{Code: "card_key_not_archived", Status: http.StatusNotFound, Message: "卡密不存在或未归档"}
+)
@@ -1,8 +1,11 @@
 package services
 
 import (
+	"net/http"
+
 	"github.com/nodeloc-faka/database"
 	"github.com/nodeloc-faka/models"
+	"gorm.io/gorm"
 )
 
 // CategoryService 分类服务
@@ -15,23 +18,98 @@ func NewCategoryService() *CategoryService {
 
 // Create 创建分类
 func (s *CategoryService) Create(category *models.Category) error {
+	if err := s.validateParent(category.ID, category.ParentID); err != nil {
+		return err
+	}
 	return database.GetDB().Create(category).Error
 }
 
 // Update 更新分类
 func (s *CategoryService) Update(category *models.Category) error {
+	if err := s.validateParent(category.ID, category.ParentID); err != nil {
+		return err
+	}
 	return database.GetDB().Save(category).Error
 }
 
 // Delete 删除分类
-func (s *CategoryService) Delete(id uint) error {
-	// 检查是否有关联商品
-	var count int64
-	database.GetDB().Model(&models.Product{}).Where("category_id = ?", id).Count(&count)
-	if count > 0 {
-		return ErrCategoryHasProducts
+// 有子分类时默认拒绝删除；liftChildren 为 true 时将子分类上移到被删除分类的父级
+func (s *CategoryService) Delete(id uint, liftChildren bool) error {
+	return database.GetDB().Transaction(func(tx *gorm.DB) error {
+		var category models.Category
+		if err := tx.First(&category, id).Error; err != nil {
+			return ErrCategoryNotFound
+		}
+
+		// 检查是否有关联商品（已归档的商品不影响删除）
+		var count int64
+		tx.Model(&models.Product{}).Where("category_id = ?", id).Count(&count)
+		if count > 0 {
+			return ErrCategoryHasProducts
+		}
+
+		var children int64
+		tx.Model(&models.Category{}).Where("parent_id = ?", id).Count(&children)
+		if children > 0 {
+			if !liftChildren {
+				return ErrCategoryHasChildren
+			}
+			if err := tx.Model(&models.Category{}).
+				Where("parent_id = ?", id).
+				Update("parent_id", category.ParentID).Error; err != nil {
+				return err
+			}
+		}
+
+		return tx.Delete(&models.Category{}, id).Error
+	})
+}
+
+// GetArchived 获取已归档的分类
+func (s *CategoryService) GetArchived() ([]models.Category, error) {
+	var categories []models.Category
+	if err := database.GetDB().Unscoped().
+		Where("deleted_at IS NOT NULL").
+		Order("deleted_at desc").
+		Find(&categories).Error; err != nil {
+		return nil, err
 	}
-	return database.GetDB().Delete(&models.Category{}, id).Error
+	return categories, nil
+}
+
+// Restore 恢复已归档的分类（父分类需未归档）
+func (s *CategoryService) Restore(id uint) error {
+	var category models.Category
+	if err := database.GetDB().Unscoped().Where("deleted_at IS NOT NULL").First(&category, id).Error; err != nil {
+		return ErrCategoryNotArchived
+	}
+
+	if category.ParentID != nil {
+		if _, err := s.FindByID(*category.ParentID); err != nil {
+			return ErrCategoryArchived
+		}
+	}
+
+	return database.GetDB().Unscoped().Model(&category).Update("deleted_at", nil).Error
+}
+
+// validateParent 校验父分类存在，且不是分类自身或其子孙分类
+func (s *CategoryService) validateParent(id uint, parentID *uint) error {
+	if parentID == nil {
+		return nil
+	}
+	if _, err := s.FindByID(*parentID); err != nil {
+		return ErrCategoryNotFound
+	}
+	if id == 0 {
+		return nil
+	}
+	for _, descendant := range s.SubtreeIDs(id) {
+		if descendant == *parentID {
+			return ErrCategoryCycle
+		}
+	}
+	return nil
 }
 
 // FindByID 根据ID查找分类
@@ -61,18 +139,126 @@ func (s *CategoryService) GetActive() ([]models.Category, error) {
 	return categories, nil
 }
 
-// GetWithProducts 获取分类及其商品
+// GetTree 获取分类树
+func (s *CategoryService) GetTree(activeOnly bool) ([]models.Category, error) {
+	categories, err := s.loadAll(activeOnly)
+	if err != nil {
+		return nil, err
+	}
+	return buildCategoryTree(indexChildren(categories), 0), nil
+}
+
+// GetWithProducts 获取顶级分类树及其商品
+// 每个顶级分类的商品列表包含其所有子孙分类下的商品，子分类仅用于导航
 func (s *CategoryService) GetWithProducts() ([]models.Category, error) {
-	var categories []models.Category
-	if err := database.GetDB().Preload("Products", "is_active = ?", true).
+	roots, err := s.GetTree(true)
+	if err != nil {
+		return nil, err
+	}
+
+	var products []models.Product
+	if err := database.GetDB().
+		Preload("Variants", activeVariants).
+		Preload("Tags").
 		Where("is_active = ?", true).
-		Order("sort asc, id asc").
-		Find(&categories).Error; err != nil {
+		Order("sort asc, id desc").
+		Find(&products).Error; err != nil {
+		return nil, err
+	}
+
+	for i := range roots {
+		subtree := make(map[uint]bool)
+		collectCategoryIDs(&roots[i], subtree)
+		roots[i].Products = []models.Product{}
+		for _, product := range products {
+			if subtree[product.CategoryID] {
+				roots[i].Products = append(roots[i].Products, product)
+			}
+		}
+	}
+	return roots, nil
+}
+
+// SubtreeIDs 获取分类及其所有子孙分类的ID
+func (s *CategoryService) SubtreeIDs(id uint) []uint {
+	categories, _ := s.loadAll(false)
+	children := indexChildren(categories)
+
+	ids := []uint{id}
+	for i := 0; i < len(ids); i++ {
+		for _, child := range children[ids[i]] {
+			ids = append(ids, child.ID)
+		}
+	}
+	return ids
+}
+
+// Breadcrumb 获取从顶级分类到指定分类的路径
+func (s *CategoryService) Breadcrumb(id uint) []models.CategoryCrumb {
+	categories, _ := s.loadAll(false)
+	byID := make(map[uint]models.Category, len(categories))
+	for _, category := range categories {
+		byID[category.ID] = category
+	}
+
+	var path []models.CategoryCrumb
+	visited := make(map[uint]bool)
+	for current, ok := byID[id]; ok && !visited[current.ID]; {
+		visited[current.ID] = true
+		path = append([]models.CategoryCrumb{{ID: current.ID, Name: current.Name}}, path...)
+		if current.ParentID == nil {
+			break
+		}
+		current, ok = byID[*current.ParentID]
+	}
+	return path
+}
+
+// loadAll 加载全部分类（分类数量有限，层级关系在内存中计算）
+func (s *CategoryService) loadAll(activeOnly bool) ([]models.Category, error) {
+	var categories []models.Category
+	db := database.GetDB()
+	if activeOnly {
+		db = db.Where("is_active = ?", true)
+	}
+	if err := db.Order("sort asc, id asc").Find(&categories).Error; err != nil {
 		return nil, err
 	}
 	return categories, nil
 }
 
+// indexChildren 按父分类ID分组（顶级分类的键为 0）
+func indexChildren(categories []models.Category) map[uint][]models.Category {
+	children := make(map[uint][]models.Category)
+	for _, category := range categories {
+		var parentID uint
+		if category.ParentID != nil {
+			parentID = *category.ParentID
+		}
+		children[parentID] = append(children[parentID], category)
+	}
+	return children
+}
+
+// buildCategoryTree 构建指定父分类下的子树
+func buildCategoryTree(children map[uint][]models.Category, parentID uint) []models.Category {
+	nodes := children[parentID]
+	// 防止数据异常形成环时无限递归
+	delete(children, parentID)
+	for i := range nodes {
+		nodes[i].Children = buildCategoryTree(children, nodes[i].ID)
+	}
+	return nodes
+}
+
+// collectCategoryIDs 收集子树中所有分类ID
+func collectCategoryIDs(category *models.Category, ids map[uint]bool) {
+	ids[category.ID] = true
+	for i := range category.Children {
+		collectCategoryIDs(&category.Children[i], ids)
+	}
+}
+
 // Count 获取分类数量
 func (s *CategoryService) Count() int64 {
 	var count int64
@@ -81,4 +267,11 @@ func (s *CategoryService) Count() int64 {
 }
 
 // 错误定义
-var ErrCategoryHasProducts = &ServiceError{Message: "该分类下有商品，无法删除"}
+var (
+	ErrCategoryNotFound    = &ServiceError{Code: "category_not_found", Status: http.StatusNotFound, Message: "分类不存在"}
+	ErrCategoryHasProducts = &ServiceError{Code: "category_has_products", Status: http.StatusConflict, Message: "该分类下有商品，无法删除"}
+	ErrCategoryHasChildren = &ServiceError{Code: "category_has_children", Status: http.StatusConflict, Message: "该分类下有子分类，无法删除"}
+	ErrCategoryCycle       = &ServiceError{Code: "category_cycle", Message: "不能将分类移动到自身或其子分类下"}
+	ErrCategoryNotArchived = &ServiceError{Code: "category_not_archived", Status: http.StatusNotFound, Message: "分类不存在或未归档"}
+	ErrCategoryArchived    = &ServiceError{Code: "category_archived", Status: http.StatusConflict, Message: "所属分类已归档，请先恢复该分类"}
+)
@@ -0,0 +1,192 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/nodeloc-faka/logging"
+	"github.com/nodeloc-faka/models"
+)
+
+// 支付方式
+const (
+	PayMethodNodeLoc = "nodeloc" // NodeLoc Payment（默认）
+	PayMethodBalance = "balance" // 账户余额
+)
+
+// PendingOrderTTL 待支付订单的有效期
+const PendingOrderTTL = 30 * time.Minute
+
+// CheckoutRequest 下单请求
+type CheckoutRequest struct {
+	UserID    uint
+	ProductID uint
+	VariantID uint // 为 0 时使用默认规格
+	Quantity  int
+	Contact   string
+	Remark    string
+	PayMethod string // 为空时使用 NodeLoc Payment
+}
+
+// CheckoutResult 下单结果
+type CheckoutResult struct {
+	Order      *models.Order
+	PaymentURL string // 需要跳转支付时不为空
+}
+
+// CheckoutService 下单服务：所有下单接口共用的校验、创建订单和发起支付流程
+type CheckoutService struct {
+	orderService     *OrderService
+	productService   *ProductService
+	variantService   *VariantService
+	paymentService   *PaymentService
+	cardKeyService   *CardKeyService
+	flashSaleService *FlashSaleService
+}
+
+// NewCheckoutService 创建下单服务
+func NewCheckoutService() *CheckoutService {
+	return &CheckoutService{
+		orderService:     NewOrderService(),
+		productService:   NewProductService(),
+		variantService:   NewVariantService(),
+		paymentService:   NewPaymentService(),
+		cardKeyService:   NewCardKeyService(),
+		flashSaleService: NewFlashSaleService(),
+	}
+}
+
+// Checkout 校验并创建订单，然后按支付方式完成支付：
+// 余额支付立即扣款发货；未配置 NodeLoc Payment 时按免费模式直接完成；否则发起支付并返回支付链接
+func (s *CheckoutService) Checkout(ctx context.Context, req *CheckoutRequest) (*CheckoutResult, error) {
+	if req.Quantity <= 0 {
+		return nil, ErrInvalidQuantity
+	}
+	if req.PayMethod == "" {
+		req.PayMethod = PayMethodNodeLoc
+	}
+	if req.PayMethod != PayMethodNodeLoc && req.PayMethod != PayMethodBalance {
+		return nil, ErrUnsupportedPayMethod
+	}
+
+	product, err := s.productService.FindByID(req.ProductID)
+	if err != nil {
+		return nil, ErrProductNotFound
+	}
+	// 按抢购时间窗口判断是否可售，抢购开始或结束的瞬间不依赖调度器更新上下架状态
+	if !s.flashSaleService.ProductAvailable(product.ID, time.Now()) {
+		return nil, ErrProductInactive
+	}
+	variant, err := s.variantService.Resolve(product.ID, req.VariantID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 检查库存（人工发货商品无需卡密库存）
+	if !product.IsManualDelivery() {
+		if available := s.cardKeyService.CountByVariant(variant.ID, models.CardKeyStatusAvailable); available < int64(req.Quantity) {
+			return nil, ErrInsufficientStock.WithDetails(map[string]interface{}{"available": available})
+		}
+	}
+
+	expiredAt := time.Now().Add(PendingOrderTTL)
+	order := &models.Order{
+		UserID:    req.UserID,
+		ProductID: product.ID,
+		VariantID: variant.ID,
+		Quantity:  req.Quantity,
+		Status:    models.OrderStatusPending,
+		PayMethod: req.PayMethod,
+		Contact:   req.Contact,
+		Remark:    req.Remark,
+		ExpiredAt: &expiredAt,
+	}
+	if err := s.orderService.CreatePriced(order, product, variant); err != nil {
+		return nil, err
+	}
+	order.Product = product
+	order.Variant = variant
+	ctx = logging.With(ctx, logging.OrderNo(order.OrderNo))
+
+	switch {
+	case req.PayMethod == PayMethodBalance:
+		// 返回错误时扣款未完成，订单仍为待支付；扣款成功后发货失败的订单保持已支付，由管理员处理
+		paid, err := s.orderService.PayWithBalance(order)
+		if err != nil {
+			s.cancelUnpaid(ctx, order)
+			return nil, err
+		}
+		return &CheckoutResult{Order: paid}, nil
+	case !s.paymentService.IsConfigured():
+		completed, err := s.orderService.CompleteFree(order)
+		if err != nil {
+			return nil, err
+		}
+		return &CheckoutResult{Order: completed}, nil
+	}
+
+	result, err := s.startPayment(ctx, order)
+	if err != nil {
+		// 未能发起支付的订单直接取消，避免占用待支付订单名额和抢购名额
+		s.cancelUnpaid(ctx, order)
+		return nil, err
+	}
+	return result, nil
+}
+
+// cancelUnpaid 取消未能完成支付的订单；取消失败时订单保持待支付，到期后由定时任务取消并归还抢购名额
+func (s *CheckoutService) cancelUnpaid(ctx context.Context, order *models.Order) {
+	if err := s.orderService.Cancel(order.ID); err != nil {
+		logger.ErrorContext(ctx, "取消未支付的订单失败", "error", err)
+	}
+}
+
+// Repay 为用户自己的待支付订单重新发起支付
+func (s *CheckoutService) Repay(ctx context.Context, orderNo string, userID uint) (*CheckoutResult, error) {
+	order, err := s.orderService.FindOwned(orderNo, userID)
+	if err != nil {
+		return nil, err
+	}
+	if order.Status != models.OrderStatusPending {
+		return nil, ErrOrderNotPayable
+	}
+	if order.ExpiredAt != nil && time.Now().After(*order.ExpiredAt) {
+		return nil, ErrOrderExpired
+	}
+	return s.startPayment(logging.With(ctx, logging.OrderNo(order.OrderNo)), order)
+}
+
+// startPayment 向 NodeLoc Payment 发起支付并保存交易信息
+func (s *CheckoutService) startPayment(ctx context.Context, order *models.Order) (*CheckoutResult, error) {
+	name := order.ProductName
+	if order.VariantName != "" {
+		name = fmt.Sprintf("%s（%s）", order.ProductName, order.VariantName)
+	}
+	payResp, err := s.paymentService.CreatePayment(ctx, &CreatePaymentRequest{
+		Amount:      CreditAmount(order.TotalAmount),
+		Description: fmt.Sprintf("购买 %s x%d", name, order.Quantity),
+		OrderID:     order.OrderNo,
+	})
+	if err != nil {
+		logger.ErrorContext(ctx, "发起支付失败", "error", err)
+		return nil, ErrPaymentUnavailable
+	}
+
+	order.TransactionID = payResp.TransactionID
+	order.PaymentURL = payResp.PaymentURL
+	if err := s.orderService.SetPaymentInfo(order.ID, payResp.TransactionID, payResp.PaymentURL); err != nil {
+		return nil, err
+	}
+	logger.InfoContext(logging.With(ctx, logging.TransactionID(payResp.TransactionID)), "已发起支付", "amount", payResp.Amount)
+	return &CheckoutResult{Order: order, PaymentURL: payResp.PaymentURL}, nil
+}
+
+// 错误定义
+var (
+	ErrInvalidQuantity      = &ServiceError{Code: "invalid_quantity", Message: "购买数量无效"}
+	ErrUnsupportedPayMethod = &ServiceError{Code: "unsupported_pay_method", Message: "不支持的支付方式"}
+	ErrProductInactive      = &ServiceError{Code: "product_inactive", Message: "商品已下架"}
+	ErrPaymentUnavailable   = &ServiceError{Code: "payment_unavailable", Status: http.StatusBadGateway, Message: "发起支付失败，请稍后重试"}
+)
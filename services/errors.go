@@ -1,10 +1,55 @@
 package services
 
+import "net/http"
+
 // ServiceError 服务错误
+// Code 为稳定的机器可读错误码，客户端应以此判断错误类型；Message 为默认（中文）提示，
+// 其他语言的提示在 i18n 消息目录中按错误码查找
 type ServiceError struct {
+	Code    string
+	Status  int // HTTP 状态码，为 0 时视为 400
 	Message string
+	Details map[string]interface{}
 }
 
 func (e *ServiceError) Error() string {
 	return e.Message
 }
+
+// Is 按错误码比较，使附带了详情的错误副本也能通过 errors.Is 匹配
+func (e *ServiceError) Is(target error) bool {
+	t, ok := target.(*ServiceError)
+	return ok && e.Code != "" && e.Code == t.Code
+}
+
+// HTTPStatus 错误对应的 HTTP 状态码
+func (e *ServiceError) HTTPStatus() int {
+	if e.Status == 0 {
+		return http.StatusBadRequest
+	}
+	return e.Status
+}
+
+// WithDetails 返回附带详情的错误副本
+func (e *ServiceError) WithDetails(details map[string]interface{}) *ServiceError {
+	copied := *e
+	copied.Details = details
+	return &copied
+}
+
+// WithStatus 返回使用指定 HTTP 状态码的错误副本
+func (e *ServiceError) WithStatus(status int) *ServiceError {
+	copied := *e
+	copied.Status = status
+	return &copied
+}
+
+// 通用错误
+var (
+	ErrInvalidRequest = &ServiceError{Code: "invalid_request", Message: "参数错误"}
+	ErrUnauthorized   = &ServiceError{Code: "unauthorized", Status: http.StatusUnauthorized, Message: "未登录"}
+	ErrForbidden      = &ServiceError{Code: "forbidden", Status: http.StatusForbidden, Message: "没有操作权限"}
+	ErrNotFound       = &ServiceError{Code: "not_found", Status: http.StatusNotFound, Message: "资源不存在"}
+	ErrRateLimited    = &ServiceError{Code: "rate_limited", Status: http.StatusTooManyRequests, Message: "请求过于频繁，请稍后再试"}
+	ErrInternal       = &ServiceError{Code: "internal_error", Status: http.StatusInternalServerError, Message: "服务器内部错误"}
+)
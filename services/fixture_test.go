@@ -0,0 +1,93 @@
+package services
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/nodeloc-faka/database/dbtest"
+	"github.com/nodeloc-faka/models"
+	"gorm.io/gorm"
+)
+
+// testStore 服务测试使用的临时数据库，提供常用测试数据的写入方法
+type testStore struct {
+	t     *testing.T
+	db    *gorm.DB
+	users int
+}
+
+func newTestStore(t *testing.T) *testStore {
+	t.Helper()
+	return &testStore{t: t, db: dbtest.Open(t)}
+}
+
+// create 写入测试数据，失败时终止测试
+func (s *testStore) create(values ...interface{}) {
+	s.t.Helper()
+	for _, value := range values {
+		if err := s.db.Create(value).Error; err != nil {
+			s.t.Fatalf("写入测试数据失败: %v", err)
+		}
+	}
+}
+
+// setting 写入设置（SettingService.Set 使用了 MySQL 的 NOW()，测试中直接写表）
+func (s *testStore) setting(key, value string) {
+	s.t.Helper()
+	s.create(&models.Setting{Key: key, Value: value})
+}
+
+// user 创建普通用户
+func (s *testStore) user(balance float64) *models.User {
+	s.t.Helper()
+	s.users++
+	user := &models.User{NodeLocID: 9000 + s.users, Username: fmt.Sprintf("user%d", s.users), Balance: balance}
+	s.create(user)
+	return user
+}
+
+// product 创建自动发货商品及其默认规格，并为默认规格导入 cards 张卡密
+func (s *testStore) product(price float64, cards int) (*models.Product, *models.ProductVariant) {
+	s.t.Helper()
+	product := &models.Product{Name: "测试商品", Price: price, IsActive: true, DeliveryType: models.DeliveryTypeAuto, StockCount: cards}
+	s.create(product)
+	variant := &models.ProductVariant{ProductID: product.ID, Name: models.DefaultVariantName, Price: price, IsActive: true, StockCount: cards}
+	s.create(variant)
+	for i := 0; i < cards; i++ {
+		s.create(&models.CardKey{ProductID: product.ID, VariantID: variant.ID, CardNo: fmt.Sprintf("CARD-%d-%d", product.ID, i)})
+	}
+	return product, variant
+}
+
+// order 为 user 创建一笔待支付订单
+func (s *testStore) order(user *models.User, variant *models.ProductVariant, quantity int) *models.Order {
+	s.t.Helper()
+	order := &models.Order{
+		UserID:      user.ID,
+		ProductID:   variant.ProductID,
+		VariantID:   variant.ID,
+		Quantity:    quantity,
+		UnitPrice:   variant.Price,
+		TotalAmount: variant.Price * float64(quantity),
+		Status:      models.OrderStatusPending,
+	}
+	if err := NewOrderService().Create(order); err != nil {
+		s.t.Fatalf("创建测试订单失败: %v", err)
+	}
+	return order
+}
+
+// reload 重新读取记录
+func (s *testStore) reload(value interface{}, id uint) {
+	s.t.Helper()
+	if err := s.db.First(value, id).Error; err != nil {
+		s.t.Fatalf("读取测试数据失败: %v", err)
+	}
+}
+
+// soldCards 订单已分配的卡密数量
+func (s *testStore) soldCards(orderID uint) int64 {
+	var count int64
+	s.db.Model(&models.CardKey{}).Where("order_id = ? AND status = ?", orderID, models.CardKeyStatusSold).Count(&count)
+	return count
+}
@@ -0,0 +1,261 @@
+package services
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/nodeloc-faka/database"
+	"github.com/nodeloc-faka/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// FlashSaleService 限时抢购服务
+type FlashSaleService struct{}
+
+// NewFlashSaleService 创建限时抢购服务
+func NewFlashSaleService() *FlashSaleService {
+	return &FlashSaleService{}
+}
+
+// Create 创建限时抢购
+func (s *FlashSaleService) Create(sale *models.FlashSale) error {
+	if err := validateFlashSale(sale); err != nil {
+		return err
+	}
+	sale.Status = models.FlashSaleStatusScheduled
+	return database.GetDB().Create(sale).Error
+}
+
+// Update 更新限时抢购
+func (s *FlashSaleService) Update(sale *models.FlashSale) error {
+	if err := validateFlashSale(sale); err != nil {
+		return err
+	}
+	// 延长已结束的抢购时重新交给调度器处理
+	if sale.Status == models.FlashSaleStatusEnded && sale.EndAt.After(time.Now()) {
+		sale.Status = models.FlashSaleStatusScheduled
+	}
+	return database.GetDB().Save(sale).Error
+}
+
+// Delete 删除限时抢购（已有订单的抢购只能停用，不能删除）
+func (s *FlashSaleService) Delete(id uint) error {
+	var count int64
+	database.GetDB().Model(&models.Order{}).Where("flash_sale_id = ?", id).Count(&count)
+	if count > 0 {
+		return ErrFlashSaleHasOrders
+	}
+	return database.GetDB().Delete(&models.FlashSale{}, id).Error
+}
+
+// FindByID 根据ID查找限时抢购
+func (s *FlashSaleService) FindByID(id uint) (*models.FlashSale, error) {
+	var sale models.FlashSale
+	if err := database.GetDB().First(&sale, id).Error; err != nil {
+		return nil, err
+	}
+	return &sale, nil
+}
+
+// GetAll 获取限时抢购列表（productID 为 0 时返回全部）
+func (s *FlashSaleService) GetAll(productID uint) ([]models.FlashSale, error) {
+	var sales []models.FlashSale
+	db := database.GetDB()
+	if productID > 0 {
+		db = db.Where("product_id = ?", productID)
+	}
+	if err := db.Order("start_at desc").Find(&sales).Error; err != nil {
+		return nil, err
+	}
+	return sales, nil
+}
+
+// Running 获取规格当前进行中的抢购（规格专属抢购优先）
+func (s *FlashSaleService) Running(productID, variantID uint, now time.Time) *models.FlashSale {
+	var sale models.FlashSale
+	if err := database.GetDB().
+		Where("product_id = ? AND variant_id IN ? AND is_active = ?", productID, []uint{0, variantID}, true).
+		Where("start_at <= ? AND end_at > ?", now, now).
+		Order("variant_id desc, id asc").
+		First(&sale).Error; err != nil {
+		return nil
+	}
+	return &sale
+}
+
+// Decorate 为商品填充进行中或即将开始的抢购倒计时
+func (s *FlashSaleService) Decorate(products ...*models.Product) {
+	if len(products) == 0 {
+		return
+	}
+
+	ids := make([]uint, len(products))
+	for i, product := range products {
+		ids[i] = product.ID
+	}
+
+	now := time.Now()
+	var sales []models.FlashSale
+	database.GetDB().
+		Where("product_id IN ? AND is_active = ? AND end_at > ?", ids, true, now).
+		Order("start_at asc, id asc").
+		Find(&sales)
+
+	for _, product := range products {
+		for i := range sales {
+			if sales[i].ProductID != product.ID {
+				continue
+			}
+			sale := sales[i]
+			sale.ServerTime = &now
+			sale.StartsIn = int64(sale.StartAt.Sub(now).Seconds())
+			if sale.StartsIn < 0 {
+				sale.StartsIn = 0
+			}
+			sale.EndsIn = int64(sale.EndAt.Sub(now).Seconds())
+			if sale.TotalQuota > 0 {
+				sale.RemainingQuota = sale.TotalQuota - sale.SoldCount
+			}
+			product.FlashSale = &sale
+			break
+		}
+	}
+}
+
+// Reserve 在事务中为订单占用抢购名额
+// 锁定抢购行后再校验时间窗口、个人限购和总名额，保证并发下不会超卖
+func (s *FlashSaleService) Reserve(tx *gorm.DB, saleID, userID uint, quantity int) error {
+	var sale models.FlashSale
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&sale, saleID).Error; err != nil {
+		return ErrFlashSaleNotRunning
+	}
+
+	if !sale.IsRunningAt(time.Now()) {
+		return ErrFlashSaleNotRunning
+	}
+
+	if sale.PerUserLimit > 0 {
+		var bought int64
+		tx.Model(&models.Order{}).
+			Where("flash_sale_id = ? AND user_id = ? AND status NOT IN ?", saleID, userID,
+				[]int{models.OrderStatusCancelled, models.OrderStatusRefunded}).
+			Select("COALESCE(SUM(quantity), 0)").
+			Scan(&bought)
+		if int(bought)+quantity > sale.PerUserLimit {
+			return ErrFlashSaleUserLimit
+		}
+	}
+
+	if sale.TotalQuota > 0 && sale.SoldCount+quantity > sale.TotalQuota {
+		return ErrFlashSaleSoldOut
+	}
+
+	return tx.Model(&models.FlashSale{}).
+		Where("id = ?", saleID).
+		UpdateColumn("sold_count", gorm.Expr("sold_count + ?", quantity)).
+		Error
+}
+
+// Release 订单取消时在事务中归还抢购名额
+func (s *FlashSaleService) Release(tx *gorm.DB, order *models.Order) error {
+	if order.FlashSaleID == nil {
+		return nil
+	}
+	return tx.Model(&models.FlashSale{}).
+		Where("id = ?", *order.FlashSaleID).
+		UpdateColumn("sold_count", gorm.Expr("GREATEST(sold_count - ?, 0)", order.Quantity)).
+		Error
+}
+
+// ProductAvailable 商品在 now 时是否可售（按抢购时间窗口判断，见 availableAt）
+func (s *FlashSaleService) ProductAvailable(productID uint, now time.Time) bool {
+	var count int64
+	if err := database.GetDB().Model(&models.Product{}).
+		Scopes(availableAt(now)).
+		Where("products.id = ?", productID).
+		Count(&count).Error; err != nil {
+		logger.Error("查询商品是否可售失败", "product_id", productID, "error", err)
+		return false
+	}
+	return count > 0
+}
+
+// availableAt 筛选在 now 时可售的商品
+// 调度器每隔几秒才更新一次 products.is_active，这里直接按抢购时间窗口判断：
+// 开始时自动上架的抢购一开始商品即可售，已开始且结束时自动下架的抢购一结束商品即不可售
+func availableAt(now time.Time) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("((products.is_active = ? AND NOT EXISTS (SELECT 1 FROM flash_sales ended WHERE ended.product_id = products.id"+
+			" AND ended.is_active = ? AND ended.deactivate_on_end = ? AND ended.status = ? AND ended.end_at <= ?))"+
+			" OR EXISTS (SELECT 1 FROM flash_sales running WHERE running.product_id = products.id"+
+			" AND running.is_active = ? AND running.activate_on_start = ? AND running.start_at <= ? AND running.end_at > ?))",
+			true, true, true, models.FlashSaleStatusRunning, now,
+			true, true, now, now)
+	}
+}
+
+// Tick 处理抢购开始/结束时的商品自动上下架（由调度器定期调用）
+// 更新失败的抢购保持原状态，下次调用时重试
+func (s *FlashSaleService) Tick() {
+	now := time.Now()
+
+	var starting []models.FlashSale
+	if err := database.GetDB().
+		Where("status = ? AND is_active = ? AND start_at <= ? AND end_at > ?", models.FlashSaleStatusScheduled, true, now, now).
+		Find(&starting).Error; err != nil {
+		logger.Error("查询待开始的限时抢购失败", "error", err)
+	}
+	for _, sale := range starting {
+		if sale.ActivateOnStart {
+			if err := database.GetDB().Model(&models.Product{}).Where("id = ?", sale.ProductID).Update("is_active", true).Error; err != nil {
+				logger.Error("限时抢购开始时上架商品失败", "flash_sale_id", sale.ID, "product_id", sale.ProductID, "error", err)
+				continue
+			}
+		}
+		if err := database.GetDB().Model(&models.FlashSale{}).Where("id = ?", sale.ID).Update("status", models.FlashSaleStatusRunning).Error; err != nil {
+			logger.Error("更新限时抢购状态失败", "flash_sale_id", sale.ID, "error", err)
+			continue
+		}
+		logger.Info("限时抢购开始", "flash_sale_id", sale.ID, "name", sale.Name)
+	}
+
+	var ending []models.FlashSale
+	if err := database.GetDB().
+		Where("status IN ? AND end_at <= ?", []string{models.FlashSaleStatusScheduled, models.FlashSaleStatusRunning}, now).
+		Find(&ending).Error; err != nil {
+		logger.Error("查询已到结束时间的限时抢购失败", "error", err)
+	}
+	for _, sale := range ending {
+		// 只有实际开始过的抢购才需要在结束时下架
+		if sale.DeactivateOnEnd && sale.Status == models.FlashSaleStatusRunning {
+			if err := database.GetDB().Model(&models.Product{}).Where("id = ?", sale.ProductID).Update("is_active", false).Error; err != nil {
+				logger.Error("限时抢购结束时下架商品失败", "flash_sale_id", sale.ID, "product_id", sale.ProductID, "error", err)
+				continue
+			}
+		}
+		if err := database.GetDB().Model(&models.FlashSale{}).Where("id = ?", sale.ID).Update("status", models.FlashSaleStatusEnded).Error; err != nil {
+			logger.Error("更新限时抢购状态失败", "flash_sale_id", sale.ID, "error", err)
+			continue
+		}
+		logger.Info("限时抢购结束", "flash_sale_id", sale.ID, "name", sale.Name)
+	}
+}
+
+// validateFlashSale 校验抢购设置
+func validateFlashSale(sale *models.FlashSale) error {
+	if !sale.EndAt.After(sale.StartAt) || sale.SalePrice <= 0 || sale.PerUserLimit < 0 || sale.TotalQuota < 0 {
+		return ErrInvalidFlashSale
+	}
+	return nil
+}
+
+// 错误定义
+var (
+	ErrInvalidFlashSale    = &ServiceError{Code: "invalid_flash_sale", Message: "限时抢购设置无效"}
+	ErrFlashSaleNotRunning = &ServiceError{Code: "flash_sale_not_running", Status: http.StatusConflict, Message: "限时抢购未开始或已结束"}
+	ErrFlashSaleUserLimit  = &ServiceError{Code: "flash_sale_user_limit", Message: "已超过个人限购数量"}
+	ErrFlashSaleSoldOut    = &ServiceError{Code: "flash_sale_sold_out", Status: http.StatusConflict, Message: "限时抢购名额已售罄"}
+	ErrFlashSaleHasOrders  = &ServiceError{Code: "flash_sale_has_orders", Status: http.StatusConflict, Message: "该抢购已有订单，无法删除，请停用"}
+	ErrFlashSaleNotFound   = &ServiceError{Code: "flash_sale_not_found", Status: http.StatusNotFound, Message: "限时抢购不存在"}
+)
@@ -0,0 +1,181 @@
+package services
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nodeloc-faka/models"
+)
+
+func TestProductAvailableFollowsSaleWindow(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name          string
+		productActive bool
+		sale          models.FlashSale
+		want          bool
+	}{
+		{"started before tick activates product", false,
+			models.FlashSale{StartAt: now.Add(-time.Second), EndAt: now.Add(time.Hour), ActivateOnStart: true, Status: models.FlashSaleStatusScheduled}, true},
+		{"not started yet", false,
+			models.FlashSale{StartAt: now.Add(time.Second), EndAt: now.Add(time.Hour), ActivateOnStart: true, Status: models.FlashSaleStatusScheduled}, false},
+		{"ended before tick deactivates product", true,
+			models.FlashSale{StartAt: now.Add(-time.Hour), EndAt: now, DeactivateOnEnd: true, Status: models.FlashSaleStatusRunning}, false},
+		{"ended without deactivate on end", true,
+			models.FlashSale{StartAt: now.Add(-time.Hour), EndAt: now, Status: models.FlashSaleStatusRunning}, true},
+		{"ended and processed by tick", true,
+			models.FlashSale{StartAt: now.Add(-time.Hour), EndAt: now.Add(-time.Minute), DeactivateOnEnd: true, Status: models.FlashSaleStatusEnded}, true},
+		{"inactive product without sale window", false,
+			models.FlashSale{StartAt: now.Add(-time.Hour), EndAt: now.Add(time.Hour), Status: models.FlashSaleStatusRunning}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newTestStore(t)
+			product, _ := store.product(10, 1)
+			if err := store.db.Model(product).Update("is_active", tt.productActive).Error; err != nil {
+				t.Fatal(err)
+			}
+			sale := tt.sale
+			sale.ProductID = product.ID
+			sale.SalePrice = 5
+			store.create(&sale)
+
+			if got := NewFlashSaleService().ProductAvailable(product.ID, now); got != tt.want {
+				t.Fatalf("ProductAvailable = %v，期望 %v", got, tt.want)
+			}
+			products, err := NewProductService().GetActive()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if listed := len(products) == 1; listed != tt.want {
+				t.Fatalf("商品列表中可见 = %v，期望 %v", listed, tt.want)
+			}
+		})
+	}
+}
+
+// flashSaleOrder 按抢购价为 user 下单，占用抢购名额
+func flashSaleOrder(product *models.Product, variant *models.ProductVariant, user *models.User, quantity int) error {
+	order := &models.Order{
+		UserID:    user.ID,
+		ProductID: product.ID,
+		VariantID: variant.ID,
+		Quantity:  quantity,
+		Status:    models.OrderStatusPending,
+	}
+	return NewOrderService().CreatePriced(order, product, variant)
+}
+
+func TestReserveDoesNotOversell(t *testing.T) {
+	const quota, buyers = 5, 12
+	store := newTestStore(t)
+	product, variant := store.product(10, buyers)
+	sale := &models.FlashSale{ProductID: product.ID, SalePrice: 5, TotalQuota: quota,
+		StartAt: time.Now().Add(-time.Minute), EndAt: time.Now().Add(time.Hour)}
+	store.create(sale)
+
+	users := make([]*models.User, buyers)
+	for i := range users {
+		users[i] = store.user(0)
+	}
+	var wg sync.WaitGroup
+	errs := make(chan error, buyers)
+	for _, user := range users {
+		wg.Add(1)
+		go func(user *models.User) {
+			defer wg.Done()
+			errs <- flashSaleOrder(product, variant, user, 1)
+		}(user)
+	}
+	wg.Wait()
+	close(errs)
+
+	succeeded := 0
+	for err := range errs {
+		switch {
+		case err == nil:
+			succeeded++
+		case !errors.Is(err, ErrFlashSaleSoldOut):
+			t.Errorf("下单返回 %v", err)
+		}
+	}
+	store.reload(sale, sale.ID)
+	if succeeded != quota || sale.SoldCount != quota {
+		t.Fatalf("成功下单 %d 笔，已售 %d，期望都等于名额 %d", succeeded, sale.SoldCount, quota)
+	}
+}
+
+func TestReservePerUserLimit(t *testing.T) {
+	tests := []struct {
+		name      string
+		earlier   []int // 之前的订单数量
+		cancelled bool  // 之前的订单是否已取消
+		quantity  int
+		want      error
+	}{
+		{"within limit", nil, false, 2, nil},
+		{"single order above limit", nil, false, 3, ErrFlashSaleUserLimit},
+		{"earlier orders count toward limit", []int{1}, false, 2, ErrFlashSaleUserLimit},
+		{"fills remaining limit", []int{1}, false, 1, nil},
+		{"cancelled orders do not count", []int{2}, true, 2, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newTestStore(t)
+			product, variant := store.product(10, 10)
+			store.create(&models.FlashSale{ProductID: product.ID, SalePrice: 5, PerUserLimit: 2,
+				StartAt: time.Now().Add(-time.Minute), EndAt: time.Now().Add(time.Hour)})
+			user := store.user(0)
+
+			for _, quantity := range tt.earlier {
+				if err := flashSaleOrder(product, variant, user, quantity); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if tt.cancelled {
+				if err := store.db.Model(&models.Order{}).Where("user_id = ?", user.ID).
+					Update("status", models.OrderStatusCancelled).Error; err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := flashSaleOrder(product, variant, user, tt.quantity); !errors.Is(err, tt.want) {
+				t.Fatalf("下单返回 %v，期望 %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestReservePerUserLimitConcurrent(t *testing.T) {
+	store := newTestStore(t)
+	product, variant := store.product(10, 10)
+	store.create(&models.FlashSale{ProductID: product.ID, SalePrice: 5, PerUserLimit: 2,
+		StartAt: time.Now().Add(-time.Minute), EndAt: time.Now().Add(time.Hour)})
+	user := store.user(0)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 4)
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- flashSaleOrder(product, variant, user, 1)
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	succeeded := 0
+	for err := range errs {
+		switch {
+		case err == nil:
+			succeeded++
+		case !errors.Is(err, ErrFlashSaleUserLimit):
+			t.Errorf("下单返回 %v", err)
+		}
+	}
+	if succeeded != 2 {
+		t.Fatalf("同一用户并发下单成功 %d 笔，期望限购 2 笔", succeeded)
+	}
+}
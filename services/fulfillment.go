@@ -0,0 +1,361 @@
+package services
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nodeloc-faka/database"
+	"github.com/nodeloc-faka/i18n"
+	"github.com/nodeloc-faka/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// FulfillmentService 人工发货服务
+type FulfillmentService struct {
+	settingService      *SettingService
+	notificationService *NotificationService
+}
+
+// NewFulfillmentService 创建人工发货服务
+func NewFulfillmentService() *FulfillmentService {
+	return &FulfillmentService{
+		settingService:      NewSettingService(),
+		notificationService: NewNotificationService(),
+	}
+}
+
+// DefaultFulfillSLAMinutes 默认发货时限（分钟）
+const DefaultFulfillSLAMinutes = 1440
+
+// 待发货队列筛选状态
+const (
+	FulfillStateUnclaimed = "unclaimed" // 未认领
+	FulfillStateClaimed   = "claimed"   // 已认领
+	FulfillStateOverdue   = "overdue"   // 已超时
+)
+
+// FulfillmentQueueFilter 待发货队列筛选条件
+type FulfillmentQueueFilter struct {
+	State     string // unclaimed / claimed / overdue，为空表示全部
+	ClaimedBy uint   // 认领人
+	ProductID uint   // 商品
+	Keyword   string // 订单号或联系方式
+}
+
+// FulfillmentItem 待发货队列条目
+type FulfillmentItem struct {
+	models.Order
+	SLARemaining int64 `json:"sla_remaining_seconds"` // 距离发货时限的剩余秒数，超时为负数
+	Overdue      bool  `json:"overdue"`
+}
+
+// SLA 获取当前配置的发货时限
+func (s *FulfillmentService) SLA() time.Duration {
+	minutes, err := strconv.Atoi(s.settingService.Get(SettingFulfillSLAMinutes))
+	if err != nil || minutes <= 0 {
+		minutes = DefaultFulfillSLAMinutes
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
+// Enqueue 将已支付的人工发货订单加入待发货队列
+func (s *FulfillmentService) Enqueue(order *models.Order) error {
+	deadline := time.Now().Add(s.SLA())
+	order.Status = models.OrderStatusPaid
+	order.FulfillDeadline = &deadline
+	return database.GetDB().Model(&models.Order{}).
+		Where("id = ?", order.ID).
+		Updates(map[string]interface{}{
+			"status":           models.OrderStatusPaid,
+			"fulfill_deadline": deadline,
+		}).Error
+}
+
+// fulfillmentSorts 待发货队列允许的排序字段
+var fulfillmentSorts = listSorts{
+	table: "orders",
+	columns: map[string]string{
+		"id":               "orders.id",
+		"fulfill_deadline": "orders.fulfill_deadline",
+		"paid_at":          "COALESCE(orders.paid_at, '1970-01-01')",
+		"total_amount":     "orders.total_amount",
+	},
+	def: "fulfill_deadline,id",
+}
+
+// GetQueue 分页获取待发货队列（默认按发货时限升序）
+func (s *FulfillmentService) GetQueue(filter FulfillmentQueueFilter, query ListQuery) ([]FulfillmentItem, ListPage, error) {
+	var orders []models.Order
+
+	now := time.Now()
+	db := database.GetDB().Model(&models.Order{}).
+		Where("status = ? AND fulfill_deadline IS NOT NULL", models.OrderStatusPaid)
+
+	switch filter.State {
+	case FulfillStateUnclaimed:
+		db = db.Where("claimed_by IS NULL")
+	case FulfillStateClaimed:
+		db = db.Where("claimed_by IS NOT NULL")
+	case FulfillStateOverdue:
+		db = db.Where("fulfill_deadline < ?", now)
+	}
+	if filter.ClaimedBy > 0 {
+		db = db.Where("claimed_by = ?", filter.ClaimedBy)
+	}
+	if filter.ProductID > 0 {
+		db = db.Where("product_id = ?", filter.ProductID)
+	}
+	if keyword := strings.TrimSpace(filter.Keyword); keyword != "" {
+		db = db.Where("order_no = ? OR contact LIKE ?", keyword, "%"+escapeLike(keyword)+"%")
+	}
+	page, err := paginate(db, query, fulfillmentSorts, &orders, func(db *gorm.DB) *gorm.DB {
+		return db.Preload("User").Preload("Product", includeArchived)
+	})
+	if err != nil {
+		return nil, ListPage{}, err
+	}
+
+	items := make([]FulfillmentItem, len(orders))
+	for i, order := range orders {
+		remaining := int64(order.FulfillDeadline.Sub(now).Seconds())
+		items[i] = FulfillmentItem{
+			Order:        order,
+			SLARemaining: remaining,
+			Overdue:      remaining < 0,
+		}
+	}
+	return items, page, nil
+}
+
+// CountPending 获取待发货订单数量
+func (s *FulfillmentService) CountPending() int64 {
+	var count int64
+	database.GetDB().Model(&models.Order{}).
+		Where("status = ? AND fulfill_deadline IS NOT NULL", models.OrderStatusPaid).
+		Count(&count)
+	return count
+}
+
+// Claim 认领待发货订单
+func (s *FulfillmentService) Claim(orderID, adminID uint) error {
+	if _, err := s.findQueued(database.GetDB(), orderID); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	result := database.GetDB().Model(&models.Order{}).
+		Where("id = ? AND status = ? AND (claimed_by IS NULL OR claimed_by = ?)", orderID, models.OrderStatusPaid, adminID).
+		Updates(map[string]interface{}{
+			"claimed_by": adminID,
+			"claimed_at": now,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrOrderClaimedByOther
+	}
+	return nil
+}
+
+// Release 放弃认领
+func (s *FulfillmentService) Release(orderID, adminID uint) error {
+	result := database.GetDB().Model(&models.Order{}).
+		Where("id = ? AND status = ? AND claimed_by = ?", orderID, models.OrderStatusPaid, adminID).
+		Updates(map[string]interface{}{
+			"claimed_by": nil,
+			"claimed_at": nil,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrOrderNotClaimed
+	}
+	return nil
+}
+
+// Deliver 填写发货内容并完成订单
+// 发货内容格式与批量导入卡密一致，每行一条，作为卡密挂到订单上，条数需与购买数量一致
+func (s *FulfillmentService) Deliver(orderID, adminID uint, content string) (*models.Order, error) {
+	var cards []models.CardKey
+	now := time.Now()
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		cardNo, cardPwd := parseCardLine(line)
+		if len(cardNo) > 500 || len(cardPwd) > 500 {
+			return nil, ErrDeliveryContentTooLong
+		}
+		cards = append(cards, models.CardKey{
+			CardNo:  cardNo,
+			CardPwd: cardPwd,
+			Status:  models.CardKeyStatusSold,
+			SoldAt:  &now,
+		})
+	}
+	if len(cards) == 0 {
+		return nil, ErrDeliveryContentEmpty
+	}
+
+	var order *models.Order
+	err := database.GetDB().Transaction(func(tx *gorm.DB) error {
+		var err error
+		order, err = s.findQueued(tx.Clauses(clause.Locking{Strength: "UPDATE"}), orderID)
+		if err != nil {
+			return err
+		}
+		if order.ClaimedBy != nil && *order.ClaimedBy != adminID {
+			return ErrOrderClaimedByOther
+		}
+		if len(cards) != order.Quantity {
+			return ErrDeliveryQuantityMismatch.WithDetails(map[string]interface{}{
+				"expected": order.Quantity,
+				"actual":   len(cards),
+			})
+		}
+
+		for i := range cards {
+			cards[i].ProductID = order.ProductID
+			cards[i].VariantID = order.VariantID
+			cards[i].OrderID = &order.ID
+		}
+		if err := tx.Create(&cards).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Model(&models.Order{}).
+			Where("id = ?", order.ID).
+			Updates(map[string]interface{}{
+				"status":       models.OrderStatusCompleted,
+				"claimed_by":   adminID,
+				"delivered_at": now,
+			}).Error; err != nil {
+			return err
+		}
+
+		if order.VariantID > 0 {
+			if err := tx.Model(&models.ProductVariant{}).
+				Where("id = ?", order.VariantID).
+				UpdateColumn("sales_count", gorm.Expr("sales_count + ?", order.Quantity)).
+				Error; err != nil {
+				return err
+			}
+		}
+
+		return tx.Model(&models.Product{}).
+			Where("id = ?", order.ProductID).
+			UpdateColumn("sales_count", gorm.Expr("sales_count + ?", order.Quantity)).
+			Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	locale := s.notificationService.UserLocale(order.UserID)
+	s.notificationService.Notify(
+		order.UserID,
+		models.NotificationOrderDelivered,
+		i18n.T(locale, "notification.order_delivered.title", ""),
+		i18n.Tf(locale, "notification.order_delivered.content", order.OrderNo),
+		order.OrderNo,
+	)
+
+	return NewOrderService().FindByID(order.ID)
+}
+
+// Reject 拒绝发货：余额或 NodeLoc Payment 支付的订单退款至用户余额并标记为已退款，
+// 免费模式下完成的订单没有收款，只取消订单
+func (s *FulfillmentService) Reject(orderID, adminID uint, reason string) (*models.Order, error) {
+	var order *models.Order
+	var refunded bool
+	err := database.GetDB().Transaction(func(tx *gorm.DB) error {
+		var err error
+		order, err = s.findQueued(tx.Clauses(clause.Locking{Strength: "UPDATE"}), orderID)
+		if err != nil {
+			return err
+		}
+		if order.ClaimedBy != nil && *order.ClaimedBy != adminID {
+			return ErrOrderClaimedByOther
+		}
+
+		refunded = isCollected(order)
+		status := models.OrderStatusCancelled
+		if refunded {
+			status = models.OrderStatusRefunded
+		}
+		if err := tx.Model(&models.Order{}).
+			Where("id = ?", order.ID).
+			Updates(map[string]interface{}{
+				"status":        status,
+				"claimed_by":    adminID,
+				"reject_reason": reason,
+			}).Error; err != nil {
+			return err
+		}
+
+		if !refunded {
+			return nil
+		}
+		return tx.Model(&models.User{}).
+			Where("id = ?", order.UserID).
+			UpdateColumn("balance", gorm.Expr("balance + ?", order.TotalAmount)).
+			Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	locale := s.notificationService.UserLocale(order.UserID)
+	notificationType := models.NotificationOrderRefunded
+	content := i18n.Tf(locale, "notification.order_refunded.content", order.OrderNo, order.TotalAmount)
+	if !refunded {
+		notificationType = models.NotificationOrderRejected
+		content = i18n.Tf(locale, "notification.order_rejected.content", order.OrderNo)
+	}
+	if reason != "" {
+		content += i18n.Tf(locale, "notification.order_refunded.reason", reason)
+	}
+	s.notificationService.Notify(order.UserID, notificationType,
+		i18n.T(locale, "notification."+notificationType+".title", ""), content, order.OrderNo)
+
+	return NewOrderService().FindByID(order.ID)
+}
+
+// isCollected 订单是否实际收款：余额支付，或已通过 NodeLoc Payment 支付
+func isCollected(order *models.Order) bool {
+	switch order.PayMethod {
+	case PayMethodBalance:
+		return true
+	case PayMethodNodeLoc:
+		return order.PaidAt != nil
+	}
+	return false
+}
+
+// findQueued 查找处于待发货队列中的订单
+func (s *FulfillmentService) findQueued(db *gorm.DB, orderID uint) (*models.Order, error) {
+	var order models.Order
+	if err := db.First(&order, orderID).Error; err != nil {
+		return nil, ErrOrderNotFound
+	}
+	if order.Status != models.OrderStatusPaid || order.FulfillDeadline == nil {
+		return nil, ErrOrderNotInQueue
+	}
+	return &order, nil
+}
+
+// 错误定义
+var (
+	ErrOrderNotInQueue          = &ServiceError{Code: "order_not_in_queue", Status: http.StatusConflict, Message: "订单不在待发货队列中"}
+	ErrOrderClaimedByOther      = &ServiceError{Code: "order_claimed_by_other", Status: http.StatusConflict, Message: "订单已被其他管理员认领"}
+	ErrOrderNotClaimed          = &ServiceError{Code: "order_not_claimed", Status: http.StatusForbidden, Message: "您未认领该订单"}
+	ErrDeliveryContentEmpty     = &ServiceError{Code: "delivery_content_empty", Message: "发货内容不能为空"}
+	ErrDeliveryContentTooLong   = &ServiceError{Code: "delivery_content_too_long", Message: "单条发货内容不能超过 500 个字符"}
+	ErrDeliveryQuantityMismatch = &ServiceError{Code: "delivery_quantity_mismatch", Message: "发货内容条数与购买数量不一致"}
+)
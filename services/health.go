@@ -0,0 +1,190 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nodeloc-faka/database"
+	"github.com/nodeloc-faka/models"
+)
+
+// 健康检查状态
+const (
+	HealthOK       = "ok"       // 检查通过
+	HealthFail     = "fail"     // 检查未通过，实例不应接收流量
+	HealthDisabled = "disabled" // 可选功能未启用，不影响就绪状态
+)
+
+// HealthCheckTimeout 单项检查的超时时间
+const HealthCheckTimeout = 2 * time.Second
+
+// HealthProbe 单项检查，返回 HealthOK / HealthFail / HealthDisabled 及说明
+type HealthProbe func(ctx context.Context) (status, message string)
+
+// HealthCheck 单项检查结果
+type HealthCheck struct {
+	Name       string `json:"name"`
+	Status     string `json:"status"`
+	Message    string `json:"message,omitempty"`
+	DurationMs int64  `json:"duration_ms"`
+}
+
+// HealthReport 就绪检查报告
+type HealthReport struct {
+	Status    string        `json:"status"` // 全部检查通过或未启用时为 ok，否则为 fail
+	Checks    []HealthCheck `json:"checks"`
+	CheckedAt time.Time     `json:"checked_at"`
+}
+
+// Ready 实例是否可以接收流量
+func (r *HealthReport) Ready() bool {
+	return r.Status == HealthOK
+}
+
+// healthProbeEntry 已注册的检查项
+type healthProbeEntry struct {
+	name  string
+	probe HealthProbe
+}
+
+// HealthService 就绪检查服务：数据库连接、结构版本、支付配置和上传目录为内置检查项，
+// OAuth、定时任务等依赖运行时对象的检查由调用方通过 Register 注册
+type HealthService struct {
+	paymentService *PaymentService
+	uploadDir      string
+	probes         []healthProbeEntry
+}
+
+// NewHealthService 创建就绪检查服务，uploadDir 为需要可写的上传目录
+func NewHealthService(uploadDir string) *HealthService {
+	s := &HealthService{
+		paymentService: NewPaymentService(),
+		uploadDir:      uploadDir,
+	}
+	s.Register("database", s.checkDatabase)
+	s.Register("migrations", s.checkMigrations)
+	s.Register("payment", s.checkPayment)
+	s.Register("uploads", s.checkUploads)
+	return s
+}
+
+// Register 注册检查项，按注册顺序出现在报告中（需在处理请求之前调用）
+func (s *HealthService) Register(name string, probe HealthProbe) {
+	s.probes = append(s.probes, healthProbeEntry{name: name, probe: probe})
+}
+
+// Check 并发执行全部检查，每项最多等待 HealthCheckTimeout
+func (s *HealthService) Check(ctx context.Context) *HealthReport {
+	report := &HealthReport{
+		Status:    HealthOK,
+		Checks:    make([]HealthCheck, len(s.probes)),
+		CheckedAt: time.Now(),
+	}
+
+	var wg sync.WaitGroup
+	for i, entry := range s.probes {
+		wg.Add(1)
+		go func(i int, entry healthProbeEntry) {
+			defer wg.Done()
+			report.Checks[i] = runHealthProbe(ctx, entry)
+		}(i, entry)
+	}
+	wg.Wait()
+
+	for _, check := range report.Checks {
+		if check.Status == HealthFail {
+			report.Status = HealthFail
+			logger.WarnContext(ctx, "就绪检查未通过", "check", check.Name, "message", check.Message)
+		}
+	}
+	return report
+}
+
+// runHealthProbe 执行单项检查，超时或 panic 时记为未通过
+func runHealthProbe(ctx context.Context, entry healthProbeEntry) HealthCheck {
+	ctx, cancel := context.WithTimeout(ctx, HealthCheckTimeout)
+	defer cancel()
+
+	start := time.Now()
+	done := make(chan HealthCheck, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- HealthCheck{Status: HealthFail, Message: fmt.Sprintf("检查异常: %v", r)}
+			}
+		}()
+		status, message := entry.probe(ctx)
+		done <- HealthCheck{Status: status, Message: message}
+	}()
+
+	var check HealthCheck
+	select {
+	case check = <-done:
+	case <-ctx.Done():
+		check = HealthCheck{Status: HealthFail, Message: "检查超时"}
+	}
+	check.Name = entry.name
+	check.DurationMs = time.Since(start).Milliseconds()
+	return check
+}
+
+// checkDatabase 检查数据库连接
+func (s *HealthService) checkDatabase(ctx context.Context) (string, string) {
+	sqlDB, err := database.GetDB().DB()
+	if err != nil {
+		return HealthFail, err.Error()
+	}
+	if err := sqlDB.PingContext(ctx); err != nil {
+		return HealthFail, fmt.Sprintf("数据库连接失败: %v", err)
+	}
+	return HealthOK, ""
+}
+
+// checkMigrations 检查数据库结构版本：低于当前代码要求的版本说明迁移未完成
+// 高于当前版本时为滚动升级中新版本实例已完成迁移，不影响旧实例
+func (s *HealthService) checkMigrations(ctx context.Context) (string, string) {
+	version, err := models.AppliedSchemaVersion(database.GetDB().WithContext(ctx))
+	if err != nil {
+		return HealthFail, fmt.Sprintf("读取结构版本失败: %v", err)
+	}
+	if version < models.SchemaVersion {
+		return HealthFail, fmt.Sprintf("数据库结构版本 %d，需要 %d", version, models.SchemaVersion)
+	}
+	return HealthOK, fmt.Sprintf("结构版本 %d", version)
+}
+
+// checkPayment 检查支付配置：未配置时为免费模式，配置不完整或格式不正确时未通过
+func (s *HealthService) checkPayment(ctx context.Context) (string, string) {
+	cfg := s.paymentService.GetConfig()
+	enabled := s.paymentService.settingService.Get(SettingPaymentEnabled) == "true"
+	switch {
+	case cfg.PaymentID == "" && cfg.SecretKey == "" && !enabled:
+		return HealthDisabled, "未配置支付，订单按免费模式完成"
+	case cfg.PaymentID == "" || cfg.SecretKey == "":
+		return HealthFail, "Payment ID 和密钥需同时配置"
+	case !strings.HasPrefix(cfg.PaymentID, "pay_"):
+		return HealthFail, "Payment ID 格式不正确，应以 pay_ 开头"
+	}
+	return HealthOK, ""
+}
+
+// checkUploads 检查上传目录可写：在目录中创建并删除一个临时文件
+func (s *HealthService) checkUploads(ctx context.Context) (string, string) {
+	if err := os.MkdirAll(s.uploadDir, 0755); err != nil {
+		return HealthFail, fmt.Sprintf("无法创建上传目录: %v", err)
+	}
+	file, err := os.CreateTemp(s.uploadDir, ".health-*")
+	if err != nil {
+		return HealthFail, fmt.Sprintf("上传目录不可写: %v", err)
+	}
+	name := file.Name()
+	file.Close()
+	if err := os.Remove(name); err != nil {
+		return HealthFail, fmt.Sprintf("无法删除临时文件: %v", err)
+	}
+	return HealthOK, ""
+}
@@ -0,0 +1,83 @@
+package services
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/nodeloc-faka/database"
+	"github.com/nodeloc-faka/models"
+	"gorm.io/gorm/clause"
+)
+
+// IdempotencyKeyTTL 幂等键保留时间，过期后相同的键可以重新使用
+const IdempotencyKeyTTL = 24 * time.Hour
+
+// MaxIdempotencyKeyLength 幂等键最大长度
+const MaxIdempotencyKeyLength = 100
+
+// IdempotencyService 幂等键服务
+type IdempotencyService struct{}
+
+// NewIdempotencyService 创建幂等键服务
+func NewIdempotencyService() *IdempotencyService {
+	return &IdempotencyService{}
+}
+
+// Begin 登记一次带幂等键的请求
+// 首次出现时返回新记录和 true；键已存在时返回已有记录和 false，由调用方比对请求摘要并重放响应
+func (s *IdempotencyService) Begin(userID uint, key, requestHash string) (*models.IdempotencyKey, bool, error) {
+	if key == "" || len(key) > MaxIdempotencyKeyLength {
+		return nil, false, ErrInvalidIdempotencyKey
+	}
+
+	db := database.GetDB()
+	// 过期的键视为不存在
+	db.Where("user_id = ? AND `key` = ? AND expires_at <= ?", userID, key, time.Now()).
+		Delete(&models.IdempotencyKey{})
+
+	record := &models.IdempotencyKey{
+		UserID:      userID,
+		Key:         key,
+		RequestHash: requestHash,
+		ExpiresAt:   time.Now().Add(IdempotencyKeyTTL),
+	}
+	result := db.Clauses(clause.OnConflict{DoNothing: true}).Create(record)
+	if result.Error != nil {
+		return nil, false, result.Error
+	}
+	if result.RowsAffected == 1 {
+		return record, true, nil
+	}
+
+	var existing models.IdempotencyKey
+	if err := db.Where("user_id = ? AND `key` = ?", userID, key).First(&existing).Error; err != nil {
+		return nil, false, err
+	}
+	return &existing, false, nil
+}
+
+// Complete 保存请求的响应，之后相同的请求直接重放
+func (s *IdempotencyService) Complete(record *models.IdempotencyKey, statusCode int, response string) error {
+	return database.GetDB().Model(record).Updates(map[string]interface{}{
+		"status_code": statusCode,
+		"response":    response,
+	}).Error
+}
+
+// Release 删除未完成的记录，允许客户端用相同的键重试
+func (s *IdempotencyService) Release(record *models.IdempotencyKey) error {
+	return database.GetDB().Delete(record).Error
+}
+
+// Cleanup 删除过期的幂等键
+func (s *IdempotencyService) Cleanup() (int64, error) {
+	result := database.GetDB().Where("expires_at <= ?", time.Now()).Delete(&models.IdempotencyKey{})
+	return result.RowsAffected, result.Error
+}
+
+// 错误定义
+var (
+	ErrInvalidIdempotencyKey = &ServiceError{Code: "invalid_idempotency_key", Message: "Idempotency-Key 无效"}
+	ErrIdempotencyKeyReused  = &ServiceError{Code: "idempotency_key_reused", Status: http.StatusUnprocessableEntity, Message: "Idempotency-Key 已用于不同的请求"}
+	ErrIdempotencyInProgress = &ServiceError{Code: "idempotency_in_progress", Status: http.StatusConflict, Message: "相同的请求正在处理中，请稍后重试"}
+)
@@ -0,0 +1,237 @@
+package services
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/nodeloc-faka/database"
+	"gorm.io/gorm"
+)
+
+// 后台列表分页限制
+const (
+	DefaultListPageSize = 20
+	MaxListPageSize     = 100
+)
+
+// ListQuery 后台列表的分页和排序参数
+// Cursor 不为空时使用游标分页：忽略 Page，也不统计总数，适合订单、卡密、审计日志等数据量大的表
+type ListQuery struct {
+	Page     int
+	PageSize int    // 超过 MaxListPageSize 时按 MaxListPageSize 处理
+	Sort     string // 逗号分隔的排序字段，"-" 前缀表示降序，如 "-created_at,id"；为空时使用列表的默认排序
+	Cursor   string // 上一页返回的 next_cursor
+}
+
+// ListPage 后台列表的分页信息
+type ListPage struct {
+	Total      *int64 // 游标分页时不统计，为 nil
+	Page       int
+	PageSize   int
+	NextCursor string // 还有下一页时返回
+}
+
+// listSorts 列表允许的排序字段
+type listSorts struct {
+	table   string            // 主表名，ID 列和游标定位都基于该表
+	columns map[string]string // 排序字段 -> SQL 表达式；可为空的列需用 COALESCE 包装，否则游标比较会失效
+	def     string            // 默认排序
+}
+
+// sortKey 解析后的排序项
+type sortKey struct {
+	name string
+	expr string
+	desc bool
+}
+
+// listCursor 游标内容：排序方式和上一页最后一条的 ID，排序值在查询时按 ID 读取
+type listCursor struct {
+	Sort string `json:"s"`
+	ID   uint   `json:"id"`
+}
+
+// paginate 按分页和排序参数查询列表，结果写入 dest（模型切片的指针，模型需有 ID 字段）
+// db 只应包含筛选条件，预加载等查询选项通过 scopes 传入，避免影响总数统计
+func paginate(db *gorm.DB, query ListQuery, sorts listSorts, dest interface{}, scopes ...func(*gorm.DB) *gorm.DB) (ListPage, error) {
+	if query.Page < 1 {
+		query.Page = 1
+	}
+	if query.PageSize <= 0 {
+		query.PageSize = DefaultListPageSize
+	}
+	if query.PageSize > MaxListPageSize {
+		query.PageSize = MaxListPageSize
+	}
+
+	keys, err := sorts.parse(query.Sort)
+	if err != nil {
+		return ListPage{}, err
+	}
+	signature := sortSignature(keys)
+	page := ListPage{Page: query.Page, PageSize: query.PageSize}
+
+	find := db.Session(&gorm.Session{})
+	if query.Cursor == "" {
+		var total int64
+		if err := db.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+			return ListPage{}, err
+		}
+		page.Total = &total
+		find = find.Offset((query.Page - 1) * query.PageSize)
+	} else {
+		cursor, err := decodeListCursor(query.Cursor)
+		if err != nil || cursor.Sort != signature {
+			return ListPage{}, ErrInvalidListCursor
+		}
+		// 游标所指的记录已被物理删除时无法定位
+		var count int64
+		if err := database.GetDB().Table(sorts.table).Where("id = ?", cursor.ID).Count(&count).Error; err != nil {
+			return ListPage{}, err
+		}
+		if count == 0 {
+			return ListPage{}, ErrInvalidListCursor
+		}
+		condition, args := sorts.after(keys, cursor.ID)
+		find = find.Where(condition, args...)
+	}
+
+	for _, key := range keys {
+		if key.desc {
+			find = find.Order(key.expr + " DESC")
+		} else {
+			find = find.Order(key.expr + " ASC")
+		}
+	}
+	// 多查一条用于判断是否还有下一页
+	if err := find.Scopes(scopes...).Limit(query.PageSize + 1).Find(dest).Error; err != nil {
+		return ListPage{}, err
+	}
+
+	rows := reflect.ValueOf(dest).Elem()
+	if rows.Len() > query.PageSize {
+		rows.Set(rows.Slice(0, query.PageSize))
+		last := reflect.Indirect(rows.Index(query.PageSize - 1))
+		page.NextCursor = encodeListCursor(listCursor{Sort: signature, ID: uint(last.FieldByName("ID").Uint())})
+	}
+	return page, nil
+}
+
+// parse 解析排序参数，未包含 id 时追加 id 保证顺序稳定（方向与最后一个排序字段相同）
+func (s listSorts) parse(raw string) ([]sortKey, error) {
+	if strings.TrimSpace(raw) == "" {
+		raw = s.def
+	}
+
+	var keys []sortKey
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(raw, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		desc := strings.HasPrefix(part, "-")
+		name := strings.TrimPrefix(part, "-")
+		expr, ok := s.columns[name]
+		if !ok {
+			return nil, ErrInvalidListSort.WithDetails(map[string]interface{}{"field": "sort", "allowed": s.allowed()})
+		}
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		keys = append(keys, sortKey{name: name, expr: expr, desc: desc})
+	}
+
+	if !seen["id"] {
+		desc := len(keys) > 0 && keys[len(keys)-1].desc
+		keys = append(keys, sortKey{name: "id", expr: s.idColumn(), desc: desc})
+	}
+	return keys, nil
+}
+
+// after 构造游标条件：排在游标所指记录之后的记录
+// 按 (k1, k2, ..., id) 依次比较，各排序值通过子查询按 ID 读取，支持升降序混用
+func (s listSorts) after(keys []sortKey, id uint) (string, []interface{}) {
+	var clauses []string
+	var args []interface{}
+	value := func(key sortKey) string {
+		args = append(args, id)
+		if key.expr == s.idColumn() {
+			return "?"
+		}
+		return "(SELECT " + key.expr + " FROM " + s.table + " WHERE " + s.idColumn() + " = ?)"
+	}
+
+	for i, key := range keys {
+		var parts []string
+		for _, prev := range keys[:i] {
+			parts = append(parts, prev.expr+" = "+value(prev))
+		}
+		op := " > "
+		if key.desc {
+			op = " < "
+		}
+		parts = append(parts, key.expr+op+value(key))
+		clauses = append(clauses, "("+strings.Join(parts, " AND ")+")")
+	}
+	return "(" + strings.Join(clauses, " OR ") + ")", args
+}
+
+// idColumn 主表的 ID 列
+func (s listSorts) idColumn() string {
+	return s.table + ".id"
+}
+
+// allowed 允许的排序字段（用于错误提示）
+func (s listSorts) allowed() []string {
+	names := make([]string, 0, len(s.columns))
+	for name := range s.columns {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// sortSignature 排序方式的规范写法，用于校验游标是否属于当前排序
+func sortSignature(keys []sortKey) string {
+	parts := make([]string, len(keys))
+	for i, key := range keys {
+		parts[i] = key.name
+		if key.desc {
+			parts[i] = "-" + key.name
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+// encodeListCursor 编码分页游标
+func encodeListCursor(cursor listCursor) string {
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeListCursor 解码分页游标
+func decodeListCursor(raw string) (listCursor, error) {
+	var cursor listCursor
+	data, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return cursor, err
+	}
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return cursor, err
+	}
+	if cursor.ID == 0 {
+		return cursor, ErrInvalidListCursor
+	}
+	return cursor, nil
+}
+
+// 错误定义
+var (
+	ErrInvalidListSort   = &ServiceError{Code: "invalid_sort", Message: "不支持的排序字段"}
+	ErrInvalidListCursor = &ServiceError{Code: "invalid_cursor", Message: "分页游标无效"}
+)
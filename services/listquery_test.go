@@ -0,0 +1,97 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/nodeloc-faka/models"
+)
+
+func TestCursorPaginationAcrossTiedSortKeys(t *testing.T) {
+	store := newTestStore(t)
+	// 排序值大量重复，翻页必须依靠 id 区分并列的记录
+	for i := 0; i < 9; i++ {
+		store.create(&models.Product{
+			Name:       fmt.Sprintf("商品%d", i%2),
+			Price:      float64(10 + i%3),
+			Sort:       i % 2,
+			SalesCount: i % 4,
+		})
+	}
+
+	products := NewProductService()
+	for _, sort := range []string{"", "price", "-price", "price,-sales_count", "-sort,name", "name,-price", "-id"} {
+		t.Run("sort="+sort, func(t *testing.T) {
+			all, _, err := products.GetWithPagination(ListQuery{Sort: sort, PageSize: MaxListPageSize})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var paged []uint
+			query := ListQuery{Sort: sort, PageSize: 2}
+			for page := 0; ; page++ {
+				if page > len(all) {
+					t.Fatal("游标翻页没有结束")
+				}
+				rows, result, err := products.GetWithPagination(query)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if page == 0 && (result.Total == nil || *result.Total != int64(len(all))) {
+					t.Fatalf("首页总数为 %v，期望 %d", result.Total, len(all))
+				}
+				if page > 0 && result.Total != nil {
+					t.Fatal("游标分页不应统计总数")
+				}
+				for _, row := range rows {
+					paged = append(paged, row.ID)
+				}
+				if result.NextCursor == "" {
+					break
+				}
+				query.Cursor = result.NextCursor
+			}
+
+			want := make([]uint, len(all))
+			for i, row := range all {
+				want[i] = row.ID
+			}
+			if !reflect.DeepEqual(paged, want) {
+				t.Fatalf("游标翻页得到 %v，期望 %v", paged, want)
+			}
+		})
+	}
+}
+
+func TestCursorRejectsOtherSort(t *testing.T) {
+	store := newTestStore(t)
+	for i := 0; i < 3; i++ {
+		store.product(10, 0)
+	}
+	products := NewProductService()
+	_, page, err := products.GetWithPagination(ListQuery{Sort: "price", PageSize: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		query ListQuery
+		want  error
+	}{
+		{"same sort", ListQuery{Sort: "price", Cursor: page.NextCursor}, nil},
+		{"different sort", ListQuery{Sort: "-price", Cursor: page.NextCursor}, ErrInvalidListCursor},
+		{"malformed cursor", ListQuery{Sort: "price", Cursor: "not-a-cursor"}, ErrInvalidListCursor},
+		{"missing record", ListQuery{Sort: "price", Cursor: encodeListCursor(listCursor{Sort: "price,id", ID: 999})}, ErrInvalidListCursor},
+		{"unknown sort field", ListQuery{Sort: "secret"}, ErrInvalidListSort},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := products.GetWithPagination(tt.query); !errors.Is(err, tt.want) {
+				t.Fatalf("GetWithPagination = %v，期望 %v", err, tt.want)
+			}
+		})
+	}
+}
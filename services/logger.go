@@ -0,0 +1,6 @@
+package services
+
+import "github.com/nodeloc-faka/logging"
+
+// logger 业务服务日志，级别可通过 LOG_LEVELS=services=debug 单独设置
+var logger = logging.For("services")
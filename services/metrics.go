@@ -0,0 +1,30 @@
+package services
+
+import (
+	"time"
+
+	"github.com/nodeloc-faka/metrics"
+)
+
+// 订单事件
+const (
+	orderEventCreated   = "created"
+	orderEventPaid      = "paid"
+	orderEventCancelled = "cancelled"
+)
+
+// 业务指标
+var (
+	orderEvents             = metrics.NewCounter("faka_orders_total", "订单创建、支付和取消次数", "event")
+	paymentAPIDuration      = metrics.NewHistogram("faka_payment_api_duration_seconds", "NodeLoc Payment API 调用耗时（秒）", metrics.DefaultBuckets, "operation")
+	paymentAPIErrors        = metrics.NewCounter("faka_payment_api_errors_total", "NodeLoc Payment API 调用失败次数", "operation")
+	paymentCallbackRejected = metrics.NewCounter("faka_payment_callback_verification_failures_total", "支付回调签名校验失败次数")
+)
+
+// observePaymentAPI 记录一次支付 API 调用的耗时和结果，在发起请求前通过 defer 调用
+func observePaymentAPI(operation string, start time.Time, err *error) {
+	paymentAPIDuration.Since(start, operation)
+	if *err != nil {
+		paymentAPIErrors.Inc(operation)
+	}
+}
@@ -0,0 +1,69 @@
+package services
+
+import (
+	"time"
+
+	"github.com/nodeloc-faka/database"
+	"github.com/nodeloc-faka/models"
+)
+
+// NotificationService 站内通知服务
+type NotificationService struct{}
+
+// NewNotificationService 创建通知服务
+func NewNotificationService() *NotificationService {
+	return &NotificationService{}
+}
+
+// Notify 给用户发送一条通知
+func (s *NotificationService) Notify(userID uint, notifyType, title, content, orderNo string) error {
+	notification := &models.Notification{
+		UserID:  userID,
+		Type:    notifyType,
+		Title:   title,
+		Content: content,
+		OrderNo: orderNo,
+	}
+	return database.GetDB().Create(notification).Error
+}
+
+// UserLocale 用户接收通知使用的语言：用户的语言偏好，未设置时为站点默认语言
+func (s *NotificationService) UserLocale(userID uint) string {
+	var user models.User
+	if err := database.GetDB().Select("locale").First(&user, userID).Error; err == nil && user.Locale != "" {
+		return user.Locale
+	}
+	return NewSettingService().DefaultLocale()
+}
+
+// GetByUser 获取用户的通知列表
+func (s *NotificationService) GetByUser(userID uint, limit int) ([]models.Notification, error) {
+	var notifications []models.Notification
+	if err := database.GetDB().
+		Where("user_id = ?", userID).
+		Order("id desc").
+		Limit(limit).
+		Find(&notifications).Error; err != nil {
+		return nil, err
+	}
+	return notifications, nil
+}
+
+// CountUnread 获取用户未读通知数量
+func (s *NotificationService) CountUnread(userID uint) int64 {
+	var count int64
+	database.GetDB().Model(&models.Notification{}).
+		Where("user_id = ? AND read_at IS NULL", userID).
+		Count(&count)
+	return count
+}
+
+// MarkAsRead 标记通知为已读（id 为 0 时标记该用户全部通知）
+func (s *NotificationService) MarkAsRead(userID, id uint) error {
+	db := database.GetDB().Model(&models.Notification{}).
+		Where("user_id = ? AND read_at IS NULL", userID)
+	if id > 0 {
+		db = db.Where("id = ?", id)
+	}
+	return db.Update("read_at", time.Now()).Error
+}
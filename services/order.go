@@ -1,11 +1,19 @@
 package services
 
 import (
+	"crypto/rand"
+	"crypto/subtle"
+	"errors"
 	"fmt"
+	"math/big"
+	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/nodeloc-faka/database"
 	"github.com/nodeloc-faka/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // OrderService 订单服务
@@ -18,14 +26,105 @@ func NewOrderService() *OrderService {
 
 // Create 创建订单
 func (s *OrderService) Create(order *models.Order) error {
-	// 生成订单号
-	order.OrderNo = s.generateOrderNo()
-	return database.GetDB().Create(order).Error
+	err := database.GetDB().Transaction(func(tx *gorm.DB) error {
+		return s.createWithIdentifiers(tx, order)
+	})
+	if err == nil {
+		orderEvents.Inc(orderEventCreated)
+	}
+	return err
+}
+
+// CreatePriced 按当前定价（阶梯价 / 限时抢购）计算金额并创建订单
+// 同时记录商品和规格名称快照，命中限时抢购时在同一事务中占用抢购名额
+func (s *OrderService) CreatePriced(order *models.Order, product *models.Product, variant *models.ProductVariant) error {
+	order.ProductName = product.Name
+	order.VariantName = variant.Name
+
+	pricingService := NewPricingService()
+	pricingService.ApplyToOrder(order, pricingService.Quote(variant, order.Quantity))
+
+	err := database.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := s.checkPendingLimit(tx, order.UserID); err != nil {
+			return err
+		}
+		if order.FlashSaleID != nil {
+			if err := NewFlashSaleService().Reserve(tx, *order.FlashSaleID, order.UserID, order.Quantity); err != nil {
+				return err
+			}
+		}
+		return s.createWithIdentifiers(tx, order)
+	})
+	if err == nil {
+		orderEvents.Inc(orderEventCreated)
+	}
+	return err
+}
+
+// DefaultMaxPendingOrders 默认每个用户同时存在的待支付订单上限
+const DefaultMaxPendingOrders = 5
+
+// MaxPendingOrders 获取每个用户同时存在的待支付订单上限
+func (s *OrderService) MaxPendingOrders() int64 {
+	limit, err := strconv.ParseInt(NewSettingService().Get(SettingMaxPendingOrders), 10, 64)
+	if err != nil || limit <= 0 {
+		return DefaultMaxPendingOrders
+	}
+	return limit
+}
+
+// checkPendingLimit 检查用户的待支付订单是否已达上限（锁定用户行，避免并发下单绕过上限）
+func (s *OrderService) checkPendingLimit(tx *gorm.DB, userID uint) error {
+	if userID == 0 {
+		return nil
+	}
+	var user models.User
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&user, userID).Error; err != nil {
+		return err
+	}
+	var pending int64
+	tx.Model(&models.Order{}).
+		Where("user_id = ? AND status = ?", userID, models.OrderStatusPending).
+		Count(&pending)
+	if limit := s.MaxPendingOrders(); pending >= limit {
+		return ErrTooManyPendingOrders.WithDetails(map[string]interface{}{"limit": limit})
+	}
+	return nil
 }
 
-// generateOrderNo 生成订单号
-func (s *OrderService) generateOrderNo() string {
-	return fmt.Sprintf("%s%d", time.Now().Format("20060102150405"), time.Now().UnixNano()%10000)
+// maxOrderNoAttempts 生成不重复订单号的最大尝试次数
+const maxOrderNoAttempts = 5
+
+// createWithIdentifiers 为订单分配订单号和查询凭证并写入
+// 订单号由唯一索引保证不重复，写入时冲突则重新生成（先查询再写入在并发下单时仍可能重复）
+func (s *OrderService) createWithIdentifiers(tx *gorm.DB, order *models.Order) error {
+	token, err := models.NewOrderToken()
+	if err != nil {
+		return err
+	}
+	order.PublicToken = token
+
+	for attempt := 0; attempt < maxOrderNoAttempts; attempt++ {
+		orderNo, err := s.generateOrderNo()
+		if err != nil {
+			return err
+		}
+		order.OrderNo = orderNo
+		err = tx.Create(order).Error
+		if !errors.Is(err, gorm.ErrDuplicatedKey) {
+			return err
+		}
+	}
+	return ErrOrderNoExhausted
+}
+
+// generateOrderNo 生成订单号：日期 + 12 位随机数字，不可由时间推算
+func (s *OrderService) generateOrderNo() (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(1e12))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s%012d", time.Now().Format("20060102"), n.Int64()), nil
 }
 
 // Update 更新订单
@@ -38,7 +137,8 @@ func (s *OrderService) FindByID(id uint) (*models.Order, error) {
 	var order models.Order
 	if err := database.GetDB().
 		Preload("User").
-		Preload("Product").
+		Preload("Product", includeArchived).
+		Preload("Variant").
 		Preload("CardKeys").
 		First(&order, id).Error; err != nil {
 		return nil, err
@@ -46,12 +146,32 @@ func (s *OrderService) FindByID(id uint) (*models.Order, error) {
 	return &order, nil
 }
 
+// FindOwned 查找指定用户的订单，不属于该用户时与不存在一样返回 ErrOrderNotFound，避免泄露订单是否存在
+func (s *OrderService) FindOwned(orderNo string, userID uint) (*models.Order, error) {
+	order, err := s.FindByOrderNo(orderNo)
+	if err != nil || order.UserID != userID {
+		return nil, ErrOrderNotFound
+	}
+	return order, nil
+}
+
+// FindByPublicToken 凭订单号和查询凭证查找订单，凭证不匹配时返回 ErrOrderNotFound
+func (s *OrderService) FindByPublicToken(orderNo, token string) (*models.Order, error) {
+	order, err := s.FindByOrderNo(orderNo)
+	if err != nil || token == "" || order.PublicToken == "" ||
+		subtle.ConstantTimeCompare([]byte(order.PublicToken), []byte(token)) != 1 {
+		return nil, ErrOrderNotFound
+	}
+	return order, nil
+}
+
 // FindByOrderNo 根据订单号查找订单
 func (s *OrderService) FindByOrderNo(orderNo string) (*models.Order, error) {
 	var order models.Order
 	if err := database.GetDB().
 		Preload("User").
-		Preload("Product").
+		Preload("Product", includeArchived).
+		Preload("Variant").
 		Preload("CardKeys").
 		Where("order_no = ?", orderNo).
 		First(&order).Error; err != nil {
@@ -64,7 +184,8 @@ func (s *OrderService) FindByOrderNo(orderNo string) (*models.Order, error) {
 func (s *OrderService) GetByUser(userID uint) ([]models.Order, error) {
 	var orders []models.Order
 	if err := database.GetDB().
-		Preload("Product").
+		Preload("Product", includeArchived).
+		Preload("Variant").
 		Preload("CardKeys").
 		Where("user_id = ?", userID).
 		Order("id desc").
@@ -79,7 +200,7 @@ func (s *OrderService) GetAll() ([]models.Order, error) {
 	var orders []models.Order
 	if err := database.GetDB().
 		Preload("User").
-		Preload("Product").
+		Preload("Product", includeArchived).
 		Order("id desc").
 		Find(&orders).Error; err != nil {
 		return nil, err
@@ -87,28 +208,71 @@ func (s *OrderService) GetAll() ([]models.Order, error) {
 	return orders, nil
 }
 
-// GetWithPagination 分页获取订单
-func (s *OrderService) GetWithPagination(page, pageSize int, status int) ([]models.Order, int64, error) {
-	var orders []models.Order
-	var total int64
+// OrderFilter 后台订单列表筛选条件
+type OrderFilter struct {
+	Status        *int
+	UserID        uint
+	ProductID     uint
+	From          *time.Time // 下单时间（含）
+	To            *time.Time // 下单时间（不含）
+	PayMethod     string
+	TransactionID string
+	Contact       string // 联系方式，模糊匹配
+}
 
-	db := database.GetDB().Model(&models.Order{})
-	if status >= 0 {
-		db = db.Where("status = ?", status)
+// apply 应用筛选条件
+func (f OrderFilter) apply(db *gorm.DB) *gorm.DB {
+	if f.Status != nil {
+		db = db.Where("orders.status = ?", *f.Status)
 	}
-	db.Count(&total)
-
-	offset := (page - 1) * pageSize
-	if err := db.Preload("User").
-		Preload("Product").
-		Order("id desc").
-		Offset(offset).
-		Limit(pageSize).
-		Find(&orders).Error; err != nil {
-		return nil, 0, err
+	if f.UserID > 0 {
+		db = db.Where("orders.user_id = ?", f.UserID)
+	}
+	if f.ProductID > 0 {
+		db = db.Where("orders.product_id = ?", f.ProductID)
+	}
+	if f.From != nil {
+		db = db.Where("orders.created_at >= ?", *f.From)
 	}
+	if f.To != nil {
+		db = db.Where("orders.created_at < ?", *f.To)
+	}
+	if f.PayMethod != "" {
+		db = db.Where("orders.pay_method = ?", f.PayMethod)
+	}
+	if f.TransactionID != "" {
+		db = db.Where("orders.transaction_id = ?", f.TransactionID)
+	}
+	if f.Contact != "" {
+		db = db.Where("orders.contact LIKE ?", "%"+escapeLike(f.Contact)+"%")
+	}
+	return db
+}
 
-	return orders, total, nil
+// orderSorts 订单列表允许的排序字段
+var orderSorts = listSorts{
+	table: "orders",
+	columns: map[string]string{
+		"id":           "orders.id",
+		"created_at":   "orders.created_at",
+		"paid_at":      "COALESCE(orders.paid_at, '1970-01-01')",
+		"total_amount": "orders.total_amount",
+		"status":       "orders.status",
+	},
+	def: "-id",
+}
+
+// GetWithPagination 分页获取订单
+func (s *OrderService) GetWithPagination(filter OrderFilter, query ListQuery) ([]models.Order, ListPage, error) {
+	var orders []models.Order
+	page, err := paginate(filter.apply(database.GetDB().Model(&models.Order{})), query, orderSorts, &orders,
+		func(db *gorm.DB) *gorm.DB {
+			return db.Preload("User").Preload("Product", includeArchived)
+		})
+	if err != nil {
+		return nil, ListPage{}, err
+	}
+	return orders, page, nil
 }
 
 // MarkAsPaid 标记订单为已支付
@@ -129,11 +293,31 @@ func (s *OrderService) Complete(id uint) error {
 		Update("status", models.OrderStatusCompleted).Error
 }
 
-// Cancel 取消订单
+// Cancel 取消待支付订单（限时抢购订单同时归还抢购名额），订单不是待支付状态时返回 ErrOrderNotPending
 func (s *OrderService) Cancel(id uint) error {
-	return database.GetDB().Model(&models.Order{}).
-		Where("id = ?", id).
-		Update("status", models.OrderStatusCancelled).Error
+	return database.GetDB().Transaction(func(tx *gorm.DB) error {
+		return s.cancel(tx, id)
+	})
+}
+
+// cancel 在事务中取消待支付订单，已支付或已完成的订单不能取消
+func (s *OrderService) cancel(tx *gorm.DB, id uint) error {
+	var order models.Order
+	if err := tx.First(&order, id).Error; err != nil {
+		return err
+	}
+
+	result := tx.Model(&models.Order{}).
+		Where("id = ? AND status = ?", id, models.OrderStatusPending).
+		Update("status", models.OrderStatusCancelled)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrOrderNotPending
+	}
+	orderEvents.Inc(orderEventCancelled)
+	return NewFlashSaleService().Release(tx, &order)
 }
 
 // Count 获取订单数量
@@ -160,204 +344,225 @@ func (s *OrderService) GetTotalSales() float64 {
 	return total
 }
 
-// GetTodaySales 获取今日销售额
+// GetTodaySales 获取今日销售额（按统计时区划分日期）
 func (s *OrderService) GetTodaySales() float64 {
 	var total float64
-	today := time.Now().Format("2006-01-02")
+	today := startOfDay(time.Now(), analyticsLocation())
 	database.GetDB().Model(&models.Order{}).
-		Where("status IN ? AND DATE(created_at) = ?", []int{models.OrderStatusPaid, models.OrderStatusCompleted}, today).
+		Where("status IN ? AND created_at >= ?", []int{models.OrderStatusPaid, models.OrderStatusCompleted}, today).
 		Select("COALESCE(SUM(total_amount), 0)").
 		Scan(&total)
 	return total
 }
 
-// CreatePendingOrder 创建待支付订单
-func (s *OrderService) CreatePendingOrder(userID, productID uint, quantity int, contact, remark string) (*models.Order, error) {
-	// 获取商品信息
-	productService := NewProductService()
-	product, err := productService.FindByID(productID)
-	if err != nil {
-		return nil, ErrProductNotFound
-	}
+// PayWithBalance 使用账户余额支付待支付订单，扣款成功后立即发货
+func (s *OrderService) PayWithBalance(order *models.Order) (*models.Order, error) {
+	return s.settle(order, "balance", func(tx *gorm.DB) error {
+		// 条件扣款，余额不足时不会扣成负数
+		result := tx.Model(&models.User{}).
+			Where("id = ? AND balance >= ?", order.UserID, order.TotalAmount).
+			UpdateColumn("balance", gorm.Expr("balance - ?", order.TotalAmount))
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrInsufficientBalance
+		}
+		return nil
+	})
+}
 
-	// 检查库存
-	cardKeyService := NewCardKeyService()
-	count := cardKeyService.CountByProduct(productID, models.CardKeyStatusAvailable)
-	if int(count) < quantity {
-		return nil, ErrInsufficientStock
-	}
-
-	// 创建待支付订单
-	expiredAt := time.Now().Add(30 * time.Minute) // 30分钟过期
-	order := &models.Order{
-		UserID:      userID,
-		ProductID:   productID,
-		Quantity:    quantity,
-		TotalAmount: product.Price * float64(quantity),
-		Status:      models.OrderStatusPending,
-		PayMethod:   "nodeloc",
-		Contact:     contact,
-		Remark:      remark,
-		ExpiredAt:   &expiredAt,
-	}
-
-	if err := s.Create(order); err != nil {
-		return nil, err
-	}
+// CompleteFree 免费模式（未配置支付）下直接完成待支付订单
+func (s *OrderService) CompleteFree(order *models.Order) (*models.Order, error) {
+	return s.settle(order, "free", nil)
+}
 
-	return order, nil
+// SettleReconciled 对账确认 NodeLoc Payment 已收款后，将待支付或已取消的订单标记为已支付并发货
+// 已取消的限时抢购订单不会重新占用抢购名额
+func (s *OrderService) SettleReconciled(order *models.Order, platformFee, merchantPoints int) (*models.Order, error) {
+	return s.settleFrom(order, []int{models.OrderStatusPending, models.OrderStatusCancelled}, map[string]interface{}{
+		"pay_method":      PayMethodNodeLoc,
+		"platform_fee":    platformFee,
+		"merchant_points": merchantPoints,
+	}, nil)
 }
 
-// SetPaymentInfo 设置支付信息
-func (s *OrderService) SetPaymentInfo(orderID uint, transactionID, paymentURL string) error {
+// SettlePayment 支付平台通知收款后，将待支付订单标记为已支付并发货
+// 状态判断和更新在同一条条件语句中完成，与取消订单或重复回调并发时只有一方生效，
+// 订单已不是待支付状态时返回 ErrOrderNotPayable
+func (s *OrderService) SettlePayment(order *models.Order, transactionID string, platformFee, merchantPoints int) (*models.Order, error) {
+	return s.settleFrom(order, []int{models.OrderStatusPending}, map[string]interface{}{
+		"pay_method":      PayMethodNodeLoc,
+		"transaction_id":  transactionID,
+		"platform_fee":    platformFee,
+		"merchant_points": merchantPoints,
+	}, nil)
+}
+
+// UpdatePaymentFees 按支付平台的记录更新订单的手续费和商家实收积分
+func (s *OrderService) UpdatePaymentFees(id uint, platformFee, merchantPoints int) error {
 	return database.GetDB().Model(&models.Order{}).
-		Where("id = ?", orderID).
+		Where("id = ?", id).
 		Updates(map[string]interface{}{
-			"transaction_id": transactionID,
-			"payment_url":    paymentURL,
+			"platform_fee":    platformFee,
+			"merchant_points": merchantPoints,
 		}).Error
 }
 
-// FindByTransactionID 根据交易ID查找订单
-func (s *OrderService) FindByTransactionID(transactionID string) (*models.Order, error) {
-	var order models.Order
-	if err := database.GetDB().
-		Preload("User").
-		Preload("Product").
-		Preload("CardKeys").
-		Where("transaction_id = ?", transactionID).
-		First(&order).Error; err != nil {
-		return nil, err
-	}
-	return &order, nil
+// settle 将待支付订单标记为已支付并发货，charge 不为空时在同一事务中完成扣款
+func (s *OrderService) settle(order *models.Order, payMethod string, charge func(tx *gorm.DB) error) (*models.Order, error) {
+	return s.settleFrom(order, []int{models.OrderStatusPending}, map[string]interface{}{"pay_method": payMethod}, charge)
 }
 
-// ProcessPaymentCallback 处理支付回调
-func (s *OrderService) ProcessPaymentCallback(transactionID string, amount, platformFee, merchantPoints int) (*models.Order, error) {
-	// 查找订单
-	order, err := s.FindByTransactionID(transactionID)
+// settleFrom 将处于 from 状态之一的订单标记为已支付并写入 fields，然后发货
+// 只有标记支付或扣款失败时返回错误（此时订单未被修改）；发货失败时订单保持已支付状态并记录日志
+func (s *OrderService) settleFrom(order *models.Order, from []int, fields map[string]interface{}, charge func(tx *gorm.DB) error) (*models.Order, error) {
+	now := time.Now()
+	updates := map[string]interface{}{
+		"status":  models.OrderStatusPaid,
+		"paid_at": now,
+	}
+	for column, value := range fields {
+		updates[column] = value
+	}
+	err := database.GetDB().Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.Order{}).
+			Where("id = ? AND status IN ?", order.ID, from).
+			Updates(updates)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrOrderNotPayable
+		}
+		if charge != nil {
+			return charge(tx)
+		}
+		return nil
+	})
 	if err != nil {
-		return nil, ErrOrderNotFound
+		return nil, err
 	}
+	orderEvents.Inc(orderEventPaid)
 
-	// 检查订单状态（防止重复处理）
-	if order.Status != models.OrderStatusPending {
-		// 已处理过，直接返回
-		return order, nil
+	order.Status = models.OrderStatusPaid
+	order.PaidAt = &now
+	if payMethod, ok := fields["pay_method"].(string); ok {
+		order.PayMethod = payMethod
+	}
+	if err := s.deliverPaid(order); err != nil {
+		// 已收款的订单不能因发货失败而取消，保持已支付状态，由管理员处理
+		logger.Error("已支付订单发货失败", "order_no", order.OrderNo, "error", err)
 	}
+	if reloaded, err := s.FindByID(order.ID); err == nil {
+		return reloaded, nil
+	}
+	return order, nil
+}
 
-	// 验证金额（转换为积分比较）
-	expectedAmount := int(order.TotalAmount)
-	if amount != expectedAmount {
-		return nil, ErrAmountMismatch
+// deliverPaid 为已支付订单发货：人工发货商品进入待发货队列，其余分配卡密并完成订单
+func (s *OrderService) deliverPaid(order *models.Order) error {
+	if order.Product == nil {
+		product, err := NewProductService().FindByID(order.ProductID)
+		if err != nil {
+			return err
+		}
+		order.Product = product
+	}
+	if order.Product.IsManualDelivery() {
+		return NewFulfillmentService().Enqueue(order)
 	}
 
-	// 获取可用卡密
 	cardKeyService := NewCardKeyService()
-	availableCards, err := cardKeyService.GetAvailableByProduct(order.ProductID, order.Quantity)
+	availableCards, err := cardKeyService.GetAvailableByProduct(order.ProductID, order.VariantID, order.Quantity)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	if len(availableCards) < order.Quantity {
-		return nil, ErrInsufficientStock
-	}
-
-	// 更新订单状态
-	now := time.Now()
-	order.Status = models.OrderStatusCompleted
-	order.PaidAt = &now
-	order.PlatformFee = platformFee
-	order.MerchantPoints = merchantPoints
-
-	if err := database.GetDB().Save(order).Error; err != nil {
-		return nil, err
+		// 库存不足时保持已支付状态，由管理员处理
+		return nil
 	}
 
-	// 分配卡密
 	cardIDs := make([]uint, len(availableCards))
 	for i, card := range availableCards {
 		cardIDs[i] = card.ID
 	}
 	if err := cardKeyService.MarkAsSold(cardIDs, order.ID); err != nil {
-		return nil, err
+		return err
 	}
 
-	// 更新商品库存和销量
+	// 库存和销量是统计字段，更新失败不影响发货，只记录日志
 	productService := NewProductService()
-	productService.UpdateStock(order.ProductID)
-	productService.IncrementSales(order.ProductID, order.Quantity)
-
-	// 重新加载订单（包含卡密）
-	return s.FindByID(order.ID)
-}
-
-// CreateAndProcess 创建并处理订单（免费模式，直接完成）
-func (s *OrderService) CreateAndProcess(userID, productID uint, quantity int, contact, remark string) (*models.Order, error) {
-	// 获取商品信息
-	productService := NewProductService()
-	product, err := productService.FindByID(productID)
-	if err != nil {
-		return nil, ErrProductNotFound
+	if err := productService.UpdateStock(order.ProductID); err != nil {
+		logger.Error("更新商品库存失败", "order_no", order.OrderNo, "product_id", order.ProductID, "error", err)
 	}
-
-	// 检查库存
-	cardKeyService := NewCardKeyService()
-	availableCards, err := cardKeyService.GetAvailableByProduct(productID, quantity)
-	if err != nil {
-		return nil, err
-	}
-	if len(availableCards) < quantity {
-		return nil, ErrInsufficientStock
+	if err := productService.IncrementSales(order.ProductID, order.VariantID, order.Quantity); err != nil {
+		logger.Error("更新商品销量失败", "order_no", order.OrderNo, "product_id", order.ProductID, "error", err)
 	}
 
-	// 创建订单
-	order := &models.Order{
-		UserID:      userID,
-		ProductID:   productID,
-		Quantity:    quantity,
-		TotalAmount: product.Price * float64(quantity),
-		Status:      models.OrderStatusCompleted, // 直接标记为已完成（免费发卡）
-		PayMethod:   "free",
-		Contact:     contact,
-		Remark:      remark,
-	}
-	now := time.Now()
-	order.PaidAt = &now
+	return database.GetDB().Model(&models.Order{}).
+		Where("id = ?", order.ID).
+		Update("status", models.OrderStatusCompleted).Error
+}
 
-	if err := s.Create(order); err != nil {
-		return nil, err
-	}
+// SetPaymentInfo 设置支付信息
+func (s *OrderService) SetPaymentInfo(orderID uint, transactionID, paymentURL string) error {
+	return database.GetDB().Model(&models.Order{}).
+		Where("id = ?", orderID).
+		Updates(map[string]interface{}{
+			"transaction_id": transactionID,
+			"payment_url":    paymentURL,
+		}).Error
+}
 
-	// 分配卡密
-	cardIDs := make([]uint, len(availableCards))
-	for i, card := range availableCards {
-		cardIDs[i] = card.ID
-	}
-	if err := cardKeyService.MarkAsSold(cardIDs, order.ID); err != nil {
+// FindByTransactionID 根据交易ID查找订单
+func (s *OrderService) FindByTransactionID(transactionID string) (*models.Order, error) {
+	var order models.Order
+	if err := database.GetDB().
+		Preload("User").
+		Preload("Product", includeArchived).
+		Preload("Variant").
+		Preload("CardKeys").
+		Where("transaction_id = ?", transactionID).
+		First(&order).Error; err != nil {
 		return nil, err
 	}
-
-	// 更新商品库存和销量
-	productService.UpdateStock(productID)
-	productService.IncrementSales(productID, quantity)
-
-	// 重新加载订单（包含卡密）
-	return s.FindByID(order.ID)
+	return &order, nil
 }
 
 // CancelExpiredOrders 取消过期订单
 func (s *OrderService) CancelExpiredOrders() (int64, error) {
-	result := database.GetDB().Model(&models.Order{}).
+	var ids []uint
+	if err := database.GetDB().Model(&models.Order{}).
 		Where("status = ? AND expired_at < ?", models.OrderStatusPending, time.Now()).
-		Update("status", models.OrderStatusCancelled)
-	return result.RowsAffected, result.Error
+		Pluck("id", &ids).Error; err != nil {
+		return 0, err
+	}
+
+	var cancelled int64
+	for _, id := range ids {
+		if err := s.Cancel(id); err != nil {
+			// 查询后已被支付或取消的订单跳过
+			if errors.Is(err, ErrOrderNotPending) {
+				continue
+			}
+			return cancelled, err
+		}
+		cancelled++
+	}
+	return cancelled, nil
 }
 
 // 错误定义
 var (
-	ErrProductNotFound   = &ServiceError{Message: "商品不存在"}
-	ErrInsufficientStock = &ServiceError{Message: "库存不足"}
-	ErrOrderNotFound     = &ServiceError{Message: "订单不存在"}
-	ErrAmountMismatch    = &ServiceError{Message: "支付金额不匹配"}
-	ErrOrderExpired      = &ServiceError{Message: "订单已过期"}
+	ErrProductNotFound      = &ServiceError{Code: "product_not_found", Status: http.StatusNotFound, Message: "商品不存在"}
+	ErrInsufficientStock    = &ServiceError{Code: "insufficient_stock", Status: http.StatusConflict, Message: "库存不足"}
+	ErrOrderNotFound        = &ServiceError{Code: "order_not_found", Status: http.StatusNotFound, Message: "订单不存在"}
+	ErrAmountMismatch       = &ServiceError{Code: "amount_mismatch", Message: "支付金额不匹配"}
+	ErrOrderExpired         = &ServiceError{Code: "order_expired", Status: http.StatusConflict, Message: "订单已过期"}
+	ErrTooManyPendingOrders = &ServiceError{Code: "too_many_pending_orders", Status: http.StatusTooManyRequests, Message: "待支付订单过多，请先完成支付或取消未支付的订单"}
+	ErrOrderNoExhausted     = &ServiceError{Code: "order_no_exhausted", Status: http.StatusServiceUnavailable, Message: "生成订单号失败，请重试"}
+	ErrInsufficientBalance  = &ServiceError{Code: "insufficient_balance", Status: http.StatusPaymentRequired, Message: "余额不足"}
+	ErrOrderNotPayable      = &ServiceError{Code: "order_not_payable", Status: http.StatusConflict, Message: "订单状态不允许支付"}
+	ErrInvalidOrderStatus   = &ServiceError{Code: "invalid_order_status", Message: "无效的订单状态"}
 )
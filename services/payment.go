@@ -1,10 +1,12 @@
 package services
 
 import (
+	"context"
 	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -14,6 +16,7 @@ import (
 	"time"
 
 	"github.com/nodeloc-faka/database"
+	"github.com/nodeloc-faka/logging"
 	"github.com/nodeloc-faka/models"
 )
 
@@ -67,11 +70,10 @@ type CreatePaymentResponse struct {
 }
 
 // CreatePayment 发起支付
-func (s *PaymentService) CreatePayment(req *CreatePaymentRequest) (*CreatePaymentResponse, error) {
+func (s *PaymentService) CreatePayment(ctx context.Context, req *CreatePaymentRequest) (result *CreatePaymentResponse, err error) {
 	cfg := s.GetConfig()
 	if cfg.PaymentID == "" || cfg.SecretKey == "" {
-		fmt.Printf("支付配置检查 - PaymentID: %s, SecretKey: %s\n", cfg.PaymentID, cfg.SecretKey)
-		return nil, fmt.Errorf("支付未配置 (PaymentID=%s, SecretKey=%s)", cfg.PaymentID, cfg.SecretKey)
+		return nil, fmt.Errorf("支付未配置")
 	}
 
 	// 准备签名参数
@@ -87,8 +89,7 @@ func (s *PaymentService) CreatePayment(req *CreatePaymentRequest) (*CreatePaymen
 
 	// 发送请求
 	apiURL := fmt.Sprintf("%s/payment/pay/%s/process", cfg.PaymentURL, cfg.PaymentID)
-	fmt.Printf("调用支付API: %s\n", apiURL)
-	fmt.Printf("支付参数: amount=%d, description=%s, order_id=%s\n", req.Amount, req.Description, req.OrderID)
+	logger.DebugContext(ctx, "调用支付API", "url", apiURL, "amount", req.Amount, "description", req.Description)
 
 	// 构建表单数据
 	formData := url.Values{}
@@ -96,7 +97,8 @@ func (s *PaymentService) CreatePayment(req *CreatePaymentRequest) (*CreatePaymen
 		formData.Set(k, v)
 	}
 
-	resp, err := http.PostForm(apiURL, formData)
+	defer observePaymentAPI("create", time.Now(), &err)
+	resp, err := postForm(ctx, apiURL, formData)
 	if err != nil {
 		return nil, fmt.Errorf("请求支付API失败: %w", err)
 	}
@@ -111,12 +113,12 @@ func (s *PaymentService) CreatePayment(req *CreatePaymentRequest) (*CreatePaymen
 		return nil, fmt.Errorf("支付API返回错误: %s", string(body))
 	}
 
-	var result CreatePaymentResponse
-	if err := json.Unmarshal(body, &result); err != nil {
+	result = &CreatePaymentResponse{}
+	if err := json.Unmarshal(body, result); err != nil {
 		return nil, fmt.Errorf("解析响应失败: %w", err)
 	}
 
-	return &result, nil
+	return result, nil
 }
 
 // PaymentCallback 支付回调参数
@@ -132,10 +134,10 @@ type PaymentCallback struct {
 }
 
 // VerifyCallback 验证回调签名
-func (s *PaymentService) VerifyCallback(callback *PaymentCallback) bool {
+func (s *PaymentService) VerifyCallback(ctx context.Context, callback *PaymentCallback) bool {
 	cfg := s.GetConfig()
 	if cfg.SecretKey == "" {
-		fmt.Println("回调验证失败: SecretKey 为空")
+		logger.WarnContext(ctx, "回调验证失败: 未配置支付密钥")
 		return false
 	}
 
@@ -153,23 +155,10 @@ func (s *PaymentService) VerifyCallback(callback *PaymentCallback) bool {
 	// 生成签名并比对（回调验证也使用 token_hash，和发起支付一样）
 	expectedSignature := s.generateSignatureForPayment(params, cfg.SecretKey)
 
-	// 详细日志
-	fmt.Println("=== 回调签名验证 ===")
-	fmt.Printf("接收到的回调参数:\n")
-	fmt.Printf("  transaction_id: %s\n", callback.TransactionID)
-	fmt.Printf("  external_reference: %s\n", callback.ExternalReference)
-	fmt.Printf("  amount: %d\n", callback.Amount)
-	fmt.Printf("  platform_fee: %d\n", callback.PlatformFee)
-	fmt.Printf("  merchant_points: %d\n", callback.MerchantPoints)
-	fmt.Printf("  status: %s\n", callback.Status)
-	fmt.Printf("  paid_at: %s\n", callback.PaidAt)
-	fmt.Printf("  signature (接收): %s\n", callback.Signature)
-	fmt.Printf("计算的签名: %s\n", expectedSignature)
-	fmt.Printf("SecretKey: %s\n", cfg.SecretKey)
-
 	isValid := hmac.Equal([]byte(expectedSignature), []byte(callback.Signature))
-	fmt.Printf("签名验证结果: %v\n", isValid)
-	fmt.Println("==================")
+	if !isValid {
+		logger.WarnContext(ctx, "回调签名验证失败", "status", callback.Status, "amount", callback.Amount)
+	}
 
 	return isValid
 }
@@ -192,7 +181,7 @@ type QueryPaymentResponse struct {
 }
 
 // QueryPayment 查询支付状态
-func (s *PaymentService) QueryPayment(transactionID string) (*QueryPaymentResponse, error) {
+func (s *PaymentService) QueryPayment(ctx context.Context, transactionID string) (result *QueryPaymentResponse, err error) {
 	cfg := s.GetConfig()
 	if cfg.PaymentID == "" || cfg.SecretKey == "" {
 		return nil, fmt.Errorf("支付未配置")
@@ -215,7 +204,8 @@ func (s *PaymentService) QueryPayment(transactionID string) (*QueryPaymentRespon
 		formData.Set(k, v)
 	}
 
-	resp, err := http.PostForm(apiURL, formData)
+	defer observePaymentAPI("query", time.Now(), &err)
+	resp, err := postForm(ctx, apiURL, formData)
 	if err != nil {
 		return nil, fmt.Errorf("请求查询API失败: %w", err)
 	}
@@ -230,12 +220,12 @@ func (s *PaymentService) QueryPayment(transactionID string) (*QueryPaymentRespon
 		return nil, fmt.Errorf("查询API返回错误: %s", string(body))
 	}
 
-	var result QueryPaymentResponse
-	if err := json.Unmarshal(body, &result); err != nil {
+	result = &QueryPaymentResponse{}
+	if err := json.Unmarshal(body, result); err != nil {
 		return nil, fmt.Errorf("解析响应失败: %w", err)
 	}
 
-	return &result, nil
+	return result, nil
 }
 
 // generateSignatureForPayment 生成发起支付的签名（使用 token_hash）
@@ -261,14 +251,7 @@ func (s *PaymentService) generateSignatureForPayment(params map[string]string, t
 	// 4. 计算 HMAC-SHA256(token_hash, paramString) - 根据文档第 70 行
 	h := hmac.New(sha256.New, []byte(tokenHash))
 	h.Write([]byte(paramString))
-	signature := hex.EncodeToString(h.Sum(nil))
-
-	fmt.Printf("签名调试 - 参数字符串: %s\n", paramString)
-	fmt.Printf("签名调试 - token: %s\n", token)
-	fmt.Printf("签名调试 - token_hash (SHA256): %s\n", tokenHash)
-	fmt.Printf("签名调试 - signature (HMAC-SHA256): %s\n", signature)
-
-	return signature
+	return hex.EncodeToString(h.Sum(nil))
 }
 
 // generateSignatureForCallback 生成回调验证的签名（直接使用 secret_key）
@@ -290,80 +273,74 @@ func (s *PaymentService) generateSignatureForCallback(params map[string]string,
 	// 3. 计算 HMAC-SHA256(secret_key, paramString) - 根据文档第 157 行
 	h := hmac.New(sha256.New, []byte(secretKey))
 	h.Write([]byte(paramString))
-	signature := hex.EncodeToString(h.Sum(nil))
-
-	fmt.Printf("回调签名生成 - 参数字符串: %s\n", paramString)
-	fmt.Printf("回调签名生成 - secret_key: %s\n", secretKey)
-	fmt.Printf("回调签名生成 - signature: %s\n", signature)
-
-	return signature
+	return hex.EncodeToString(h.Sum(nil))
 }
 
 // ProcessPaymentCallback 处理支付回调
-func (s *PaymentService) ProcessPaymentCallback(callback *PaymentCallback) error {
+func (s *PaymentService) ProcessPaymentCallback(ctx context.Context, callback *PaymentCallback) error {
+	ctx = logging.With(ctx, logging.OrderNo(callback.ExternalReference), logging.TransactionID(callback.TransactionID))
+
 	// 1. 验证签名
-	if !s.VerifyCallback(callback) {
+	if !s.VerifyCallback(ctx, callback) {
+		paymentCallbackRejected.Inc()
 		return fmt.Errorf("签名验证失败")
 	}
 
-	// 2. 查找订单
+	// 2. 检查支付状态
+	if callback.Status != "completed" {
+		return fmt.Errorf("支付未完成: %s", callback.Status)
+	}
+
+	// 3. 查找订单
 	orderService := NewOrderService()
 	order, err := orderService.FindByOrderNo(callback.ExternalReference)
 	if err != nil {
 		return fmt.Errorf("订单不存在: %s", callback.ExternalReference)
 	}
 
-	// 3. 检查订单状态（防止重复处理）
+	// 4. 已处理的订单直接返回（幂等），并发请求由 SettlePayment 的条件更新保证只处理一次
 	if order.Status != models.OrderStatusPending {
-		// 订单已处理，直接返回成功（幂等）
-		return nil
+		return checkSettled(ctx, order.Status)
 	}
 
-	// 4. 验证金额
-	expectedAmount := int(order.TotalAmount) // 假设1积分=1元
+	// 5. 验证金额
+	expectedAmount := CreditAmount(order.TotalAmount)
 	if callback.Amount != expectedAmount {
 		return fmt.Errorf("金额不匹配: 期望 %d, 实际 %d", expectedAmount, callback.Amount)
 	}
 
-	// 5. 检查支付状态
-	if callback.Status != "completed" {
-		return fmt.Errorf("支付未完成: %s", callback.Status)
-	}
-
-	// 6. 更新订单状态
-	now := time.Now()
-	order.Status = models.OrderStatusPaid
-	order.PaidAt = &now
-	order.PayMethod = "nodeloc"
-	order.TransactionID = callback.TransactionID
-
-	if err := database.GetDB().Save(order).Error; err != nil {
+	// 6. 标记已支付并发货
+	if _, err := orderService.SettlePayment(order, callback.TransactionID, callback.PlatformFee, callback.MerchantPoints); err != nil {
+		if errors.Is(err, ErrOrderNotPayable) {
+			// 查询订单后被并发的请求处理或取消
+			var current models.Order
+			if err := database.GetDB().Select("id", "status").First(&current, order.ID).Error; err != nil {
+				return fmt.Errorf("查询订单失败: %w", err)
+			}
+			return checkSettled(ctx, current.Status)
+		}
 		return fmt.Errorf("更新订单失败: %w", err)
 	}
+	logger.InfoContext(ctx, "订单支付成功", "amount", callback.Amount)
+	return nil
+}
 
-	// 7. 分配卡密
-	cardKeyService := NewCardKeyService()
-	availableCards, err := cardKeyService.GetAvailableByProduct(order.ProductID, order.Quantity)
-	if err != nil || len(availableCards) < order.Quantity {
-		// 库存不足，记录日志但不影响支付状态
-		// 实际项目中应该发送告警
+// checkSettled 检查收到回调时已不是待支付状态的订单：已支付或已完成时返回 nil，
+// 已取消时记录错误并返回 ErrOrderNotPayable，由对账任务按支付平台记录处理
+func checkSettled(ctx context.Context, status int) error {
+	if status == models.OrderStatusPaid || status == models.OrderStatusCompleted {
 		return nil
 	}
+	logger.ErrorContext(ctx, "已收款的订单不是待支付状态，需要对账处理", "status", status)
+	return ErrOrderNotPayable
+}
 
-	cardIDs := make([]uint, len(availableCards))
-	for i, card := range availableCards {
-		cardIDs[i] = card.ID
+// postForm 以表单方式 POST 请求支付 API，请求随 ctx 取消
+func postForm(ctx context.Context, apiURL string, form url.Values) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, apiURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
 	}
-	cardKeyService.MarkAsSold(cardIDs, order.ID)
-
-	// 8. 更新商品库存和销量
-	productService := NewProductService()
-	productService.UpdateStock(order.ProductID)
-	productService.IncrementSales(order.ProductID, order.Quantity)
-
-	// 9. 标记订单完成
-	order.Status = models.OrderStatusCompleted
-	database.GetDB().Save(order)
-
-	return nil
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return http.DefaultClient.Do(req)
 }
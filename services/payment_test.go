@@ -0,0 +1,124 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/nodeloc-faka/models"
+)
+
+const testPaymentSecret = "test-payment-secret"
+
+// signedCallback 构造已签名的收款回调
+func signedCallback(order *models.Order, amount int) *PaymentCallback {
+	callback := &PaymentCallback{
+		TransactionID:     "tx-" + order.OrderNo,
+		ExternalReference: order.OrderNo,
+		Amount:            amount,
+		PlatformFee:       1,
+		MerchantPoints:    amount - 1,
+		Status:            "completed",
+		PaidAt:            "2026-10-19T00:00:00Z",
+	}
+	callback.Signature = (&PaymentService{}).generateSignatureForPayment(map[string]string{
+		"transaction_id":     callback.TransactionID,
+		"external_reference": callback.ExternalReference,
+		"amount":             fmt.Sprintf("%d", callback.Amount),
+		"platform_fee":       fmt.Sprintf("%d", callback.PlatformFee),
+		"merchant_points":    fmt.Sprintf("%d", callback.MerchantPoints),
+		"status":             callback.Status,
+		"paid_at":            callback.PaidAt,
+	}, testPaymentSecret)
+	return callback
+}
+
+func newPaymentStore(t *testing.T) *testStore {
+	store := newTestStore(t)
+	store.setting(SettingPaymentID, "pay_test")
+	store.setting(SettingPaymentSecret, testPaymentSecret)
+	return store
+}
+
+func TestProcessPaymentCallback(t *testing.T) {
+	tests := []struct {
+		name       string
+		prepare    func(store *testStore, order *models.Order)
+		amount     int
+		wantErr    error
+		wantStatus int
+		wantCards  int64
+	}{
+		{"pending order is completed", nil, 20, nil, models.OrderStatusCompleted, 2},
+		{"cancelled order stays cancelled", func(store *testStore, order *models.Order) {
+			if err := NewOrderService().Cancel(order.ID); err != nil {
+				t.Fatal(err)
+			}
+		}, 20, ErrOrderNotPayable, models.OrderStatusCancelled, 0},
+		{"amount mismatch", nil, 19, errAny, models.OrderStatusPending, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newPaymentStore(t)
+			_, variant := store.product(10, 3)
+			order := store.order(store.user(0), variant, 2)
+			if tt.prepare != nil {
+				tt.prepare(store, order)
+			}
+
+			err := NewPaymentService().ProcessPaymentCallback(context.Background(), signedCallback(order, tt.amount))
+			switch {
+			case tt.wantErr == nil && err != nil:
+				t.Fatalf("处理回调失败: %v", err)
+			case tt.wantErr == errAny && err == nil, tt.wantErr != nil && tt.wantErr != errAny && !errors.Is(err, tt.wantErr):
+				t.Fatalf("返回 %v，期望 %v", err, tt.wantErr)
+			}
+
+			var got models.Order
+			store.reload(&got, order.ID)
+			if got.Status != tt.wantStatus {
+				t.Fatalf("订单状态为 %d，期望 %d", got.Status, tt.wantStatus)
+			}
+			if cards := store.soldCards(order.ID); cards != tt.wantCards {
+				t.Fatalf("分配了 %d 张卡密，期望 %d", cards, tt.wantCards)
+			}
+			if tt.wantStatus == models.OrderStatusCompleted && (got.PlatformFee != 1 || got.MerchantPoints != 19 || got.TransactionID == "") {
+				t.Fatalf("未记录支付信息: fee=%d points=%d tx=%q", got.PlatformFee, got.MerchantPoints, got.TransactionID)
+			}
+		})
+	}
+}
+
+func TestProcessPaymentCallbackDeliversOnce(t *testing.T) {
+	store := newPaymentStore(t)
+	_, variant := store.product(10, 6)
+	order := store.order(store.user(0), variant, 2)
+	callback := signedCallback(order, 20)
+
+	// 重复回调和状态查询并发处理同一笔收款
+	var wg sync.WaitGroup
+	errs := make(chan error, 5)
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- NewPaymentService().ProcessPaymentCallback(context.Background(), callback)
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("处理回调失败: %v", err)
+		}
+	}
+
+	if cards := store.soldCards(order.ID); cards != 2 {
+		t.Fatalf("分配了 %d 张卡密，期望 2", cards)
+	}
+}
+
+// errAny 表示期望返回任意错误
+var errAny = errors.New("any error")
@@ -0,0 +1,175 @@
+package services
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/nodeloc-faka/database"
+	"github.com/nodeloc-faka/models"
+	"gorm.io/gorm"
+)
+
+// PricingService 定价服务（阶梯价）
+type PricingService struct{}
+
+// NewPricingService 创建定价服务
+func NewPricingService() *PricingService {
+	return &PricingService{}
+}
+
+// PriceQuote 报价结果
+type PriceQuote struct {
+	ProductID   uint               `json:"product_id"`
+	VariantID   uint               `json:"variant_id"`
+	Quantity    int                `json:"quantity"`
+	BasePrice   float64            `json:"base_price"` // 规格原单价
+	UnitPrice   float64            `json:"unit_price"` // 实际成交单价
+	TotalAmount float64            `json:"total_amount"`
+	Tier        *models.PriceTier  `json:"tier,omitempty"`       // 命中的阶梯
+	Tiers       []models.PriceTier `json:"tiers"`                // 该规格可用的全部阶梯
+	FlashSale   *models.FlashSale  `json:"flash_sale,omitempty"` // 进行中的限时抢购（优先于阶梯价）
+}
+
+// GetTiers 获取商品的所有阶梯价
+func (s *PricingService) GetTiers(productID uint) ([]models.PriceTier, error) {
+	var tiers []models.PriceTier
+	if err := database.GetDB().
+		Where("product_id = ?", productID).
+		Order("variant_id asc, min_quantity asc").
+		Find(&tiers).Error; err != nil {
+		return nil, err
+	}
+	return tiers, nil
+}
+
+// tiersFor 获取规格适用的阶梯：优先使用规格专属阶梯，没有时使用商品通用阶梯
+func (s *PricingService) tiersFor(productID, variantID uint) []models.PriceTier {
+	var tiers []models.PriceTier
+	database.GetDB().
+		Where("product_id = ? AND variant_id = ?", productID, variantID).
+		Order("min_quantity asc").
+		Find(&tiers)
+	if len(tiers) > 0 {
+		return tiers
+	}
+	database.GetDB().
+		Where("product_id = ? AND variant_id = 0", productID).
+		Order("min_quantity asc").
+		Find(&tiers)
+	return tiers
+}
+
+// Quote 计算指定规格和数量的价格
+// 进行中的限时抢购优先于阶梯价
+func (s *PricingService) Quote(variant *models.ProductVariant, quantity int) *PriceQuote {
+	quote := &PriceQuote{
+		ProductID: variant.ProductID,
+		VariantID: variant.ID,
+		Quantity:  quantity,
+		BasePrice: variant.Price,
+		UnitPrice: variant.Price,
+		Tiers:     s.tiersFor(variant.ProductID, variant.ID),
+	}
+	for i := range quote.Tiers {
+		if quote.Tiers[i].Matches(quantity) {
+			quote.Tier = &quote.Tiers[i]
+			quote.UnitPrice = quote.Tiers[i].UnitPrice
+			break
+		}
+	}
+	if sale := NewFlashSaleService().Running(variant.ProductID, variant.ID, time.Now()); sale != nil {
+		quote.FlashSale = sale
+		quote.Tier = nil
+		quote.UnitPrice = sale.SalePrice
+	}
+	// 支付以整数积分结算，总价取整后与实际扣款金额一致
+	quote.TotalAmount = float64(CreditAmount(quote.UnitPrice * float64(quantity)))
+	return quote
+}
+
+// CreditAmount 将金额换算为支付积分（1 积分 = 1 元，四舍五入到整数）
+// 发起支付、校验回调和对账都按此换算，避免各处取整方式不同导致金额不一致
+func CreditAmount(amount float64) int {
+	return int(math.Round(amount))
+}
+
+// ApplyToOrder 将报价写入订单（含阶梯留档）
+func (s *PricingService) ApplyToOrder(order *models.Order, quote *PriceQuote) {
+	order.UnitPrice = quote.UnitPrice
+	order.TotalAmount = quote.TotalAmount
+	if quote.Tier != nil {
+		tierID := quote.Tier.ID
+		order.PriceTierID = &tierID
+		order.PriceTierLabel = quote.Tier.Label()
+	}
+	if quote.FlashSale != nil {
+		saleID := quote.FlashSale.ID
+		order.FlashSaleID = &saleID
+	}
+}
+
+// ReplaceTiers 替换商品的全部阶梯价
+func (s *PricingService) ReplaceTiers(productID uint, tiers []models.PriceTier) error {
+	var ids []uint
+	if err := database.GetDB().Model(&models.ProductVariant{}).Where("product_id = ?", productID).Pluck("id", &ids).Error; err != nil {
+		return err
+	}
+	variantIDs := make(map[uint]bool, len(ids))
+	for _, id := range ids {
+		variantIDs[id] = true
+	}
+	if err := validateTiers(tiers, variantIDs); err != nil {
+		return err
+	}
+
+	return database.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("product_id = ?", productID).Delete(&models.PriceTier{}).Error; err != nil {
+			return err
+		}
+		if len(tiers) == 0 {
+			return nil
+		}
+		for i := range tiers {
+			tiers[i].ID = 0
+			tiers[i].ProductID = productID
+		}
+		return tx.Create(&tiers).Error
+	})
+}
+
+// validateTiers 校验阶梯区间合法、同一规格内互不重叠，且指定的规格属于商品（variantIDs 为商品的全部规格）
+func validateTiers(tiers []models.PriceTier, variantIDs map[uint]bool) error {
+	byVariant := make(map[uint][]models.PriceTier)
+	for _, tier := range tiers {
+		if tier.VariantID != 0 && !variantIDs[tier.VariantID] {
+			return ErrVariantNotFound
+		}
+		if tier.MinQuantity < 1 || tier.UnitPrice <= 0 {
+			return ErrInvalidPriceTier
+		}
+		if tier.MaxQuantity != 0 && tier.MaxQuantity < tier.MinQuantity {
+			return ErrInvalidPriceTier
+		}
+		byVariant[tier.VariantID] = append(byVariant[tier.VariantID], tier)
+	}
+
+	for _, group := range byVariant {
+		sort.Slice(group, func(i, j int) bool {
+			return group[i].MinQuantity < group[j].MinQuantity
+		})
+		for i := 1; i < len(group); i++ {
+			prev := group[i-1]
+			if prev.MaxQuantity == 0 || prev.MaxQuantity >= group[i].MinQuantity {
+				return ErrOverlappingPriceTiers
+			}
+		}
+	}
+	return nil
+}
+
+// 错误定义
+var (
+	ErrInvalidPriceTier      = &ServiceError{Code: "invalid_price_tier", Message: "阶梯价设置无效"}
+	ErrOverlappingPriceTiers = &ServiceError{Code: "overlapping_price_tiers", Message: "阶梯价数量区间存在重叠"}
+)
@@ -0,0 +1,107 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/nodeloc-faka/models"
+)
+
+func TestValidateTiers(t *testing.T) {
+	variants := map[uint]bool{1: true, 2: true}
+	tests := []struct {
+		name  string
+		tiers []models.PriceTier
+		want  error
+	}{
+		{"empty", nil, nil},
+		{"adjacent ranges", []models.PriceTier{
+			{MinQuantity: 1, MaxQuantity: 9, UnitPrice: 10},
+			{MinQuantity: 10, UnitPrice: 8},
+		}, nil},
+		{"same ranges on different variants", []models.PriceTier{
+			{VariantID: 1, MinQuantity: 5, UnitPrice: 8},
+			{VariantID: 2, MinQuantity: 5, UnitPrice: 7},
+		}, nil},
+		{"zero min quantity", []models.PriceTier{{MinQuantity: 0, UnitPrice: 10}}, ErrInvalidPriceTier},
+		{"zero price", []models.PriceTier{{MinQuantity: 1, UnitPrice: 0}}, ErrInvalidPriceTier},
+		{"max below min", []models.PriceTier{{MinQuantity: 5, MaxQuantity: 4, UnitPrice: 10}}, ErrInvalidPriceTier},
+		{"overlapping ranges", []models.PriceTier{
+			{MinQuantity: 1, MaxQuantity: 10, UnitPrice: 10},
+			{MinQuantity: 10, UnitPrice: 8},
+		}, ErrOverlappingPriceTiers},
+		{"range after open-ended tier", []models.PriceTier{
+			{MinQuantity: 10, UnitPrice: 8},
+			{MinQuantity: 20, UnitPrice: 6},
+		}, ErrOverlappingPriceTiers},
+		{"variant of another product", []models.PriceTier{{VariantID: 3, MinQuantity: 1, UnitPrice: 10}}, ErrVariantNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateTiers(tt.tiers, variants); !errors.Is(err, tt.want) {
+				t.Fatalf("validateTiers = %v，期望 %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestQuoteSelectsTier(t *testing.T) {
+	store := newTestStore(t)
+	product, standard := store.product(10, 0)
+	special := &models.ProductVariant{ProductID: product.ID, Name: "专属阶梯", Price: 12, IsActive: true}
+	store.create(special)
+
+	pricing := NewPricingService()
+	if err := pricing.ReplaceTiers(product.ID, []models.PriceTier{
+		{MinQuantity: 3, MaxQuantity: 9, UnitPrice: 9.5},
+		{MinQuantity: 10, UnitPrice: 8},
+		{VariantID: special.ID, MinQuantity: 2, UnitPrice: 11},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		variant   *models.ProductVariant
+		quantity  int
+		wantUnit  float64
+		wantTotal float64
+		wantTier  bool
+	}{
+		{"below first tier", standard, 2, 10, 20, false},
+		{"first tier rounds total to whole credits", standard, 3, 9.5, 29, true},
+		{"open-ended tier", standard, 12, 8, 96, true},
+		{"variant tiers replace product tiers", special, 10, 11, 110, true},
+		{"below variant tier", special, 1, 12, 12, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			quote := pricing.Quote(tt.variant, tt.quantity)
+			if quote.UnitPrice != tt.wantUnit || quote.TotalAmount != tt.wantTotal {
+				t.Fatalf("单价 %v 总价 %v，期望 %v / %v", quote.UnitPrice, quote.TotalAmount, tt.wantUnit, tt.wantTotal)
+			}
+			if (quote.Tier != nil) != tt.wantTier {
+				t.Fatalf("命中阶梯 = %v，期望 %v", quote.Tier != nil, tt.wantTier)
+			}
+		})
+	}
+
+	// 进行中的限时抢购优先于阶梯价
+	store.create(&models.FlashSale{ProductID: product.ID, SalePrice: 5, StartAt: time.Now().Add(-time.Minute), EndAt: time.Now().Add(time.Hour)})
+	quote := pricing.Quote(standard, 12)
+	if quote.FlashSale == nil || quote.Tier != nil || quote.TotalAmount != 60 {
+		t.Fatalf("抢购期间报价为 %+v，期望按抢购价 5 计算", quote)
+	}
+}
+
+func TestReplaceTiersRejectsForeignVariant(t *testing.T) {
+	store := newTestStore(t)
+	product, _ := store.product(10, 0)
+	_, foreign := store.product(10, 0)
+
+	err := NewPricingService().ReplaceTiers(product.ID, []models.PriceTier{{VariantID: foreign.ID, MinQuantity: 2, UnitPrice: 9}})
+	if !errors.Is(err, ErrVariantNotFound) {
+		t.Fatalf("ReplaceTiers = %v，期望 ErrVariantNotFound", err)
+	}
+}
@@ -1,8 +1,12 @@
 package services
 
 import (
+	"net/http"
+	"time"
+
 	"github.com/nodeloc-faka/database"
 	"github.com/nodeloc-faka/models"
+	"gorm.io/gorm"
 )
 
 // ProductService 商品服务
@@ -13,9 +17,25 @@ func NewProductService() *ProductService {
 	return &ProductService{}
 }
 
-// Create 创建商品
+// Create 创建商品（同时创建默认规格）
 func (s *ProductService) Create(product *models.Product) error {
-	return database.GetDB().Create(product).Error
+	return database.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Variants").Create(product).Error; err != nil {
+			return err
+		}
+		variant := models.ProductVariant{
+			ProductID: product.ID,
+			Name:      models.DefaultVariantName,
+			Price:     product.Price,
+			OrigPrice: product.OrigPrice,
+			IsActive:  true,
+		}
+		if err := tx.Create(&variant).Error; err != nil {
+			return err
+		}
+		product.Variants = []models.ProductVariant{variant}
+		return nil
+	})
 }
 
 // Update 更新商品
@@ -28,23 +48,96 @@ func (s *ProductService) Delete(id uint) error {
 	// 检查是否有未售出的卡密
 	var count int64
 	database.GetDB().Model(&models.CardKey{}).
-		Where("product_id = ? AND status = ?", id, models.CardKeyStatusAvailable).
+		Where("product_id = ? AND status IN ?", id, []int{models.CardKeyStatusAvailable, models.CardKeyStatusLocked}).
 		Count(&count)
 	if count > 0 {
 		return ErrProductHasCards
 	}
+	// 软删除（归档），保留规格、阶梯价和标签以便恢复，历史订单仍可展示商品信息
 	return database.GetDB().Delete(&models.Product{}, id).Error
 }
 
+// archivedProductSorts 已归档商品列表允许的排序字段
+var archivedProductSorts = listSorts{
+	table: "products",
+	columns: map[string]string{
+		"id":         "products.id",
+		"deleted_at": "products.deleted_at",
+		"name":       "products.name",
+	},
+	def: "-deleted_at",
+}
+
+// GetArchived 分页获取已归档的商品
+func (s *ProductService) GetArchived(query ListQuery) ([]models.Product, ListPage, error) {
+	var products []models.Product
+	db := database.GetDB().Unscoped().Model(&models.Product{}).Where("products.deleted_at IS NOT NULL")
+	page, err := paginate(db, query, archivedProductSorts, &products, func(db *gorm.DB) *gorm.DB {
+		return db.Preload("Category", includeArchived)
+	})
+	if err != nil {
+		return nil, ListPage{}, err
+	}
+	return products, page, nil
+}
+
+// Restore 恢复已归档的商品（所属分类需未归档）
+func (s *ProductService) Restore(id uint) error {
+	var product models.Product
+	if err := database.GetDB().Unscoped().Where("deleted_at IS NOT NULL").First(&product, id).Error; err != nil {
+		return ErrProductNotArchived
+	}
+
+	if _, err := NewCategoryService().FindByID(product.CategoryID); err != nil {
+		return ErrCategoryArchived
+	}
+
+	if err := database.GetDB().Unscoped().Model(&product).Update("deleted_at", nil).Error; err != nil {
+		return err
+	}
+	return s.UpdateStock(id)
+}
+
 // FindByID 根据ID查找商品
 func (s *ProductService) FindByID(id uint) (*models.Product, error) {
 	var product models.Product
-	if err := database.GetDB().Preload("Category").First(&product, id).Error; err != nil {
+	if err := database.GetDB().Preload("Category").
+		Preload("Variants", func(db *gorm.DB) *gorm.DB {
+			return db.Order("sort asc, id asc")
+		}).
+		Preload("Tags").
+		First(&product, id).Error; err != nil {
 		return nil, err
 	}
 	return &product, nil
 }
 
+// FindActiveByID 根据ID查找可售商品（只包含启用的规格）
+func (s *ProductService) FindActiveByID(id uint) (*models.Product, error) {
+	var product models.Product
+	if err := database.GetDB().Preload("Category").
+		Preload("Variants", activeVariants).
+		Preload("PriceTiers", func(db *gorm.DB) *gorm.DB {
+			return db.Order("variant_id asc, min_quantity asc")
+		}).
+		Preload("Tags").
+		Scopes(availableAt(time.Now())).
+		First(&product, id).Error; err != nil {
+		return nil, err
+	}
+	return &product, nil
+}
+
+// includeArchived 预加载时包含已归档的记录（历史订单需要展示已归档的商品）
+func includeArchived(db *gorm.DB) *gorm.DB {
+	return db.Unscoped()
+}
+
+// activeVariants 预加载启用的规格
+func activeVariants(db *gorm.DB) *gorm.DB {
+	return db.Where("is_active = ?", true).Order("sort asc, id asc")
+}
+
 // GetAll 获取所有商品
 func (s *ProductService) GetAll() ([]models.Product, error) {
 	var products []models.Product
@@ -54,11 +147,13 @@ func (s *ProductService) GetAll() ([]models.Product, error) {
 	return products, nil
 }
 
-// GetActive 获取启用的商品
+// GetActive 获取可售的商品
 func (s *ProductService) GetActive() ([]models.Product, error) {
 	var products []models.Product
 	if err := database.GetDB().Preload("Category").
-		Where("is_active = ?", true).
+		Preload("Variants", activeVariants).
+		Preload("Tags").
+		Scopes(availableAt(time.Now())).
 		Order("sort asc, id desc").
 		Find(&products).Error; err != nil {
 		return nil, err
@@ -66,11 +161,14 @@ func (s *ProductService) GetActive() ([]models.Product, error) {
 	return products, nil
 }
 
-// GetByCategory 根据分类获取商品
+// GetByCategory 根据分类获取商品（包含所有子孙分类下的商品）
 func (s *ProductService) GetByCategory(categoryID uint) ([]models.Product, error) {
 	var products []models.Product
 	if err := database.GetDB().Preload("Category").
-		Where("category_id = ? AND is_active = ?", categoryID, true).
+		Preload("Variants", activeVariants).
+		Preload("Tags").
+		Where("category_id IN ?", NewCategoryService().SubtreeIDs(categoryID)).
+		Scopes(availableAt(time.Now())).
 		Order("sort asc, id desc").
 		Find(&products).Error; err != nil {
 		return nil, err
@@ -78,40 +176,138 @@ func (s *ProductService) GetByCategory(categoryID uint) ([]models.Product, error
 	return products, nil
 }
 
-// GetWithPagination 分页获取商品
-func (s *ProductService) GetWithPagination(page, pageSize int) ([]models.Product, int64, error) {
+// GetByTag 根据标签获取商品
+func (s *ProductService) GetByTag(tagID uint) ([]models.Product, error) {
 	var products []models.Product
-	var total int64
-
-	db := database.GetDB().Model(&models.Product{})
-	db.Count(&total)
-
-	offset := (page - 1) * pageSize
-	if err := db.Preload("Category").
-		Order("sort asc, id desc").
-		Offset(offset).
-		Limit(pageSize).
+	if err := database.GetDB().Preload("Category").
+		Preload("Variants", activeVariants).
+		Preload("Tags").
+		Joins("JOIN product_tags ON product_tags.product_id = products.id").
+		Where("product_tags.tag_id = ?", tagID).
+		Scopes(availableAt(time.Now())).
+		Order("products.sort asc, products.id desc").
 		Find(&products).Error; err != nil {
-		return nil, 0, err
+		return nil, err
 	}
+	return products, nil
+}
 
-	return products, total, nil
+// SetTags 设置商品标签
+func (s *ProductService) SetTags(product *models.Product, tagIDs []uint) error {
+	tags, err := NewTagService().FindByIDs(tagIDs)
+	if err != nil {
+		return err
+	}
+	if err := database.GetDB().Model(product).Association("Tags").Replace(tags); err != nil {
+		return err
+	}
+	product.Tags = tags
+	return nil
+}
+
+// productSorts 商品列表允许的排序字段
+var productSorts = listSorts{
+	table: "products",
+	columns: map[string]string{
+		"id":          "products.id",
+		"sort":        "products.sort",
+		"name":        "products.name",
+		"price":       "products.price",
+		"stock_count": "products.stock_count",
+		"sales_count": "products.sales_count",
+		"created_at":  "products.created_at",
+	},
+	def: "sort,-id",
+}
+
+// GetWithPagination 分页获取商品
+func (s *ProductService) GetWithPagination(query ListQuery) ([]models.Product, ListPage, error) {
+	var products []models.Product
+	page, err := paginate(database.GetDB().Model(&models.Product{}), query, productSorts, &products, func(db *gorm.DB) *gorm.DB {
+		return db.Preload("Category").
+			Preload("Variants", func(db *gorm.DB) *gorm.DB {
+				return db.Order("sort asc, id asc")
+			}).
+			Preload("Tags")
+	})
+	if err != nil {
+		return nil, ListPage{}, err
+	}
+	return products, page, nil
 }
 
-// UpdateStock 更新库存
+// UpdateStock 更新库存（各规格库存及商品总库存）
 func (s *ProductService) UpdateStock(id uint) error {
+	var variants []models.ProductVariant
+	database.GetDB().Where("product_id = ?", id).Find(&variants)
+	for _, variant := range variants {
+		var variantCount int64
+		database.GetDB().Model(&models.CardKey{}).
+			Where("variant_id = ? AND status = ?", variant.ID, models.CardKeyStatusAvailable).
+			Count(&variantCount)
+		database.GetDB().Model(&models.ProductVariant{}).
+			Where("id = ?", variant.ID).
+			Update("stock_count", variantCount)
+	}
+
 	var count int64
 	database.GetDB().Model(&models.CardKey{}).
 		Where("product_id = ? AND status = ?", id, models.CardKeyStatusAvailable).
 		Count(&count)
-	
+
 	return database.GetDB().Model(&models.Product{}).
 		Where("id = ?", id).
 		Update("stock_count", count).Error
 }
 
-// IncrementSales 增加销量
-func (s *ProductService) IncrementSales(id uint, quantity int) error {
+// ProductStock 商品的可售卡密数量
+type ProductStock struct {
+	ProductID uint
+	Name      string
+	Available int64
+}
+
+// AvailableStock 统计未归档的自动发货商品的可售卡密数量（人工发货商品没有卡密库存）
+func (s *ProductService) AvailableStock() ([]ProductStock, error) {
+	var stocks []ProductStock
+	err := database.GetDB().Table("products AS p").
+		Select("p.id AS product_id, p.name, COUNT(c.id) AS available").
+		Joins("LEFT JOIN card_keys AS c ON c.product_id = p.id AND c.status = ? AND c.deleted_at IS NULL", models.CardKeyStatusAvailable).
+		Where("p.deleted_at IS NULL AND p.delivery_type <> ?", models.DeliveryTypeManual).
+		Group("p.id, p.name").
+		Scan(&stocks).Error
+	return stocks, err
+}
+
+// SyncPrice 将商品展示价格同步为启用规格中的最低价
+func (s *ProductService) SyncPrice(id uint) error {
+	return s.syncPrice(database.GetDB(), id)
+}
+
+// syncPrice 在指定事务中同步商品展示价格
+func (s *ProductService) syncPrice(tx *gorm.DB, id uint) error {
+	var variant models.ProductVariant
+	if err := tx.
+		Where("product_id = ? AND is_active = ?", id, true).
+		Order("price asc, id asc").
+		First(&variant).Error; err != nil {
+		return nil
+	}
+	return tx.Model(&models.Product{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"price":      variant.Price,
+			"orig_price": variant.OrigPrice,
+		}).Error
+}
+
+// IncrementSales 增加销量（商品及规格）
+func (s *ProductService) IncrementSales(id, variantID uint, quantity int) error {
+	if variantID > 0 {
+		database.GetDB().Model(&models.ProductVariant{}).
+			Where("id = ?", variantID).
+			UpdateColumn("sales_count", gorm.Expr("sales_count + ?", quantity))
+	}
 	return database.GetDB().Model(&models.Product{}).
 		Where("id = ?", id).
 		UpdateColumn("sales_count", database.GetDB().Raw("sales_count + ?", quantity)).
@@ -126,4 +322,10 @@ func (s *ProductService) Count() int64 {
 }
 
 // 错误定义
-var ErrProductHasCards = &ServiceError{Message: "该商品下有未售出的卡密，无法删除"}
+var (
+	ErrProductHasCards     = &ServiceError{Code: "product_has_cards", Status: http.StatusConflict, Message: "该商品下有未售出的卡密，无法删除"}
+	ErrProductNotArchived  = &ServiceError{Code: "product_not_archived", Status: http.StatusNotFound, Message: "商品不存在或未归档"}
+	ErrProductArchived     = &ServiceError{Code: "product_archived", Status: http.StatusConflict, Message: "所属商品已归档，请先恢复商品"}
+	ErrInvalidDeliveryType = &ServiceError{Code: "invalid_delivery_type", Message: "无效的发货方式"}
+	ErrProductPriceLocked  = &ServiceError{Code: "product_price_locked", Status: http.StatusConflict, Message: "商品有多个规格，价格取自规格，请修改规格价格"}
+)
@@ -0,0 +1,105 @@
+package services
+
+import (
+	"net/http"
+
+	"github.com/nodeloc-faka/models"
+)
+
+// 权限分组（与后台路由分组对应）
+const (
+	PermGroupCatalog  = "catalog"   // 分类、商品、规格、标签、阶梯价、限时抢购
+	PermGroupCardKeys = "card_keys" // 卡密
+	PermGroupOrders   = "orders"    // 订单及人工发货
+	PermGroupUsers    = "users"     // 用户
+	PermGroupSettings = "settings"  // 系统设置（支付配置除外）
+	PermGroupPayments = "payments"  // 支付配置
+	PermGroupStaff    = "staff"     // 后台人员及角色
+	PermGroupAudit    = "audit"     // 审计日志（只读）
+)
+
+// 权限级别，写权限包含读权限
+const (
+	PermRead  = "read"
+	PermWrite = "write"
+)
+
+// PermGroups 所有权限分组
+var PermGroups = []string{
+	PermGroupCatalog,
+	PermGroupCardKeys,
+	PermGroupOrders,
+	PermGroupUsers,
+	PermGroupSettings,
+	PermGroupPayments,
+	PermGroupStaff,
+	PermGroupAudit,
+}
+
+// rolePermissions 角色在各权限分组上的权限级别，未列出的分组无权限
+var rolePermissions = map[string]map[string]string{
+	models.RoleOwner: {
+		PermGroupCatalog:  PermWrite,
+		PermGroupCardKeys: PermWrite,
+		PermGroupOrders:   PermWrite,
+		PermGroupUsers:    PermWrite,
+		PermGroupSettings: PermWrite,
+		PermGroupPayments: PermWrite,
+		PermGroupStaff:    PermWrite,
+		PermGroupAudit:    PermRead,
+	},
+	models.RoleOperator: {
+		PermGroupCatalog:  PermWrite,
+		PermGroupCardKeys: PermWrite,
+		PermGroupOrders:   PermWrite,
+		PermGroupUsers:    PermWrite,
+		PermGroupSettings: PermRead,
+		PermGroupStaff:    PermRead,
+	},
+	models.RoleStockManager: {
+		PermGroupCatalog:  PermRead,
+		PermGroupCardKeys: PermWrite,
+		PermGroupOrders:   PermRead,
+	},
+	models.RoleSupport: {
+		PermGroupCatalog:  PermRead,
+		PermGroupCardKeys: PermRead,
+		PermGroupOrders:   PermWrite,
+		PermGroupUsers:    PermWrite,
+	},
+	models.RoleViewer: {
+		PermGroupCatalog:  PermRead,
+		PermGroupCardKeys: PermRead,
+		PermGroupOrders:   PermRead,
+		PermGroupUsers:    PermRead,
+	},
+}
+
+// ValidRole 检查角色是否存在
+func ValidRole(role string) bool {
+	_, ok := rolePermissions[role]
+	return ok
+}
+
+// HasPermission 检查角色是否拥有指定分组的权限
+func HasPermission(role, group, level string) bool {
+	granted, ok := rolePermissions[role][group]
+	if !ok {
+		return false
+	}
+	return granted == PermWrite || level == PermRead
+}
+
+// RolePermissions 获取角色在各分组上的权限级别
+func RolePermissions(role string) map[string]string {
+	permissions := make(map[string]string)
+	for group, level := range rolePermissions[role] {
+		permissions[group] = level
+	}
+	return permissions
+}
+
+// 错误定义
+var (
+	ErrAdminRequired = &ServiceError{Code: "admin_required", Status: http.StatusForbidden, Message: "需要管理员权限"}
+)
@@ -0,0 +1,431 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/nodeloc-faka/database"
+	"github.com/nodeloc-faka/logging"
+	"github.com/nodeloc-faka/models"
+	"gorm.io/gorm"
+)
+
+// 对账任务状态
+const (
+	ReconciliationRunning   = "running"
+	ReconciliationCompleted = "completed"
+	ReconciliationFailed    = "failed"
+)
+
+// 对账差异类型
+const (
+	ReconciliationIssuePaidNotRecorded    = "paid_not_recorded"   // 支付平台已收款，本地仍为待支付
+	ReconciliationIssuePaidButCancelled   = "paid_but_cancelled"  // 支付平台已收款，本地已取消
+	ReconciliationIssueAmountMismatch     = "amount_mismatch"     // 实收金额与订单金额不一致
+	ReconciliationIssueFeeMismatch        = "fee_mismatch"        // 手续费或商家实收积分与支付平台记录不一致
+	ReconciliationIssueMissingTransaction = "missing_transaction" // 本地已支付的 NodeLoc 订单没有交易ID
+	ReconciliationIssueUnpaidCompletion   = "unpaid_completion"   // 本地已支付或已完成，支付平台未收款
+	ReconciliationIssueQueryFailed        = "query_failed"        // 查询交易失败，需重新对账
+)
+
+// 差异处理结果
+const (
+	ReconciliationFixed   = "fixed"   // 已按支付平台记录修正订单
+	ReconciliationIgnored = "ignored" // 管理员确认无需处理
+	ReconciliationCleared = "cleared" // 处理时差异已不存在
+)
+
+// MaxReconciliationDays 单次对账的最大天数
+const MaxReconciliationDays = 31
+
+// reconciliationBatchSize 每批读取的订单数，每批结束后更新任务进度
+const reconciliationBatchSize = 100
+
+// paymentStatusCompleted 支付平台的已支付状态
+const paymentStatusCompleted = "completed"
+
+// reconciliationMu 同一进程内同时只运行一个对账任务
+var reconciliationMu sync.Mutex
+
+// ReconciliationService 支付对账服务
+type ReconciliationService struct {
+	paymentService *PaymentService
+	orderService   *OrderService
+}
+
+// NewReconciliationService 创建支付对账服务
+func NewReconciliationService() *ReconciliationService {
+	return &ReconciliationService{
+		paymentService: NewPaymentService(),
+		orderService:   NewOrderService(),
+	}
+}
+
+// ReconciliationIssueFilter 对账差异筛选条件
+type ReconciliationIssueFilter struct {
+	RunID    uint
+	Type     string
+	Resolved *bool // 为空表示全部
+}
+
+// apply 将筛选条件应用到查询
+func (f ReconciliationIssueFilter) apply(db *gorm.DB) *gorm.DB {
+	if f.RunID > 0 {
+		db = db.Where("run_id = ?", f.RunID)
+	}
+	if f.Type != "" {
+		db = db.Where("type = ?", f.Type)
+	}
+	if f.Resolved != nil {
+		if *f.Resolved {
+			db = db.Where("resolution <> ''")
+		} else {
+			db = db.Where("resolution = ''")
+		}
+	}
+	return db
+}
+
+// reconciliationRunSorts 对账任务列表允许的排序字段
+var reconciliationRunSorts = listSorts{
+	table: "reconciliation_runs",
+	columns: map[string]string{
+		"id":          "reconciliation_runs.id",
+		"started_at":  "reconciliation_runs.started_at",
+		"issue_count": "reconciliation_runs.issue_count",
+	},
+	def: "-id",
+}
+
+// reconciliationIssueSorts 对账差异列表允许的排序字段
+var reconciliationIssueSorts = listSorts{
+	table: "reconciliation_issues",
+	columns: map[string]string{
+		"id":       "reconciliation_issues.id",
+		"order_id": "reconciliation_issues.order_id",
+		"type":     "reconciliation_issues.type",
+	},
+	def: "id",
+}
+
+// Start 创建对账任务并在后台执行，from、to 为下单日期（含），startedBy 为发起的管理员
+func (s *ReconciliationService) Start(from, to string, startedBy *uint) (*models.ReconciliationRun, error) {
+	run, start, end, err := s.begin(from, to, startedBy)
+	if err != nil {
+		return nil, err
+	}
+	go func() {
+		defer reconciliationMu.Unlock()
+		s.execute(run, start, end)
+	}()
+	return run, nil
+}
+
+// ReconcileYesterday 核对前一天的订单，供定时任务调用；当天已由定时任务完成过的不再重复执行
+func (s *ReconciliationService) ReconcileYesterday() (*models.ReconciliationRun, error) {
+	if !s.paymentService.IsConfigured() {
+		return nil, nil
+	}
+	day := startOfDay(time.Now(), analyticsLocation()).AddDate(0, 0, -1).Format(dayLayout)
+
+	var count int64
+	if err := database.GetDB().Model(&models.ReconciliationRun{}).
+		Where("`from` = ? AND `to` = ? AND started_by IS NULL AND status = ?", day, day, ReconciliationCompleted).
+		Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count > 0 {
+		return nil, nil
+	}
+
+	run, start, end, err := s.begin(day, day, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer reconciliationMu.Unlock()
+	s.execute(run, start, end)
+	return run, nil
+}
+
+// begin 校验日期范围并创建任务记录，成功时持有 reconciliationMu，由调用方在任务结束后释放
+func (s *ReconciliationService) begin(from, to string, startedBy *uint) (*models.ReconciliationRun, time.Time, time.Time, error) {
+	if !s.paymentService.IsConfigured() {
+		return nil, time.Time{}, time.Time{}, ErrPaymentNotConfigured
+	}
+	location := analyticsLocation()
+	start, err := time.ParseInLocation(dayLayout, from, location)
+	if err != nil {
+		return nil, time.Time{}, time.Time{}, ErrInvalidReconciliationRange.WithDetails(map[string]interface{}{"field": "from"})
+	}
+	last, err := time.ParseInLocation(dayLayout, to, location)
+	if err != nil {
+		return nil, time.Time{}, time.Time{}, ErrInvalidReconciliationRange.WithDetails(map[string]interface{}{"field": "to"})
+	}
+	end := last.AddDate(0, 0, 1)
+	if start.After(last) || start.AddDate(0, 0, MaxReconciliationDays).Before(end) {
+		return nil, time.Time{}, time.Time{}, ErrInvalidReconciliationRange
+	}
+
+	if !reconciliationMu.TryLock() {
+		return nil, time.Time{}, time.Time{}, ErrReconciliationRunning
+	}
+	run := &models.ReconciliationRun{
+		From:      from,
+		To:        to,
+		Status:    ReconciliationRunning,
+		StartedBy: startedBy,
+		StartedAt: time.Now(),
+	}
+	if err := database.GetDB().Create(run).Error; err != nil {
+		reconciliationMu.Unlock()
+		return nil, time.Time{}, time.Time{}, err
+	}
+	return run, start, end, nil
+}
+
+// execute 执行对账并记录结果
+func (s *ReconciliationService) execute(run *models.ReconciliationRun, start, end time.Time) {
+	err := s.check(run, start, end)
+
+	now := time.Now()
+	run.FinishedAt = &now
+	run.Status = ReconciliationCompleted
+	if err != nil {
+		run.Status = ReconciliationFailed
+		run.Error = truncateRunes(err.Error(), 500)
+		logger.Error("对账任务失败", "run_id", run.ID, "error", err)
+	}
+	if err := database.GetDB().Model(run).Updates(map[string]interface{}{
+		"status":      run.Status,
+		"checked":     run.Checked,
+		"issue_count": run.IssueCount,
+		"error":       run.Error,
+		"finished_at": now,
+	}).Error; err != nil {
+		logger.Error("保存对账任务结果失败", "run_id", run.ID, "error", err)
+	}
+}
+
+// check 逐笔核对范围内使用 NodeLoc Payment 或有交易ID的订单
+func (s *ReconciliationService) check(run *models.ReconciliationRun, start, end time.Time) error {
+	var orders []models.Order
+	return database.GetDB().
+		Where("created_at >= ? AND created_at < ?", start, end).
+		Where("pay_method = ? OR transaction_id <> ''", PayMethodNodeLoc).
+		FindInBatches(&orders, reconciliationBatchSize, func(tx *gorm.DB, batch int) error {
+			for i := range orders {
+				run.Checked++
+				issue := s.inspect(&orders[i])
+				if issue == nil {
+					continue
+				}
+				issue.RunID = run.ID
+				if err := database.GetDB().Create(issue).Error; err != nil {
+					return err
+				}
+				run.IssueCount++
+			}
+			return database.GetDB().Model(run).Updates(map[string]interface{}{
+				"checked":     run.Checked,
+				"issue_count": run.IssueCount,
+			}).Error
+		}).Error
+}
+
+// inspect 查询订单的交易记录并与本地状态核对，一致时返回 nil
+func (s *ReconciliationService) inspect(order *models.Order) *models.ReconciliationIssue {
+	paidLocally := order.Status == models.OrderStatusPaid || order.Status == models.OrderStatusCompleted
+	issue := &models.ReconciliationIssue{
+		OrderID:       order.ID,
+		OrderNo:       order.OrderNo,
+		TransactionID: order.TransactionID,
+		LocalStatus:   order.Status,
+		LocalAmount:   order.TotalAmount,
+	}
+
+	if order.TransactionID == "" {
+		// 未能发起支付的订单会直接取消，只有已支付的订单缺少交易ID才是差异
+		if paidLocally && order.PayMethod == PayMethodNodeLoc {
+			issue.Type = ReconciliationIssueMissingTransaction
+			issue.Detail = "订单已支付但没有交易ID"
+			return issue
+		}
+		return nil
+	}
+
+	ctx := logging.With(context.Background(), logging.OrderNo(order.OrderNo), logging.TransactionID(order.TransactionID))
+	payment, err := s.paymentService.QueryPayment(ctx, order.TransactionID)
+	if err != nil {
+		issue.Type = ReconciliationIssueQueryFailed
+		issue.Detail = truncateRunes(err.Error(), 500)
+		return issue
+	}
+	issue.UpstreamStatus = payment.Status
+	issue.UpstreamAmount = payment.Amount
+	issue.UpstreamPlatformFee = payment.PlatformFee
+	issue.UpstreamMerchantPoints = payment.MerchantPoints
+
+	paidUpstream := payment.Status == paymentStatusCompleted
+	switch {
+	case paidUpstream && payment.Amount != CreditAmount(order.TotalAmount):
+		issue.Type = ReconciliationIssueAmountMismatch
+		issue.Detail = fmt.Sprintf("订单金额 %d，实收 %d", CreditAmount(order.TotalAmount), payment.Amount)
+	case paidUpstream && order.Status == models.OrderStatusPending:
+		issue.Type = ReconciliationIssuePaidNotRecorded
+		issue.Detail = "支付平台已收款，订单仍为待支付"
+	case paidUpstream && order.Status == models.OrderStatusCancelled:
+		issue.Type = ReconciliationIssuePaidButCancelled
+		issue.Detail = "支付平台已收款，订单已取消"
+	case paidUpstream && paidLocally && (payment.PlatformFee != order.PlatformFee || payment.MerchantPoints != order.MerchantPoints):
+		issue.Type = ReconciliationIssueFeeMismatch
+		issue.Detail = fmt.Sprintf("手续费 %d / %d，商家实收 %d / %d（本地 / 支付平台）",
+			order.PlatformFee, payment.PlatformFee, order.MerchantPoints, payment.MerchantPoints)
+	case !paidUpstream && paidLocally:
+		issue.Type = ReconciliationIssueUnpaidCompletion
+		issue.Detail = fmt.Sprintf("订单已支付，支付平台状态为 %s", payment.Status)
+	default:
+		return nil
+	}
+	return issue
+}
+
+// GetRuns 分页获取对账任务（默认最新的在前）
+func (s *ReconciliationService) GetRuns(query ListQuery) ([]models.ReconciliationRun, ListPage, error) {
+	var runs []models.ReconciliationRun
+	page, err := paginate(database.GetDB().Model(&models.ReconciliationRun{}), query, reconciliationRunSorts, &runs)
+	if err != nil {
+		return nil, ListPage{}, err
+	}
+	return runs, page, nil
+}
+
+// FindRun 获取对账任务
+func (s *ReconciliationService) FindRun(id uint) (*models.ReconciliationRun, error) {
+	var run models.ReconciliationRun
+	if err := database.GetDB().First(&run, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrReconciliationRunNotFound
+		}
+		return nil, err
+	}
+	return &run, nil
+}
+
+// GetIssues 分页获取对账差异
+func (s *ReconciliationService) GetIssues(filter ReconciliationIssueFilter, query ListQuery) ([]models.ReconciliationIssue, ListPage, error) {
+	var issues []models.ReconciliationIssue
+	db := filter.apply(database.GetDB().Model(&models.ReconciliationIssue{}))
+	page, err := paginate(db, query, reconciliationIssueSorts, &issues)
+	if err != nil {
+		return nil, ListPage{}, err
+	}
+	return issues, page, nil
+}
+
+// Fix 按支付平台的最新记录修正差异，修正走正常的订单状态流转：
+// 已收款的待支付或已取消订单标记为已支付并发货，手续费不一致时同步手续费
+// 未收款的已完成订单已经发货，不能直接取消，需人工核实后处理
+// 处理前会重新查询交易，差异已不存在时记为 cleared，差异类型发生变化时需重新对账
+func (s *ReconciliationService) Fix(id, adminID uint) (*models.ReconciliationIssue, error) {
+	issue, err := s.findOpenIssue(id)
+	if err != nil {
+		return nil, err
+	}
+	order, err := s.orderService.FindByID(issue.OrderID)
+	if err != nil {
+		return nil, ErrOrderNotFound
+	}
+
+	current := s.inspect(order)
+	if current == nil {
+		return s.resolve(issue, ReconciliationCleared, adminID)
+	}
+	if current.Type != issue.Type {
+		return nil, ErrReconciliationIssueChanged.WithDetails(map[string]interface{}{"type": current.Type})
+	}
+
+	switch issue.Type {
+	case ReconciliationIssuePaidNotRecorded, ReconciliationIssuePaidButCancelled:
+		_, err = s.orderService.SettleReconciled(order, current.UpstreamPlatformFee, current.UpstreamMerchantPoints)
+	case ReconciliationIssueFeeMismatch:
+		err = s.orderService.UpdatePaymentFees(order.ID, current.UpstreamPlatformFee, current.UpstreamMerchantPoints)
+	default:
+		return nil, ErrReconciliationIssueNotFixable
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s.resolve(issue, ReconciliationFixed, adminID)
+}
+
+// Ignore 确认差异无需处理
+func (s *ReconciliationService) Ignore(id, adminID uint) (*models.ReconciliationIssue, error) {
+	issue, err := s.findOpenIssue(id)
+	if err != nil {
+		return nil, err
+	}
+	return s.resolve(issue, ReconciliationIgnored, adminID)
+}
+
+// findOpenIssue 获取未处理的差异
+func (s *ReconciliationService) findOpenIssue(id uint) (*models.ReconciliationIssue, error) {
+	var issue models.ReconciliationIssue
+	if err := database.GetDB().First(&issue, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrReconciliationIssueNotFound
+		}
+		return nil, err
+	}
+	if issue.Resolution != "" {
+		return nil, ErrReconciliationIssueResolved
+	}
+	return &issue, nil
+}
+
+// resolve 记录差异的处理结果，并发处理时只有一次生效
+func (s *ReconciliationService) resolve(issue *models.ReconciliationIssue, resolution string, adminID uint) (*models.ReconciliationIssue, error) {
+	now := time.Now()
+	result := database.GetDB().Model(&models.ReconciliationIssue{}).
+		Where("id = ? AND resolution = ''", issue.ID).
+		Updates(map[string]interface{}{
+			"resolution":  resolution,
+			"resolved_by": adminID,
+			"resolved_at": now,
+		})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrReconciliationIssueResolved
+	}
+
+	issue.Resolution = resolution
+	issue.ResolvedBy = &adminID
+	issue.ResolvedAt = &now
+	return issue, nil
+}
+
+// truncateRunes 按字符数截断文本
+func truncateRunes(s string, max int) string {
+	runes := []rune(s)
+	if len(runes) <= max {
+		return s
+	}
+	return string(runes[:max])
+}
+
+// 错误定义
+var (
+	ErrPaymentNotConfigured          = &ServiceError{Code: "payment_not_configured", Status: http.StatusConflict, Message: "未配置 NodeLoc Payment"}
+	ErrInvalidReconciliationRange    = &ServiceError{Code: "invalid_reconciliation_range", Message: "对账日期范围无效（日期格式为 2006-01-02，最长 31 天）"}
+	ErrReconciliationRunning         = &ServiceError{Code: "reconciliation_running", Status: http.StatusConflict, Message: "已有对账任务正在运行"}
+	ErrReconciliationRunNotFound     = &ServiceError{Code: "reconciliation_run_not_found", Status: http.StatusNotFound, Message: "对账任务不存在"}
+	ErrReconciliationIssueNotFound   = &ServiceError{Code: "reconciliation_issue_not_found", Status: http.StatusNotFound, Message: "对账差异不存在"}
+	ErrReconciliationIssueResolved   = &ServiceError{Code: "reconciliation_issue_resolved", Status: http.StatusConflict, Message: "该差异已处理"}
+	ErrReconciliationIssueChanged    = &ServiceError{Code: "reconciliation_issue_changed", Status: http.StatusConflict, Message: "订单或交易状态已变化，请重新对账"}
+	ErrReconciliationIssueNotFixable = &ServiceError{Code: "reconciliation_issue_not_fixable", Status: http.StatusConflict, Message: "该差异无法自动修正，请人工核实后标记为已处理"}
+)
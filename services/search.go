@@ -0,0 +1,399 @@
+package services
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"html"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/nodeloc-faka/database"
+	"github.com/nodeloc-faka/models"
+	"gorm.io/gorm"
+)
+
+// SearchService 商品搜索服务
+type SearchService struct{}
+
+// NewSearchService 创建商品搜索服务
+func NewSearchService() *SearchService {
+	return &SearchService{}
+}
+
+// 搜索排序方式
+const (
+	SearchSortDefault   = "default"    // 后台排序值
+	SearchSortRelevance = "relevance"  // 相关度（有关键词时默认）
+	SearchSortPriceAsc  = "price_asc"  // 价格从低到高
+	SearchSortPriceDesc = "price_desc" // 价格从高到低
+	SearchSortSales     = "sales"      // 销量
+	SearchSortNewest    = "newest"     // 最新上架
+)
+
+// 搜索分页限制
+const (
+	DefaultSearchLimit = 20
+	MaxSearchLimit     = 100
+)
+
+// ngramTokenSize MySQL ngram 分词长度（默认 2），更短的关键词无法使用全文索引
+const ngramTokenSize = 2
+
+// ProductSearchQuery 商品搜索条件
+type ProductSearchQuery struct {
+	Keyword    string
+	CategoryID uint // 包含子孙分类
+	TagID      uint
+	MinPrice   *float64
+	MaxPrice   *float64
+	InStock    bool   // 仅显示有货（人工发货商品视为有货）
+	Sort       string // 见 SearchSort* 常量
+	Cursor     string // 上一页返回的 next_cursor
+	Limit      int
+}
+
+// ProductSearchHit 搜索结果条目
+type ProductSearchHit struct {
+	models.Product
+	Highlight map[string]string `json:"highlight,omitempty"` // 命中片段，关键词以 <em> 标记
+}
+
+// ProductSearchResult 搜索结果
+type ProductSearchResult struct {
+	Items      []ProductSearchHit `json:"items"`
+	Total      int64              `json:"total"`
+	NextCursor string             `json:"next_cursor,omitempty"`
+}
+
+// searchCursor 游标内容：上一页最后一条的排序值和ID
+type searchCursor struct {
+	Value float64 `json:"v"`
+	ID    uint    `json:"id"`
+}
+
+// searchRow 第一阶段查询结果
+type searchRow struct {
+	ID    uint
+	Value float64
+}
+
+// searchOrder 排序定义
+type searchOrder struct {
+	expr   string // 排序表达式
+	desc   bool   // 排序表达式是否降序
+	idDesc bool   // 相同排序值时 ID 是否降序
+}
+
+// SearchProducts 搜索上架商品
+// 先按条件和游标查出当前页的商品ID，再加载商品详情，保证游标分页稳定
+func (s *SearchService) SearchProducts(query ProductSearchQuery) (*ProductSearchResult, error) {
+	if query.Limit <= 0 {
+		query.Limit = DefaultSearchLimit
+	}
+	if query.Limit > MaxSearchLimit {
+		query.Limit = MaxSearchLimit
+	}
+
+	terms := searchTerms(query.Keyword)
+	order, orderArgs := s.resolveOrder(query.Sort, terms)
+
+	db := s.applyFilters(database.GetDB().Model(&models.Product{}), query, terms)
+
+	var total int64
+	if err := db.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, err
+	}
+
+	page := db.Session(&gorm.Session{})
+	if query.Cursor != "" {
+		cursor, err := decodeSearchCursor(query.Cursor)
+		if err != nil {
+			return nil, ErrInvalidSearchCursor
+		}
+		valueOp, idOp := ">", ">"
+		if order.desc {
+			valueOp = "<"
+		}
+		if order.idDesc {
+			idOp = "<"
+		}
+		args := append(append([]interface{}{}, orderArgs...), cursor.Value)
+		args = append(args, orderArgs...)
+		args = append(args, cursor.Value, cursor.ID)
+		page = page.Where("("+order.expr+" "+valueOp+" ?) OR ("+order.expr+" = ? AND products.id "+idOp+" ?)", args...)
+	}
+
+	var rows []searchRow
+	direction := func(desc bool) string {
+		if desc {
+			return " DESC"
+		}
+		return " ASC"
+	}
+	if err := page.
+		Select("products.id AS id, "+order.expr+" AS value", orderArgs...).
+		Order("value" + direction(order.desc)).
+		Order("products.id" + direction(order.idDesc)).
+		Limit(query.Limit + 1).
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	result := &ProductSearchResult{Items: []ProductSearchHit{}, Total: total}
+	if len(rows) > query.Limit {
+		rows = rows[:query.Limit]
+		last := rows[len(rows)-1]
+		result.NextCursor = encodeSearchCursor(searchCursor{Value: last.Value, ID: last.ID})
+	}
+	if len(rows) == 0 {
+		return result, nil
+	}
+
+	ids := make([]uint, len(rows))
+	for i, row := range rows {
+		ids[i] = row.ID
+	}
+	var products []models.Product
+	if err := database.GetDB().Preload("Category").
+		Preload("Variants", activeVariants).
+		Preload("Tags").
+		Where("id IN ?", ids).
+		Find(&products).Error; err != nil {
+		return nil, err
+	}
+	byID := make(map[uint]models.Product, len(products))
+	for _, product := range products {
+		byID[product.ID] = product
+	}
+
+	for _, id := range ids {
+		product, ok := byID[id]
+		if !ok {
+			continue
+		}
+		result.Items = append(result.Items, ProductSearchHit{
+			Product:   product,
+			Highlight: highlightProduct(&product, terms),
+		})
+	}
+
+	decorated := make([]*models.Product, len(result.Items))
+	for i := range result.Items {
+		decorated[i] = &result.Items[i].Product
+	}
+	NewFlashSaleService().Decorate(decorated...)
+
+	return result, nil
+}
+
+// applyFilters 应用搜索筛选条件
+func (s *SearchService) applyFilters(db *gorm.DB, query ProductSearchQuery, terms []string) *gorm.DB {
+	db = db.Scopes(availableAt(time.Now()))
+
+	if len(terms) > 0 {
+		if fulltext := booleanQuery(terms); fulltext != "" {
+			db = db.Where("MATCH(products.name, products.description) AGAINST(? IN BOOLEAN MODE)", fulltext)
+		}
+		// 短于 ngram 分词长度的关键词无法走全文索引，退回 LIKE 匹配
+		for _, term := range terms {
+			if utf8.RuneCountInString(term) < ngramTokenSize {
+				like := "%" + escapeLike(term) + "%"
+				db = db.Where("(products.name LIKE ? OR products.description LIKE ?)", like, like)
+			}
+		}
+	}
+	if query.CategoryID > 0 {
+		db = db.Where("products.category_id IN ?", NewCategoryService().SubtreeIDs(query.CategoryID))
+	}
+	if query.TagID > 0 {
+		db = db.Where("products.id IN (?)",
+			database.GetDB().Table("product_tags").Select("product_id").Where("tag_id = ?", query.TagID))
+	}
+	if query.MinPrice != nil {
+		db = db.Where("products.price >= ?", *query.MinPrice)
+	}
+	if query.MaxPrice != nil {
+		db = db.Where("products.price <= ?", *query.MaxPrice)
+	}
+	if query.InStock {
+		db = db.Where("(products.stock_count > 0 OR products.delivery_type = ?)", models.DeliveryTypeManual)
+	}
+	return db
+}
+
+// resolveOrder 解析排序方式
+func (s *SearchService) resolveOrder(sort string, terms []string) (searchOrder, []interface{}) {
+	if sort == "" {
+		sort = SearchSortDefault
+		if len(terms) > 0 {
+			sort = SearchSortRelevance
+		}
+	}
+
+	switch sort {
+	case SearchSortRelevance:
+		if fulltext := booleanQuery(terms); fulltext != "" {
+			return searchOrder{
+				expr:   "MATCH(products.name, products.description) AGAINST(? IN BOOLEAN MODE)",
+				desc:   true,
+				idDesc: true,
+			}, []interface{}{fulltext}
+		}
+	case SearchSortPriceAsc:
+		return searchOrder{expr: "products.price"}, nil
+	case SearchSortPriceDesc:
+		return searchOrder{expr: "products.price", desc: true, idDesc: true}, nil
+	case SearchSortSales:
+		return searchOrder{expr: "products.sales_count", desc: true, idDesc: true}, nil
+	case SearchSortNewest:
+		return searchOrder{expr: "products.id", desc: true, idDesc: true}, nil
+	}
+	return searchOrder{expr: "products.sort", idDesc: true}, nil
+}
+
+// searchTerms 拆分关键词
+func searchTerms(keyword string) []string {
+	// 去掉全文检索的布尔运算符，避免用户输入影响查询语义
+	cleaned := strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`+-<>()~*"@`, r) {
+			return ' '
+		}
+		return r
+	}, keyword)
+	return strings.Fields(cleaned)
+}
+
+// booleanQuery 构造全文检索布尔查询：每个关键词都必须作为短语出现
+func booleanQuery(terms []string) string {
+	var parts []string
+	for _, term := range terms {
+		if utf8.RuneCountInString(term) >= ngramTokenSize {
+			parts = append(parts, `+"`+term+`"`)
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// escapeLike 转义 LIKE 通配符
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// htmlTagPattern 匹配 HTML 标签
+var htmlTagPattern = regexp.MustCompile(`<[^>]*>`)
+
+// highlightRadius 命中片段中关键词前后保留的字符数
+const highlightRadius = 30
+
+// highlightProduct 生成商品名称和描述的命中片段
+func highlightProduct(product *models.Product, terms []string) map[string]string {
+	if len(terms) == 0 {
+		return nil
+	}
+	highlight := make(map[string]string)
+	if snippet, ok := highlightText(product.Name, terms, 0); ok {
+		highlight["name"] = snippet
+	}
+	plain := htmlTagPattern.ReplaceAllString(html.UnescapeString(product.Description), " ")
+	if snippet, ok := highlightText(plain, terms, highlightRadius); ok {
+		highlight["description"] = snippet
+	}
+	if len(highlight) == 0 {
+		return nil
+	}
+	return highlight
+}
+
+// highlightText 截取首个命中位置附近的文本并用 <em> 标记关键词
+// radius 为 0 时返回全文；返回内容已做 HTML 转义
+func highlightText(text string, terms []string, radius int) (string, bool) {
+	runes := []rune(text)
+	lower := []rune(strings.ToLower(text))
+	if len(lower) != len(runes) {
+		lower = runes
+	}
+
+	// 标记所有命中字符
+	marked := make([]bool, len(runes))
+	first := -1
+	for _, term := range terms {
+		needle := []rune(strings.ToLower(term))
+		if len(needle) == 0 {
+			continue
+		}
+		for i := 0; i+len(needle) <= len(lower); i++ {
+			if string(lower[i:i+len(needle)]) != string(needle) {
+				continue
+			}
+			for j := i; j < i+len(needle); j++ {
+				marked[j] = true
+			}
+			if first == -1 || i < first {
+				first = i
+			}
+		}
+	}
+	if first == -1 {
+		return "", false
+	}
+
+	start, end := 0, len(runes)
+	if radius > 0 {
+		if first-radius > start {
+			start = first - radius
+		}
+		if first+radius*2 < end {
+			end = first + radius*2
+		}
+	}
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("…")
+	}
+	inMark := false
+	for i := start; i < end; i++ {
+		if marked[i] && !inMark {
+			b.WriteString("<em>")
+			inMark = true
+		} else if !marked[i] && inMark {
+			b.WriteString("</em>")
+			inMark = false
+		}
+		b.WriteString(html.EscapeString(string(runes[i])))
+	}
+	if inMark {
+		b.WriteString("</em>")
+	}
+	if end < len(runes) {
+		b.WriteString("…")
+	}
+	return strings.Join(strings.Fields(b.String()), " "), true
+}
+
+// encodeSearchCursor 编码游标
+func encodeSearchCursor(cursor searchCursor) string {
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeSearchCursor 解码游标
+func decodeSearchCursor(raw string) (*searchCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return nil, err
+	}
+	var cursor searchCursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return nil, err
+	}
+	return &cursor, nil
+}
+
+// 错误定义
+var (
+	ErrInvalidSearchCursor = &ServiceError{Code: "invalid_search_cursor", Message: "分页游标无效"}
+	ErrInvalidPriceRange   = &ServiceError{Code: "invalid_price_range", Message: "价格区间无效"}
+)
@@ -1,7 +1,10 @@
 package services
 
 import (
+	"strings"
+
 	"github.com/nodeloc-faka/database"
+	"github.com/nodeloc-faka/i18n"
 	"github.com/nodeloc-faka/models"
 )
 
@@ -84,10 +87,43 @@ const (
 	SettingPaymentSecret   = "payment_secret"
 	SettingPaymentEnabled  = "payment_enabled"
 	SettingPaymentCallback = "payment_callback"
+	// 人工发货相关设置
+	SettingFulfillSLAMinutes = "fulfill_sla_minutes"
+	// 安全相关设置
+	SettingRequireAdmin2FA  = "require_admin_2fa"  // 所有后台人员（包括 OAuth 登录）必须启用两步验证
+	SettingMaxPendingOrders = "max_pending_orders" // 每个用户同时存在的待支付订单上限
+	// 多语言相关设置
+	SettingDefaultLocale = "default_locale" // 站点默认语言，无法从用户偏好和 Accept-Language 确定语言时使用
+	// 销售统计（由定时任务维护，不在后台设置中展示）
+	SettingAnalyticsRefreshedAt = "analytics_refreshed_at" // 每日销售汇总最近一次更新的时间
+	SettingAnalyticsTimezone    = "analytics_timezone"     // 每日销售汇总使用的时区，变化时重建汇总
 )
 
-// GetSiteSettings 获取网站设置
-func (s *SettingService) GetSiteSettings() map[string]string {
+// LocalizableSettings 可按语言分别设置的网站文案
+// 各语言的文案保存在 LocalizedSettingKey 返回的键下，未设置时使用原设置
+var LocalizableSettings = []string{
+	SettingSiteName,
+	SettingSiteDescription,
+	SettingSiteKeywords,
+	SettingAnnouncement,
+	SettingFooterText,
+}
+
+// LocalizedSettingKey 设置在指定语言下的键，例如 site_name_en、site_name_zh_cn
+func LocalizedSettingKey(key, locale string) string {
+	return key + "_" + strings.ToLower(strings.ReplaceAll(locale, "-", "_"))
+}
+
+// DefaultLocale 站点默认语言
+func (s *SettingService) DefaultLocale() string {
+	if locale := i18n.Normalize(s.Get(SettingDefaultLocale)); locale != "" {
+		return locale
+	}
+	return i18n.DefaultLocale
+}
+
+// GetSiteSettings 获取网站设置，可按语言设置的文案使用 locale 对应的版本
+func (s *SettingService) GetSiteSettings(locale string) map[string]string {
 	keys := []string{
 		SettingSiteName,
 		SettingSiteDescription,
@@ -103,5 +139,12 @@ func (s *SettingService) GetSiteSettings() map[string]string {
 	for _, key := range keys {
 		result[key] = s.Get(key)
 	}
+	for _, key := range LocalizableSettings {
+		if value := s.Get(LocalizedSettingKey(key, locale)); value != "" {
+			result[key] = value
+		}
+	}
+	result["locale"] = locale
+	result[SettingDefaultLocale] = s.DefaultLocale()
 	return result
 }
@@ -0,0 +1,144 @@
+package services
+
+import (
+	"net/http"
+
+	"github.com/nodeloc-faka/database"
+	"github.com/nodeloc-faka/models"
+	"gorm.io/gorm"
+)
+
+// TagService 商品标签服务
+type TagService struct{}
+
+// NewTagService 创建商品标签服务
+func NewTagService() *TagService {
+	return &TagService{}
+}
+
+// Create 创建标签
+func (s *TagService) Create(tag *models.Tag) error {
+	if s.nameTaken(tag.Name, 0) {
+		return ErrTagExists
+	}
+	return database.GetDB().Create(tag).Error
+}
+
+// Update 更新标签
+func (s *TagService) Update(tag *models.Tag) error {
+	if s.nameTaken(tag.Name, tag.ID) {
+		return ErrTagExists
+	}
+	return database.GetDB().Save(tag).Error
+}
+
+// Delete 删除标签（同时解除与商品的关联）
+func (s *TagService) Delete(id uint) error {
+	return database.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("DELETE FROM product_tags WHERE tag_id = ?", id).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.Tag{}, id).Error
+	})
+}
+
+// FindByID 根据ID查找标签
+func (s *TagService) FindByID(id uint) (*models.Tag, error) {
+	var tag models.Tag
+	if err := database.GetDB().First(&tag, id).Error; err != nil {
+		return nil, err
+	}
+	return &tag, nil
+}
+
+// FindByIDs 根据ID列表查找标签
+func (s *TagService) FindByIDs(ids []uint) ([]models.Tag, error) {
+	var tags []models.Tag
+	if len(ids) == 0 {
+		return tags, nil
+	}
+	if err := database.GetDB().Where("id IN ?", ids).Find(&tags).Error; err != nil {
+		return nil, err
+	}
+	if len(tags) != len(uniqueIDs(ids)) {
+		return nil, ErrTagNotFound
+	}
+	return tags, nil
+}
+
+// GetAll 获取所有标签及其商品数量
+func (s *TagService) GetAll() ([]models.Tag, error) {
+	return s.withCounts(false)
+}
+
+// GetActive 获取有上架商品的标签及其上架商品数量
+func (s *TagService) GetActive() ([]models.Tag, error) {
+	tags, err := s.withCounts(true)
+	if err != nil {
+		return nil, err
+	}
+	active := make([]models.Tag, 0, len(tags))
+	for _, tag := range tags {
+		if tag.ProductCount > 0 {
+			active = append(active, tag)
+		}
+	}
+	return active, nil
+}
+
+// withCounts 查询标签并统计关联商品数量
+func (s *TagService) withCounts(activeOnly bool) ([]models.Tag, error) {
+	var tags []models.Tag
+	if err := database.GetDB().Order("sort asc, id asc").Find(&tags).Error; err != nil {
+		return nil, err
+	}
+
+	var counts []struct {
+		TagID uint
+		Count int64
+	}
+	db := database.GetDB().Table("product_tags").
+		Select("product_tags.tag_id, COUNT(*) AS count").
+		Joins("JOIN products ON products.id = product_tags.product_id AND products.deleted_at IS NULL")
+	if activeOnly {
+		db = db.Where("products.is_active = ?", true)
+	}
+	if err := db.Group("product_tags.tag_id").Scan(&counts).Error; err != nil {
+		return nil, err
+	}
+
+	byTag := make(map[uint]int64, len(counts))
+	for _, count := range counts {
+		byTag[count.TagID] = count.Count
+	}
+	for i := range tags {
+		tags[i].ProductCount = byTag[tags[i].ID]
+	}
+	return tags, nil
+}
+
+// nameTaken 标签名是否已被其他标签使用
+func (s *TagService) nameTaken(name string, exceptID uint) bool {
+	var count int64
+	database.GetDB().Model(&models.Tag{}).Where("name = ? AND id <> ?", name, exceptID).Count(&count)
+	return count > 0
+}
+
+// uniqueIDs ID 去重
+func uniqueIDs(ids []uint) []uint {
+	seen := make(map[uint]bool, len(ids))
+	result := make([]uint, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			result = append(result, id)
+		}
+	}
+	return result
+}
+
+// 错误定义
+var (
+	ErrTagNotFound = &ServiceError{Code: "tag_not_found", Status: http.StatusNotFound, Message: "标签不存在"}
+	ErrTagExists   = &ServiceError{Code: "tag_exists", Status: http.StatusConflict, Message: "标签名称已存在"}
+)
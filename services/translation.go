@@ -0,0 +1,201 @@
+package services
+
+import (
+	"github.com/nodeloc-faka/database"
+	"github.com/nodeloc-faka/i18n"
+	"github.com/nodeloc-faka/models"
+	"gorm.io/gorm"
+)
+
+// LocalizedText 某个语言的名称和描述
+type LocalizedText struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// TranslationService 商品和分类的多语言文案服务
+// 商品和分类本身的名称、描述为站点默认语言，其他语言的版本单独保存，缺少时回退到原文
+type TranslationService struct{}
+
+// NewTranslationService 创建多语言文案服务
+func NewTranslationService() *TranslationService {
+	return &TranslationService{}
+}
+
+// GetProductTranslations 获取商品的所有语言版本
+func (s *TranslationService) GetProductTranslations(productID uint) ([]models.ProductTranslation, error) {
+	var translations []models.ProductTranslation
+	err := database.GetDB().Where("product_id = ?", productID).Order("locale asc").Find(&translations).Error
+	return translations, err
+}
+
+// GetCategoryTranslations 获取分类的所有语言版本
+func (s *TranslationService) GetCategoryTranslations(categoryID uint) ([]models.CategoryTranslation, error) {
+	var translations []models.CategoryTranslation
+	err := database.GetDB().Where("category_id = ?", categoryID).Order("locale asc").Find(&translations).Error
+	return translations, err
+}
+
+// SetProductTranslations 整体替换商品的语言版本，名称和描述都为空的语言视为删除
+func (s *TranslationService) SetProductTranslations(productID uint, translations map[string]LocalizedText) error {
+	if err := validateTranslations(translations); err != nil {
+		return err
+	}
+	return database.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("product_id = ?", productID).Delete(&models.ProductTranslation{}).Error; err != nil {
+			return err
+		}
+		for locale, text := range translations {
+			if text.Name == "" && text.Description == "" {
+				continue
+			}
+			if err := tx.Create(&models.ProductTranslation{
+				ProductID:   productID,
+				Locale:      locale,
+				Name:        text.Name,
+				Description: text.Description,
+			}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// SetCategoryTranslations 整体替换分类的语言版本，名称和描述都为空的语言视为删除
+func (s *TranslationService) SetCategoryTranslations(categoryID uint, translations map[string]LocalizedText) error {
+	if err := validateTranslations(translations); err != nil {
+		return err
+	}
+	return database.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("category_id = ?", categoryID).Delete(&models.CategoryTranslation{}).Error; err != nil {
+			return err
+		}
+		for locale, text := range translations {
+			if text.Name == "" && text.Description == "" {
+				continue
+			}
+			if err := tx.Create(&models.CategoryTranslation{
+				CategoryID:  categoryID,
+				Locale:      locale,
+				Name:        text.Name,
+				Description: text.Description,
+			}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// LocalizeProducts 将商品及其所属分类的名称和描述替换为 locale 版本
+func (s *TranslationService) LocalizeProducts(locale string, products ...*models.Product) {
+	var categories []*models.Category
+	for _, product := range products {
+		if product.Category != nil {
+			categories = append(categories, product.Category)
+		}
+	}
+	s.localizeProducts(locale, products)
+	s.LocalizeCategories(locale, categories...)
+}
+
+// LocalizeCategories 将分类（包括子分类、面包屑和分类下的商品）的名称和描述替换为 locale 版本
+func (s *TranslationService) LocalizeCategories(locale string, categories ...*models.Category) {
+	var all []*models.Category
+	var products []*models.Product
+	var collect func(category *models.Category)
+	collect = func(category *models.Category) {
+		all = append(all, category)
+		for i := range category.Children {
+			collect(&category.Children[i])
+		}
+		for i := range category.Products {
+			products = append(products, &category.Products[i])
+		}
+	}
+	for _, category := range categories {
+		collect(category)
+	}
+	s.localizeProducts(locale, products)
+
+	ids := make([]uint, 0, len(all))
+	for _, category := range all {
+		ids = append(ids, category.ID)
+		for _, crumb := range category.Path {
+			ids = append(ids, crumb.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return
+	}
+
+	var translations []models.CategoryTranslation
+	if err := database.GetDB().Where("category_id IN ? AND locale = ?", ids, locale).Find(&translations).Error; err != nil {
+		return
+	}
+	byID := make(map[uint]models.CategoryTranslation, len(translations))
+	for _, translation := range translations {
+		byID[translation.CategoryID] = translation
+	}
+	for _, category := range all {
+		if translation, ok := byID[category.ID]; ok {
+			applyLocalizedText(&category.Name, &category.Description, translation.Name, translation.Description)
+		}
+		for i := range category.Path {
+			if translation, ok := byID[category.Path[i].ID]; ok && translation.Name != "" {
+				category.Path[i].Name = translation.Name
+			}
+		}
+	}
+}
+
+// localizeProducts 替换商品自身的名称和描述
+func (s *TranslationService) localizeProducts(locale string, products []*models.Product) {
+	if len(products) == 0 {
+		return
+	}
+	ids := make([]uint, len(products))
+	for i, product := range products {
+		ids[i] = product.ID
+	}
+
+	var translations []models.ProductTranslation
+	if err := database.GetDB().Where("product_id IN ? AND locale = ?", ids, locale).Find(&translations).Error; err != nil {
+		return
+	}
+	byID := make(map[uint]models.ProductTranslation, len(translations))
+	for _, translation := range translations {
+		byID[translation.ProductID] = translation
+	}
+	for _, product := range products {
+		if translation, ok := byID[product.ID]; ok {
+			applyLocalizedText(&product.Name, &product.Description, translation.Name, translation.Description)
+		}
+	}
+}
+
+// applyLocalizedText 用非空的译文替换原文
+func applyLocalizedText(name, description *string, localizedName, localizedDescription string) {
+	if localizedName != "" {
+		*name = localizedName
+	}
+	if localizedDescription != "" {
+		*description = localizedDescription
+	}
+}
+
+// validateTranslations 检查语言是否受支持
+func validateTranslations(translations map[string]LocalizedText) error {
+	for locale := range translations {
+		if !i18n.Supported(locale) {
+			return ErrUnsupportedLocale.WithDetails(map[string]interface{}{"locale": locale})
+		}
+	}
+	return nil
+}
+
+// 错误定义
+var (
+	ErrUnsupportedLocale = &ServiceError{Code: "unsupported_locale", Message: "不支持的语言"}
+)
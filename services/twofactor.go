@@ -0,0 +1,241 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/nodeloc-faka/database"
+	"github.com/nodeloc-faka/models"
+	"github.com/nodeloc-faka/totp"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// TwoFactorService 两步验证服务（TOTP + 恢复码）
+type TwoFactorService struct {
+	settingService *SettingService
+}
+
+// NewTwoFactorService 创建两步验证服务
+func NewTwoFactorService() *TwoFactorService {
+	return &TwoFactorService{
+		settingService: NewSettingService(),
+	}
+}
+
+// RecoveryCodeCount 每次生成的恢复码数量
+const RecoveryCodeCount = 10
+
+// TwoFactorSetup 两步验证绑定信息
+type TwoFactorSetup struct {
+	Secret string `json:"secret"`
+	URL    string `json:"url"` // otpauth:// 地址，用于生成二维码
+}
+
+// Required 是否要求所有后台人员启用两步验证
+func (s *TwoFactorService) Required() bool {
+	return s.settingService.Get(SettingRequireAdmin2FA) == "true"
+}
+
+// Setup 生成新的 TOTP 密钥，启用前需调用 Enable 验证一次
+func (s *TwoFactorService) Setup(userID uint) (*TwoFactorSetup, error) {
+	user, err := NewUserService().FindByID(userID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+	if !user.IsStaff() {
+		return nil, ErrNotStaff
+	}
+	if user.TOTPEnabled {
+		return nil, ErrTwoFactorEnabled
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+	if err := database.GetDB().Model(user).Updates(map[string]interface{}{
+		"totp_secret":    secret,
+		"totp_last_step": 0,
+	}).Error; err != nil {
+		return nil, err
+	}
+
+	issuer := s.settingService.Get(SettingSiteName)
+	if issuer == "" {
+		issuer = "NodeLoc Faka"
+	}
+	return &TwoFactorSetup{Secret: secret, URL: totp.URL(issuer, user.Username, secret)}, nil
+}
+
+// Enable 验证首个验证码后启用两步验证，返回恢复码（只展示一次）
+func (s *TwoFactorService) Enable(userID uint, code string) ([]string, error) {
+	var codes []string
+	err := database.GetDB().Transaction(func(tx *gorm.DB) error {
+		user, err := lockUser(tx, userID)
+		if err != nil {
+			return err
+		}
+		if user.TOTPEnabled {
+			return ErrTwoFactorEnabled
+		}
+		if user.TOTPSecret == "" {
+			return ErrTwoFactorNotSetup
+		}
+
+		step, ok := totp.Validate(user.TOTPSecret, code, time.Now(), user.TOTPLastStep)
+		if !ok {
+			return ErrInvalidTwoFactorCode
+		}
+		if err := tx.Model(user).Updates(map[string]interface{}{
+			"totp_enabled":   true,
+			"totp_last_step": step,
+		}).Error; err != nil {
+			return err
+		}
+
+		codes, err = replaceRecoveryCodes(tx, userID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// Disable 验证后关闭两步验证
+func (s *TwoFactorService) Disable(userID uint, code string) error {
+	if err := s.Verify(userID, code); err != nil {
+		return err
+	}
+	return s.Reset(userID)
+}
+
+// Reset 清除两步验证（管理员为丢失设备的人员重置）
+func (s *TwoFactorService) Reset(userID uint) error {
+	return database.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+			"totp_secret":    "",
+			"totp_enabled":   false,
+			"totp_last_step": 0,
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error
+	})
+}
+
+// RegenerateRecoveryCodes 验证后重新生成恢复码，旧恢复码全部失效
+func (s *TwoFactorService) RegenerateRecoveryCodes(userID uint, code string) ([]string, error) {
+	if err := s.Verify(userID, code); err != nil {
+		return nil, err
+	}
+	var codes []string
+	err := database.GetDB().Transaction(func(tx *gorm.DB) error {
+		var err error
+		codes, err = replaceRecoveryCodes(tx, userID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// Verify 校验 TOTP 验证码或恢复码，恢复码使用后失效
+func (s *TwoFactorService) Verify(userID uint, code string) error {
+	code = strings.TrimSpace(code)
+	return database.GetDB().Transaction(func(tx *gorm.DB) error {
+		user, err := lockUser(tx, userID)
+		if err != nil {
+			return err
+		}
+		if !user.TOTPEnabled {
+			return ErrTwoFactorNotEnabled
+		}
+
+		if len(code) == totp.Digits {
+			step, ok := totp.Validate(user.TOTPSecret, code, time.Now(), user.TOTPLastStep)
+			if !ok {
+				return ErrInvalidTwoFactorCode
+			}
+			return tx.Model(user).Update("totp_last_step", step).Error
+		}
+
+		result := tx.Model(&models.RecoveryCode{}).
+			Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, hashRecoveryCode(code)).
+			Update("used_at", time.Now())
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrInvalidTwoFactorCode
+		}
+		return nil
+	})
+}
+
+// RemainingRecoveryCodes 获取未使用的恢复码数量
+func (s *TwoFactorService) RemainingRecoveryCodes(userID uint) int64 {
+	var count int64
+	database.GetDB().Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND used_at IS NULL", userID).
+		Count(&count)
+	return count
+}
+
+// lockUser 加锁读取用户
+func lockUser(tx *gorm.DB, userID uint) (*models.User, error) {
+	var user models.User
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, userID).Error; err != nil {
+		return nil, ErrUserNotFound
+	}
+	return &user, nil
+}
+
+// replaceRecoveryCodes 删除旧恢复码并生成新的恢复码
+func replaceRecoveryCodes(tx *gorm.DB, userID uint) ([]string, error) {
+	if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, RecoveryCodeCount)
+	records := make([]models.RecoveryCode, RecoveryCodeCount)
+	for i := range codes {
+		b := make([]byte, 5)
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+		raw := strings.ToLower(base32.StdEncoding.EncodeToString(b))
+		codes[i] = raw[:4] + "-" + raw[4:]
+		records[i] = models.RecoveryCode{UserID: userID, CodeHash: hashRecoveryCode(codes[i])}
+	}
+	if err := tx.Create(&records).Error; err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// hashRecoveryCode 计算恢复码哈希（忽略大小写和分隔符）
+func hashRecoveryCode(code string) string {
+	normalized := strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
+
+// 错误定义
+var (
+	ErrTwoFactorEnabled          = &ServiceError{Code: "two_factor_enabled", Status: http.StatusConflict, Message: "两步验证已启用"}
+	ErrTwoFactorNotSetup         = &ServiceError{Code: "two_factor_not_setup", Message: "请先生成两步验证密钥"}
+	ErrTwoFactorNotEnabled       = &ServiceError{Code: "two_factor_not_enabled", Message: "未启用两步验证"}
+	ErrInvalidTwoFactorCode      = &ServiceError{Code: "invalid_two_factor_code", Message: "验证码错误"}
+	ErrTwoFactorRequired         = &ServiceError{Code: "two_factor_required", Status: http.StatusForbidden, Message: "请先完成两步验证"}
+	ErrTwoFactorSetupRequired    = &ServiceError{Code: "two_factor_setup_required", Status: http.StatusForbidden, Message: "请先启用两步验证"}
+	ErrTwoFactorAttemptsExceeded = &ServiceError{Code: "two_factor_attempts_exceeded", Status: http.StatusUnauthorized, Message: "验证失败次数过多，请重新登录"}
+	ErrTwoFactorSelfNotEnabled   = &ServiceError{Code: "two_factor_self_not_enabled", Status: http.StatusConflict, Message: "请先为自己的账号启用两步验证，再要求所有后台人员启用"}
+)
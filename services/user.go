@@ -1,10 +1,14 @@
 package services
 
 import (
+	"net/http"
 	"time"
 
 	"github.com/nodeloc-faka/database"
+	"github.com/nodeloc-faka/i18n"
 	"github.com/nodeloc-faka/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // UserService 用户服务
@@ -36,6 +40,7 @@ func (s *UserService) CreateOrUpdate(nodeLocID int, username, name, email, avata
 			AvatarURL:   avatarURL,
 			TrustLevel:  trustLevel,
 			IsAdmin:     isFirstUser, // 第一个用户自动成为管理员
+			Role:        firstUserRole(isFirstUser),
 			LastLoginAt: &now,
 		}
 		if err := database.GetDB().Create(&user).Error; err != nil {
@@ -57,6 +62,30 @@ func (s *UserService) CreateOrUpdate(nodeLocID int, username, name, email, avata
 	return &user, nil
 }
 
+// createLocal 创建本地后台人员用户（不关联 NodeLoc 账号）
+// 本地用户使用负数 NodeLocID，避免与 NodeLoc 用户冲突
+func (s *UserService) createLocal(tx *gorm.DB, username, role string) (*models.User, error) {
+	var minID int
+	if err := tx.Model(&models.User{}).
+		Select("COALESCE(MIN(node_loc_id), 0)").
+		Where("node_loc_id < 0").
+		Scan(&minID).Error; err != nil {
+		return nil, err
+	}
+
+	user := &models.User{
+		NodeLocID: minID - 1,
+		Username:  username,
+		Name:      username,
+		IsAdmin:   role != "",
+		Role:      role,
+	}
+	if err := tx.Create(user).Error; err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
 // FindByID 根据ID查找用户
 func (s *UserService) FindByID(id uint) (*models.User, error) {
 	var user models.User
@@ -84,23 +113,56 @@ func (s *UserService) GetAll() ([]models.User, error) {
 	return users, nil
 }
 
-// GetWithPagination 分页获取用户
-func (s *UserService) GetWithPagination(page, pageSize int) ([]models.User, int64, error) {
-	var users []models.User
-	var total int64
-
-	db := database.GetDB().Model(&models.User{})
-	db.Count(&total)
+// UserFilter 后台用户列表筛选条件
+type UserFilter struct {
+	TrustLevel    *int
+	IsBlocked     *bool
+	IsAdmin       *bool
+	LastLoginFrom *time.Time // 最后登录时间（含）
+	LastLoginTo   *time.Time // 最后登录时间（不含）
+}
 
-	offset := (page - 1) * pageSize
-	if err := db.Order("id desc").
-		Offset(offset).
-		Limit(pageSize).
-		Find(&users).Error; err != nil {
-		return nil, 0, err
+// apply 应用筛选条件
+func (f UserFilter) apply(db *gorm.DB) *gorm.DB {
+	if f.TrustLevel != nil {
+		db = db.Where("users.trust_level = ?", *f.TrustLevel)
 	}
+	if f.IsBlocked != nil {
+		db = db.Where("users.is_blocked = ?", *f.IsBlocked)
+	}
+	if f.IsAdmin != nil {
+		db = db.Where("users.is_admin = ?", *f.IsAdmin)
+	}
+	if f.LastLoginFrom != nil {
+		db = db.Where("users.last_login_at >= ?", *f.LastLoginFrom)
+	}
+	if f.LastLoginTo != nil {
+		db = db.Where("users.last_login_at < ?", *f.LastLoginTo)
+	}
+	return db
+}
 
-	return users, total, nil
+// userSorts 用户列表允许的排序字段
+var userSorts = listSorts{
+	table: "users",
+	columns: map[string]string{
+		"id":            "users.id",
+		"created_at":    "users.created_at",
+		"last_login_at": "COALESCE(users.last_login_at, '1970-01-01')",
+		"trust_level":   "users.trust_level",
+		"username":      "users.username",
+	},
+	def: "-id",
+}
+
+// GetWithPagination 分页获取用户
+func (s *UserService) GetWithPagination(filter UserFilter, query ListQuery) ([]models.User, ListPage, error) {
+	var users []models.User
+	page, err := paginate(filter.apply(database.GetDB().Model(&models.User{})), query, userSorts, &users)
+	if err != nil {
+		return nil, ListPage{}, err
+	}
+	return users, page, nil
 }
 
 // Block 封禁用户
@@ -113,6 +175,14 @@ func (s *UserService) Unblock(id uint) error {
 	return database.GetDB().Model(&models.User{}).Where("id = ?", id).Update("is_blocked", false).Error
 }
 
+// SetLocale 设置用户的语言偏好，为空表示跟随浏览器
+func (s *UserService) SetLocale(id uint, locale string) error {
+	if locale != "" && !i18n.Supported(locale) {
+		return ErrUnsupportedLocale
+	}
+	return database.GetDB().Model(&models.User{}).Where("id = ?", id).Update("locale", locale).Error
+}
+
 // UpdateBalance 更新余额
 func (s *UserService) UpdateBalance(id uint, amount float64) error {
 	return database.GetDB().Model(&models.User{}).
@@ -137,9 +207,64 @@ func (s *UserService) IsBlocked(id uint) bool {
 	return user.IsBlocked
 }
 
-// SetAdmin 设置用户为管理员
+// SetAdmin 设置用户为管理员（兼容旧接口：设为管理员时默认授予运营角色，取消时撤销角色）
 func (s *UserService) SetAdmin(id uint, isAdmin bool) error {
-	return database.GetDB().Model(&models.User{}).Where("id = ?", id).Update("is_admin", isAdmin).Error
+	user, err := s.FindByID(id)
+	if err != nil {
+		return ErrUserNotFound
+	}
+	if !isAdmin {
+		return s.SetRole(id, "")
+	}
+	if user.IsStaff() {
+		return nil
+	}
+	return s.SetRole(id, models.RoleOperator)
+}
+
+// SetRole 设置用户的后台角色，role 为空表示撤销后台权限
+// 最后一个所有者不能被降级
+func (s *UserService) SetRole(id uint, role string) error {
+	if role != "" && !ValidRole(role) {
+		return ErrInvalidRole
+	}
+	return database.GetDB().Transaction(func(tx *gorm.DB) error {
+		var user models.User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, id).Error; err != nil {
+			return ErrUserNotFound
+		}
+		if user.Role == models.RoleOwner && role != models.RoleOwner {
+			var owners []models.User
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+				Where("role = ?", models.RoleOwner).
+				Find(&owners).Error; err != nil {
+				return err
+			}
+			if len(owners) <= 1 {
+				return ErrLastOwner
+			}
+		}
+		return tx.Model(&user).Updates(map[string]interface{}{
+			"role":     role,
+			"is_admin": role != "",
+		}).Error
+	})
+}
+
+// IsLastOwner 检查用户是否为唯一的所有者
+func (s *UserService) IsLastOwner(id uint) bool {
+	var owners []uint
+	database.GetDB().Model(&models.User{}).Where("role = ?", models.RoleOwner).Pluck("id", &owners)
+	return len(owners) == 1 && owners[0] == id
+}
+
+// GetStaff 获取所有后台人员
+func (s *UserService) GetStaff() ([]models.User, error) {
+	var users []models.User
+	if err := database.GetDB().Where("role <> ''").Order("id asc").Find(&users).Error; err != nil {
+		return nil, err
+	}
+	return users, nil
 }
 
 // IsAdmin 检查用户是否是管理员
@@ -148,5 +273,20 @@ func (s *UserService) IsAdmin(id uint) bool {
 	if err := database.GetDB().First(&user, id).Error; err != nil {
 		return false
 	}
-	return user.IsAdmin
+	return user.IsStaff()
 }
+
+// firstUserRole 第一个用户自动成为所有者
+func firstUserRole(isFirstUser bool) string {
+	if isFirstUser {
+		return models.RoleOwner
+	}
+	return ""
+}
+
+// 错误定义
+var (
+	ErrUserNotFound = &ServiceError{Code: "user_not_found", Status: http.StatusNotFound, Message: "用户不存在"}
+	ErrInvalidRole  = &ServiceError{Code: "invalid_role", Message: "角色不存在"}
+	ErrLastOwner    = &ServiceError{Code: "last_owner", Status: http.StatusConflict, Message: "至少需要保留一个所有者"}
+)
@@ -0,0 +1,100 @@
+package services
+
+import (
+	"net/http"
+
+	"github.com/nodeloc-faka/database"
+	"github.com/nodeloc-faka/models"
+)
+
+// VariantService 商品规格服务
+type VariantService struct{}
+
+// NewVariantService 创建商品规格服务
+func NewVariantService() *VariantService {
+	return &VariantService{}
+}
+
+// Create 创建规格
+func (s *VariantService) Create(variant *models.ProductVariant) error {
+	if err := database.GetDB().Create(variant).Error; err != nil {
+		return err
+	}
+	return NewProductService().SyncPrice(variant.ProductID)
+}
+
+// Update 更新规格
+func (s *VariantService) Update(variant *models.ProductVariant) error {
+	if err := database.GetDB().Save(variant).Error; err != nil {
+		return err
+	}
+	return NewProductService().SyncPrice(variant.ProductID)
+}
+
+// Delete 删除规格
+func (s *VariantService) Delete(id uint) error {
+	variant, err := s.FindByID(id)
+	if err != nil {
+		return ErrVariantNotFound
+	}
+
+	var count int64
+	database.GetDB().Model(&models.ProductVariant{}).Where("product_id = ?", variant.ProductID).Count(&count)
+	if count <= 1 {
+		return ErrLastVariant
+	}
+
+	cardKeyService := NewCardKeyService()
+	if cardKeyService.CountByVariant(id, models.CardKeyStatusAvailable)+
+		cardKeyService.CountByVariant(id, models.CardKeyStatusLocked) > 0 {
+		return ErrVariantHasCards
+	}
+
+	if err := database.GetDB().Delete(&models.ProductVariant{}, id).Error; err != nil {
+		return err
+	}
+	database.GetDB().Where("variant_id = ?", id).Delete(&models.PriceTier{})
+	return NewProductService().SyncPrice(variant.ProductID)
+}
+
+// FindByID 根据ID查找规格
+func (s *VariantService) FindByID(id uint) (*models.ProductVariant, error) {
+	var variant models.ProductVariant
+	if err := database.GetDB().First(&variant, id).Error; err != nil {
+		return nil, err
+	}
+	return &variant, nil
+}
+
+// GetByProduct 获取商品的所有规格
+func (s *VariantService) GetByProduct(productID uint) ([]models.ProductVariant, error) {
+	var variants []models.ProductVariant
+	if err := database.GetDB().
+		Where("product_id = ?", productID).
+		Order("sort asc, id asc").
+		Find(&variants).Error; err != nil {
+		return nil, err
+	}
+	return variants, nil
+}
+
+// Resolve 获取下单使用的规格
+// variantID 为 0 时返回商品排序最靠前的启用规格
+func (s *VariantService) Resolve(productID, variantID uint) (*models.ProductVariant, error) {
+	var variant models.ProductVariant
+	db := database.GetDB().Where("product_id = ? AND is_active = ?", productID, true)
+	if variantID > 0 {
+		db = db.Where("id = ?", variantID)
+	}
+	if err := db.Order("sort asc, id asc").First(&variant).Error; err != nil {
+		return nil, ErrVariantNotFound
+	}
+	return &variant, nil
+}
+
+// 错误定义
+var (
+	ErrVariantNotFound = &ServiceError{Code: "variant_not_found", Status: http.StatusNotFound, Message: "商品规格不存在"}
+	ErrVariantHasCards = &ServiceError{Code: "variant_has_cards", Status: http.StatusConflict, Message: "该规格下有未售出的卡密，无法删除"}
+	ErrLastVariant     = &ServiceError{Code: "last_variant", Status: http.StatusConflict, Message: "商品至少需要保留一个规格"}
+)